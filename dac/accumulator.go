@@ -0,0 +1,379 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Accumulator-based revocation is the alternative to re-signing every non-revoked user each epoch.
+// The revocation authority signs once the user's public key along with a random revocation handle
+// (SignNonRevoke with the handle in place of the epoch) and issues the witness that the handle is in the accumulator.
+// Revoking a user changes the accumulator value, the other users update their witnesses from the published update,
+// and the revoked user cannot.
+// The accumulator value is in G1 and its public key is in G2.
+
+// AccumulatorWitness is the witness that the revocation handle is in the accumulator
+type AccumulatorWitness struct {
+	Handle *FP256BN.BIG
+	W      *FP256BN.ECP
+}
+
+// AccumulatorUpdate is published by the revocation authority when it revokes a handle.
+// Value is the new accumulator value.
+type AccumulatorUpdate struct {
+	Revoked *FP256BN.BIG
+	Value   *FP256BN.ECP
+}
+
+// AccumulatorProof is a NIZK that a user holds the revocation authority's signature of the user's public key
+// along with a revocation handle, and that the handle is in the accumulator (i.e. is not revoked).
+type AccumulatorProof struct {
+	// the proof of the signature with the hidden handle, its challenge is that of the whole proof
+	revocation RevocationProof
+	resX       *FP256BN.BIG
+	resRho     *FP256BN.BIG
+	wBar       *FP256BN.ECP
+	dBar       *FP256BN.ECP
+}
+
+// GenerateAccumulator generates the accumulator key pair and the initial (empty) accumulator value
+func GenerateAccumulator(prg *amcl.RAND) (sk SK, pk PK, value *FP256BN.ECP) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	sk = FP256BN.Randomnum(q, prg)
+	pk = FP256BN.ECP2_generator().Mul(sk)
+	value = FP256BN.ECP_generator().Mul(FP256BN.Randomnum(q, prg))
+
+	return
+}
+
+// AccumulatorIssue generates a fresh revocation handle and its witness for the current accumulator value.
+// Needs the accumulator secret key.
+// The handle has to be signed along with the user's public key with SignNonRevoke.
+func AccumulatorIssue(prg *amcl.RAND, sk SK, value *FP256BN.ECP) (witness AccumulatorWitness) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	witness.Handle = FP256BN.Randomnum(q, prg)
+	witness.W = value.Mul(accumulatorExponent(sk, witness.Handle))
+
+	return
+}
+
+// AccumulatorRevoke removes the handle from the accumulator.
+// Needs the accumulator secret key.
+// Returns the update to be published, its Value is the new accumulator value.
+func AccumulatorRevoke(sk SK, value *FP256BN.ECP, handle *FP256BN.BIG) (update AccumulatorUpdate) {
+	update.Revoked = FP256BN.NewBIGcopy(handle)
+	update.Value = value.Mul(accumulatorExponent(sk, handle))
+
+	return
+}
+
+// Update brings the witness up to date with the published updates (applied in order).
+// Returns error, and leaves the witness unchanged, if the witness's own handle is revoked.
+func (witness *AccumulatorWitness) Update(updates ...AccumulatorUpdate) (e error) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	w := FP256BN.NewECP()
+	w.Copy(witness.W)
+
+	for index, update := range updates {
+		if bigEqual(update.Revoked, witness.Handle) {
+			return fmt.Errorf("AccumulatorWitness.Update: the handle is revoked by update %d", index)
+		}
+
+		// W' = (W / V')^(1 / (revoked - handle))
+		w.Sub(update.Value)
+		w = w.Mul(bigInverse(bigMinusMod(update.Revoked, witness.Handle, q), q))
+	}
+
+	witness.W = w
+
+	return
+}
+
+// Verify checks that the handle is in the accumulator with the value under the accumulator public key
+func (witness *AccumulatorWitness) Verify(pk PK, value *FP256BN.ECP) (e error) {
+	if e = validatePoints(witness.W, value); e != nil {
+		return fmt.Errorf("AccumulatorWitness.Verify: invalid witness or value: %v", e)
+	}
+	if e = validatePointInGroup(pk, false); e != nil {
+		return fmt.Errorf("AccumulatorWitness.Verify: invalid public key: %v", e)
+	}
+
+	// e(W, pk * g2^handle) == e(V, g2)
+	g2 := FP256BN.ECP2_generator()
+	pkHandle := pointMultiply(g2, witness.Handle)
+	pointAdd(pkHandle, pk)
+
+	if !FP256BN.Fexp(ate(witness.W, pkHandle)).Equals(FP256BN.Fexp(ate(value, g2))) {
+		return fmt.Errorf("AccumulatorWitness.Verify: verification failed")
+	}
+
+	return
+}
+
+// AccumulatorProve generates a NIZK of the signature of user's public key along with the handle
+// and of the handle being in the accumulator with the value
+func AccumulatorProve(prg *amcl.RAND, signature GrothSignature, witness AccumulatorWitness, sk SK, skNym SK, h interface{}, ys []interface{}, value *FP256BN.ECP) (proof AccumulatorProof, e error) {
+	return accumulatorProveBound(prg, signature, witness, sk, skNym, h, ys, value, binding{}, nil, nil)
+}
+
+// AccumulatorProveWithMessage is AccumulatorProve that also signs the message m and the (optional) verifier's nonce.
+// Verify the proof with VerifyWithMessage.
+func AccumulatorProveWithMessage(prg *amcl.RAND, signature GrothSignature, witness AccumulatorWitness, sk SK, skNym SK, h interface{}, ys []interface{}, value *FP256BN.ECP, m []byte, nonce []byte) (proof AccumulatorProof, e error) {
+	return accumulatorProveBound(prg, signature, witness, sk, skNym, h, ys, value, binding{}, m, nonce)
+}
+
+// AccumulatorProveWithParameters is AccumulatorProve that takes h and the revocation y-values from the system parameters (see SystemParameters).
+// Verify the proof with AccumulatorProof.VerifyWithParameters.
+func AccumulatorProveWithParameters(prg *amcl.RAND, signature GrothSignature, witness AccumulatorWitness, sk SK, skNym SK, value *FP256BN.ECP, params *SystemParameters, context string) (proof AccumulatorProof, e error) {
+	if params.RevocationPK == nil {
+		return proof, fmt.Errorf("AccumulatorProve: system parameters do not include revocation values")
+	}
+
+	return accumulatorProveBound(prg, signature, witness, sk, skNym, params.H, params.RevocationYs, value, binding{params.Fingerprint(), context}, nil, nil)
+}
+
+// accumulatorProveBound is AccumulatorProve that binds the proof to the system parameters fingerprint and the context,
+// and signs the message and the nonce
+func accumulatorProveBound(prg *amcl.RAND, signature GrothSignature, witness AccumulatorWitness, sk SK, skNym SK, h interface{}, ys []interface{}, value *FP256BN.ECP, b binding, m []byte, nonce []byte) (proof AccumulatorProof, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			proof, e = AccumulatorProof{}, r.(error)
+		}
+	}()
+
+	if len(ys) < 2 {
+		return proof, fmt.Errorf("AccumulatorProve: two y-values are required, got %d", len(ys))
+	}
+	if witness.W == nil || witness.Handle == nil {
+		return proof, fmt.Errorf("AccumulatorProve: witness is incomplete")
+	}
+	if e = validatePoints(h, value); e != nil {
+		return proof, fmt.Errorf("AccumulatorProve: invalid h or value: %v", e)
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	g1 := generatorSameGroup(h)
+	g2 := generatorSameGroup(ys[0])
+
+	// the signature part, with the handle hidden: e(g1, g2)^-rX joins com2
+	rX := FP256BN.Randomnum(q, prg)
+	prover := revocationCommit(prg, signature, h, ys, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg))
+	prover.com2.Mul(FP256BN.Fexp(ate(pointMultiply(g1, bigNegate(rX, q)), g2)))
+
+	// the accumulator part: randomized witness wBar = W^rho and dBar = V^rho * wBar^-handle (= wBar^sk)
+	rho := FP256BN.Randomnum(q, prg)
+	rRho := FP256BN.Randomnum(q, prg)
+
+	proof.wBar = witness.W.Mul(rho)
+	proof.dBar = productOfExponents(value, rho, proof.wBar, bigNegate(witness.Handle, q)).(*FP256BN.ECP)
+	comAcc := productOfExponents(value, rRho, proof.wBar, bigNegate(rX, q))
+
+	t := newTranscript(_ProtocolAccumulator, b)
+	appendAccumulator(t, h, ys, prover.sigmaPrime.r, prover.sigmaPrime.s, prover.com1, prover.com2, prover.com3, value, proof.wBar, proof.dBar, comAcc)
+	t.append("m", m)
+	t.append("nonce", nonce)
+
+	c := t.challenge(q)
+
+	proof.revocation = prover.respond(c, sk, skNym)
+	proof.revocation.fingerprint = b.fingerprint
+
+	proof.resX = FP256BN.Modmul(c, witness.Handle, q)
+	proof.resX = proof.resX.Plus(rX)
+	proof.resX.Mod(q)
+
+	proof.resRho = FP256BN.Modmul(c, rho, q)
+	proof.resRho = proof.resRho.Plus(rRho)
+	proof.resRho.Mod(q)
+
+	return
+}
+
+// Verify validates the NIZK that the user is not revoked in the accumulator with the value
+func (proof *AccumulatorProof) Verify(pkNym PK, h interface{}, pkRev PK, ys []interface{}, accPk PK, value *FP256BN.ECP) (e error) {
+	return proof.verify(pkNym, h, pkRev, ys, accPk, value, "", nil, nil)
+}
+
+// VerifyWithParameters is Verify that takes h and the revocation values from the system parameters (see SystemParameters).
+func (proof *AccumulatorProof) VerifyWithParameters(pkNym PK, accPk PK, value *FP256BN.ECP, params *SystemParameters, context string) (e error) {
+	if params.RevocationPK == nil {
+		return fmt.Errorf("AccumulatorProof.Verify: system parameters do not include revocation values")
+	}
+	if e = params.checkFingerprint(proof.revocation.fingerprint); e != nil {
+		return fmt.Errorf("AccumulatorProof.Verify: %v", e)
+	}
+
+	return proof.verify(pkNym, params.H, params.RevocationPK, params.RevocationYs, accPk, value, context, nil, nil)
+}
+
+// VerifyWithMessage validates the proof generated with AccumulatorProveWithMessage.
+// The message and the nonce have to be the ones the proof was generated with.
+func (proof *AccumulatorProof) VerifyWithMessage(pkNym PK, h interface{}, pkRev PK, ys []interface{}, accPk PK, value *FP256BN.ECP, m []byte, nonce []byte) (e error) {
+	return proof.verify(pkNym, h, pkRev, ys, accPk, value, "", m, nonce)
+}
+
+// verify is Verify for the proof bound to the context (and to the fingerprint it carries) that signs the message and the nonce
+func (proof *AccumulatorProof) verify(pkNym PK, h interface{}, pkRev PK, ys []interface{}, accPk PK, value *FP256BN.ECP, context string, m []byte, nonce []byte) (e error) {
+	if e = validatePoints(proof.wBar, proof.dBar, value); e != nil {
+		return fmt.Errorf("AccumulatorProof.Verify: invalid proof or accumulator value: %v", e)
+	}
+	if e = validatePointInGroup(accPk, false); e != nil {
+		return fmt.Errorf("AccumulatorProof.Verify: invalid accumulator public key: %v", e)
+	}
+
+	// dBar = wBar^sk, hence wBar is a witness for the hidden handle (up to the exponent rho)
+	if !FP256BN.Fexp(ate(proof.wBar, accPk)).Equals(FP256BN.Fexp(ate(proof.dBar, FP256BN.ECP2_generator()))) {
+		return fmt.Errorf("AccumulatorProof.Verify: verification failed early at e(wBar, accPk) == e(dBar, g2)")
+	}
+
+	com1, com2, com3, e := proof.revocation.commitmentsWithEpoch(pkNym, proof.resX, h, pkRev, ys)
+	if e != nil {
+		return fmt.Errorf("AccumulatorProof.Verify: %v", e)
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	comAcc := productOfExponents(value, proof.resRho, proof.wBar, bigNegate(proof.resX, q))
+	pointAdd(comAcc, pointMultiply(proof.dBar, bigNegate(proof.revocation.c, q)))
+
+	t := newTranscript(_ProtocolAccumulator, binding{proof.revocation.fingerprint, context})
+	appendAccumulator(t, h, ys, proof.revocation.rPrime, proof.revocation.sPrime, com1, com2, com3, value, proof.wBar, proof.dBar, comAcc)
+	t.append("m", m)
+	t.append("nonce", nonce)
+
+	if !bigEqual(t.challenge(q), proof.revocation.c) {
+		return fmt.Errorf("AccumulatorProof.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// accumulatorExponent computes 1 / (sk + handle)
+func accumulatorExponent(sk SK, handle *FP256BN.BIG) *FP256BN.BIG {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	sum := FP256BN.NewBIGcopy(sk)
+	sum = sum.Plus(handle)
+	sum.Mod(q)
+
+	return bigInverse(sum, q)
+}
+
+// appendAccumulator adds the public values and the commitments of the accumulator proof to the transcript
+func appendAccumulator(t *transcript, h interface{}, ys []interface{}, r, s interface{}, com1 *FP256BN.FP12, com2 *FP256BN.FP12, com3 interface{}, value, wBar, dBar, comAcc interface{}) {
+	t.appendPoint("h", h)
+	t.appendPoints("ys", ys)
+	t.appendPoint("rPrime", r)
+	t.appendPoint("sPrime", s)
+	t.appendFP("com1", com1)
+	t.appendFP("com2", com2)
+	t.appendPoint("com3", com3)
+	t.appendPoint("value", value)
+	t.appendPoint("wBar", wBar)
+	t.appendPoint("dBar", dBar)
+	t.appendPoint("comAcc", comAcc)
+}
+
+type accumulatorProofMarshal struct {
+	Revocation []byte
+	ResX       []byte
+	ResRho     []byte
+	WBar       []byte
+	DBar       []byte
+}
+
+// ToBytes marshals the NIZK object using ASN1 encoding
+func (proof *AccumulatorProof) ToBytes() (result []byte) {
+	var marshal accumulatorProofMarshal
+
+	marshal.Revocation = proof.revocation.ToBytes()
+	marshal.ResX = bigToBytes(proof.resX)
+	marshal.ResRho = bigToBytes(proof.resRho)
+	marshal.WBar = PointToBytes(proof.wBar)
+	marshal.DBar = PointToBytes(proof.dBar)
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// AccumulatorProofFromBytes un-marshals the NIZK object using ASN1 encoding
+// Panics if the input is malformed, see ParseAccumulatorProof for a version that returns error.
+func AccumulatorProofFromBytes(input []byte) (proof *AccumulatorProof) {
+	proof, e := ParseAccumulatorProof(input)
+	if e != nil {
+		panic("un-marshalling accumulator proof failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseAccumulatorProof un-marshals and validates the NIZK object using ASN1 encoding
+func ParseAccumulatorProof(input []byte) (proof *AccumulatorProof, e error) {
+	var marshal accumulatorProofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorProof: %v", e)
+	}
+
+	revocation, e := ParseRevocationProof(marshal.Revocation)
+	if e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorProof: %v", e)
+	}
+
+	proof = &AccumulatorProof{revocation: *revocation}
+
+	scalars, e := bigsFromBytes(marshal.ResX, marshal.ResRho)
+	if e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorProof: %v", e)
+	}
+	proof.resX, proof.resRho = scalars[0], scalars[1]
+
+	wBar, e := pointFromBytesInGroup(marshal.WBar, true, false)
+	if e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorProof: wBar: %v", e)
+	}
+	dBar, e := pointFromBytesInGroup(marshal.DBar, true, false)
+	if e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorProof: dBar: %v", e)
+	}
+	proof.wBar, proof.dBar = wBar.(*FP256BN.ECP), dBar.(*FP256BN.ECP)
+
+	return
+}
+
+type accumulatorUpdateMarshal struct {
+	Revoked []byte
+	Value   []byte
+}
+
+// ToBytes marshals the update using ASN1 encoding
+func (update *AccumulatorUpdate) ToBytes() (result []byte) {
+	result, _ = asn1.Marshal(accumulatorUpdateMarshal{bigToBytes(update.Revoked), PointToBytes(update.Value)})
+
+	return
+}
+
+// ParseAccumulatorUpdate un-marshals and validates the update using ASN1 encoding
+func ParseAccumulatorUpdate(input []byte) (update *AccumulatorUpdate, e error) {
+	var marshal accumulatorUpdateMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorUpdate: %v", e)
+	}
+
+	revoked, e := bigFromBytes(marshal.Revoked)
+	if e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorUpdate: %v", e)
+	}
+	value, e := pointFromBytesInGroup(marshal.Value, true, false)
+	if e != nil {
+		return nil, fmt.Errorf("ParseAccumulatorUpdate: value: %v", e)
+	}
+
+	return &AccumulatorUpdate{revoked, value.(*FP256BN.ECP)}, nil
+}
@@ -0,0 +1,389 @@
+package dac
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// accumulatorSetup holds the revocation authority's and a user's values for the accumulator tests
+type accumulatorSetup struct {
+	h        interface{}
+	ys       []interface{}
+	revokeSk SK
+	revokePk PK
+	accSk    SK
+	accPk    PK
+	value    *FP256BN.ECP
+}
+
+// helper that generates the authority's keys and the initial accumulator
+func makeAccumulatorSetup(prg *amcl.RAND) (setup accumulatorSetup) {
+	const YsNum = 10
+
+	setup.h = getH(prg)
+	setup.ys = GenerateYs(!hFirst, YsNum, prg)
+	setup.revokeSk, setup.revokePk = MakeGroth(prg, !hFirst, setup.ys).Generate()
+	setup.accSk, setup.accPk, setup.value = GenerateAccumulator(prg)
+
+	return
+}
+
+// helper that enrolls a user: issues the handle with its witness and signs it along with user's public key
+func (setup *accumulatorSetup) enroll(prg *amcl.RAND) (userSk SK, skNym SK, pkNym PK, witness AccumulatorWitness, signature GrothSignature) {
+	userSk, userPk := GenerateKeys(prg, map[bool]int{true: 0, false: 1}[hFirst])
+
+	witness = AccumulatorIssue(prg, setup.accSk, setup.value)
+	signature = SignNonRevoke(prg, setup.revokeSk, userPk, witness.Handle, setup.ys)
+
+	skNym, pkNym = GenerateNymKeys(prg, userSk, setup.h)
+
+	return
+}
+
+func (setup *accumulatorSetup) prove(prg *amcl.RAND, signature GrothSignature, witness AccumulatorWitness, userSk SK, skNym SK) AccumulatorProof {
+	proof, _ := AccumulatorProve(prg, signature, witness, userSk, skNym, setup.h, setup.ys, setup.value)
+	return proof
+}
+
+func (setup *accumulatorSetup) verify(proof AccumulatorProof, pkNym PK) error {
+	return proof.Verify(pkNym, setup.h, setup.revokePk, setup.ys, setup.accPk, setup.value)
+}
+
+// helper that generates system parameters with the revocation authority's values of the setup
+func (setup *accumulatorSetup) parameters() (params *SystemParameters) {
+	params, _, _ = generateSystemParameters(getNewRand(SEED))
+	params.H, params.RevocationPK, params.RevocationYs, params.AuditorPK = setup.h, setup.revokePk, setup.ys, nil
+	if e := params.Validate(); e != nil {
+		panic(e)
+	}
+
+	return
+}
+
+// Tests
+
+func TestAccumulator(t *testing.T) {
+	for _, first := range []bool{true, false} {
+
+		hFirst = first
+
+		t.Run(fmt.Sprintf("h in g%d", map[bool]int{true: 1, false: 2}[first]), func(t *testing.T) {
+			for _, test := range []func(*testing.T){
+				testAccumulatorHappyPath,
+				testAccumulatorRevoke,
+				testAccumulatorVerificationFail,
+				testAccumulatorMessage,
+				testAccumulatorProveErrors,
+				testAccumulatorParameters,
+				testAccumulatorMarshal,
+				testAccumulatorParseRejectsMalformed,
+			} {
+				t.Run(funcToString(reflect.ValueOf(test)), test)
+			}
+		})
+	}
+}
+
+func testAccumulatorHappyPath(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	userSk, skNym, pkNym, witness, signature := setup.enroll(prg)
+
+	assert.NilError(t, witness.Verify(setup.accPk, setup.value))
+
+	proof := setup.prove(prg, signature, witness, userSk, skNym)
+	assert.NilError(t, setup.verify(proof, pkNym))
+}
+
+func testAccumulatorRevoke(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	aliceSk, aliceSkNym, alicePkNym, aliceWitness, aliceSignature := setup.enroll(prg)
+	bobSk, bobSkNym, bobPkNym, bobWitness, bobSignature := setup.enroll(prg)
+	_, _, _, carolWitness, _ := setup.enroll(prg)
+
+	staleProof := setup.prove(prg, bobSignature, bobWitness, bobSk, bobSkNym)
+
+	// bob and then carol are revoked
+	updates := []AccumulatorUpdate{AccumulatorRevoke(setup.accSk, setup.value, bobWitness.Handle)}
+	updates = append(updates, AccumulatorRevoke(setup.accSk, updates[0].Value, carolWitness.Handle))
+	setup.value = updates[1].Value
+
+	// alice updates the witness and proves against the new value
+	assert.ErrorContains(t, aliceWitness.Verify(setup.accPk, setup.value), "verification failed")
+	assert.NilError(t, aliceWitness.Update(updates...))
+	assert.NilError(t, aliceWitness.Verify(setup.accPk, setup.value))
+	assert.NilError(t, setup.verify(setup.prove(prg, aliceSignature, aliceWitness, aliceSk, aliceSkNym), alicePkNym))
+
+	// a user enrolled after the revocations needs no updates
+	lateSk, lateSkNym, latePkNym, lateWitness, lateSignature := setup.enroll(prg)
+	assert.NilError(t, setup.verify(setup.prove(prg, lateSignature, lateWitness, lateSk, lateSkNym), latePkNym))
+
+	// bob cannot update, and neither the old proof nor the old witness work
+	unchanged := bobWitness.W
+	assert.ErrorContains(t, bobWitness.Update(updates...), "revoked by update 0")
+	assert.Check(t, bobWitness.W.Equals(unchanged))
+
+	assert.ErrorContains(t, setup.verify(staleProof, bobPkNym), "verification failed")
+	assert.ErrorContains(t, setup.verify(setup.prove(prg, bobSignature, bobWitness, bobSk, bobSkNym), bobPkNym), "verification failed")
+}
+
+func testAccumulatorVerificationFail(t *testing.T) {
+	type TestCase string
+	const (
+		WrongNym       TestCase = "pkNym of another user"
+		WrongHandle    TestCase = "witness of another handle"
+		WrongAccPk     TestCase = "wrong accumulator key"
+		WrongRevokePk  TestCase = "wrong revocation key"
+		EpochSignature TestCase = "signature without handle"
+		RevocationPart TestCase = "tampered handle response"
+		MissingAccPk   TestCase = "missing accumulator key"
+	)
+
+	for _, tc := range []TestCase{WrongNym, WrongHandle, WrongAccPk, WrongRevokePk, EpochSignature, RevocationPart, MissingAccPk} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			setup := makeAccumulatorSetup(prg)
+			userSk, skNym, pkNym, witness, signature := setup.enroll(prg)
+			otherSk, _, _, otherWitness, _ := setup.enroll(prg)
+
+			expected := "verification failed"
+			var proof AccumulatorProof
+			switch tc {
+			case WrongNym:
+				proof = setup.prove(prg, signature, witness, userSk, skNym)
+				_, pkNym = GenerateNymKeys(prg, otherSk, setup.h)
+			case WrongHandle:
+				proof = setup.prove(prg, signature, otherWitness, userSk, skNym)
+			case WrongAccPk:
+				proof = setup.prove(prg, signature, witness, userSk, skNym)
+				_, setup.accPk, _ = GenerateAccumulator(prg)
+			case WrongRevokePk:
+				proof = setup.prove(prg, signature, witness, userSk, skNym)
+				_, setup.revokePk = MakeGroth(prg, !hFirst, setup.ys).Generate()
+			case EpochSignature:
+				userPk := pointMultiply(generatorSameGroup(setup.ys[0]), userSk)
+				signature = SignNonRevoke(prg, setup.revokeSk, userPk, FP256BN.NewBIGint(0x13), setup.ys)
+				proof = setup.prove(prg, signature, witness, userSk, skNym)
+			case RevocationPart:
+				proof = setup.prove(prg, signature, witness, userSk, skNym)
+				proof.resX = bigMinusMod(proof.resX, FP256BN.NewBIGint(1), FP256BN.NewBIGints(FP256BN.CURVE_Order))
+			case MissingAccPk:
+				proof = setup.prove(prg, signature, witness, userSk, skNym)
+				setup.accPk = nil
+				expected = "invalid accumulator public key"
+			}
+
+			assert.ErrorContains(t, setup.verify(proof, pkNym), expected)
+		})
+	}
+}
+
+func testAccumulatorMessage(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	userSk, skNym, pkNym, witness, signature := setup.enroll(prg)
+
+	m, nonce := []byte("transaction"), []byte("nonce")
+	proof, e := AccumulatorProveWithMessage(prg, signature, witness, userSk, skNym, setup.h, setup.ys, setup.value, m, nonce)
+	assert.NilError(t, e)
+
+	verify := func(m []byte, nonce []byte) error {
+		return proof.VerifyWithMessage(pkNym, setup.h, setup.revokePk, setup.ys, setup.accPk, setup.value, m, nonce)
+	}
+
+	assert.NilError(t, verify(m, nonce))
+	assert.ErrorContains(t, verify([]byte("another transaction"), nonce), "verification failed")
+	assert.ErrorContains(t, verify(m, []byte("another nonce")), "verification failed")
+	assert.ErrorContains(t, setup.verify(proof, pkNym), "verification failed")
+}
+
+// malformed input yields an error rather than a panic
+func testAccumulatorProveErrors(t *testing.T) {
+	type TestCase string
+	const (
+		NoYs        TestCase = "no y-values"
+		OneY        TestCase = "one y-value"
+		WrongYs     TestCase = "y-values of wrong type"
+		NoWitness   TestCase = "witness missing"
+		NoValue     TestCase = "value missing"
+		WrongGroupH TestCase = "h of wrong type"
+	)
+
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	userSk, skNym, _, witness, signature := setup.enroll(prg)
+
+	for _, tc := range []TestCase{NoYs, OneY, WrongYs, NoWitness, NoValue, WrongGroupH} {
+		t.Run(string(tc), func(t *testing.T) {
+			h, ys, witness, value := setup.h, setup.ys, witness, setup.value
+			switch tc {
+			case NoYs:
+				ys = nil
+			case OneY:
+				ys = ys[:1]
+			case WrongYs:
+				ys = []interface{}{"y1", "y2"}
+			case NoWitness:
+				witness = AccumulatorWitness{}
+			case NoValue:
+				value = nil
+			case WrongGroupH:
+				h = "h"
+			}
+
+			_, e := AccumulatorProve(prg, signature, witness, userSk, skNym, h, ys, value)
+			assert.ErrorContains(t, e, "")
+			_, e = AccumulatorProveWithMessage(prg, signature, witness, userSk, skNym, h, ys, value, []byte("m"), nil)
+			assert.ErrorContains(t, e, "")
+		})
+	}
+}
+
+func testAccumulatorParameters(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	params := setup.parameters()
+	userSk, skNym, pkNym, witness, signature := setup.enroll(prg)
+
+	proof, e := AccumulatorProveWithParameters(prg, signature, witness, userSk, skNym, setup.value, params, "context")
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyWithParameters(pkNym, setup.accPk, setup.value, params, "context"))
+	assert.ErrorContains(t, proof.VerifyWithParameters(pkNym, setup.accPk, setup.value, params, "other"), "verification failed")
+	assert.ErrorContains(t, setup.verify(proof, pkNym), "verification failed")
+
+	recovered, e := ParseAccumulatorProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.VerifyWithParameters(pkNym, setup.accPk, setup.value, params, "context"))
+
+	other, _, _ := generateSystemParameters(getNewRand(SEED + 2))
+	other.H, other.RevocationPK, other.RevocationYs, other.AuditorPK = params.H, params.RevocationPK, params.RevocationYs, nil
+	assert.ErrorContains(t, proof.VerifyWithParameters(pkNym, setup.accPk, setup.value, other, "context"), "mismatch")
+
+	unbound := setup.prove(prg, signature, witness, userSk, skNym)
+	assert.ErrorContains(t, unbound.VerifyWithParameters(pkNym, setup.accPk, setup.value, params, "context"), "not bound")
+
+	params.RevocationPK, params.RevocationYs = nil, nil
+	_, e = AccumulatorProveWithParameters(prg, signature, witness, userSk, skNym, setup.value, params, "context")
+	assert.ErrorContains(t, e, "do not include revocation values")
+	assert.ErrorContains(t, proof.VerifyWithParameters(pkNym, setup.accPk, setup.value, params, "context"), "do not include revocation values")
+}
+
+func testAccumulatorMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	userSk, skNym, pkNym, witness, signature := setup.enroll(prg)
+
+	proof := setup.prove(prg, signature, witness, userSk, skNym)
+
+	recovered, e := ParseAccumulatorProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, setup.verify(*recovered, pkNym))
+	assert.DeepEqual(t, recovered.ToBytes(), proof.ToBytes())
+
+	assert.NilError(t, setup.verify(*AccumulatorProofFromBytes(proof.ToBytes()), pkNym))
+
+	update := AccumulatorRevoke(setup.accSk, setup.value, witness.Handle)
+	recoveredUpdate, e := ParseAccumulatorUpdate(update.ToBytes())
+	assert.NilError(t, e)
+	assert.Check(t, bigEqual(recoveredUpdate.Revoked, update.Revoked))
+	assert.Check(t, recoveredUpdate.Value.Equals(update.Value))
+}
+
+func testAccumulatorParseRejectsMalformed(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	userSk, skNym, _, witness, signature := setup.enroll(prg)
+
+	proof := setup.prove(prg, signature, witness, userSk, skNym)
+	valid := proof.ToBytes()
+
+	type TestCase string
+	const (
+		Empty         TestCase = "empty input"
+		Trailing      TestCase = "trailing bytes"
+		BadRevocation TestCase = "malformed revocation part"
+		BadScalar     TestCase = "scalar out of range"
+		WrongGroup    TestCase = "wBar in G2"
+		NoPoint       TestCase = "missing dBar"
+	)
+
+	for _, tc := range []TestCase{Empty, Trailing, BadRevocation, BadScalar, WrongGroup, NoPoint} {
+		t.Run(string(tc), func(t *testing.T) {
+			var input []byte
+			var marshal accumulatorProofMarshal
+
+			switch tc {
+			case Empty:
+				input = []byte{}
+			case Trailing:
+				input = append(valid, 0x13)
+			case BadRevocation:
+				input = remarshal(t, valid, &marshal, func() { marshal.Revocation = []byte{0x13} })
+			case BadScalar:
+				input = remarshal(t, valid, &marshal, func() { marshal.ResRho = make([]byte, 40) })
+			case WrongGroup:
+				input = remarshal(t, valid, &marshal, func() { marshal.WBar = PointToBytes(FP256BN.ECP2_generator()) })
+			case NoPoint:
+				input = remarshal(t, valid, &marshal, func() { marshal.DBar = []byte{} })
+			}
+
+			_, e := ParseAccumulatorProof(input)
+			assert.ErrorContains(t, e, "ParseAccumulatorProof")
+		})
+	}
+
+	_, e := ParseAccumulatorUpdate([]byte{0x13})
+	assert.ErrorContains(t, e, "ParseAccumulatorUpdate")
+}
+
+// Benchmarks
+
+func BenchmarkAccumulator(b *testing.B) {
+	prg := getNewRand(SEED)
+
+	setup := makeAccumulatorSetup(prg)
+	userSk, skNym, pkNym, witness, signature := setup.enroll(prg)
+	proof := setup.prove(prg, signature, witness, userSk, skNym)
+	update := AccumulatorRevoke(setup.accSk, setup.value, FP256BN.NewBIGint(0x13))
+
+	b.Run("prove", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			setup.prove(prg, signature, witness, userSk, skNym)
+		}
+	})
+
+	b.Run("verify", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_ = setup.verify(proof, pkNym)
+		}
+	})
+
+	b.Run("revoke", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			AccumulatorRevoke(setup.accSk, setup.value, FP256BN.NewBIGint(0x13))
+		}
+	})
+
+	b.Run("update witness", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			updated := witness
+			_ = updated.Update(update)
+		}
+	})
+}
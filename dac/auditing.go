@@ -15,6 +15,15 @@ type AuditingProof struct {
 	res1 *FP256BN.BIG
 	res2 *FP256BN.BIG
 	res3 *FP256BN.BIG
+
+	// fingerprint of the system parameters the proof is bound to (empty if not bound)
+	fingerprint []byte
+}
+
+// DecryptionProof is a NIZK that the plaintext is the decryption of the auditing encryption under auditor's public key.
+// It lets anyone check whom the auditor has named.
+type DecryptionProof struct {
+	proof equalityProof
 }
 
 // AuditingEncryption is the ElGamal encryption of user's public key under auditor's public key
@@ -53,32 +62,96 @@ func (encryption *AuditingEncryption) AuditingDecrypt(audSk SK) (plaintext inter
 	return
 }
 
+// AuditingDecryptWithProof decrypts the auditing encryption like AuditingDecrypt
+// and proves that the plaintext is the correct decryption under auditor's public key.
+func (encryption *AuditingEncryption) AuditingDecryptWithProof(prg *amcl.RAND, audSk SK) (plaintext interface{}, proof DecryptionProof) {
+	g := generatorSameGroup(encryption.enc2)
+
+	plaintext = encryption.AuditingDecrypt(audSk)
+
+	// enc1 - plaintext = enc2^sk and audPk = g^sk
+	t := decryptionTranscript(encryption, plaintext)
+	proof.proof = proveEquality(prg, t, g, pointMultiply(g, audSk), encryption.enc2, pointMultiply(encryption.enc2, audSk), audSk)
+
+	return
+}
+
 // AuditingProve generate a NIZK proof of "honest" encryption.
 // It needs the auditing encryption, user's key pair, pseudonym pair and auditor's public key.
 func AuditingProve(prg *amcl.RAND, encryption AuditingEncryption, pk PK, sk SK, pkNym PK, skNym SK, audPk PK, r *FP256BN.BIG, h interface{}) (proof AuditingProof) {
+	return auditingProveBound(prg, encryption, pk, sk, pkNym, skNym, audPk, r, h, binding{}, nil)
+}
+
+// AuditingProveWithMessage is AuditingProve that also signs the message m (e.g. the transaction)
+// and binds the proof to the verifier's context, so that the encryption and its proof cannot be attached to another transaction.
+// Verify the proof with VerifyWithMessage.
+func AuditingProveWithMessage(prg *amcl.RAND, encryption AuditingEncryption, pk PK, sk SK, pkNym PK, skNym SK, audPk PK, r *FP256BN.BIG, h interface{}, m []byte, context string) (proof AuditingProof) {
+	return auditingProveBound(prg, encryption, pk, sk, pkNym, skNym, audPk, r, h, binding{context: context}, m)
+}
+
+// auditingProveBound is AuditingProve that binds the proof to the system parameters fingerprint and the context,
+// and signs the message (may be empty)
+func auditingProveBound(prg *amcl.RAND, encryption AuditingEncryption, pk PK, sk SK, pkNym PK, skNym SK, audPk PK, r *FP256BN.BIG, h interface{}, b binding, m []byte) (proof AuditingProof) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	prover := auditingCommit(prg, audPk, h, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg))
+
+	t := newTranscript(_ProtocolAuditing, b)
+	appendAuditing(t, audPk, h, prover.com1, prover.com2, prover.com3, encryption, pkNym)
+	t.append("m", m)
+
+	proof = prover.respond(t.challenge(q), sk, skNym, r)
+	proof.fingerprint = b.fingerprint
+
+	return
+}
+
+// auditingProver holds the state of the proof generation between the commitments and the responses
+type auditingProver struct {
+	r1   *FP256BN.BIG
+	r2   *FP256BN.BIG
+	r3   *FP256BN.BIG
+	com1 interface{}
+	com2 interface{}
+	com3 interface{}
+}
+
+// auditingCommit computes the commitments of the proof.
+// rSk and rNym are the randomness for the secret key and the pseudonym secret key,
+// a proof sharing them with other proofs under the same challenge shows that the secret keys are the same.
+func auditingCommit(prg *amcl.RAND, audPk PK, h interface{}, rSk *FP256BN.BIG, rNym *FP256BN.BIG) (prover *auditingProver) {
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 	g := generatorSameGroup(h)
 
-	r1 := FP256BN.Randomnum(q, prg)
-	r2 := FP256BN.Randomnum(q, prg)
-	r3 := FP256BN.Randomnum(q, prg)
+	prover = &auditingProver{
+		r1: rSk,
+		r2: FP256BN.Randomnum(q, prg),
+		r3: rNym,
+	}
 
-	com1 := productOfExponents(g, r1, audPk, r2)
-	com2 := pointMultiply(g, r2)
-	com3 := productOfExponents(g, r1, h, r3)
+	prover.com1 = productOfExponents(g, prover.r1, audPk, prover.r2)
+	prover.com2 = pointMultiply(g, prover.r2)
+	prover.com3 = productOfExponents(g, prover.r1, h, prover.r3)
 
-	proof.c = hashAuditing(q, com1, com2, com3, encryption, pkNym)
+	return
+}
+
+// respond computes the responses of the proof for the challenge c
+func (prover *auditingProver) respond(c *FP256BN.BIG, sk SK, skNym SK, r *FP256BN.BIG) (proof AuditingProof) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	proof.c = c
 
 	proof.res1 = FP256BN.Modmul(proof.c, sk, q)
-	proof.res1 = proof.res1.Plus(r1)
+	proof.res1 = proof.res1.Plus(prover.r1)
 	proof.res1.Mod(q)
 
 	proof.res2 = FP256BN.Modmul(proof.c, r, q)
-	proof.res2 = proof.res2.Plus(r2)
+	proof.res2 = proof.res2.Plus(prover.r2)
 	proof.res2.Mod(q)
 
 	proof.res3 = FP256BN.Modmul(proof.c, skNym, q)
-	proof.res3 = proof.res3.Plus(r3)
+	proof.res3 = proof.res3.Plus(prover.r3)
 	proof.res3.Mod(q)
 
 	return
@@ -87,37 +160,110 @@ func AuditingProve(prg *amcl.RAND, encryption AuditingEncryption, pk PK, sk SK,
 // Verify validates the auditing NIZK.
 // Successfull validation means that the encryption is "honest".
 func (proof *AuditingProof) Verify(encryption AuditingEncryption, pkNym PK, audPk PK, h interface{}) (e error) {
+	return proof.verify(encryption, pkNym, audPk, h, "", nil)
+}
+
+// VerifyWithMessage validates the proof generated with AuditingProveWithMessage.
+// The message and the context have to be the ones the proof was generated with.
+func (proof *AuditingProof) VerifyWithMessage(encryption AuditingEncryption, pkNym PK, audPk PK, h interface{}, m []byte, context string) (e error) {
+	return proof.verify(encryption, pkNym, audPk, h, context, m)
+}
+
+// verify is Verify for the proof bound to the context (and to the fingerprint it carries) that signs the message
+func (proof *AuditingProof) verify(encryption AuditingEncryption, pkNym PK, audPk PK, h interface{}, context string, m []byte) (e error) {
+	com1, com2, com3, e := proof.commitments(encryption, pkNym, audPk, h)
+	if e != nil {
+		return
+	}
+
+	t := newTranscript(_ProtocolAuditing, binding{proof.fingerprint, context})
+	appendAuditing(t, audPk, h, com1, com2, com3, encryption, pkNym)
+	t.append("m", m)
+
+	if !bigEqual(t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order)), proof.c) {
+		e = fmt.Errorf("AuditingProof.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// commitments validates the encryption and recomputes the commitments of the proof from the challenge and the responses
+func (proof *AuditingProof) commitments(encryption AuditingEncryption, pkNym PK, audPk PK, h interface{}) (com1, com2, com3 interface{}, e error) {
+	if e = validatePoints(encryption.enc1, encryption.enc2, pkNym); e != nil {
+		e = fmt.Errorf("AuditingProof.Verify: invalid encryption or pkNym: %v", e)
+		return
+	}
+
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 	g := generatorSameGroup(h)
 	cNeg := bigNegate(proof.c, q)
 
-	com1 := productOfExponents(g, proof.res1, audPk, proof.res2)
+	com1 = productOfExponents(g, proof.res1, audPk, proof.res2)
 	pointAdd(com1, pointMultiply(encryption.enc1, cNeg))
 
-	com2 := productOfExponents(g, proof.res2, encryption.enc2, cNeg)
+	com2 = productOfExponents(g, proof.res2, encryption.enc2, cNeg)
 
-	com3 := productOfExponents(g, proof.res1, h, proof.res3)
+	com3 = productOfExponents(g, proof.res1, h, proof.res3)
 	pointAdd(com3, pointMultiply(pkNym, cNeg))
 
-	cPrime := hashAuditing(q, com1, com2, com3, encryption, pkNym)
+	return
+}
 
-	if !bigEqual(cPrime, proof.c) {
-		e = fmt.Errorf("AuditingProof.Verify: verification failed at cPrime == c")
+// Verify checks that the plaintext is the decryption of the encryption under auditor's public key
+func (proof *DecryptionProof) Verify(encryption AuditingEncryption, plaintext interface{}, audPk PK) (e error) {
+	if e = validateSameGroup(encryption.enc2, encryption.enc1, plaintext, audPk); e != nil {
+		return fmt.Errorf("DecryptionProof.Verify: invalid encryption, plaintext or auditor's public key: %v", e)
+	}
+
+	g := generatorSameGroup(encryption.enc2)
+
+	mask := pointMultiply(encryption.enc1, FP256BN.NewBIGint(1))
+	pointSubtract(mask, plaintext)
+
+	t := decryptionTranscript(&encryption, plaintext)
+	if !proof.proof.verify(t, g, audPk, encryption.enc2, mask) {
+		return fmt.Errorf("DecryptionProof.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// validateSameGroup validates the required points and ensures they are all in the group of the first one
+func validateSameGroup(g interface{}, gs ...interface{}) (e error) {
+	if e = ValidatePoint(g); e != nil {
+		return fmt.Errorf("point 0: %v", e)
+	}
+
+	_, first := g.(*FP256BN.ECP)
+	for index, other := range gs {
+		if e = validatePointInGroup(other, first); e != nil {
+			return fmt.Errorf("point %d: %v", index+1, e)
+		}
 	}
 
 	return
 }
 
-func hashAuditing(q *FP256BN.BIG, com1, com2, com3 interface{}, encryption AuditingEncryption, pkNym PK) *FP256BN.BIG {
-	var raw []byte
-	raw = append(raw, PointToBytes(com1)...)
-	raw = append(raw, PointToBytes(com2)...)
-	raw = append(raw, PointToBytes(com3)...)
-	raw = append(raw, PointToBytes(encryption.enc1)...)
-	raw = append(raw, PointToBytes(encryption.enc2)...)
-	raw = append(raw, PointToBytes(pkNym)...)
+func decryptionTranscript(encryption *AuditingEncryption, plaintext interface{}) (t *transcript) {
+	t = newTranscript(_ProtocolDecryption, binding{})
+
+	t.appendPoint("enc1", encryption.enc1)
+	t.appendPoint("enc2", encryption.enc2)
+	t.appendPoint("plaintext", plaintext)
+
+	return
+}
 
-	return sha3(q, raw)
+// appendAuditing adds the public values and the commitments of the auditing proof to the transcript
+func appendAuditing(t *transcript, audPk PK, h interface{}, com1, com2, com3 interface{}, encryption AuditingEncryption, pkNym PK) {
+	t.appendPoint("audPk", audPk)
+	t.appendPoint("h", h)
+	t.appendPoint("com1", com1)
+	t.appendPoint("com2", com2)
+	t.appendPoint("com3", com3)
+	t.appendPoint("enc1", encryption.enc1)
+	t.appendPoint("enc2", encryption.enc2)
+	t.appendPoint("pkNym", pkNym)
 }
 
 type auditingProofMarshal struct {
@@ -125,6 +271,8 @@ type auditingProofMarshal struct {
 	Res1 []byte
 	Res2 []byte
 	Res3 []byte
+
+	Fingerprint []byte `asn1:"optional"`
 }
 
 // ToBytes marshals the NIZK object using ASN1 encoding
@@ -135,6 +283,7 @@ func (proof *AuditingProof) ToBytes() (result []byte) {
 	marshal.Res1 = bigToBytes(proof.res1)
 	marshal.Res2 = bigToBytes(proof.res2)
 	marshal.Res3 = bigToBytes(proof.res3)
+	marshal.Fingerprint = proof.fingerprint
 
 	result, _ = asn1.Marshal(marshal)
 
@@ -142,18 +291,72 @@ func (proof *AuditingProof) ToBytes() (result []byte) {
 }
 
 // AuditingProofFromBytes un-marshals the NIZK object using ASN1 encoding
+// Panics if the input is malformed, see ParseAuditingProof for a version that returns error.
 func AuditingProofFromBytes(input []byte) (proof *AuditingProof) {
+	proof, e := ParseAuditingProof(input)
+	if e != nil {
+		panic("un-marshalling auditing proof failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseAuditingProof un-marshals and validates the NIZK object using ASN1 encoding
+func ParseAuditingProof(input []byte) (proof *AuditingProof, e error) {
 	var marshal auditingProofMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling schnorr signature failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseAuditingProof: %v", e)
+	}
+
+	scalars, e := bigsFromBytes(marshal.C, marshal.Res1, marshal.Res2, marshal.Res3)
+	if e != nil {
+		return nil, fmt.Errorf("ParseAuditingProof: %v", e)
+	}
+
+	proof = &AuditingProof{
+		c:    scalars[0],
+		res1: scalars[1],
+		res2: scalars[2],
+		res3: scalars[3],
+	}
+
+	if proof.fingerprint, e = fingerprintFromBytes(marshal.Fingerprint); e != nil {
+		return nil, fmt.Errorf("ParseAuditingProof: %v", e)
+	}
+
+	return
+}
+
+type decryptionProofMarshal struct {
+	C   []byte
+	Res []byte
+}
+
+// ToBytes marshals the NIZK object using ASN1 encoding
+func (proof *DecryptionProof) ToBytes() (result []byte) {
+	var marshal decryptionProofMarshal
+
+	marshal.C = bigToBytes(proof.proof.c)
+	marshal.Res = bigToBytes(proof.proof.res)
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParseDecryptionProof un-marshals and validates the NIZK object using ASN1 encoding
+func ParseDecryptionProof(input []byte) (proof *DecryptionProof, e error) {
+	var marshal decryptionProofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseDecryptionProof: %v", e)
 	}
 
-	proof = &AuditingProof{}
+	scalars, e := bigsFromBytes(marshal.C, marshal.Res)
+	if e != nil {
+		return nil, fmt.Errorf("ParseDecryptionProof: %v", e)
+	}
 
-	proof.c = FP256BN.FromBytes(marshal.C)
-	proof.res1 = FP256BN.FromBytes(marshal.Res1)
-	proof.res2 = FP256BN.FromBytes(marshal.Res2)
-	proof.res3 = FP256BN.FromBytes(marshal.Res3)
+	proof = &DecryptionProof{equalityProof{scalars[0], scalars[1]}}
 
 	return
 }
@@ -176,16 +379,32 @@ func (encryption *AuditingEncryption) ToBytes() (result []byte) {
 }
 
 // AuditingEncryptionFromBytes un-marshals the NIZK object using ASN1 encoding
+// Panics if the input is malformed, see ParseAuditingEncryption for a version that returns error.
 func AuditingEncryptionFromBytes(input []byte) (encryption *AuditingEncryption) {
+	encryption, e := ParseAuditingEncryption(input)
+	if e != nil {
+		panic("un-marshalling auditing encryption failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseAuditingEncryption un-marshals and validates the encryption object using ASN1 encoding
+func ParseAuditingEncryption(input []byte) (encryption *AuditingEncryption, e error) {
 	var marshal auditingEncryptionMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling schnorr signature failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseAuditingEncryption: %v", e)
 	}
 
 	encryption = &AuditingEncryption{}
 
-	encryption.enc1, _ = PointFromBytes(marshal.Enc1)
-	encryption.enc2, _ = PointFromBytes(marshal.Enc2)
+	var first bool
+	if encryption.enc1, first, e = requiredPointFromBytes(marshal.Enc1); e != nil {
+		return nil, fmt.Errorf("ParseAuditingEncryption: enc1: %v", e)
+	}
+	if encryption.enc2, e = pointFromBytesInGroup(marshal.Enc2, first, false); e != nil {
+		return nil, fmt.Errorf("ParseAuditingEncryption: enc2: %v", e)
+	}
 
 	return
 }
@@ -49,6 +49,13 @@ func TestAuditing(t *testing.T) {
 				testAuditingMarshal,
 				testAuditingEncryptionUnMarshalFails,
 				testAuditingProofUnMarshalFails,
+				testAuditingParse,
+				testAuditingParseRejectsMalformed,
+				testAuditingRejectsInvalidPoints,
+				testAuditingMessage,
+				testAuditingReplay,
+				testAuditingDecryptionProof,
+				testAuditingDecryptionProofParse,
 			} {
 				t.Run(funcToString(reflect.ValueOf(test)), test)
 			}
@@ -139,6 +146,269 @@ func testAuditingProofUnMarshalFails(t *testing.T) {
 	AuditingProofFromBytes([]byte{0x13})
 }
 
+// parsing yields the original objects
+func testAuditingParse(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	h, userSk, userPk, _, auditPk, encryption, r := auditingEncrypt(prg)
+
+	proof, pkNym := auditingProve(prg, userSk, h, encryption, userPk, auditPk, r)
+
+	recoveredEnc, e := ParseAuditingEncryption(encryption.ToBytes())
+	assert.NilError(t, e)
+	recoveredProof, e := ParseAuditingProof(proof.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Check(t, recoveredProof.Verify(*recoveredEnc, pkNym, auditPk, h))
+}
+
+// parsing rejects malformed input without panicking
+func testAuditingParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed   TestCase = "malformed ASN1"
+		OffCurve    TestCase = "enc1 not on curve"
+		Infinity    TestCase = "enc2 at infinity"
+		WrongGroup  TestCase = "enc2 in wrong group"
+		Missing     TestCase = "enc1 missing"
+		LargeScalar TestCase = "scalar not reduced"
+		ShortScalar TestCase = "short scalar"
+	)
+
+	prg := getNewRand(SEED)
+
+	h, userSk, userPk, _, auditPk, encryption, r := auditingEncrypt(prg)
+
+	proof, _ := auditingProve(prg, userSk, h, encryption, userPk, auditPk, r)
+
+	for _, tc := range []TestCase{Malformed, OffCurve, Infinity, WrongGroup, Missing} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal auditingEncryptionMarshal
+			bytes := remarshal(t, encryption.ToBytes(), &marshal, func() {
+				switch tc {
+				case OffCurve:
+					marshal.Enc1 = offCurveBytes(hFirst)
+				case Infinity:
+					marshal.Enc2 = infinityBytes(hFirst)
+				case WrongGroup:
+					marshal.Enc2 = pointBytes(!hFirst)
+				case Missing:
+					marshal.Enc1 = nil
+				}
+			})
+
+			if tc == Malformed {
+				bytes = append(bytes, 0x13)
+			}
+
+			_, e := ParseAuditingEncryption(bytes)
+			assert.ErrorContains(t, e, "ParseAuditingEncryption")
+		})
+	}
+
+	for _, tc := range []TestCase{Malformed, LargeScalar, ShortScalar} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal auditingProofMarshal
+			bytes := remarshal(t, proof.ToBytes(), &marshal, func() {
+				switch tc {
+				case LargeScalar:
+					marshal.Res2 = orderBytes()
+				case ShortScalar:
+					marshal.C = nil
+				}
+			})
+
+			if tc == Malformed {
+				bytes = append(bytes, 0x13)
+			}
+
+			_, e := ParseAuditingProof(bytes)
+			assert.ErrorContains(t, e, "ParseAuditingProof")
+		})
+	}
+}
+
+// verification rejects points outside of the prime-order subgroups
+func testAuditingRejectsInvalidPoints(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	h, userSk, userPk, _, auditPk, encryption, r := auditingEncrypt(prg)
+
+	proof, pkNym := auditingProve(prg, userSk, h, encryption, userPk, auditPk, r)
+
+	points := invalidPointsLike(h)
+	points["missing"] = nil
+
+	for _, component := range []string{"enc1", "enc2", "pkNym"} {
+		for name, point := range points {
+			t.Run(fmt.Sprintf("%s %s", component, name), func(t *testing.T) {
+				tampered := encryption
+				tamperedPkNym := pkNym
+				switch component {
+				case "enc1":
+					tampered.enc1 = point
+				case "enc2":
+					tampered.enc2 = point
+				case "pkNym":
+					tamperedPkNym = point
+				}
+
+				e := proof.Verify(tampered, tamperedPkNym, auditPk, h)
+				assert.ErrorContains(t, e, "invalid")
+			})
+		}
+	}
+}
+
+// proof bound to a message and a context verifies only with them, also after marshalling
+func testAuditingMessage(t *testing.T) {
+	type TestCase string
+	const (
+		Correct      TestCase = "correct"
+		Marshalled   TestCase = "marshalled"
+		WrongMessage TestCase = "wrong message"
+		WrongContext TestCase = "wrong context"
+		NoMessage    TestCase = "verified without message"
+		Unbound      TestCase = "proof without message"
+	)
+
+	for _, tc := range []TestCase{Correct, Marshalled, WrongMessage, WrongContext, NoMessage, Unbound} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			h, userSk, userPk, _, auditPk, encryption, r := auditingEncrypt(prg)
+			skNym, pkNym := GenerateNymKeys(prg, userSk, h)
+
+			m, context := []byte("transaction"), "channel"
+
+			proof := AuditingProveWithMessage(prg, encryption, userPk, userSk, pkNym, skNym, auditPk, r, h, m, context)
+
+			var e error
+			switch tc {
+			case Correct:
+				e = proof.VerifyWithMessage(encryption, pkNym, auditPk, h, m, context)
+			case Marshalled:
+				recovered, pe := ParseAuditingProof(proof.ToBytes())
+				assert.NilError(t, pe)
+				e = recovered.VerifyWithMessage(encryption, pkNym, auditPk, h, m, context)
+			case WrongMessage:
+				e = proof.VerifyWithMessage(encryption, pkNym, auditPk, h, []byte("another transaction"), context)
+			case WrongContext:
+				e = proof.VerifyWithMessage(encryption, pkNym, auditPk, h, m, "another channel")
+			case NoMessage:
+				e = proof.Verify(encryption, pkNym, auditPk, h)
+			case Unbound:
+				unbound := AuditingProve(prg, encryption, userPk, userSk, pkNym, skNym, auditPk, r, h)
+				e = unbound.VerifyWithMessage(encryption, pkNym, auditPk, h, m, context)
+			}
+
+			if tc == Correct || tc == Marshalled {
+				assert.NilError(t, e)
+			} else {
+				assert.ErrorContains(t, e, "failed")
+			}
+		})
+	}
+}
+
+// encryption and its proof lifted from one transaction are rejected in another one
+func testAuditingReplay(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	h, userSk, userPk, _, auditPk, encryption, r := auditingEncrypt(prg)
+	skNym, pkNym := GenerateNymKeys(prg, userSk, h)
+
+	first, second := []byte("first transaction"), []byte("second transaction")
+
+	proof := AuditingProveWithMessage(prg, encryption, userPk, userSk, pkNym, skNym, auditPk, r, h, first, "channel")
+
+	// what an attacker observes on the wire
+	liftedEncryption, e := ParseAuditingEncryption(encryption.ToBytes())
+	assert.NilError(t, e)
+	liftedProof, e := ParseAuditingProof(proof.ToBytes())
+	assert.NilError(t, e)
+
+	assert.NilError(t, liftedProof.VerifyWithMessage(*liftedEncryption, pkNym, auditPk, h, first, "channel"))
+	assert.ErrorContains(t, liftedProof.VerifyWithMessage(*liftedEncryption, pkNym, auditPk, h, second, "channel"), "failed")
+}
+
+// the auditor's decryption is publicly verifiable, a wrong plaintext or key is detected
+func testAuditingDecryptionProof(t *testing.T) {
+	type TestCase string
+	const (
+		Correct        TestCase = "correct"
+		Marshalled     TestCase = "marshalled"
+		WrongPlaintext TestCase = "wrong plaintext"
+		WrongKey       TestCase = "wrong auditor key"
+		WrongEncrypted TestCase = "another encryption"
+		NoPlaintext    TestCase = "missing plaintext"
+		WrongGroup     TestCase = "plaintext in wrong group"
+	)
+
+	for _, tc := range []TestCase{Correct, Marshalled, WrongPlaintext, WrongKey, WrongEncrypted, NoPlaintext, WrongGroup} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			_, _, userPk, auditSk, auditPk, encryption, _ := auditingEncrypt(prg)
+
+			plaintext, proof := encryption.AuditingDecryptWithProof(prg, auditSk)
+			assert.Check(t, pointEqual(plaintext, userPk))
+
+			var e error
+			expected := "failed"
+			switch tc {
+			case Correct:
+				e = proof.Verify(encryption, plaintext, auditPk)
+			case Marshalled:
+				recovered, pe := ParseDecryptionProof(proof.ToBytes())
+				assert.NilError(t, pe)
+				e = recovered.Verify(encryption, plaintext, auditPk)
+			case WrongPlaintext:
+				_, otherPk := GenerateKeys(prg, map[bool]int{true: 1, false: 2}[hFirst])
+				e = proof.Verify(encryption, otherPk, auditPk)
+			case WrongKey:
+				_, otherAuditPk := GenerateKeys(prg, map[bool]int{true: 1, false: 2}[hFirst])
+				e = proof.Verify(encryption, plaintext, otherAuditPk)
+			case WrongEncrypted:
+				other, _ := AuditingEncrypt(prg, auditPk, userPk)
+				e = proof.Verify(other, plaintext, auditPk)
+			case NoPlaintext:
+				e = proof.Verify(encryption, nil, auditPk)
+				expected = "invalid"
+			case WrongGroup:
+				_, otherPk := GenerateKeys(prg, map[bool]int{true: 2, false: 1}[hFirst])
+				e = proof.Verify(encryption, otherPk, auditPk)
+				expected = "expected to be in"
+			}
+
+			if tc == Correct || tc == Marshalled {
+				assert.NilError(t, e)
+			} else {
+				assert.ErrorContains(t, e, expected)
+			}
+		})
+	}
+}
+
+func testAuditingDecryptionProofParse(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, _, _, auditSk, _, encryption, _ := auditingEncrypt(prg)
+	_, proof := encryption.AuditingDecryptWithProof(prg, auditSk)
+
+	var marshal decryptionProofMarshal
+
+	for _, input := range [][]byte{
+		{},
+		append(proof.ToBytes(), 0x13),
+		remarshal(t, proof.ToBytes(), &marshal, func() { marshal.C = []byte{} }),
+		remarshal(t, proof.ToBytes(), &marshal, func() { marshal.Res = make([]byte, 40) }),
+	} {
+		_, e := ParseDecryptionProof(input)
+		assert.ErrorContains(t, e, "ParseDecryptionProof")
+	}
+}
+
 // Benchmarks
 
 func BenchmarkAuditing(b *testing.B) {
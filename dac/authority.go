@@ -0,0 +1,392 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dbogatov/dac-lib/internal/atomicfile"
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// _SeedLength is the number of bytes the PRG of each worker is seeded with
+const _SeedLength = 128
+
+// RevocationAuthority keeps the revocation authority's keys, the registered users and the revocation list,
+// and issues the non-revocation signatures (see SignNonRevoke) epoch by epoch.
+// It is safe for concurrent use.
+type RevocationAuthority struct {
+	mutex sync.RWMutex
+
+	sk    SK
+	pk    PK
+	ys    []interface{}
+	first bool
+	epoch *FP256BN.BIG
+
+	// users' public keys, keyed by their hex-encoded PointToBytes
+	users   map[string]PK
+	revoked map[string]bool
+}
+
+// NonRevocation is the non-revocation signature issued to the user with the public key Pk
+type NonRevocation struct {
+	Pk        PK
+	Signature GrothSignature
+}
+
+// EpochAnnouncement is the revocation authority's signed statement of the current epoch.
+// Verifiers check it with the authority's public key before verifying revocation proofs against the epoch.
+type EpochAnnouncement struct {
+	Epoch *FP256BN.BIG
+	// Issued is the time of the announcement in seconds since the Unix epoch
+	Issued int64
+
+	signature SchnorrSignature
+}
+
+// MakeRevocationAuthority creates the revocation authority with a fresh key pair, starting at epoch 1
+// (epoch 0 would be signed as the point at infinity).
+// first defines the group of the users' public keys (the group opposite to h), ys are in the same group.
+func MakeRevocationAuthority(prg *amcl.RAND, first bool, ys []interface{}) (authority *RevocationAuthority) {
+	authority = &RevocationAuthority{
+		ys:      ys,
+		first:   first,
+		epoch:   FP256BN.NewBIGint(1),
+		users:   make(map[string]PK),
+		revoked: make(map[string]bool),
+	}
+	authority.sk, authority.pk = MakeGroth(prg, first, ys).Generate()
+
+	return
+}
+
+// PK returns the authority's public key, verifiers use it as pkRev
+func (authority *RevocationAuthority) PK() PK {
+	return authority.pk
+}
+
+// Ys returns the y-values of the authority's Groth signatures
+func (authority *RevocationAuthority) Ys() []interface{} {
+	return authority.ys
+}
+
+// Epoch returns the current epoch
+func (authority *RevocationAuthority) Epoch() *FP256BN.BIG {
+	authority.mutex.RLock()
+	defer authority.mutex.RUnlock()
+
+	return FP256BN.NewBIGcopy(authority.epoch)
+}
+
+// Register adds the user's public key to the ones that get non-revocation signatures.
+// Returns error if the key is not in the users' group or is revoked.
+func (authority *RevocationAuthority) Register(userPk PK) (e error) {
+	if e = validatePointInGroup(userPk, authority.first); e != nil {
+		return fmt.Errorf("RevocationAuthority.Register: %v", e)
+	}
+
+	authority.mutex.Lock()
+	defer authority.mutex.Unlock()
+
+	k := pkKey(userPk)
+	if authority.revoked[k] {
+		return fmt.Errorf("RevocationAuthority.Register: public key is revoked")
+	}
+	authority.users[k] = userPk
+
+	return
+}
+
+// Revoke puts the user's public key on the revocation list.
+// The user gets no signatures from the next issuance on.
+func (authority *RevocationAuthority) Revoke(userPk PK) (e error) {
+	if e = validatePointInGroup(userPk, authority.first); e != nil {
+		return fmt.Errorf("RevocationAuthority.Revoke: %v", e)
+	}
+
+	authority.mutex.Lock()
+	defer authority.mutex.Unlock()
+
+	authority.revoked[pkKey(userPk)] = true
+
+	return
+}
+
+// IsRevoked checks if the user's public key is on the revocation list
+func (authority *RevocationAuthority) IsRevoked(userPk PK) bool {
+	authority.mutex.RLock()
+	defer authority.mutex.RUnlock()
+
+	return authority.revoked[pkKey(userPk)]
+}
+
+// AdvanceEpoch moves the authority to the next epoch and returns it
+func (authority *RevocationAuthority) AdvanceEpoch() (epoch *FP256BN.BIG) {
+	authority.mutex.Lock()
+	defer authority.mutex.Unlock()
+
+	authority.epoch = authority.epoch.Plus(FP256BN.NewBIGint(1))
+	authority.epoch.Mod(FP256BN.NewBIGints(FP256BN.CURVE_Order))
+	if bigEqual(authority.epoch, FP256BN.NewBIGint(0)) {
+		authority.epoch = FP256BN.NewBIGint(1)
+	}
+
+	return FP256BN.NewBIGcopy(authority.epoch)
+}
+
+// IssueNonRevocation signs the current epoch for every registered user that is not revoked.
+// The signatures are computed concurrently (at most Workers at a time, all at once if Workers is 0),
+// each worker with its own PRG seeded from prg.
+// The result is sorted by the encoding of the public keys.
+func (authority *RevocationAuthority) IssueNonRevocation(prg *amcl.RAND) (issued []NonRevocation) {
+	authority.mutex.RLock()
+	defer authority.mutex.RUnlock()
+
+	var keys []string
+	for _, k := range authority.userKeys() {
+		if !authority.revoked[k] {
+			keys = append(keys, k)
+		}
+	}
+
+	issued = make([]NonRevocation, len(keys))
+	if len(keys) == 0 {
+		return
+	}
+
+	workers := int(Workers)
+	if workers < 1 || workers > len(keys) {
+		workers = len(keys)
+	}
+
+	prgs := make([]*amcl.RAND, workers)
+	for worker := range prgs {
+		seed := make([]byte, _SeedLength)
+		for index := range seed {
+			seed[index] = prg.GetByte()
+		}
+		prgs[worker] = amcl.NewRAND()
+		prgs[worker].Clean()
+		prgs[worker].Seed(_SeedLength, seed)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for worker := 0; worker < workers; worker++ {
+		go func(worker int) {
+			defer wg.Done()
+
+			for index := worker; index < len(keys); index += workers {
+				userPk := authority.users[keys[index]]
+				issued[index] = NonRevocation{userPk, SignNonRevoke(prgs[worker], authority.sk, userPk, authority.epoch, authority.ys)}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	return
+}
+
+// Announce signs the announcement of the current epoch
+func (authority *RevocationAuthority) Announce(prg *amcl.RAND) (announcement EpochAnnouncement) {
+	authority.mutex.RLock()
+	defer authority.mutex.RUnlock()
+
+	announcement.Epoch = FP256BN.NewBIGcopy(authority.epoch)
+	announcement.Issued = time.Now().Unix()
+	announcement.signature = MakeSiblings(prg, authority.first, authority.ys).SignSchnorr(authority.sk, announcement.message())
+
+	return
+}
+
+// Verify checks the announcement against the revocation authority's public key
+func (announcement *EpochAnnouncement) Verify(pkRev PK) (e error) {
+	if e = ValidatePoint(pkRev); e != nil {
+		return fmt.Errorf("EpochAnnouncement.Verify: invalid public key: %v", e)
+	}
+	if announcement.Epoch == nil || announcement.signature.s == nil || announcement.signature.e == nil {
+		return fmt.Errorf("EpochAnnouncement.Verify: announcement is incomplete")
+	}
+
+	_, first := pkRev.(*FP256BN.ECP)
+	if e = MakeSchnorr(nil, first).Verify(pkRev, announcement.signature, announcement.message()); e != nil {
+		return fmt.Errorf("EpochAnnouncement.Verify: %v", e)
+	}
+
+	return
+}
+
+func (announcement *EpochAnnouncement) message() []byte {
+	t := newTranscript(_ProtocolEpoch, binding{})
+	t.appendBig("epoch", announcement.Epoch)
+	t.append("issued", []byte(strconv.FormatInt(announcement.Issued, 10)))
+
+	return t.digest()
+}
+
+func pkKey(pk PK) string {
+	return hex.EncodeToString(PointToBytes(pk))
+}
+
+type epochAnnouncementMarshal struct {
+	Epoch     []byte
+	Issued    int64
+	Signature []byte
+}
+
+// ToBytes marshals the announcement using ASN1 encoding
+func (announcement *EpochAnnouncement) ToBytes() (result []byte) {
+	result, _ = asn1.Marshal(epochAnnouncementMarshal{
+		bigToBytes(announcement.Epoch),
+		announcement.Issued,
+		announcement.signature.ToBytes(),
+	})
+
+	return
+}
+
+// ParseEpochAnnouncement un-marshals the announcement using ASN1 encoding.
+// Note, this does not verify the signature, use EpochAnnouncement.Verify for that.
+func ParseEpochAnnouncement(input []byte) (announcement *EpochAnnouncement, e error) {
+	var marshal epochAnnouncementMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseEpochAnnouncement: %v", e)
+	}
+
+	announcement = &EpochAnnouncement{Issued: marshal.Issued}
+
+	if announcement.Epoch, e = bigFromBytes(marshal.Epoch); e != nil {
+		return nil, fmt.Errorf("ParseEpochAnnouncement: epoch: %v", e)
+	}
+
+	signature, e := ParseSchnorrSignature(marshal.Signature)
+	if e != nil {
+		return nil, fmt.Errorf("ParseEpochAnnouncement: %v", e)
+	}
+	announcement.signature = *signature
+
+	return
+}
+
+type revocationAuthorityMarshal struct {
+	Sk      []byte
+	Ys      [][]byte
+	First   bool
+	Epoch   []byte
+	Users   [][]byte
+	Revoked [][]byte
+}
+
+// Save writes the authority's state, including its secret key, to the file at path.
+// The file is replaced atomically and is readable only by the owner.
+func (authority *RevocationAuthority) Save(path string) (e error) {
+	authority.mutex.RLock()
+	marshal := revocationAuthorityMarshal{
+		Sk:    bigToBytes(authority.sk),
+		Ys:    make([][]byte, len(authority.ys)),
+		First: authority.first,
+		Epoch: bigToBytes(authority.epoch),
+	}
+	for index, y := range authority.ys {
+		marshal.Ys[index] = PointToBytes(y)
+	}
+	for _, k := range authority.userKeys() {
+		bytes, _ := hex.DecodeString(k)
+		marshal.Users = append(marshal.Users, bytes)
+	}
+	for _, k := range authority.revokedKeys() {
+		bytes, _ := hex.DecodeString(k)
+		marshal.Revoked = append(marshal.Revoked, bytes)
+	}
+	authority.mutex.RUnlock()
+
+	data, e := asn1.Marshal(marshal)
+	if e != nil {
+		return fmt.Errorf("RevocationAuthority.Save: %v", e)
+	}
+
+	if e = atomicfile.Write(path, data); e != nil {
+		return fmt.Errorf("RevocationAuthority.Save: %v", e)
+	}
+
+	return
+}
+
+// LoadRevocationAuthority reads the authority's state written by Save
+func LoadRevocationAuthority(path string) (authority *RevocationAuthority, e error) {
+	data, e := ioutil.ReadFile(path)
+	if e != nil {
+		return nil, fmt.Errorf("LoadRevocationAuthority: %v", e)
+	}
+
+	var marshal revocationAuthorityMarshal
+	if e = unmarshal(data, &marshal); e != nil {
+		return nil, fmt.Errorf("LoadRevocationAuthority: %v", e)
+	}
+
+	authority = &RevocationAuthority{
+		first:   marshal.First,
+		users:   make(map[string]PK),
+		revoked: make(map[string]bool),
+	}
+
+	scalars, e := bigsFromBytes(marshal.Sk, marshal.Epoch)
+	if e != nil {
+		return nil, fmt.Errorf("LoadRevocationAuthority: %v", e)
+	}
+	authority.sk, authority.epoch = scalars[0], scalars[1]
+
+	if authority.ys, e = pointsFromBytesInGroup(marshal.Ys, marshal.First, false); e != nil {
+		return nil, fmt.Errorf("LoadRevocationAuthority: ys: %v", e)
+	}
+	authority.pk = pointMultiply(MakeGroth(nil, marshal.First, authority.ys).g2, authority.sk)
+
+	users, e := pointsFromBytesInGroup(marshal.Users, marshal.First, false)
+	if e != nil {
+		return nil, fmt.Errorf("LoadRevocationAuthority: users: %v", e)
+	}
+	for _, userPk := range users {
+		authority.users[pkKey(userPk)] = userPk
+	}
+
+	revoked, e := pointsFromBytesInGroup(marshal.Revoked, marshal.First, false)
+	if e != nil {
+		return nil, fmt.Errorf("LoadRevocationAuthority: revoked: %v", e)
+	}
+	for _, userPk := range revoked {
+		authority.revoked[pkKey(userPk)] = true
+	}
+
+	return
+}
+
+// userKeys returns the keys of the registered users in ascending order.
+// The caller must hold the lock.
+func (authority *RevocationAuthority) userKeys() (keys []string) {
+	keys = make([]string, 0, len(authority.users))
+	for k := range authority.users {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return
+}
+
+// revokedKeys returns the keys of the revoked users in ascending order.
+// The caller must hold the lock.
+func (authority *RevocationAuthority) revokedKeys() (keys []string) {
+	keys = make([]string, 0, len(authority.revoked))
+	for k := range authority.revoked {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return
+}
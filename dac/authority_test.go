@@ -0,0 +1,334 @@
+package dac
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// helper that creates the authority with n registered users, whose keys are in the group opposite to h
+func authoritySetup(prg *amcl.RAND, n int) (authority *RevocationAuthority, userSks []SK, userPks []PK) {
+	const YsNum = 10
+
+	authority = MakeRevocationAuthority(prg, !hFirst, GenerateYs(!hFirst, YsNum, prg))
+
+	for i := 0; i < n; i++ {
+		userSk, userPk := GenerateKeys(prg, map[bool]int{true: 0, false: 1}[hFirst])
+		if e := authority.Register(userPk); e != nil {
+			panic(e)
+		}
+
+		userSks = append(userSks, userSk)
+		userPks = append(userPks, userPk)
+	}
+
+	return
+}
+
+// helper that finds the signature issued to the user
+func authorityFind(issued []NonRevocation, userPk PK) (signature GrothSignature, found bool) {
+	for _, nonRevocation := range issued {
+		if PkEqual(nonRevocation.Pk, userPk) {
+			return nonRevocation.Signature, true
+		}
+	}
+
+	return
+}
+
+// Tests
+
+func TestAuthority(t *testing.T) {
+	for _, first := range []bool{true, false} {
+
+		hFirst = first
+
+		t.Run(fmt.Sprintf("h in g%d", map[bool]int{true: 1, false: 2}[first]), func(t *testing.T) {
+			for _, test := range []func(*testing.T){
+				testAuthorityIssue,
+				testAuthorityIssueWorkers,
+				testAuthorityRevoke,
+				testAuthorityRegisterErrors,
+				testAuthorityAdvanceEpoch,
+				testAuthorityAnnouncement,
+				testAuthorityAnnouncementTampered,
+				testAuthorityAnnouncementParse,
+				testAuthoritySaveLoad,
+				testAuthorityLoadFails,
+				testAuthorityRevocationProof,
+			} {
+				t.Run(funcToString(reflect.ValueOf(test)), test)
+			}
+		})
+	}
+}
+
+func testAuthorityIssue(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	authority, _, userPks := authoritySetup(prg, 4)
+	groth := MakeGroth(prg, !hFirst, authority.Ys())
+	g := generatorSameGroup(userPks[0])
+
+	issued := authority.IssueNonRevocation(prg)
+	assert.Equal(t, len(issued), len(userPks))
+
+	for _, userPk := range userPks {
+		signature, found := authorityFind(issued, userPk)
+		assert.Check(t, found)
+		assert.NilError(t, groth.Verify(authority.PK(), signature, []interface{}{userPk, pointMultiply(g, authority.Epoch())}))
+	}
+
+	for index := 1; index < len(issued); index++ {
+		assert.Check(t, pkKey(issued[index-1].Pk) < pkKey(issued[index].Pk))
+	}
+}
+
+func testAuthorityIssueWorkers(t *testing.T) {
+	defer func(workers uint) { Workers = workers }(Workers)
+
+	for _, workers := range []uint{0, 1, 3, 10} {
+		t.Run(fmt.Sprintf("workers %d", workers), func(t *testing.T) {
+			Workers = workers
+
+			prg := getNewRand(SEED)
+
+			authority, _, userPks := authoritySetup(prg, 5)
+			groth := MakeGroth(prg, !hFirst, authority.Ys())
+			g := generatorSameGroup(userPks[0])
+
+			issued := authority.IssueNonRevocation(prg)
+			assert.Equal(t, len(issued), len(userPks))
+
+			for _, nonRevocation := range issued {
+				assert.NilError(t, groth.Verify(authority.PK(), nonRevocation.Signature, []interface{}{nonRevocation.Pk, pointMultiply(g, authority.Epoch())}))
+			}
+		})
+	}
+}
+
+func testAuthorityRevoke(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	authority, _, userPks := authoritySetup(prg, 3)
+
+	assert.NilError(t, authority.Revoke(userPks[1]))
+	assert.Check(t, authority.IsRevoked(userPks[1]))
+	assert.Check(t, !authority.IsRevoked(userPks[0]))
+
+	issued := authority.IssueNonRevocation(prg)
+	assert.Equal(t, len(issued), 2)
+
+	_, found := authorityFind(issued, userPks[1])
+	assert.Check(t, !found)
+
+	for _, index := range []int{0, 2} {
+		_, found := authorityFind(issued, userPks[index])
+		assert.Check(t, found)
+	}
+
+	// revoked keys cannot come back
+	assert.ErrorContains(t, authority.Register(userPks[1]), "revoked")
+
+	// revoking the remaining keys leaves nobody to sign for
+	assert.NilError(t, authority.Revoke(userPks[0]))
+	assert.NilError(t, authority.Revoke(userPks[2]))
+	assert.Equal(t, len(authority.IssueNonRevocation(prg)), 0)
+}
+
+func testAuthorityRegisterErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	authority, _, _ := authoritySetup(prg, 0)
+
+	_, wrongPk := GenerateKeys(prg, map[bool]int{true: 1, false: 0}[hFirst])
+
+	assert.ErrorContains(t, authority.Register(wrongPk), "point is expected to be in")
+	assert.ErrorContains(t, authority.Register(nil), "Register")
+	assert.ErrorContains(t, authority.Revoke(wrongPk), "point is expected to be in")
+}
+
+func testAuthorityAdvanceEpoch(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	authority, _, userPks := authoritySetup(prg, 2)
+	groth := MakeGroth(prg, !hFirst, authority.Ys())
+	g := generatorSameGroup(userPks[0])
+
+	assert.Check(t, bigEqual(authority.Epoch(), FP256BN.NewBIGint(1)))
+
+	old := authority.IssueNonRevocation(prg)
+
+	epoch := authority.AdvanceEpoch()
+	assert.Check(t, bigEqual(epoch, FP256BN.NewBIGint(2)))
+	assert.Check(t, bigEqual(authority.Epoch(), epoch))
+
+	// returned epoch is a copy
+	epoch.Zero()
+	assert.Check(t, bigEqual(authority.Epoch(), FP256BN.NewBIGint(2)))
+
+	// signatures of the previous epoch are no longer valid
+	assert.ErrorContains(t, groth.Verify(authority.PK(), old[0].Signature, []interface{}{old[0].Pk, pointMultiply(g, authority.Epoch())}), "")
+
+	issued := authority.IssueNonRevocation(prg)
+	assert.NilError(t, groth.Verify(authority.PK(), issued[0].Signature, []interface{}{issued[0].Pk, pointMultiply(g, authority.Epoch())}))
+}
+
+func testAuthorityAnnouncement(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	authority, _, _ := authoritySetup(prg, 0)
+	authority.AdvanceEpoch()
+
+	announcement := authority.Announce(prg)
+
+	assert.Check(t, bigEqual(announcement.Epoch, authority.Epoch()))
+	assert.Check(t, announcement.Issued > 0)
+	assert.NilError(t, announcement.Verify(authority.PK()))
+}
+
+func testAuthorityAnnouncementTampered(t *testing.T) {
+	type TestCase string
+	const (
+		Epoch     TestCase = "epoch"
+		Issued    TestCase = "issued"
+		Signature TestCase = "signature"
+		WrongPK   TestCase = "wrong public key"
+		Missing   TestCase = "missing epoch"
+	)
+
+	for _, tc := range []TestCase{Epoch, Issued, Signature, WrongPK, Missing} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			authority, _, _ := authoritySetup(prg, 0)
+			announcement := authority.Announce(prg)
+			pkRev := authority.PK()
+
+			expected := "verification failed"
+			switch tc {
+			case Epoch:
+				announcement.Epoch = FP256BN.NewBIGint(0x13)
+			case Issued:
+				announcement.Issued++
+			case Signature:
+				announcement.signature.s = FP256BN.NewBIGint(0x13)
+			case WrongPK:
+				pkRev = MakeRevocationAuthority(prg, !hFirst, authority.Ys()).PK()
+			case Missing:
+				announcement.Epoch = nil
+				expected = "incomplete"
+			}
+
+			assert.ErrorContains(t, announcement.Verify(pkRev), expected)
+		})
+	}
+}
+
+func testAuthorityAnnouncementParse(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	authority, _, _ := authoritySetup(prg, 0)
+	announcement := authority.Announce(prg)
+
+	recovered, e := ParseEpochAnnouncement(announcement.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Check(t, bigEqual(recovered.Epoch, announcement.Epoch))
+	assert.Equal(t, recovered.Issued, announcement.Issued)
+	assert.NilError(t, recovered.Verify(authority.PK()))
+
+	_, e = ParseEpochAnnouncement([]byte{0x13})
+	assert.ErrorContains(t, e, "ParseEpochAnnouncement")
+}
+
+func testAuthoritySaveLoad(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	dir, e := ioutil.TempDir("", "authority")
+	assert.NilError(t, e)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "authority.state")
+
+	authority, _, userPks := authoritySetup(prg, 3)
+	assert.NilError(t, authority.Revoke(userPks[0]))
+	authority.AdvanceEpoch()
+
+	assert.NilError(t, authority.Save(path))
+
+	info, e := os.Stat(path)
+	assert.NilError(t, e)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0600))
+
+	loaded, e := LoadRevocationAuthority(path)
+	assert.NilError(t, e)
+
+	assert.Check(t, PkEqual(loaded.PK(), authority.PK()))
+	assert.Check(t, bigEqual(loaded.Epoch(), authority.Epoch()))
+	assert.Check(t, pointListEquals(loaded.Ys(), authority.Ys()))
+	assert.Check(t, loaded.IsRevoked(userPks[0]))
+	assert.Check(t, !loaded.IsRevoked(userPks[1]))
+
+	issued := loaded.IssueNonRevocation(prg)
+	assert.Equal(t, len(issued), 2)
+
+	groth := MakeGroth(prg, !hFirst, loaded.Ys())
+	g := generatorSameGroup(userPks[0])
+	for _, nonRevocation := range issued {
+		assert.NilError(t, groth.Verify(authority.PK(), nonRevocation.Signature, []interface{}{nonRevocation.Pk, pointMultiply(g, authority.Epoch())}))
+	}
+
+	// saving again replaces the file and leaves no temporary files behind
+	assert.NilError(t, loaded.Save(path))
+	files, e := ioutil.ReadDir(dir)
+	assert.NilError(t, e)
+	assert.Equal(t, len(files), 1)
+}
+
+func testAuthorityLoadFails(t *testing.T) {
+	dir, e := ioutil.TempDir("", "authority")
+	assert.NilError(t, e)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "authority.state")
+
+	_, e = LoadRevocationAuthority(path)
+	assert.ErrorContains(t, e, "LoadRevocationAuthority")
+
+	assert.NilError(t, ioutil.WriteFile(path, []byte{0x13}, 0600))
+	_, e = LoadRevocationAuthority(path)
+	assert.ErrorContains(t, e, "LoadRevocationAuthority")
+}
+
+// the full flow: the verifier checks the announcement, then the proof against the announced epoch
+func testAuthorityRevocationProof(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	h := getH(prg)
+
+	authority, userSks, userPks := authoritySetup(prg, 2)
+	authority.AdvanceEpoch()
+
+	issued := authority.IssueNonRevocation(prg)
+	announced := authority.Announce(prg)
+	announcement, e := ParseEpochAnnouncement(announced.ToBytes())
+	assert.NilError(t, e)
+
+	signature, found := authorityFind(issued, userPks[1])
+	assert.Check(t, found)
+
+	skNym, pkNym := GenerateNymKeys(prg, userSks[1], h)
+	proof := RevocationProve(prg, signature, userSks[1], skNym, announcement.Epoch, h, authority.Ys())
+
+	assert.NilError(t, announcement.Verify(authority.PK()))
+	assert.NilError(t, proof.Verify(pkNym, announcement.Epoch, h, authority.PK(), authority.Ys()))
+
+	// the proof does not verify against another epoch
+	assert.ErrorContains(t, proof.Verify(pkNym, FP256BN.NewBIGint(0x13), h, authority.PK(), authority.Ys()), "")
+}
@@ -0,0 +1,538 @@
+package dac
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// ValueType is the type of a typed attribute value
+type ValueType byte
+
+// The types of the attribute values
+const (
+	TypeString ValueType = iota + 1
+	TypeInt64
+	TypeBool
+	TypeTimestamp
+	TypeBytes
+)
+
+func (kind ValueType) String() string {
+	switch kind {
+	case TypeString:
+		return "string"
+	case TypeInt64:
+		return "int64"
+	case TypeBool:
+		return "bool"
+	case TypeTimestamp:
+		return "timestamp"
+	case TypeBytes:
+		return "bytes"
+	default:
+		return "type " + strconv.Itoa(int(kind))
+	}
+}
+
+// MarshalText encodes the type as its name, so that schemas and policies read well in JSON
+func (kind ValueType) MarshalText() ([]byte, error) {
+	if !kind.known() {
+		return nil, fmt.Errorf("unknown value type %d", kind)
+	}
+	return []byte(kind.String()), nil
+}
+
+// UnmarshalText decodes the type from its name
+func (kind *ValueType) UnmarshalText(text []byte) error {
+	for candidate := TypeString; candidate.known(); candidate++ {
+		if candidate.String() == string(text) {
+			*kind = candidate
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown value type %q", text)
+}
+
+// known checks that the type is one of the types above
+func (kind ValueType) known() bool {
+	return kind >= TypeString && kind <= TypeBytes
+}
+
+// Value is a typed attribute value, it encodes deterministically into an attribute of any level (see Value.Attribute).
+// The encoding does not carry the type: strings are encoded as in AttributeFromString,
+// integers as in NumericAttribute and timestamps as in ExpiryAttribute (with a precision of one second),
+// so that the predicates apply to them; booleans and byte strings are hashed.
+// Hence the verifier has to know the type it expects, e.g. from a schema, unless the value comes in a DisclosedProof.
+type Value struct {
+	kind    ValueType
+	payload []byte
+}
+
+// NewString makes a string value
+func NewString(s string) Value {
+	return Value{TypeString, []byte(s)}
+}
+
+// NewInt64 makes an integer value
+func NewInt64(x int64) Value {
+	var payload [8]byte
+	binary.BigEndian.PutUint64(payload[:], uint64(x))
+
+	return Value{TypeInt64, payload[:]}
+}
+
+// NewBool makes a boolean value
+func NewBool(b bool) Value {
+	if b {
+		return Value{TypeBool, []byte{1}}
+	}
+	return Value{TypeBool, []byte{0}}
+}
+
+// NewTimestamp makes a timestamp value, truncated to seconds
+func NewTimestamp(t time.Time) Value {
+	value := NewInt64(t.Unix())
+	value.kind = TypeTimestamp
+
+	return value
+}
+
+// NewBytes makes a byte string value
+func NewBytes(b []byte) Value {
+	return Value{TypeBytes, append([]byte{}, b...)}
+}
+
+// Type returns the type of the value (0 for the zero Value)
+func (value Value) Type() ValueType {
+	return value.kind
+}
+
+// AsString returns the string value
+func (value Value) AsString() (string, error) {
+	if e := value.expect("AsString", TypeString); e != nil {
+		return "", e
+	}
+	return string(value.payload), nil
+}
+
+// AsInt64 returns the integer value
+func (value Value) AsInt64() (int64, error) {
+	if e := value.expect("AsInt64", TypeInt64); e != nil {
+		return 0, e
+	}
+	return int64(binary.BigEndian.Uint64(value.payload)), nil
+}
+
+// AsBool returns the boolean value
+func (value Value) AsBool() (bool, error) {
+	if e := value.expect("AsBool", TypeBool); e != nil {
+		return false, e
+	}
+	return value.payload[0] == 1, nil
+}
+
+// AsTimestamp returns the timestamp value in UTC
+func (value Value) AsTimestamp() (time.Time, error) {
+	if e := value.expect("AsTimestamp", TypeTimestamp); e != nil {
+		return time.Time{}, e
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(value.payload)), 0).UTC(), nil
+}
+
+// AsBytes returns the byte string value
+func (value Value) AsBytes() ([]byte, error) {
+	if e := value.expect("AsBytes", TypeBytes); e != nil {
+		return nil, e
+	}
+	return append([]byte{}, value.payload...), nil
+}
+
+// Equals checks that the values are of the same type and are equal
+func (value Value) Equals(other Value) bool {
+	return value.kind == other.kind && bytes.Equal(value.payload, other.payload)
+}
+
+func (value Value) String() string {
+	if value.validate() != nil {
+		return "invalid value"
+	}
+
+	switch value.kind {
+	case TypeString:
+		s, _ := value.AsString()
+		return strconv.Quote(s)
+	case TypeInt64:
+		x, _ := value.AsInt64()
+		return strconv.FormatInt(x, 10)
+	case TypeBool:
+		b, _ := value.AsBool()
+		return strconv.FormatBool(b)
+	case TypeTimestamp:
+		t, _ := value.AsTimestamp()
+		return t.Format(time.RFC3339)
+	default:
+		return hex.EncodeToString(value.payload)
+	}
+}
+
+// Exponent returns the exponent of the attributes encoding the value, the attribute is the generator to its power.
+// Supply it in AttributeValues and list it in Membership predicates.
+func (value Value) Exponent() (*FP256BN.BIG, error) {
+	if e := value.validate(); e != nil {
+		return nil, fmt.Errorf("Value.Exponent: %v", e)
+	}
+
+	switch value.kind {
+	case TypeString:
+		return StringValue(string(value.payload)), nil
+	case TypeBool, TypeBytes:
+		t := &transcript{}
+		t.append(value.kind.String(), value.payload)
+		return t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order)), nil
+	default:
+		return NumericValue(int64(binary.BigEndian.Uint64(value.payload))), nil
+	}
+}
+
+// Attribute encodes the value as an attribute of level L (in G1 for odd levels and in G2 for even ones)
+func (value Value) Attribute(L int) (Attribute, error) {
+	if L < 1 {
+		return Attribute{}, fmt.Errorf("attributes exist only for levels 1 and deeper, got %d", L)
+	}
+
+	exponent, e := value.Exponent()
+	if e != nil {
+		return Attribute{}, e
+	}
+	if bigEqual(exponent, FP256BN.NewBIG()) {
+		return Attribute{}, fmt.Errorf("value %s encodes the point at infinity", value)
+	}
+
+	g, _ := TypedPoint(pointMultiply(levelGenerator(L), exponent))
+	return Attribute{g}, nil
+}
+
+// EncodeAttributes encodes the values as the attributes of level L, see Value.Attribute
+func EncodeAttributes(L int, values ...Value) (attributes []Attribute, e error) {
+	attributes = make([]Attribute, len(values))
+	for index, value := range values {
+		if attributes[index], e = value.Attribute(L); e != nil {
+			return nil, fmt.Errorf("EncodeAttributes: value %d: %v", index, e)
+		}
+	}
+
+	return
+}
+
+// Disclose makes a DisclosedValue for disclosing the attribute at level i and position j, which encodes the value
+func (value Value) Disclose(i, j int) DisclosedValue {
+	return DisclosedValue{i, j, value}
+}
+
+// valueJSON is the JSON form of a value: the name of the type, and the value as a JSON string (string, timestamp in RFC 3339),
+// number (int64), boolean or base64 string (bytes)
+type valueJSON struct {
+	Type  ValueType
+	Value json.RawMessage
+}
+
+// MarshalJSON encodes the value along with its type, e.g. {"Type":"int64","Value":25}
+func (value Value) MarshalJSON() ([]byte, error) {
+	if e := value.validate(); e != nil {
+		return nil, e
+	}
+
+	var raw interface{}
+	switch value.kind {
+	case TypeString:
+		raw, _ = value.AsString()
+	case TypeInt64:
+		raw, _ = value.AsInt64()
+	case TypeBool:
+		raw, _ = value.AsBool()
+	case TypeTimestamp:
+		t, _ := value.AsTimestamp()
+		raw = t.Format(time.RFC3339)
+	default:
+		raw = value.payload
+	}
+
+	data, _ := json.Marshal(raw)
+	return json.Marshal(valueJSON{value.kind, data})
+}
+
+// UnmarshalJSON decodes the value encoded by MarshalJSON
+func (value *Value) UnmarshalJSON(data []byte) (e error) {
+	var marshal valueJSON
+	if e = json.Unmarshal(data, &marshal); e != nil {
+		return
+	}
+
+	switch marshal.Type {
+	case TypeString:
+		var s string
+		e = json.Unmarshal(marshal.Value, &s)
+		*value = NewString(s)
+	case TypeInt64:
+		var x int64
+		e = json.Unmarshal(marshal.Value, &x)
+		*value = NewInt64(x)
+	case TypeBool:
+		var b bool
+		e = json.Unmarshal(marshal.Value, &b)
+		*value = NewBool(b)
+	case TypeTimestamp:
+		var t time.Time
+		e = json.Unmarshal(marshal.Value, &t)
+		*value = NewTimestamp(t)
+	case TypeBytes:
+		var b []byte
+		e = json.Unmarshal(marshal.Value, &b)
+		*value = NewBytes(b)
+	default:
+		return fmt.Errorf("value type is missing")
+	}
+	if e != nil {
+		return fmt.Errorf("%s value: %v", marshal.Type, e)
+	}
+
+	return
+}
+
+// ToBytes marshals the value using ASN1 encoding
+func (value Value) ToBytes() (result []byte) {
+	result, _ = asn1.Marshal(value.marshal())
+
+	return
+}
+
+// ParseValue un-marshals and validates the value using ASN1 encoding
+func ParseValue(input []byte) (value Value, e error) {
+	var marshal valueMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return Value{}, fmt.Errorf("ParseValue: %v", e)
+	}
+
+	if value, e = marshal.unmarshal(); e != nil {
+		return Value{}, fmt.Errorf("ParseValue: %v", e)
+	}
+
+	return
+}
+
+// expect returns error if the value is not of the given type
+func (value Value) expect(method string, kind ValueType) error {
+	if value.kind != kind {
+		return fmt.Errorf("Value.%s: value is of type %s, not %s", method, value.kind, kind)
+	}
+	return nil
+}
+
+// validate checks that the payload fits the type
+func (value Value) validate() error {
+	var length int
+	switch value.kind {
+	case TypeString, TypeBytes:
+		return nil
+	case TypeInt64, TypeTimestamp:
+		length = 8
+	case TypeBool:
+		if len(value.payload) == 1 && value.payload[0] > 1 {
+			return fmt.Errorf("bool value must be 0 or 1, got %d", value.payload[0])
+		}
+		length = 1
+	default:
+		return fmt.Errorf("unknown value type %d", value.kind)
+	}
+
+	if len(value.payload) != length {
+		return fmt.Errorf("%s value must be %d bytes, got %d", value.kind, length, len(value.payload))
+	}
+
+	return nil
+}
+
+type valueMarshal struct {
+	Type    int
+	Payload []byte
+}
+
+func (value Value) marshal() valueMarshal {
+	return valueMarshal{int(value.kind), value.payload}
+}
+
+func (marshal valueMarshal) unmarshal() (value Value, e error) {
+	if marshal.Type < 0 || marshal.Type > 0xFF {
+		return Value{}, fmt.Errorf("unknown value type %d", marshal.Type)
+	}
+
+	value = Value{ValueType(marshal.Type), marshal.Payload}
+	if value.payload == nil {
+		value.payload = []byte{}
+	}
+
+	if e = value.validate(); e != nil {
+		return Value{}, e
+	}
+
+	return
+}
+
+// Disclosed values
+
+// DisclosedValue is the typed value of the disclosed attribute at level I and position J
+type DisclosedValue struct {
+	I, J  int
+	Value Value
+}
+
+// DisclosedValues is the set of disclosed typed values, it replaces Indices in ProveDisclosing
+type DisclosedValues []DisclosedValue
+
+// Indices encodes the values as the attributes of their levels, the result is the D of Prove and VerifyProof
+func (disclosed DisclosedValues) Indices() (D Indices, e error) {
+	D = make(Indices, len(disclosed))
+	for index, value := range disclosed {
+		attribute, e := value.Value.Attribute(value.I)
+		if e != nil {
+			return nil, fmt.Errorf("disclosed value of attribute (%d, %d): %v", value.I, value.J, e)
+		}
+		D[index] = attribute.Disclose(value.I, value.J)
+	}
+
+	return
+}
+
+// Value looks up the disclosed value of the attribute at level i and position j
+func (disclosed DisclosedValues) Value(i, j int) (Value, bool) {
+	for _, value := range disclosed {
+		if value.I == i && value.J == j {
+			return value.Value, true
+		}
+	}
+
+	return Value{}, false
+}
+
+// DisclosedProof is a credentials proof along with the typed values of the disclosed attributes.
+// The verifier re-encodes the values (see DisclosedProof.Verify), so that the application reads them from Values.
+// The proof is made over the message bound to the positions and the types of the values,
+// so that a value cannot be relabeled with another type of the same encoding (e.g. a timestamp as an integer).
+type DisclosedProof struct {
+	Proof  Proof
+	Values DisclosedValues
+}
+
+// ProveDisclosing is Prove that discloses the attributes as typed values.
+// Returns error if a value does not encode the attribute of the credentials.
+func (creds *Credentials) ProveDisclosing(prg *amcl.RAND, sk SK, pk PK, disclosed DisclosedValues, m []byte, grothYs [][]interface{}, h interface{}, skNym SK) (proof DisclosedProof, e error) {
+	D, e := disclosed.Indices()
+	if e != nil {
+		return proof, fmt.Errorf("ProveDisclosing: %v", e)
+	}
+
+	if e = creds.checkDisclosed(D); e != nil {
+		return proof, fmt.Errorf("ProveDisclosing: %v", e)
+	}
+
+	if proof.Proof, e = creds.Prove(prg, sk, pk, D, disclosed.message(m), grothYs, h, skNym); e != nil {
+		return
+	}
+	proof.Values = append(DisclosedValues{}, disclosed...)
+
+	return
+}
+
+// checkDisclosed makes sure the disclosed attributes (re-encoded from the values) are those of the credentials
+func (creds *Credentials) checkDisclosed(D Indices) error {
+	for _, index := range D {
+		if index.I < 1 || index.I >= len(creds.Attributes) || index.J < 0 || index.J >= len(creds.Attributes[index.I]) {
+			return fmt.Errorf("disclosed attribute (%d, %d) does not exist", index.I, index.J)
+		}
+		if !pointEqual(index.Attribute, creds.Attributes[index.I][index.J]) {
+			return fmt.Errorf("disclosed value of attribute (%d, %d) does not match the attribute", index.I, index.J)
+		}
+	}
+
+	return nil
+}
+
+// Verify re-encodes the disclosed values and verifies the credentials proof with them (see VerifyProof)
+func (proof *DisclosedProof) Verify(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, m []byte) (e error) {
+	D, e := proof.Values.Indices()
+	if e != nil {
+		return fmt.Errorf("DisclosedProof.Verify: %v", e)
+	}
+
+	return proof.Proof.VerifyProof(pk, grothYs, h, pkNym, D, proof.Values.message(m))
+}
+
+// message binds m to the positions and the types of the values, the credentials proof is made over it
+func (disclosed DisclosedValues) message(m []byte) []byte {
+	t := newTranscript(_ProtocolDisclosedValues, binding{})
+	for _, value := range disclosed {
+		t.append("position", []byte(fmt.Sprintf("%d-%d", value.I, value.J)))
+		t.append("type", []byte{byte(value.Value.kind)})
+	}
+	t.append("m", m)
+
+	return t.digest()
+}
+
+type disclosedValueMarshal struct {
+	I, J  int
+	Value valueMarshal
+}
+
+type disclosedProofMarshal struct {
+	Proof  []byte
+	Values []disclosedValueMarshal
+}
+
+// ToBytes marshals the proof along with the values using ASN1 encoding
+func (proof *DisclosedProof) ToBytes() (result []byte) {
+	var marshal disclosedProofMarshal
+
+	marshal.Proof = proof.Proof.ToBytes()
+	marshal.Values = make([]disclosedValueMarshal, len(proof.Values))
+	for index, value := range proof.Values {
+		marshal.Values[index] = disclosedValueMarshal{value.I, value.J, value.Value.marshal()}
+	}
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParseDisclosedProof un-marshals and validates the proof along with the values using ASN1 encoding
+func ParseDisclosedProof(input []byte) (proof *DisclosedProof, e error) {
+	var marshal disclosedProofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseDisclosedProof: %v", e)
+	}
+
+	credsProof, e := ParseProof(marshal.Proof)
+	if e != nil {
+		return nil, fmt.Errorf("ParseDisclosedProof: %v", e)
+	}
+
+	proof = &DisclosedProof{Proof: *credsProof, Values: make(DisclosedValues, len(marshal.Values))}
+	for index, valueMarshal := range marshal.Values {
+		value, e := valueMarshal.Value.unmarshal()
+		if e != nil {
+			return nil, fmt.Errorf("ParseDisclosedProof: value %d: %v", index, e)
+		}
+		proof.Values[index] = DisclosedValue{valueMarshal.I, valueMarshal.J, value}
+	}
+
+	return
+}
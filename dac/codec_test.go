@@ -0,0 +1,402 @@
+package dac
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// helper that constructs a chain of 2 levels, where the attributes at level 1 encode "admin" and 25,
+// and the attributes at level 2 encode true and _Now
+func codecChain() (creds *Credentials, sk SK, pk PK, ys [][]interface{}, skNym SK, pkNym PK, h interface{}) {
+	return generateChainWith(SEED, 2, 2, map[Position]interface{}{
+		{1, 0}: NewString("admin"),
+		{1, 1}: NewInt64(25),
+		{2, 0}: NewBool(true),
+		{2, 1}: NewTimestamp(_Now),
+	})
+}
+
+// Tests
+
+func TestCodec(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testCodecValues,
+		testCodecEncoding,
+		testCodecMarshal,
+		testCodecHappyPath,
+		testCodecZeroValues,
+		testCodecPredicates,
+		testCodecProveErrors,
+		testCodecVerificationFail,
+		testCodecProofMarshal,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testCodecValues(t *testing.T) {
+	s, e := NewString("hello").AsString()
+	assert.NilError(t, e)
+	assert.Equal(t, s, "hello")
+
+	for _, x := range []int64{0, 25, -25, math.MaxInt64, math.MinInt64} {
+		recovered, e := NewInt64(x).AsInt64()
+		assert.NilError(t, e)
+		assert.Equal(t, recovered, x)
+	}
+
+	for _, b := range []bool{true, false} {
+		recovered, e := NewBool(b).AsBool()
+		assert.NilError(t, e)
+		assert.Equal(t, recovered, b)
+	}
+
+	timestamp, e := NewTimestamp(_Now.Add(time.Millisecond).In(time.Local)).AsTimestamp()
+	assert.NilError(t, e)
+	assert.Equal(t, timestamp, _Now)
+
+	input := []byte{0x13, 0x14}
+	value := NewBytes(input)
+	input[0] = 0x15
+	recovered, e := value.AsBytes()
+	assert.NilError(t, e)
+	assert.DeepEqual(t, recovered, []byte{0x13, 0x14})
+
+	_, e = NewString("hello").AsInt64()
+	assert.ErrorContains(t, e, "Value.AsInt64: value is of type string, not int64")
+
+	_, e = NewInt64(1).AsBool()
+	assert.ErrorContains(t, e, "value is of type int64, not bool")
+
+	_, e = Value{}.AsString()
+	assert.ErrorContains(t, e, "value is of type type 0, not string")
+
+	assert.Check(t, NewString("hello").Equals(NewString("hello")))
+	assert.Check(t, !NewString("hello").Equals(NewBytes([]byte("hello"))))
+	assert.Check(t, !NewInt64(1).Equals(NewBool(true)))
+
+	for value, expected := range map[*Value]string{
+		{}:                                "invalid value",
+		{TypeString, []byte("hi")}:        `"hi"`,
+		{TypeInt64, NewInt64(-1).payload}: "-1",
+		{TypeBool, []byte{1}}:             "true",
+		{TypeTimestamp, NewTimestamp(_Now).payload}: "2020-01-01T00:00:00Z",
+		{TypeBytes, []byte{0x13}}:                   "13",
+	} {
+		assert.Equal(t, value.String(), expected)
+	}
+}
+
+func testCodecEncoding(t *testing.T) {
+	for L := 1; L <= 2; L++ {
+		for _, tc := range []struct {
+			value    Value
+			expected interface{}
+		}{
+			{NewString("hello"), AttributeFromString("hello", LevelInG1(L))},
+			{NewInt64(25), NumericAttribute(L, 25)},
+			{NewInt64(-25), NumericAttribute(L, -25)},
+			{NewTimestamp(_Now), ExpiryAttribute(L, _Now)},
+		} {
+			attribute, e := tc.value.Attribute(L)
+			assert.NilError(t, e)
+			assert.Equal(t, attribute.InG1(), LevelInG1(L))
+			assert.Check(t, pointEqual(attribute.Untyped(), tc.expected), "value %s at level %d", tc.value, L)
+		}
+
+		// booleans are neither 0 nor 1
+		yes, e := NewBool(true).Attribute(L)
+		assert.NilError(t, e)
+		no, e := NewBool(false).Attribute(L)
+		assert.NilError(t, e)
+		assert.Check(t, !pointEqual(yes.Untyped(), no.Untyped()))
+		assert.Check(t, !pointEqual(yes.Untyped(), NumericAttribute(L, 1)))
+
+		// byte strings are not encoded as strings
+		attribute, e := NewBytes([]byte("hello")).Attribute(L)
+		assert.NilError(t, e)
+		assert.Check(t, !pointEqual(attribute.Untyped(), AttributeFromString("hello", LevelInG1(L))))
+
+		again, _ := NewBytes([]byte("hello")).Attribute(L)
+		assert.Check(t, pointEqual(attribute.Untyped(), again.Untyped()))
+
+		attributes, e := EncodeAttributes(L, NewString("hello"), NewInt64(25))
+		assert.NilError(t, e)
+		assert.Equal(t, len(attributes), 2)
+		assert.Check(t, pointEqual(attributes[1].Untyped(), NumericAttribute(L, 25)))
+	}
+
+	exponent, e := NewString("hello").Exponent()
+	assert.NilError(t, e)
+	assert.Check(t, bigEqual(exponent, StringValue("hello")))
+
+	_, e = NewString("hello").Attribute(0)
+	assert.ErrorContains(t, e, "levels 1 and deeper")
+
+	zero, e := NewInt64(0).Attribute(1)
+	assert.NilError(t, e)
+	assert.Check(t, pointEqual(zero.Untyped(), NumericAttribute(1, 0)))
+
+	epoch, e := NewTimestamp(time.Unix(0, 0)).Attribute(2)
+	assert.NilError(t, e)
+	assert.Check(t, pointEqual(epoch.Untyped(), ExpiryAttribute(2, time.Unix(0, 0))))
+
+	_, e = Value{}.Attribute(1)
+	assert.ErrorContains(t, e, "Value.Exponent: unknown value type 0")
+
+	_, e = EncodeAttributes(1, NewString("hello"), Value{TypeBool, []byte{2}})
+	assert.ErrorContains(t, e, "EncodeAttributes: value 1: Value.Exponent: bool value must be 0 or 1, got 2")
+}
+
+func testCodecMarshal(t *testing.T) {
+	for _, value := range []Value{
+		NewString("hello"),
+		NewString(""),
+		NewInt64(-25),
+		NewBool(true),
+		NewTimestamp(_Now),
+		NewBytes([]byte{0x13, 0x14}),
+		NewBytes(nil),
+	} {
+		recovered, e := ParseValue(value.ToBytes())
+		assert.NilError(t, e)
+		assert.Check(t, recovered.Equals(value), "value %s", value)
+	}
+
+	type TestCase string
+	const (
+		UnknownType  TestCase = "unknown type"
+		NegativeType TestCase = "negative type"
+		ShortInt     TestCase = "short int64"
+		LongBool     TestCase = "long bool"
+		InvalidBool  TestCase = "invalid bool"
+	)
+
+	for _, tc := range []TestCase{UnknownType, NegativeType, ShortInt, LongBool, InvalidBool} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal valueMarshal
+			var expected string
+
+			bytes := remarshal(t, NewBool(true).ToBytes(), &marshal, func() {
+				switch tc {
+				case UnknownType:
+					marshal.Type = 0x13
+					expected = "unknown value type 19"
+				case NegativeType:
+					marshal.Type = -1
+					expected = "unknown value type -1"
+				case ShortInt:
+					marshal.Type = int(TypeInt64)
+					expected = "int64 value must be 8 bytes, got 1"
+				case LongBool:
+					marshal.Payload = []byte{1, 1}
+					expected = "bool value must be 1 bytes, got 2"
+				case InvalidBool:
+					marshal.Payload = []byte{2}
+					expected = "bool value must be 0 or 1, got 2"
+				}
+			})
+
+			_, e := ParseValue(bytes)
+			assert.ErrorContains(t, e, "ParseValue: "+expected)
+		})
+	}
+
+	_, e := ParseValue(append(NewBool(true).ToBytes(), 0x13))
+	assert.ErrorContains(t, e, "trailing")
+}
+
+func testCodecHappyPath(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := codecChain()
+
+	disclosed := DisclosedValues{NewString("admin").Disclose(1, 0), NewTimestamp(_Now).Disclose(2, 1)}
+	m := []byte("message")
+
+	proof, e := creds.ProveDisclosing(prg, sk, pk, disclosed, m, ys, h, skNym)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.Verify(pk, ys, h, pkNym, m))
+
+	value, ok := proof.Values.Value(2, 1)
+	assert.Check(t, ok)
+	timestamp, e := value.AsTimestamp()
+	assert.NilError(t, e)
+	assert.Equal(t, timestamp, _Now)
+
+	_, ok = proof.Values.Value(1, 1)
+	assert.Check(t, !ok)
+
+	// the proof is a regular credentials proof over the message bound to the types
+	D, e := disclosed.Indices()
+	assert.NilError(t, e)
+	assert.NilError(t, proof.Proof.VerifyProof(pk, ys, h, pkNym, D, disclosed.message(m)))
+
+	// nothing disclosed
+	proof, e = creds.ProveDisclosing(prg, sk, pk, DisclosedValues{}, m, ys, h, skNym)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.Verify(pk, ys, h, pkNym, m))
+}
+
+func testCodecZeroValues(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{
+		{1, 0}: NewInt64(0),
+		{1, 1}: NewBool(false),
+		{2, 0}: NewTimestamp(time.Unix(0, 0)),
+	})
+
+	assert.NilError(t, creds.Verify(sk, pk, ys))
+	_, e := ParseCredentials(creds.ToBytes())
+	assert.NilError(t, e)
+
+	disclosed := DisclosedValues{NewInt64(0).Disclose(1, 0), NewBool(false).Disclose(1, 1), NewTimestamp(time.Unix(0, 0)).Disclose(2, 0)}
+	m := []byte("message")
+
+	proof, e := creds.ProveDisclosing(prg, sk, pk, disclosed, m, ys, h, skNym)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.Verify(pk, ys, h, pkNym, m))
+
+	// the predicates apply to the zero values too
+	zero, _ := NewInt64(0).Exponent()
+	no, _ := NewBool(false).Exponent()
+	yes, _ := NewBool(true).Exponent()
+	predicates := Predicates{
+		Ranges:      []Range{Between(Position{1, 0}, 0, 0)},
+		Memberships: []Membership{NotMemberOf(Position{1, 1}, yes)},
+	}
+	predicateProof, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, m, ys, h, skNym, predicates, AttributeValues{{1, 0}: zero, {1, 1}: no})
+	assert.NilError(t, e)
+	assert.NilError(t, predicateProof.Verify(pk, ys, h, pkNym, Indices{}, m, predicates))
+}
+
+func testCodecPredicates(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := codecChain()
+
+	admin, _ := NewString("admin").Exponent()
+	user, _ := NewString("user").Exponent()
+	age, _ := NewInt64(25).Exponent()
+
+	predicates := Predicates{
+		Expiry:      &Expiry{Position{2, 1}, _Now.Add(-time.Hour)},
+		Ranges:      []Range{AtLeast(Position{1, 1}, 18)},
+		Memberships: []Membership{MemberOf(Position{1, 0}, admin, user)},
+	}
+	values := AttributeValues{{1, 0}: admin, {1, 1}: age, {2, 1}: ExpiryValue(_Now)}
+
+	proof, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, predicates, values)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.Verify(pk, ys, h, pkNym, Indices{}, []byte("message"), predicates))
+}
+
+func testCodecProveErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, _, h := codecChain()
+
+	for _, tc := range []struct {
+		disclosed DisclosedValues
+		expected  string
+	}{
+		{DisclosedValues{NewString("user").Disclose(1, 0)}, "disclosed value of attribute (1, 0) does not match the attribute"},
+		{DisclosedValues{NewBool(true).Disclose(1, 1)}, "disclosed value of attribute (1, 1) does not match the attribute"},
+		{DisclosedValues{NewString("admin").Disclose(3, 0)}, "disclosed attribute (3, 0) does not exist"},
+		{DisclosedValues{NewString("admin").Disclose(1, 2)}, "disclosed attribute (1, 2) does not exist"},
+		{DisclosedValues{NewString("admin").Disclose(0, 0)}, "disclosed value of attribute (0, 0): attributes exist only for levels 1 and deeper"},
+		{DisclosedValues{Value{}.Disclose(1, 0)}, "disclosed value of attribute (1, 0): Value.Exponent: unknown value type 0"},
+	} {
+		_, e := creds.ProveDisclosing(prg, sk, pk, tc.disclosed, []byte("message"), ys, h, skNym)
+		assert.ErrorContains(t, e, "ProveDisclosing: "+tc.expected)
+	}
+}
+
+func testCodecVerificationFail(t *testing.T) {
+	type TestCase string
+	const (
+		AnotherValue TestCase = "another value"
+		AnotherType  TestCase = "another type"
+		Dropped      TestCase = "value dropped"
+		Moved        TestCase = "value moved"
+		Relabeled    TestCase = "type relabeled"
+		Invalid      TestCase = "invalid value"
+		Message      TestCase = "wrong message"
+	)
+
+	for _, tc := range []TestCase{AnotherValue, AnotherType, Dropped, Moved, Relabeled, Invalid, Message} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, pk, ys, skNym, pkNym, h := codecChain()
+
+			m := []byte("message")
+			proof, e := creds.ProveDisclosing(prg, sk, pk, DisclosedValues{NewString("admin").Disclose(1, 0), NewBool(true).Disclose(2, 0)}, m, ys, h, skNym)
+			assert.NilError(t, e)
+
+			expected := "verification failed"
+			switch tc {
+			case AnotherValue:
+				proof.Values[0].Value = NewString("root")
+			case AnotherType:
+				// booleans are hashed, true is not the integer 1
+				proof.Values[1].Value = NewInt64(1)
+			case Dropped:
+				proof.Values = proof.Values[:1]
+				expected = "response for the hidden attribute (2, 0) is missing"
+			case Moved:
+				proof.Values[0].J = 1
+				expected = "response for the hidden attribute (1, 0) is missing"
+			case Relabeled:
+				// the timestamp and the integer of its seconds encode the same attribute
+				proof, e = creds.ProveDisclosing(prg, sk, pk, DisclosedValues{NewTimestamp(_Now).Disclose(2, 1)}, m, ys, h, skNym)
+				assert.NilError(t, e)
+				proof.Values[0].Value = NewInt64(_Now.Unix())
+			case Invalid:
+				proof.Values[1].Value = Value{TypeBool, []byte{2}}
+				expected = "DisclosedProof.Verify: disclosed value of attribute (2, 0): Value.Exponent: bool value must be 0 or 1, got 2"
+			case Message:
+				m = []byte("another message")
+			}
+
+			assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, m), expected)
+		})
+	}
+}
+
+func testCodecProofMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := codecChain()
+
+	m := []byte("message")
+	proof, e := creds.ProveDisclosing(prg, sk, pk, DisclosedValues{NewString("admin").Disclose(1, 0), NewInt64(25).Disclose(1, 1)}, m, ys, h, skNym)
+	assert.NilError(t, e)
+
+	recovered, e := ParseDisclosedProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.Verify(pk, ys, h, pkNym, m))
+	assert.DeepEqual(t, recovered.ToBytes(), proof.ToBytes())
+
+	value, ok := recovered.Values.Value(1, 1)
+	assert.Check(t, ok)
+	x, e := value.AsInt64()
+	assert.NilError(t, e)
+	assert.Equal(t, x, int64(25))
+
+	_, e = ParseDisclosedProof(append(proof.ToBytes(), 0x13))
+	assert.ErrorContains(t, e, "trailing")
+
+	var marshal disclosedProofMarshal
+	_, e = ParseDisclosedProof(remarshal(t, proof.ToBytes(), &marshal, func() { marshal.Values[1].Value.Type = 0x13 }))
+	assert.ErrorContains(t, e, "ParseDisclosedProof: value 1: unknown value type 19")
+
+	_, e = ParseDisclosedProof(remarshal(t, proof.ToBytes(), &marshal, func() { marshal.Proof = marshal.Proof[1:] }))
+	assert.ErrorContains(t, e, "ParseDisclosedProof: ParseProof")
+}
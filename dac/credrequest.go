@@ -23,6 +23,12 @@ type CredRequest struct {
 // L is a level of credentials for which the request is generated
 // (should match public key type)
 func MakeCredRequest(prg *amcl.RAND, sk SK, nonce []byte, L int) (credReq *CredRequest) {
+	return MakeCredRequestWithContext(prg, sk, nonce, L, "")
+}
+
+// MakeCredRequestWithContext is MakeCredRequest for the request bound to the context (e.g. the issuer's name).
+// Validate the request with ValidateWithContext.
+func MakeCredRequestWithContext(prg *amcl.RAND, sk SK, nonce []byte, L int, context string) (credReq *CredRequest) {
 	credReq = &CredRequest{}
 
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
@@ -42,7 +48,7 @@ func MakeCredRequest(prg *amcl.RAND, sk SK, nonce []byte, L int) (credReq *CredR
 	credReq.Pk = pointMultiply(g, sk)
 
 	// c := H(t, y, nonce)
-	c := hashCredRequest(q, credReq.ResT, credReq.Pk, nonce)
+	c := hashCredRequest(q, context, credReq.ResT, credReq.Pk, nonce)
 
 	// r := v + x * c
 	credReq.ResR = v.Plus(FP256BN.Modmul(sk, c, q))
@@ -56,11 +62,24 @@ func MakeCredRequest(prg *amcl.RAND, sk SK, nonce []byte, L int) (credReq *CredR
 // Validate verifies the NIZK
 // Note that cheking the nonce is not included (needs to be done separately)
 func (credReq *CredRequest) Validate() (e error) {
+	return credReq.ValidateWithContext("")
+}
+
+// ValidateWithContext verifies the NIZK of the request generated with MakeCredRequestWithContext.
+// The context has to be the one the request was generated with.
+func (credReq *CredRequest) ValidateWithContext(context string) (e error) {
+	if e = ValidatePoint(credReq.Pk); e != nil {
+		return fmt.Errorf("CredRequest.Validate: invalid public key: %v", e)
+	}
+	if e = ValidatePoint(credReq.ResT); e != nil {
+		return fmt.Errorf("CredRequest.Validate: invalid commitment: %v", e)
+	}
+
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 	g := generatorSameGroup(credReq.ResT)
 
 	// c := H(t, y, nonce)
-	c := hashCredRequest(q, credReq.ResT, credReq.Pk, credReq.Nonce)
+	c := hashCredRequest(q, context, credReq.ResT, credReq.Pk, credReq.Nonce)
 
 	// t' := g^r * y^-c
 	t := productOfExponents(g, credReq.ResR, pointNegate(credReq.Pk), c)
@@ -73,13 +92,15 @@ func (credReq *CredRequest) Validate() (e error) {
 	return
 }
 
-func hashCredRequest(q *FP256BN.BIG, t interface{}, y interface{}, nonce []byte) *FP256BN.BIG {
-	var raw []byte
-	raw = append(raw, PointToBytes(t)...)
-	raw = append(raw, PointToBytes(y)...)
-	raw = append(raw, nonce...)
+func hashCredRequest(q *FP256BN.BIG, context string, t interface{}, y interface{}, nonce []byte) *FP256BN.BIG {
+	transcript := newTranscript(_ProtocolCredRequest, binding{context: context})
+
+	transcript.appendPoint("g", generatorSameGroup(y))
+	transcript.appendPoint("t", t)
+	transcript.appendPoint("y", y)
+	transcript.append("nonce", nonce)
 
-	return sha3(q, raw)
+	return transcript.challenge(q)
 }
 
 type credRequestMarshal struct {
@@ -90,18 +111,36 @@ type credRequestMarshal struct {
 }
 
 // CredRequestFromBytes un-marshals the credential request object using ASN1 encoding
+// Panics if the input is malformed, see ParseCredRequest for a version that returns error.
 func CredRequestFromBytes(input []byte) (credReq *CredRequest) {
+	credReq, e := ParseCredRequest(input)
+	if e != nil {
+		panic("un-marshalling cred-request failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseCredRequest un-marshals and validates the credential request object using ASN1 encoding
+func ParseCredRequest(input []byte) (credReq *CredRequest, e error) {
 	var marshal credRequestMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling cred-request failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseCredRequest: %v", e)
 	}
 
 	credReq = &CredRequest{}
 
 	credReq.Nonce = marshal.Nonce
-	credReq.Pk, _ = PointFromBytes(marshal.PK)
-	credReq.ResT, _ = PointFromBytes(marshal.ResT)
-	credReq.ResR = FP256BN.FromBytes(marshal.ResR)
+	var first bool
+	if credReq.Pk, first, e = requiredPointFromBytes(marshal.PK); e != nil {
+		return nil, fmt.Errorf("ParseCredRequest: public key: %v", e)
+	}
+	if credReq.ResT, e = pointFromBytesInGroup(marshal.ResT, first, false); e != nil {
+		return nil, fmt.Errorf("ParseCredRequest: t: %v", e)
+	}
+	if credReq.ResR, e = bigFromBytes(marshal.ResR); e != nil {
+		return nil, fmt.Errorf("ParseCredRequest: r: %v", e)
+	}
 
 	return
 }
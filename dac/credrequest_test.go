@@ -29,8 +29,12 @@ func TestCredRequest(t *testing.T) {
 				testCredRequestValidateNoCrash,
 				testCredRequestValidateCorrect,
 				testCredRequestValidateTampered,
+				testCredRequestValidateContext,
 				testCredRequestMarshaling,
 				testCredRequestUnMarshalingFail,
+				testCredRequestParse,
+				testCredRequestParseRejectsMalformed,
+				testCredRequestRejectsInvalidPoints,
 			} {
 				t.Run(funcToString(reflect.ValueOf(test)), test)
 			}
@@ -145,6 +149,22 @@ func testCredRequestValidateCorrect(t *testing.T) {
 	assert.Check(t, result)
 }
 
+// request bound to a context validates only with that context, also after marshalling
+func testCredRequestValidateContext(t *testing.T) {
+	prg := getNewRand(SEED + 3)
+
+	sk, _ := GenerateKeys(prg, L)
+	credReq := MakeCredRequestWithContext(prg, sk, credRequestNonce, L, "issuer")
+
+	assert.NilError(t, credReq.ValidateWithContext("issuer"))
+	assert.ErrorContains(t, credReq.ValidateWithContext("another issuer"), "verification failed")
+	assert.ErrorContains(t, credReq.Validate(), "verification failed")
+
+	recovered, e := ParseCredRequest(credReq.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.ValidateWithContext("issuer"))
+}
+
 // validation rejects malformed request
 func testCredRequestValidateTampered(t *testing.T) {
 
@@ -207,6 +227,88 @@ func testCredRequestUnMarshalingFail(t *testing.T) {
 	CredRequestFromBytes([]byte{0x13})
 }
 
+// parsing yields the original object
+func testCredRequestParse(t *testing.T) {
+	prg := getNewRand(SEED + 3)
+
+	sk, _ := GenerateKeys(prg, L)
+	credReq := MakeCredRequest(prg, sk, credRequestNonce, L)
+
+	recovered, e := ParseCredRequest(credReq.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Check(t, credReq.equal(recovered))
+}
+
+// parsing rejects malformed input without panicking
+func testCredRequestParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed   TestCase = "malformed ASN1"
+		OffCurve    TestCase = "public key not on curve"
+		Infinity    TestCase = "public key at infinity"
+		WrongGroup  TestCase = "t in wrong group"
+		LargeScalar TestCase = "scalar not reduced"
+		ShortScalar TestCase = "short scalar"
+	)
+
+	prg := getNewRand(SEED + 3)
+
+	sk, _ := GenerateKeys(prg, L)
+	credReq := MakeCredRequest(prg, sk, credRequestNonce, L)
+
+	for _, tc := range []TestCase{Malformed, OffCurve, Infinity, WrongGroup, LargeScalar, ShortScalar} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal credRequestMarshal
+			bytes := remarshal(t, credReq.ToBytes(), &marshal, func() {
+				switch tc {
+				case OffCurve:
+					marshal.PK = offCurveBytes(L%2 == 1)
+				case Infinity:
+					marshal.PK = infinityBytes(L%2 == 1)
+				case WrongGroup:
+					marshal.ResT = pointBytes(L%2 != 1)
+				case LargeScalar:
+					marshal.ResR = orderBytes()
+				case ShortScalar:
+					marshal.ResR = marshal.ResR[:10]
+				}
+			})
+
+			if tc == Malformed {
+				bytes[0] = 0x13
+			}
+
+			_, e := ParseCredRequest(bytes)
+			assert.ErrorContains(t, e, "ParseCredRequest")
+		})
+	}
+}
+
+// validation rejects points outside of the prime-order subgroups
+func testCredRequestRejectsInvalidPoints(t *testing.T) {
+	prg := getNewRand(SEED + 3)
+
+	sk, _ := GenerateKeys(prg, L)
+	credReq := MakeCredRequest(prg, sk, credRequestNonce, L)
+
+	for name, point := range invalidPointsLike(credReq.Pk) {
+		t.Run("public key "+name, func(t *testing.T) {
+			tampered := *credReq
+			tampered.Pk = point
+
+			assert.ErrorContains(t, tampered.Validate(), "invalid")
+		})
+
+		t.Run("commitment "+name, func(t *testing.T) {
+			tampered := *credReq
+			tampered.ResT = point
+
+			assert.ErrorContains(t, tampered.Validate(), "invalid")
+		})
+	}
+}
+
 // Benchmarks
 
 func BenchmarkCredRequest(b *testing.B) {
@@ -0,0 +1,341 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Equality states that the hidden attributes at A and B are equal, i.e. are the generators of their groups to the same power.
+// The attributes may be at levels of different parity, then they are compared across G1 and G2.
+// In a joint proof (see ProveJoint), CredentialA and CredentialB are the indices of the credentials A and B refer to,
+// otherwise they must be 0.
+// The proof shares the randomness of the attributes, so that their responses resA are equal (or have the same exponent).
+type Equality struct {
+	A, B                     Position
+	CredentialA, CredentialB int
+}
+
+// Presentation is the public part of one of the credentials of a joint proof:
+// the authority's public key, the pseudonym and the disclosed attributes
+type Presentation struct {
+	Pk    PK
+	PkNym PK
+	D     Indices
+}
+
+// Holding is the Presentation along with what only the holder of the credentials has
+type Holding struct {
+	Presentation
+	Creds *Credentials
+	Sk    SK
+	SkNym SK
+}
+
+// JointProof proves several credentials (e.g. issued by different authorities) under a single challenge,
+// along with the equalities between their hidden attributes
+type JointProof struct {
+	proofs []Proof
+}
+
+// attributeReference is an attribute of one of the credentials
+type attributeReference struct {
+	credential int
+	Position
+}
+
+// ProveJoint proves the credentials of the holdings and the equalities between their hidden attributes, signing m.
+// All credentials use the same grothYs and h.
+// Verify the proof with JointProof.Verify.
+func ProveJoint(prg *amcl.RAND, holdings []Holding, m []byte, grothYs [][]interface{}, h interface{}, equalities []Equality) (proof JointProof, e error) {
+	return proveJoint(prg, holdings, m, grothYs, h, equalities, binding{})
+}
+
+// ProveJointWithParameters is ProveJoint that takes grothYs and h from the system parameters (see SystemParameters).
+// The credentials may still be issued by different authorities (see Presentation.Pk).
+// Verify the proof with JointProof.VerifyWithParameters.
+func ProveJointWithParameters(prg *amcl.RAND, holdings []Holding, params *SystemParameters, context string, m []byte, equalities []Equality) (proof JointProof, e error) {
+	return proveJoint(prg, holdings, m, params.GrothYs, params.H, equalities, binding{params.Fingerprint(), context})
+}
+
+func proveJoint(prg *amcl.RAND, holdings []Holding, m []byte, grothYs [][]interface{}, h interface{}, equalities []Equality, b binding) (proof JointProof, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if len(holdings) == 0 {
+		return proof, fmt.Errorf("ProveJoint: no credentials to prove")
+	}
+
+	attributes := make([][][]interface{}, len(holdings))
+	for index, holding := range holdings {
+		attributes[index] = holding.Creds.Attributes
+	}
+	if e = checkEqualities(equalities, attributes, func(credential int) Indices { return holdings[credential].D }); e != nil {
+		return proof, fmt.Errorf("ProveJoint: %v", e)
+	}
+	if e = checkEqualitiesHold(equalities, attributes); e != nil {
+		return proof, fmt.Errorf("ProveJoint: %v", e)
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	rhoShared := sharedRandomness(prg, equalities, len(holdings))
+
+	provers := make([]*credentialsProver, len(holdings))
+	for index, holding := range holdings {
+		if provers[index], e = holding.Creds.proveCommitSharing(prg, holding.D, grothYs, h, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg), rhoShared[index]); e != nil {
+			return proof, fmt.Errorf("ProveJoint: credentials %d: %v", index, e)
+		}
+	}
+
+	t := newTranscript(_ProtocolJoint, b)
+	t.append("credentials", []byte(strconv.Itoa(len(holdings))))
+	for index, holding := range holdings {
+		appendCommitments(t, grothYs, holding.Pk, h, provers[index].rPrime, provers[index].coms, provers[index].comNym, holding.D)
+	}
+	appendEqualities(t, equalities)
+	t.append("m", m)
+
+	c := t.challenge(q)
+
+	proof.proofs = make([]Proof, len(holdings))
+	for index, holding := range holdings {
+		proof.proofs[index] = provers[index].respond(c, holding.Sk, holding.SkNym)
+		proof.proofs[index].fingerprint = b.fingerprint
+	}
+
+	return
+}
+
+// Verify checks the proofs of the credentials like VerifyProof and that their hidden attributes satisfy the equalities.
+// The presentations and the equalities must be the same as in the generation.
+func (proof *JointProof) Verify(presentations []Presentation, m []byte, grothYs [][]interface{}, h interface{}, equalities []Equality) (e error) {
+	return proof.verify(presentations, m, grothYs, h, equalities, "")
+}
+
+// VerifyWithParameters is Verify that takes grothYs and h from the system parameters (see SystemParameters).
+func (proof *JointProof) VerifyWithParameters(presentations []Presentation, params *SystemParameters, context string, m []byte, equalities []Equality) (e error) {
+	for index := range proof.proofs {
+		if e = params.checkFingerprint(proof.proofs[index].fingerprint); e != nil {
+			return fmt.Errorf("JointProof.Verify: credentials %d: %v", index, e)
+		}
+	}
+
+	return proof.verify(presentations, m, params.GrothYs, params.H, equalities, context)
+}
+
+// verify is Verify for the proof bound to the context (and to the fingerprint its proofs carry)
+func (proof *JointProof) verify(presentations []Presentation, m []byte, grothYs [][]interface{}, h interface{}, equalities []Equality, context string) (e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if len(proof.proofs) != len(presentations) {
+		return fmt.Errorf("JointProof.Verify: %d proofs for %d credentials", len(proof.proofs), len(presentations))
+	}
+	if len(presentations) == 0 {
+		return fmt.Errorf("JointProof.Verify: no credentials to verify")
+	}
+
+	for index, presentation := range presentations {
+		if e = proof.proofs[index].validate(); e != nil {
+			return fmt.Errorf("JointProof.Verify: credentials %d: invalid proof: %v", index, e)
+		}
+		if e = ValidatePoint(presentation.PkNym); e != nil {
+			return fmt.Errorf("JointProof.Verify: credentials %d: invalid pkNym: %v", index, e)
+		}
+		if e = presentation.D.validate(); e != nil {
+			return fmt.Errorf("JointProof.Verify: credentials %d: %v", index, e)
+		}
+		if index > 0 && !bigEqual(proof.proofs[index].c, proof.proofs[0].c) {
+			return fmt.Errorf("JointProof.Verify: credentials %d: proof is not under the joint challenge", index)
+		}
+		if index > 0 && !bytesEqual(proof.proofs[index].fingerprint, proof.proofs[0].fingerprint) {
+			return fmt.Errorf("JointProof.Verify: credentials %d: proof is not bound to the parameters of the joint proof", index)
+		}
+	}
+
+	responses := make([][][]interface{}, len(presentations))
+	for index := range presentations {
+		responses[index] = proof.proofs[index].resA
+	}
+	if e = checkEqualities(equalities, responses, func(credential int) Indices { return presentations[credential].D }); e != nil {
+		return fmt.Errorf("JointProof.Verify: %v", e)
+	}
+	if e = checkEqualitiesHold(equalities, responses); e != nil {
+		return fmt.Errorf("JointProof.Verify: verification failed at %v", e)
+	}
+
+	t := newTranscript(_ProtocolJoint, binding{proof.proofs[0].fingerprint, context})
+	t.append("credentials", []byte(strconv.Itoa(len(presentations))))
+	for index, presentation := range presentations {
+		coms, comNym, e := proof.proofs[index].commitments(presentation.Pk, grothYs, h, presentation.PkNym, presentation.D)
+		if e != nil {
+			return fmt.Errorf("JointProof.Verify: credentials %d: %v", index, e)
+		}
+		appendCommitments(t, grothYs, presentation.Pk, h, proof.proofs[index].rPrime, coms, comNym, presentation.D)
+	}
+	appendEqualities(t, equalities)
+	t.append("m", m)
+
+	if !bigEqual(proof.proofs[0].c, t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order))) {
+		return fmt.Errorf("JointProof.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// checkEqualities ensures that the equalities refer to existing, hidden and distinct attributes (or their responses)
+// of the credentials; Ds gives the disclosed attributes of each credentials
+func checkEqualities(equalities []Equality, attributes [][][]interface{}, Ds func(credential int) Indices) (e error) {
+	positions := make([][]Position, len(attributes))
+	seen := make(map[attributeReference]bool)
+
+	for _, equality := range equalities {
+		for _, reference := range equality.references() {
+			if reference.credential < 0 || reference.credential >= len(attributes) {
+				return fmt.Errorf("credentials %d of equality do not exist", reference.credential)
+			}
+			if !seen[reference] {
+				seen[reference] = true
+				positions[reference.credential] = append(positions[reference.credential], reference.Position)
+			}
+		}
+		if equality.references()[0] == equality.references()[1] {
+			return fmt.Errorf("attribute (%d, %d) is equated to itself", equality.A.I, equality.A.J)
+		}
+	}
+
+	for credential := range attributes {
+		if e = checkHiddenPositions("equal attribute", positions[credential], Ds(credential), attributes[credential]); e != nil {
+			if len(attributes) > 1 {
+				return fmt.Errorf("credentials %d: %v", credential, e)
+			}
+			return
+		}
+	}
+
+	return
+}
+
+// checkEqualitiesHold checks the equalities over the attributes (the prover) or over their responses (the verifier)
+func checkEqualitiesHold(equalities []Equality, attributes [][][]interface{}) (e error) {
+	for _, equality := range equalities {
+		a := attributes[equality.CredentialA][equality.A.I][equality.A.J]
+		b := attributes[equality.CredentialB][equality.B.I][equality.B.J]
+
+		if !exponentsEqual(a, b) {
+			return fmt.Errorf("attributes (%d, %d) and (%d, %d) are not equal", equality.A.I, equality.A.J, equality.B.I, equality.B.J)
+		}
+	}
+
+	return
+}
+
+// sharedRandomness assigns the same random scalar to the attributes connected by the equalities,
+// indexed by the credentials and the positions
+func sharedRandomness(prg *amcl.RAND, equalities []Equality, credentials int) (rhoShared []map[Position]*FP256BN.BIG) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	// union-find over the attributes
+	parent := make(map[attributeReference]attributeReference)
+	var find func(reference attributeReference) attributeReference
+	find = func(reference attributeReference) attributeReference {
+		if _, ok := parent[reference]; !ok {
+			parent[reference] = reference
+		}
+		if parent[reference] != reference {
+			parent[reference] = find(parent[reference])
+		}
+		return parent[reference]
+	}
+
+	for _, equality := range equalities {
+		references := equality.references()
+		parent[find(references[0])] = find(references[1])
+	}
+
+	rhoShared = make([]map[Position]*FP256BN.BIG, credentials)
+	for credential := range rhoShared {
+		rhoShared[credential] = make(map[Position]*FP256BN.BIG)
+	}
+
+	roots := make(map[attributeReference]*FP256BN.BIG)
+	for _, equality := range equalities {
+		for _, reference := range equality.references() {
+			root := find(reference)
+			if roots[root] == nil {
+				roots[root] = FP256BN.Randomnum(q, prg)
+			}
+			rhoShared[reference.credential][reference.Position] = roots[root]
+		}
+	}
+
+	return
+}
+
+// exponentsEqual checks that a and b are the generators of their groups (either one) to the same power
+func exponentsEqual(a interface{}, b interface{}) bool {
+	_, aFirst := a.(*FP256BN.ECP)
+	_, bFirst := b.(*FP256BN.ECP)
+
+	if aFirst == bFirst {
+		return pointEqual(a, b)
+	}
+
+	// e(a, g) = e(g, b)
+	return FP256BN.Fexp(ate2(a, generatorSameGroup(b), pointNegate(generatorSameGroup(a)), b)).Isunity()
+}
+
+func (equality Equality) references() [2]attributeReference {
+	return [2]attributeReference{{equality.CredentialA, equality.A}, {equality.CredentialB, equality.B}}
+}
+
+func appendEqualities(t *transcript, equalities []Equality) {
+	t.append("equalities", []byte(strconv.Itoa(len(equalities))))
+	for _, equality := range equalities {
+		for _, reference := range equality.references() {
+			t.append("credential", []byte(strconv.Itoa(reference.credential)))
+			t.append("i", []byte(strconv.Itoa(reference.I)))
+			t.append("j", []byte(strconv.Itoa(reference.J)))
+		}
+	}
+}
+
+// ToBytes marshals the proof using ASN1 encoding
+func (proof *JointProof) ToBytes() (result []byte) {
+	marshal := make([][]byte, len(proof.proofs))
+	for index := range proof.proofs {
+		marshal[index] = proof.proofs[index].ToBytes()
+	}
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParseJointProof un-marshals and validates the proof using ASN1 encoding
+func ParseJointProof(input []byte) (proof *JointProof, e error) {
+	var marshal [][]byte
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseJointProof: %v", e)
+	}
+
+	proof = &JointProof{proofs: make([]Proof, len(marshal))}
+	for index, bytes := range marshal {
+		credsProof, e := ParseProof(bytes)
+		if e != nil {
+			return nil, fmt.Errorf("ParseJointProof: credentials %d: %v", index, e)
+		}
+		proof.proofs[index] = *credsProof
+	}
+
+	return
+}
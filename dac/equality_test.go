@@ -0,0 +1,358 @@
+package dac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// helper that constructs two chains under different roots, where the first has "org" at (1, 0)
+// and the second has it at (2, 1)
+func equalityHoldings() (holdings []Holding, ys [][]interface{}, h interface{}) {
+	first, sk1, pk1, ys, skNym1, pkNym1, h := generateChainWith(SEED, 1, 2, map[Position]interface{}{{1, 0}: ProduceAttributes(1, "org")[0]})
+	second, sk2, pk2, _, skNym2, pkNym2, _ := generateChainWith(SEED+1, 2, 2, map[Position]interface{}{{2, 1}: ProduceAttributes(2, "org")[0]})
+
+	holdings = []Holding{
+		{Presentation{pk1, pkNym1, Indices{}}, first, sk1, skNym1},
+		{Presentation{pk2, pkNym2, Indices{{1, 0, second.Attributes[1][0]}}}, second, sk2, skNym2},
+	}
+
+	return
+}
+
+func equalityPresentations(holdings []Holding) (presentations []Presentation) {
+	for _, holding := range holdings {
+		presentations = append(presentations, holding.Presentation)
+	}
+
+	return
+}
+
+// Tests
+
+func TestEquality(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testEqualityPredicates,
+		testEqualityPredicatesFail,
+		testEqualityErrors,
+		testEqualityExponents,
+		testEqualitySharedRandomness,
+		testEqualityJoint,
+		testEqualityJointParameters,
+		testEqualityJointFail,
+		testEqualityJointMarshal,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testEqualityPredicates(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	// "org" at levels 1 and 3 (both G1) and at level 2 (G2)
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 3, 2, map[Position]interface{}{
+		{1, 0}: ProduceAttributes(1, "org")[0],
+		{2, 1}: ProduceAttributes(2, "org")[0],
+		{3, 0}: ProduceAttributes(3, "org")[0],
+		{3, 1}: NumericAttribute(3, 25),
+	})
+
+	D := Indices{{1, 1, creds.Attributes[1][1]}}
+	m := []byte("message")
+
+	for name, predicates := range map[string]Predicates{
+		"same group":  {Equalities: []Equality{{A: Position{1, 0}, B: Position{3, 0}}}},
+		"cross group": {Equalities: []Equality{{A: Position{1, 0}, B: Position{2, 1}}}},
+		"chained":     {Equalities: []Equality{{A: Position{1, 0}, B: Position{2, 1}}, {A: Position{3, 0}, B: Position{2, 1}}}},
+		"with range": {
+			Equalities:  []Equality{{A: Position{1, 0}, B: Position{3, 0}}},
+			Ranges:      []Range{AtLeast(Position{3, 1}, 18)},
+			Memberships: []Membership{MemberOf(Position{1, 0}, StringValue("org"), StringValue("another org"))},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			values := AttributeValues{{3, 1}: NumericValue(25), {1, 0}: StringValue("org")}
+
+			proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, values)
+			assert.NilError(t, e)
+
+			assert.NilError(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates))
+
+			for _, equality := range predicates.Equalities {
+				assert.Check(t, exponentsEqual(proof.proof.resA[equality.A.I][equality.A.J], proof.proof.resA[equality.B.I][equality.B.J]))
+			}
+		})
+	}
+}
+
+func testEqualityPredicatesFail(t *testing.T) {
+	type TestCase string
+	const (
+		AnotherPair   TestCase = "another pair"
+		Swapped       TestCase = "swapped positions"
+		Dropped       TestCase = "equality dropped"
+		Response      TestCase = "response"
+		NotEqual      TestCase = "attributes not equal"
+		CredsDetached TestCase = "credentials proof detached"
+	)
+
+	for _, tc := range []TestCase{AnotherPair, Swapped, Dropped, Response, NotEqual, CredsDetached} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{
+				{1, 0}: ProduceAttributes(1, "org")[0],
+				{2, 1}: ProduceAttributes(2, "org")[0],
+			})
+
+			D := Indices{}
+			m := []byte("message")
+			predicates := Predicates{Equalities: []Equality{{A: Position{1, 0}, B: Position{2, 1}}}}
+
+			proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, AttributeValues{})
+			assert.NilError(t, e)
+
+			expected := "verification failed"
+			switch tc {
+			case AnotherPair:
+				predicates.Equalities[0].B = Position{2, 0}
+				expected = "verification failed at attributes (1, 0) and (2, 0) are not equal"
+			case Swapped:
+				predicates.Equalities[0] = Equality{A: Position{2, 1}, B: Position{1, 0}}
+			case Dropped:
+				predicates.Equalities = nil
+			case Response:
+				proof.proof.resA[2][1] = pointMultiply(proof.proof.resA[2][1], FP256BN.NewBIGint(0x13))
+				expected = "are not equal"
+			case NotEqual:
+				predicates.Equalities[0].B = Position{2, 0}
+				_, e = creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, AttributeValues{})
+				assert.ErrorContains(t, e, "attributes (1, 0) and (2, 0) are not equal")
+				return
+			case CredsDetached:
+				e = proof.proof.VerifyProof(pk, ys, h, pkNym, D, m)
+				assert.ErrorContains(t, e, "verification failed")
+				return
+			}
+
+			assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates), expected)
+		})
+	}
+}
+
+func testEqualityErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, _, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{})
+	D := Indices{{2, 0, creds.Attributes[2][0]}}
+
+	for _, tc := range []struct {
+		equality Equality
+		expected string
+	}{
+		{Equality{A: Position{1, 0}, B: Position{1, 0}}, "attribute (1, 0) is equated to itself"},
+		{Equality{A: Position{1, 0}, B: Position{2, 0}}, "equal attribute (2, 0) is disclosed"},
+		{Equality{A: Position{1, 0}, B: Position{3, 0}}, "equal attribute (3, 0) does not exist"},
+		{Equality{A: Position{1, 0}, B: Position{1, 1}, CredentialB: 1}, "refers to other credentials"},
+	} {
+		_, e := creds.ProveWithPredicates(prg, sk, pk, D, []byte("message"), ys, h, skNym, Predicates{Equalities: []Equality{tc.equality}}, AttributeValues{})
+		assert.ErrorContains(t, e, tc.expected)
+	}
+}
+
+func testEqualityExponents(t *testing.T) {
+	a := FP256BN.NewBIGint(0x13)
+	b := FP256BN.NewBIGint(0x14)
+
+	g1 := FP256BN.ECP_generator()
+	g2 := FP256BN.ECP2_generator()
+
+	assert.Check(t, exponentsEqual(g1.Mul(a), g1.Mul(a)))
+	assert.Check(t, exponentsEqual(g2.Mul(a), g2.Mul(a)))
+	assert.Check(t, exponentsEqual(g1.Mul(a), g2.Mul(a)))
+	assert.Check(t, exponentsEqual(g2.Mul(a), g1.Mul(a)))
+
+	assert.Check(t, !exponentsEqual(g1.Mul(a), g1.Mul(b)))
+	assert.Check(t, !exponentsEqual(g2.Mul(a), g2.Mul(b)))
+	assert.Check(t, !exponentsEqual(g1.Mul(a), g2.Mul(b)))
+	assert.Check(t, !exponentsEqual(g2.Mul(a), g1.Mul(b)))
+}
+
+func testEqualitySharedRandomness(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	equalities := []Equality{
+		{A: Position{1, 0}, B: Position{2, 0}},
+		{A: Position{3, 0}, B: Position{1, 1}, CredentialA: 1, CredentialB: 1},
+		{A: Position{2, 0}, B: Position{1, 1}, CredentialB: 1},
+		{A: Position{1, 2}, B: Position{2, 2}},
+	}
+
+	rhoShared := sharedRandomness(prg, equalities, 2)
+	assert.Equal(t, len(rhoShared), 2)
+
+	// (1, 0), (2, 0), 1:(3, 0) and 1:(1, 1) are connected
+	rho := rhoShared[0][Position{1, 0}]
+	assert.Check(t, rho == rhoShared[0][Position{2, 0}])
+	assert.Check(t, rho == rhoShared[1][Position{3, 0}])
+	assert.Check(t, rho == rhoShared[1][Position{1, 1}])
+
+	// (1, 2) and (2, 2) are on their own
+	assert.Check(t, rhoShared[0][Position{1, 2}] == rhoShared[0][Position{2, 2}])
+	assert.Check(t, !bigEqual(rhoShared[0][Position{1, 2}], rho))
+
+	assert.Equal(t, len(rhoShared[0]), 4)
+	assert.Equal(t, len(rhoShared[1]), 2)
+}
+
+func testEqualityJoint(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	holdings, ys, h := equalityHoldings()
+	equalities := []Equality{{A: Position{1, 0}, B: Position{2, 1}, CredentialB: 1}}
+	m := []byte("message")
+
+	proof, e := ProveJoint(prg, holdings, m, ys, h, equalities)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.Verify(equalityPresentations(holdings), m, ys, h, equalities))
+
+	// each part is a proof of its credentials under the joint challenge only
+	assert.ErrorContains(t, proof.proofs[0].VerifyProof(holdings[0].Pk, ys, h, holdings[0].PkNym, holdings[0].D, m), "verification failed")
+
+	// no equalities
+	proof, e = ProveJoint(prg, holdings, m, ys, h, nil)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.Verify(equalityPresentations(holdings), m, ys, h, nil))
+}
+
+func testEqualityJointParameters(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	holdings, ys, _ := equalityHoldings()
+	params := generateSystemParametersFor(holdings[0].Pk, ys)
+	for index := range holdings {
+		holdings[index].SkNym, holdings[index].PkNym = GenerateNymKeys(prg, holdings[index].Sk, params.H)
+	}
+	presentations := equalityPresentations(holdings)
+
+	m := []byte("message")
+	equalities := []Equality{{A: Position{1, 0}, B: Position{2, 1}, CredentialA: 0, CredentialB: 1}}
+
+	proof, e := ProveJointWithParameters(prg, holdings, params, "context", m, equalities)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyWithParameters(presentations, params, "context", m, equalities))
+	assert.ErrorContains(t, proof.VerifyWithParameters(presentations, params, "other", m, equalities), "verification failed")
+	assert.ErrorContains(t, proof.Verify(presentations, m, ys, params.H, equalities), "verification failed")
+
+	recovered, e := ParseJointProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.VerifyWithParameters(presentations, params, "context", m, equalities))
+
+	other, _, _ := generateSystemParameters(getNewRand(SEED + 2))
+	other.AuthorityPK, other.GrothYs, other.H = params.AuthorityPK, params.GrothYs, params.H
+	assert.ErrorContains(t, proof.VerifyWithParameters(presentations, other, "context", m, equalities), "mismatch")
+
+	unbound, e := ProveJoint(prg, holdings, m, ys, params.H, equalities)
+	assert.NilError(t, e)
+	assert.ErrorContains(t, unbound.VerifyWithParameters(presentations, params, "context", m, equalities), "not bound")
+
+	mixed, e := ProveJointWithParameters(prg, holdings, params, "context", m, equalities)
+	assert.NilError(t, e)
+	mixed.proofs[1].fingerprint = nil
+	assert.ErrorContains(t, mixed.verify(presentations, m, ys, params.H, equalities, "context"), "not bound to the parameters of the joint proof")
+}
+
+func testEqualityJointFail(t *testing.T) {
+	type TestCase string
+	const (
+		WrongMessage TestCase = "wrong message"
+		Reordered    TestCase = "credentials reordered"
+		Dropped      TestCase = "credentials dropped"
+		Equalities   TestCase = "equalities changed"
+		Challenge    TestCase = "challenge of a part"
+		Pseudonym    TestCase = "pseudonym"
+		NotEqual     TestCase = "attributes not equal"
+		NoHoldings   TestCase = "no credentials"
+		Credential   TestCase = "credentials do not exist"
+	)
+
+	for _, tc := range []TestCase{WrongMessage, Reordered, Dropped, Equalities, Challenge, Pseudonym, NotEqual, NoHoldings, Credential} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			holdings, ys, h := equalityHoldings()
+			equalities := []Equality{{A: Position{1, 0}, B: Position{2, 1}, CredentialB: 1}}
+			m := []byte("message")
+
+			proof, e := ProveJoint(prg, holdings, m, ys, h, equalities)
+			assert.NilError(t, e)
+
+			presentations := equalityPresentations(holdings)
+
+			expected := "verification failed"
+			switch tc {
+			case WrongMessage:
+				m = []byte("another message")
+			case Reordered:
+				presentations[0], presentations[1] = presentations[1], presentations[0]
+				proof.proofs[0], proof.proofs[1] = proof.proofs[1], proof.proofs[0]
+				expected = "credentials 0: equal attribute (1, 0) is disclosed"
+			case Dropped:
+				presentations = presentations[:1]
+				expected = "2 proofs for 1 credentials"
+			case Equalities:
+				equalities = nil
+			case Challenge:
+				proof.proofs[1].c = FP256BN.NewBIGint(0x13)
+				expected = "not under the joint challenge"
+			case Pseudonym:
+				presentations[1].PkNym = presentations[0].PkNym
+			case NotEqual:
+				equalities[0].B = Position{2, 0}
+				_, e = ProveJoint(prg, holdings, m, ys, h, equalities)
+				assert.ErrorContains(t, e, "attributes (1, 0) and (2, 0) are not equal")
+				return
+			case NoHoldings:
+				_, e = ProveJoint(prg, nil, m, ys, h, nil)
+				assert.ErrorContains(t, e, "no credentials")
+				return
+			case Credential:
+				equalities[0].CredentialB = 2
+				expected = "credentials 2 of equality do not exist"
+			}
+
+			assert.ErrorContains(t, proof.Verify(presentations, m, ys, h, equalities), expected)
+		})
+	}
+}
+
+func testEqualityJointMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	holdings, ys, h := equalityHoldings()
+	equalities := []Equality{{A: Position{1, 0}, B: Position{2, 1}, CredentialB: 1}}
+	m := []byte("message")
+
+	proof, e := ProveJoint(prg, holdings, m, ys, h, equalities)
+	assert.NilError(t, e)
+
+	recovered, e := ParseJointProof(proof.ToBytes())
+	assert.NilError(t, e)
+
+	assert.NilError(t, recovered.Verify(equalityPresentations(holdings), m, ys, h, equalities))
+	assert.DeepEqual(t, recovered.ToBytes(), proof.ToBytes())
+
+	_, e = ParseJointProof(append(proof.ToBytes(), 0x13))
+	assert.ErrorContains(t, e, "trailing")
+
+	_, e = ParseJointProof(remarshal(t, proof.ToBytes(), &[][]byte{}, func() {}))
+	assert.NilError(t, e)
+
+	var marshal [][]byte
+	_, e = ParseJointProof(remarshal(t, proof.ToBytes(), &marshal, func() { marshal[1] = marshal[1][1:] }))
+	assert.ErrorContains(t, e, "credentials 1")
+}
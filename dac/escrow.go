@@ -0,0 +1,341 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Position is the position of an attribute in credentials: level I and index J within the level
+type Position struct {
+	I, J int
+}
+
+// EscrowedAttribute is the ElGamal encryption of the hidden attribute at Position under auditor's escrow key.
+// The auditor recovers the attribute with AuditingDecrypt (or AuditingDecryptWithProof) with the escrow secret key.
+type EscrowedAttribute struct {
+	Position
+	AuditingEncryption
+}
+
+// EscrowProof is a credentials proof along with the encryptions of some of the hidden attributes
+// and the proof that they encrypt the very attributes the credentials proof is about.
+// Both parts are under a single challenge, so that the encryptions cannot be swapped or detached.
+type EscrowProof struct {
+	proof    Proof
+	escrowed []EscrowedAttribute
+	resR     []*FP256BN.BIG
+}
+
+// GenerateEscrowKeys generates the auditor's escrow key pair.
+// Attributes of odd levels are in G1 and those of even levels are in G2,
+// so the public keys are indexed like grothYs: pks[0] is in G2 and pks[1] is in G1.
+func GenerateEscrowKeys(prg *amcl.RAND) (sk SK, pks []PK) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	sk = FP256BN.Randomnum(q, prg)
+	pks = []PK{
+		FP256BN.ECP2_generator().Mul(sk),
+		FP256BN.ECP_generator().Mul(sk),
+	}
+
+	return
+}
+
+// ProveWithEscrow is Prove that also encrypts the hidden attributes at the given positions under audPks
+// (see GenerateEscrowKeys) and proves that the encryptions hold the same attributes the credentials are proven for.
+// The escrowed attributes must not be disclosed.
+func (creds *Credentials) ProveWithEscrow(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK, positions []Position, audPks []PK) (proof EscrowProof, e error) {
+	return creds.proveWithEscrow(prg, sk, pk, D, m, grothYs, h, skNym, positions, audPks, binding{})
+}
+
+// ProveWithEscrowAndParameters is ProveWithEscrow that takes the public values from the system parameters (see SystemParameters).
+// Verify the proof with EscrowProof.VerifyWithParameters.
+func (creds *Credentials) ProveWithEscrowAndParameters(prg *amcl.RAND, sk SK, params *SystemParameters, context string, D Indices, m []byte, skNym SK, positions []Position, audPks []PK) (proof EscrowProof, e error) {
+	return creds.proveWithEscrow(prg, sk, params.AuthorityPK, D, m, params.GrothYs, params.H, skNym, positions, audPks, binding{params.Fingerprint(), context})
+}
+
+func (creds *Credentials) proveWithEscrow(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK, positions []Position, audPks []PK, b binding) (proof EscrowProof, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if e = checkHiddenPositions("escrowed attribute", positions, D, creds.Attributes); e != nil {
+		return proof, fmt.Errorf("ProveWithEscrow: %v", e)
+	}
+	if e = validateEscrowKeys(audPks); e != nil {
+		return proof, fmt.Errorf("ProveWithEscrow: %v", e)
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	prover, e := creds.proveCommit(prg, D, grothYs, h, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg))
+	if e != nil {
+		return
+	}
+
+	proof.escrowed = make([]EscrowedAttribute, len(positions))
+	rs := make([]*FP256BN.BIG, len(positions))
+	rhoRs := make([]*FP256BN.BIG, len(positions))
+	coms := make([][2]interface{}, len(positions))
+
+	for index, position := range positions {
+		g := levelGenerator(position.I)
+		audPk := audPks[position.I%2]
+
+		encryption, r := AuditingEncrypt(prg, audPk, creds.Attributes[position.I][position.J])
+		proof.escrowed[index] = EscrowedAttribute{position, encryption}
+		rs[index] = r
+
+		// the attribute's randomness is shared with the credentials proof, which links the attributes
+		rhoRs[index] = FP256BN.Randomnum(q, prg)
+		coms[index][0] = productOfExponents(g, prover.rhoA[position.I][position.J], audPk, rhoRs[index])
+		coms[index][1] = pointMultiply(g, rhoRs[index])
+	}
+
+	t := newTranscript(_ProtocolEscrow, b)
+	appendCommitments(t, grothYs, pk, h, prover.rPrime, prover.coms, prover.comNym, D)
+	appendEscrow(t, audPks, proof.escrowed, coms)
+	t.append("m", m)
+
+	c := t.challenge(q)
+
+	proof.proof = prover.respond(c, sk, skNym)
+	proof.proof.fingerprint = b.fingerprint
+
+	proof.resR = make([]*FP256BN.BIG, len(positions))
+	for index := range positions {
+		proof.resR[index] = FP256BN.Modmul(c, rs[index], q)
+		proof.resR[index] = proof.resR[index].Plus(rhoRs[index])
+		proof.resR[index].Mod(q)
+	}
+
+	return
+}
+
+// Escrowed returns the encrypted attributes carried by the proof
+func (proof *EscrowProof) Escrowed() []EscrowedAttribute {
+	return proof.escrowed
+}
+
+// Verify checks the credentials proof like VerifyProof and that the escrowed attributes
+// are the encryptions of the hidden attributes at their positions under audPks.
+func (proof *EscrowProof) Verify(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, audPks []PK) (e error) {
+	return proof.verify(pk, grothYs, h, pkNym, D, m, audPks, "")
+}
+
+// VerifyWithParameters is Verify that takes the public values from the system parameters (see SystemParameters).
+func (proof *EscrowProof) VerifyWithParameters(params *SystemParameters, context string, pkNym PK, D Indices, m []byte, audPks []PK) (e error) {
+	if e = params.checkFingerprint(proof.proof.fingerprint); e != nil {
+		return fmt.Errorf("EscrowProof.Verify: %v", e)
+	}
+
+	return proof.verify(params.AuthorityPK, params.GrothYs, params.H, pkNym, D, m, audPks, context)
+}
+
+// verify is Verify for the proof bound to the context (and to the fingerprint it carries)
+func (proof *EscrowProof) verify(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, audPks []PK, context string) (e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if len(proof.escrowed) != len(proof.resR) {
+		return fmt.Errorf("EscrowProof.Verify: %d escrowed attributes, %d responses", len(proof.escrowed), len(proof.resR))
+	}
+	if e = proof.proof.validate(); e != nil {
+		return fmt.Errorf("EscrowProof.Verify: invalid proof: %v", e)
+	}
+	if e = ValidatePoint(pkNym); e != nil {
+		return fmt.Errorf("EscrowProof.Verify: invalid pkNym: %v", e)
+	}
+	if e = D.validate(); e != nil {
+		return fmt.Errorf("EscrowProof.Verify: %v", e)
+	}
+	if e = validateEscrowKeys(audPks); e != nil {
+		return fmt.Errorf("EscrowProof.Verify: %v", e)
+	}
+
+	positions := make([]Position, len(proof.escrowed))
+	for index, escrowed := range proof.escrowed {
+		positions[index] = escrowed.Position
+	}
+	if e = checkHiddenPositions("escrowed attribute", positions, D, proof.proof.resA); e != nil {
+		return fmt.Errorf("EscrowProof.Verify: %v", e)
+	}
+	for index, escrowed := range proof.escrowed {
+		first := escrowed.I%2 == 1
+		if e = validatePointInGroup(escrowed.enc1, first); e != nil {
+			return fmt.Errorf("EscrowProof.Verify: escrowed attribute %d: %v", index, e)
+		}
+		if e = validatePointInGroup(escrowed.enc2, first); e != nil {
+			return fmt.Errorf("EscrowProof.Verify: escrowed attribute %d: %v", index, e)
+		}
+	}
+
+	credsComs, comNym, e := proof.proof.commitments(pk, grothYs, h, pkNym, D)
+	if e != nil {
+		return
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	cNeg := bigNegate(proof.proof.c, q)
+
+	coms := make([][2]interface{}, len(proof.escrowed))
+	for index, escrowed := range proof.escrowed {
+		g := levelGenerator(escrowed.I)
+
+		// resA = g^rhoA * a^c, so resA * audPk^resR * enc1^-c = g^rhoA * audPk^rhoR
+		coms[index][0] = productOfExponents(audPks[escrowed.I%2], proof.resR[index], escrowed.enc1, cNeg)
+		pointAdd(coms[index][0], proof.proof.resA[escrowed.I][escrowed.J])
+
+		coms[index][1] = productOfExponents(g, proof.resR[index], escrowed.enc2, cNeg)
+	}
+
+	t := newTranscript(_ProtocolEscrow, binding{proof.proof.fingerprint, context})
+	appendCommitments(t, grothYs, pk, h, proof.proof.rPrime, credsComs, comNym, D)
+	appendEscrow(t, audPks, proof.escrowed, coms)
+	t.append("m", m)
+
+	if !bigEqual(proof.proof.c, t.challenge(q)) {
+		return fmt.Errorf("EscrowProof.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// levelGenerator returns the generator of the group of attributes of level L
+func levelGenerator(L int) interface{} {
+	if L%2 == 1 {
+		return FP256BN.ECP_generator()
+	}
+	return FP256BN.ECP2_generator()
+}
+
+// checkHiddenPositions ensures that the positions are distinct, exist in the attributes (or their responses)
+// and are not disclosed; what names the attributes in the errors
+func checkHiddenPositions(what string, positions []Position, D Indices, attributes [][]interface{}) (e error) {
+	seen := make(map[Position]bool)
+
+	for _, position := range positions {
+		if position.I < 1 || position.I >= len(attributes) || position.J < 0 || position.J >= len(attributes[position.I]) {
+			return fmt.Errorf("%s (%d, %d) does not exist", what, position.I, position.J)
+		}
+		if D.contains(position.I, position.J) != nil {
+			return fmt.Errorf("%s (%d, %d) is disclosed", what, position.I, position.J)
+		}
+		if attributes[position.I][position.J] == nil {
+			return fmt.Errorf("%s (%d, %d) is missing", what, position.I, position.J)
+		}
+		if seen[position] {
+			return fmt.Errorf("%s (%d, %d) is repeated", what, position.I, position.J)
+		}
+		seen[position] = true
+	}
+
+	return
+}
+
+// validateEscrowKeys ensures there are the keys for both groups, pks[0] in G2 and pks[1] in G1
+func validateEscrowKeys(pks []PK) (e error) {
+	if len(pks) != 2 {
+		return fmt.Errorf("escrow public keys must be exactly 2, got %d", len(pks))
+	}
+	if e = validatePointInGroup(pks[0], false); e != nil {
+		return fmt.Errorf("escrow public key 0: %v", e)
+	}
+	if e = validatePointInGroup(pks[1], true); e != nil {
+		return fmt.Errorf("escrow public key 1: %v", e)
+	}
+
+	return
+}
+
+// appendEscrow adds the escrow keys, the encryptions and their commitments to the transcript
+func appendEscrow(t *transcript, audPks []PK, escrowed []EscrowedAttribute, coms [][2]interface{}) {
+	t.appendPoints("audPks", audPks)
+	t.append("escrowed", []byte(strconv.Itoa(len(escrowed))))
+	for index, attribute := range escrowed {
+		t.append("i", []byte(strconv.Itoa(attribute.I)))
+		t.append("j", []byte(strconv.Itoa(attribute.J)))
+		t.appendPoint("enc1", attribute.enc1)
+		t.appendPoint("enc2", attribute.enc2)
+		t.appendPoint("com1", coms[index][0])
+		t.appendPoint("com2", coms[index][1])
+	}
+}
+
+type escrowedAttributeMarshal struct {
+	I, J int
+	Enc1 []byte
+	Enc2 []byte
+}
+
+type escrowProofMarshal struct {
+	Proof    []byte
+	Escrowed []escrowedAttributeMarshal
+	ResR     [][]byte
+}
+
+// ToBytes marshals the proof using ASN1 encoding
+func (proof *EscrowProof) ToBytes() (result []byte) {
+	var marshal escrowProofMarshal
+
+	marshal.Proof = proof.proof.ToBytes()
+	marshal.Escrowed = make([]escrowedAttributeMarshal, len(proof.escrowed))
+	for index, escrowed := range proof.escrowed {
+		marshal.Escrowed[index] = escrowedAttributeMarshal{escrowed.I, escrowed.J, PointToBytes(escrowed.enc1), PointToBytes(escrowed.enc2)}
+	}
+	marshal.ResR = make([][]byte, len(proof.resR))
+	for index, res := range proof.resR {
+		marshal.ResR[index] = bigToBytes(res)
+	}
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParseEscrowProof un-marshals and validates the proof using ASN1 encoding
+func ParseEscrowProof(input []byte) (proof *EscrowProof, e error) {
+	var marshal escrowProofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseEscrowProof: %v", e)
+	}
+
+	if len(marshal.Escrowed) != len(marshal.ResR) {
+		return nil, fmt.Errorf("ParseEscrowProof: %d escrowed attributes, %d responses", len(marshal.Escrowed), len(marshal.ResR))
+	}
+
+	credsProof, e := ParseProof(marshal.Proof)
+	if e != nil {
+		return nil, fmt.Errorf("ParseEscrowProof: %v", e)
+	}
+
+	proof = &EscrowProof{proof: *credsProof}
+
+	if proof.resR, e = bigsFromBytes(marshal.ResR...); e != nil {
+		return nil, fmt.Errorf("ParseEscrowProof: %v", e)
+	}
+
+	proof.escrowed = make([]EscrowedAttribute, len(marshal.Escrowed))
+	for index, escrowed := range marshal.Escrowed {
+		first := escrowed.I%2 == 1
+
+		proof.escrowed[index].Position = Position{escrowed.I, escrowed.J}
+		if proof.escrowed[index].enc1, e = pointFromBytesInGroup(escrowed.Enc1, first, false); e != nil {
+			return nil, fmt.Errorf("ParseEscrowProof: escrowed attribute %d: enc1: %v", index, e)
+		}
+		if proof.escrowed[index].enc2, e = pointFromBytesInGroup(escrowed.Enc2, first, false); e != nil {
+			return nil, fmt.Errorf("ParseEscrowProof: escrowed attribute %d: enc2: %v", index, e)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,271 @@
+package dac
+
+import (
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// Tests
+
+func TestEscrow(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testEscrowHappyPath,
+		testEscrowParameters,
+		testEscrowVerificationFail,
+		testEscrowPositionErrors,
+		testEscrowKeyErrors,
+		testEscrowMarshal,
+		testEscrowParseRejectsMalformed,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testEscrowHappyPath(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(3, 2)
+	audSk, audPks := GenerateEscrowKeys(prg)
+
+	D := Indices{{3, 0, creds.Attributes[3][0]}}
+	positions := []Position{{1, 0}, {2, 1}}
+	m := []byte("message")
+
+	proof, e := creds.ProveWithEscrow(prg, sk, pk, D, m, ys, h, skNym, positions, audPks)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.Verify(pk, ys, h, pkNym, D, m, audPks))
+
+	escrowed := proof.Escrowed()
+	assert.Equal(t, len(escrowed), 2)
+	for index, attribute := range escrowed {
+		assert.Equal(t, attribute.Position, positions[index])
+		assert.Check(t, pointEqual(attribute.AuditingDecrypt(audSk), creds.Attributes[attribute.I][attribute.J]))
+	}
+}
+
+func testEscrowParameters(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, _, _, _, _ := generateChain(2, 2)
+	params := generateSystemParametersFor(pk, ys)
+	skNym, pkNym := GenerateNymKeys(prg, sk, params.H)
+	_, audPks := GenerateEscrowKeys(prg)
+
+	D := Indices{}
+	m := []byte("message")
+	positions := []Position{{1, 0}}
+
+	proof, e := creds.ProveWithEscrowAndParameters(prg, sk, params, "context", D, m, skNym, positions, audPks)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyWithParameters(params, "context", pkNym, D, m, audPks))
+	assert.ErrorContains(t, proof.VerifyWithParameters(params, "other", pkNym, D, m, audPks), "verification failed")
+	assert.ErrorContains(t, proof.Verify(pk, ys, params.H, pkNym, D, m, audPks), "verification failed")
+
+	recovered, e := ParseEscrowProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.VerifyWithParameters(params, "context", pkNym, D, m, audPks))
+
+	other, _, _ := generateSystemParameters(getNewRand(SEED + 2))
+	other.AuthorityPK, other.GrothYs, other.H = params.AuthorityPK, params.GrothYs, params.H
+	assert.ErrorContains(t, proof.VerifyWithParameters(other, "context", pkNym, D, m, audPks), "mismatch")
+
+	unbound, e := creds.ProveWithEscrow(prg, sk, pk, D, m, ys, params.H, skNym, positions, audPks)
+	assert.NilError(t, e)
+	assert.ErrorContains(t, unbound.VerifyWithParameters(params, "context", pkNym, D, m, audPks), "not bound")
+}
+
+func testEscrowVerificationFail(t *testing.T) {
+	type TestCase string
+	const (
+		WrongMessage  TestCase = "wrong message"
+		WrongKeys     TestCase = "wrong escrow keys"
+		Swapped       TestCase = "encryption of another attribute"
+		Moved         TestCase = "position changed"
+		Dropped       TestCase = "escrowed attribute dropped"
+		Disclosed     TestCase = "escrowed attribute disclosed"
+		CredsDetached TestCase = "credentials proof detached"
+	)
+
+	for _, tc := range []TestCase{WrongMessage, WrongKeys, Swapped, Moved, Dropped, Disclosed, CredsDetached} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(2, 2)
+			_, audPks := GenerateEscrowKeys(prg)
+
+			D := Indices{}
+			m := []byte("message")
+
+			proof, e := creds.ProveWithEscrow(prg, sk, pk, D, m, ys, h, skNym, []Position{{1, 0}, {2, 0}}, audPks)
+			assert.NilError(t, e)
+
+			expected := "verification failed"
+			switch tc {
+			case WrongMessage:
+				m = []byte("another message")
+			case WrongKeys:
+				_, audPks = GenerateEscrowKeys(prg)
+			case Swapped:
+				// honest encryption of another hidden attribute of the same level
+				proof.escrowed[0].AuditingEncryption, _ = AuditingEncrypt(prg, audPks[1], creds.Attributes[1][1])
+			case Moved:
+				proof.escrowed[0].J = 1
+			case Dropped:
+				proof.escrowed = proof.escrowed[1:]
+				proof.resR = proof.resR[1:]
+			case Disclosed:
+				D = Indices{{1, 0, creds.Attributes[1][0]}}
+				expected = "is disclosed"
+			case CredsDetached:
+				e = proof.proof.VerifyProof(pk, ys, h, pkNym, D, m)
+				assert.ErrorContains(t, e, "verification failed")
+				return
+			}
+
+			assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, D, m, audPks), expected)
+		})
+	}
+}
+
+func testEscrowPositionErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, _, h, _ := generateChain(2, 2)
+	_, audPks := GenerateEscrowKeys(prg)
+
+	D := Indices{{2, 1, creds.Attributes[2][1]}}
+
+	for _, tc := range []struct {
+		positions []Position
+		expected  string
+	}{
+		{[]Position{{0, 0}}, "(0, 0) does not exist"},
+		{[]Position{{3, 0}}, "(3, 0) does not exist"},
+		{[]Position{{1, 2}}, "(1, 2) does not exist"},
+		{[]Position{{1, -1}}, "(1, -1) does not exist"},
+		{[]Position{{2, 1}}, "(2, 1) is disclosed"},
+		{[]Position{{1, 0}, {1, 0}}, "(1, 0) is repeated"},
+	} {
+		_, e := creds.ProveWithEscrow(prg, sk, pk, D, []byte("message"), ys, h, skNym, tc.positions, audPks)
+		assert.ErrorContains(t, e, tc.expected)
+	}
+}
+
+func testEscrowKeyErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(1, 2)
+	_, audPks := GenerateEscrowKeys(prg)
+
+	for _, tc := range []struct {
+		pks      []PK
+		expected string
+	}{
+		{audPks[:1], "exactly 2"},
+		{[]PK{audPks[1], audPks[0]}, "expected to be in"},
+		{[]PK{audPks[0], nil}, "escrow public key 1"},
+	} {
+		_, e := creds.ProveWithEscrow(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, []Position{{1, 0}}, tc.pks)
+		assert.ErrorContains(t, e, tc.expected)
+	}
+
+	proof, e := creds.ProveWithEscrow(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, []Position{{1, 0}}, audPks)
+	assert.NilError(t, e)
+	assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, Indices{}, []byte("message"), audPks[1:]), "exactly 2")
+}
+
+func testEscrowMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(2, 2)
+	audSk, audPks := GenerateEscrowKeys(prg)
+
+	proof, e := creds.ProveWithEscrow(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, []Position{{2, 1}, {1, 1}}, audPks)
+	assert.NilError(t, e)
+
+	recovered, e := ParseEscrowProof(proof.ToBytes())
+	assert.NilError(t, e)
+
+	assert.NilError(t, recovered.Verify(pk, ys, h, pkNym, Indices{}, []byte("message"), audPks))
+	assert.Check(t, recovered.proof.Equals(proof.proof))
+	for _, attribute := range recovered.Escrowed() {
+		assert.Check(t, pointEqual(attribute.AuditingDecrypt(audSk), creds.Attributes[attribute.I][attribute.J]))
+	}
+}
+
+func testEscrowParseRejectsMalformed(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, _, h, _ := generateChain(2, 2)
+	_, audPks := GenerateEscrowKeys(prg)
+
+	proof, e := creds.ProveWithEscrow(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, []Position{{2, 1}, {1, 1}}, audPks)
+	assert.NilError(t, e)
+	valid := proof.ToBytes()
+
+	type TestCase string
+	const (
+		Empty      TestCase = "empty input"
+		Trailing   TestCase = "trailing bytes"
+		Lengths    TestCase = "lengths disagree"
+		BadProof   TestCase = "malformed credentials proof"
+		WrongGroup TestCase = "encryption in wrong group"
+		NoPoint    TestCase = "missing point"
+		BadScalar  TestCase = "scalar out of range"
+	)
+
+	for _, tc := range []TestCase{Empty, Trailing, Lengths, BadProof, WrongGroup, NoPoint, BadScalar} {
+		t.Run(string(tc), func(t *testing.T) {
+			var input []byte
+			var marshal escrowProofMarshal
+
+			switch tc {
+			case Empty:
+				input = []byte{}
+			case Trailing:
+				input = append(valid, 0x13)
+			case Lengths:
+				input = remarshal(t, valid, &marshal, func() { marshal.ResR = marshal.ResR[1:] })
+			case BadProof:
+				input = remarshal(t, valid, &marshal, func() { marshal.Proof = marshal.Proof[1:] })
+			case WrongGroup:
+				input = remarshal(t, valid, &marshal, func() { marshal.Escrowed[0].I = 1 })
+			case NoPoint:
+				input = remarshal(t, valid, &marshal, func() { marshal.Escrowed[1].Enc2 = []byte{} })
+			case BadScalar:
+				input = remarshal(t, valid, &marshal, func() { marshal.ResR[0] = make([]byte, 40) })
+			}
+
+			_, e := ParseEscrowProof(input)
+			assert.ErrorContains(t, e, "ParseEscrowProof")
+		})
+	}
+}
+
+// Benchmarks
+
+func BenchmarkEscrow(b *testing.B) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(2, 2)
+	_, audPks := GenerateEscrowKeys(prg)
+	positions := []Position{{1, 0}, {2, 0}}
+
+	proof, _ := creds.ProveWithEscrow(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, positions, audPks)
+
+	b.Run("prove", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_, _ = creds.ProveWithEscrow(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, positions, audPks)
+		}
+	})
+
+	b.Run("verify", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_ = proof.Verify(pk, ys, h, pkNym, Indices{}, []byte("message"), audPks)
+		}
+	})
+}
@@ -92,16 +92,8 @@ func (groth *Groth) Sign(sk SK, m []interface{}) (signature GrothSignature) {
 // If verification fails, the error will not be nil, and will identify the part of pipeline, which failed
 func (groth *Groth) Verify(pk PK, signature GrothSignature, m []interface{}) (e error) {
 
-	if ce := groth.consistencyCheck(m); ce != nil {
-		return ce
-	}
-
-	if ce := groth.consistencyCheck(signature.ts); ce != nil {
-		return ce
-	}
-
-	if len(m) != len(signature.ts) {
-		return fmt.Errorf("m (%d) must be equal to Ts (%d)", len(m), len(signature.ts))
+	if e = groth.check(pk, signature, m); e != nil {
+		return
 	}
 
 	var wg sync.WaitGroup
@@ -138,6 +130,99 @@ func (groth *Groth) Verify(pk PK, signature GrothSignature, m []interface{}) (e
 	return
 }
 
+// VerifyBatch verifies many (pk, signature, message) tuples at once.
+// All equations of all signatures are combined with random 128-bit exponents
+// into a single product of pairings with one final exponentiation.
+// A single tuple may be given to check one signature's equations this way.
+// If the combined check fails, the signatures are verified one by one to identify the first invalid one.
+func (groth *Groth) VerifyBatch(pks []PK, signatures []GrothSignature, ms [][]interface{}) (e error) {
+	if len(pks) != len(signatures) || len(pks) != len(ms) {
+		return fmt.Errorf("numbers of public keys (%d), signatures (%d) and messages (%d) must be equal", len(pks), len(signatures), len(ms))
+	}
+
+	var args []*eArg
+	for index := range signatures {
+		tupleArgs, te := groth.batchArgs(pks[index], signatures[index], ms[index])
+		if te != nil {
+			return fmt.Errorf("signature %d: %v", index, te)
+		}
+		args = append(args, tupleArgs...)
+	}
+
+	if len(args) == 0 {
+		return
+	}
+
+	if result := eProduct(args...); result != nil && result.Isunity() {
+		return
+	}
+
+	for index := range signatures {
+		if ve := groth.Verify(pks[index], signatures[index], ms[index]); ve != nil {
+			return fmt.Errorf("signature %d: %v", index, ve)
+		}
+	}
+
+	return fmt.Errorf("batch verification failed")
+}
+
+// batchArgs returns the pairing arguments of the randomized combination of the signature equations.
+// The product of their pairings is the identity if the signature is valid.
+// e(R, S^d0 * prod Ti^di) * e(V, g1^d0 * prod yi^di)^-1 * e(g2, y1^d0 * prod mi^di)^-1 FOR b = 2
+func (groth *Groth) batchArgs(pk PK, signature GrothSignature, m []interface{}) (args []*eArg, e error) {
+	if e = groth.check(pk, signature, m); e != nil {
+		return
+	}
+
+	d := randomSmallBig()
+
+	lhs := pointMultiply(signature.s, d)
+	keyed := pointMultiply(groth.g1, d)
+	free := pointMultiply(groth.y[0], d)
+
+	for index := 0; index < len(m); index++ {
+		d = randomSmallBig()
+
+		pointAdd(lhs, pointMultiply(signature.ts[index], d))
+		pointAdd(keyed, pointMultiply(groth.y[index], d))
+		pointAdd(free, pointMultiply(m[index], d))
+	}
+
+	args = []*eArg{
+		{signature.r, lhs, nil},
+		{pk, pointNegate(keyed), nil},
+		{groth.g2, pointNegate(free), nil},
+	}
+
+	return
+}
+
+// check validates the points of a tuple and its lengths
+func (groth *Groth) check(pk PK, signature GrothSignature, m []interface{}) (e error) {
+
+	if ve := validatePoints(append([]interface{}{pk, signature.r, signature.s}, signature.ts...)...); ve != nil {
+		return fmt.Errorf("invalid signature or public key: %v", ve)
+	}
+
+	if ve := validatePoints(m...); ve != nil {
+		return fmt.Errorf("invalid message: %v", ve)
+	}
+
+	if ce := groth.consistencyCheck(m); ce != nil {
+		return ce
+	}
+
+	if ce := groth.consistencyCheck(signature.ts); ce != nil {
+		return ce
+	}
+
+	if len(m) != len(signature.ts) {
+		return fmt.Errorf("m (%d) must be equal to Ts (%d)", len(m), len(signature.ts))
+	}
+
+	return
+}
+
 // Randomize changes the signature by randomizing each of its components.
 // Randomized signature is valid for the original message.
 // If rPrime is provided, it will be used for randomization, otherwise it will be generated using internal PRG.
@@ -214,14 +299,20 @@ type grothSignatureMarshal struct {
 	Ts [][]byte
 }
 
-func (marshal *grothSignatureMarshal) toGrothSignature() (signature *GrothSignature) {
+// toGrothSignature validates the marshalled values and converts them to a signature.
+// R has to be in the group opposite to that of S and Ts.
+func (marshal *grothSignatureMarshal) toGrothSignature() (signature *GrothSignature, e error) {
 	signature = &GrothSignature{}
 
-	signature.r, _ = PointFromBytes(marshal.R)
-	signature.s, _ = PointFromBytes(marshal.S)
-	signature.ts = make([]interface{}, len(marshal.Ts))
-	for j := 0; j < len(marshal.Ts); j++ {
-		signature.ts[j], _ = PointFromBytes(marshal.Ts[j])
+	var first bool
+	if signature.r, first, e = requiredPointFromBytes(marshal.R); e != nil {
+		return nil, fmt.Errorf("r: %v", e)
+	}
+	if signature.s, e = pointFromBytesInGroup(marshal.S, !first, false); e != nil {
+		return nil, fmt.Errorf("s: %v", e)
+	}
+	if signature.ts, e = pointsFromBytesInGroup(marshal.Ts, !first, false); e != nil {
+		return nil, fmt.Errorf("ts: %v", e)
 	}
 
 	return
@@ -241,12 +332,26 @@ func (signature *GrothSignature) toMarshal() (marshal *grothSignatureMarshal) {
 }
 
 // GrothSignatureFromBytes marshals the Groth signature object using ASN1 encoding
+// Panics if the input is malformed, see ParseGrothSignature for a version that returns error.
 func GrothSignatureFromBytes(input []byte) (signature *GrothSignature) {
+	signature, e := ParseGrothSignature(input)
+	if e != nil {
+		panic("un-marshalling groth signature failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseGrothSignature un-marshals and validates the Groth signature object using ASN1 encoding
+func ParseGrothSignature(input []byte) (signature *GrothSignature, e error) {
 	var marshal grothSignatureMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling groth signature failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseGrothSignature: %v", e)
+	}
+
+	if signature, e = marshal.toGrothSignature(); e != nil {
+		return nil, fmt.Errorf("ParseGrothSignature: %v", e)
 	}
-	signature = marshal.toGrothSignature()
 
 	return
 }
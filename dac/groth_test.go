@@ -45,6 +45,10 @@ func TestGroth(t *testing.T) {
 				testGrothSignatureEquality,
 				testGrothSignatureMarshal,
 				testGrothSignatureUnMarshalFails,
+				testGrothSignatureParse,
+				testGrothSignatureParseRejectsMalformed,
+				testGrothRejectsInvalidPoints,
+				testGrothVerifyBatch,
 			} {
 				t.Run(funcToString(reflect.ValueOf(test)), test)
 			}
@@ -305,6 +309,176 @@ func testGrothSignatureUnMarshalFails(t *testing.T) {
 	GrothSignatureFromBytes([]byte{0x13})
 }
 
+// parsing yields the original object
+func testGrothSignatureParse(t *testing.T) {
+	sk, _ := groth.Generate()
+
+	signature := groth.Sign(sk, grothMessage)
+	recovered, e := ParseGrothSignature(signature.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Check(t, signature.equals(*recovered))
+}
+
+// parsing rejects malformed input without panicking
+func testGrothSignatureParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed  TestCase = "malformed ASN1"
+		OffCurve   TestCase = "s not on curve"
+		Infinity   TestCase = "r at infinity"
+		WrongGroup TestCase = "r in wrong group"
+		MixedGroup TestCase = "ts in mixed groups"
+		Missing    TestCase = "r missing"
+	)
+
+	_, first := groth.g1.(*FP256BN.ECP)
+
+	sk, _ := groth.Generate()
+	signature := groth.Sign(sk, grothMessage)
+
+	for _, tc := range []TestCase{Malformed, OffCurve, Infinity, WrongGroup, MixedGroup, Missing} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal grothSignatureMarshal
+			bytes := remarshal(t, signature.ToBytes(), &marshal, func() {
+				switch tc {
+				case OffCurve:
+					marshal.S = offCurveBytes(first)
+				case Infinity:
+					marshal.R = infinityBytes(!first)
+				case WrongGroup:
+					marshal.R = pointBytes(first)
+				case MixedGroup:
+					marshal.Ts[1] = pointBytes(!first)
+				case Missing:
+					marshal.R = nil
+				}
+			})
+
+			if tc == Malformed {
+				bytes = append([]byte{0x13}, bytes...)
+			}
+
+			_, e := ParseGrothSignature(bytes)
+			assert.ErrorContains(t, e, "ParseGrothSignature")
+		})
+	}
+}
+
+// verification rejects points outside of the prime-order subgroups
+func testGrothRejectsInvalidPoints(t *testing.T) {
+	sk, pk := groth.Generate()
+
+	signature := groth.Sign(sk, grothMessage)
+
+	for _, component := range []string{"r", "s", "ts", "pk", "message"} {
+		var like interface{}
+		switch component {
+		case "r", "pk":
+			like = signature.r
+		default:
+			like = signature.s
+		}
+
+		for name, point := range invalidPointsLike(like) {
+			t.Run(fmt.Sprintf("%s %s", component, name), func(t *testing.T) {
+				tampered := signature
+				tamperedPk := pk
+				tamperedMessage := append([]interface{}{}, grothMessage...)
+				switch component {
+				case "r":
+					tampered.r = point
+				case "s":
+					tampered.s = point
+				case "ts":
+					tampered.ts = append([]interface{}{}, signature.ts...)
+					tampered.ts[1] = point
+				case "pk":
+					tamperedPk = point
+				case "message":
+					tamperedMessage[1] = point
+				}
+
+				assert.ErrorContains(t, groth.Verify(tamperedPk, tampered, tamperedMessage), "invalid")
+			})
+		}
+	}
+}
+
+// helper that signs N distinct messages, each under its own key
+func grothBatch(N int) (pks []PK, signatures []GrothSignature, ms [][]interface{}) {
+	_, first := groth.g1.(*FP256BN.ECP)
+
+	for i := 0; i < N; i++ {
+		sk, pk := groth.Generate()
+		m := []interface{}{StringToECPb(fmt.Sprintf("hello %d", i), first), StringToECPb("world", first), StringToECPb("!", first)}
+
+		pks = append(pks, pk)
+		signatures = append(signatures, groth.Sign(sk, m))
+		ms = append(ms, m)
+	}
+
+	return
+}
+
+// batch verification accepts valid tuples and rejects any invalid one
+func testGrothVerifyBatch(t *testing.T) {
+	type TestCase string
+	const (
+		Correct       TestCase = "correct"
+		Single        TestCase = "single signature"
+		Empty         TestCase = "empty batch"
+		WrongMessage  TestCase = "wrong message"
+		WrongPK       TestCase = "wrong public key"
+		TamperedS     TestCase = "tampered S"
+		SwappedTs     TestCase = "swapped Ts"
+		LengthsDiffer TestCase = "lengths differ"
+		InvalidPoint  TestCase = "invalid point"
+	)
+
+	for _, tc := range []TestCase{Correct, Single, Empty, WrongMessage, WrongPK, TamperedS, SwappedTs, LengthsDiffer, InvalidPoint} {
+		t.Run(string(tc), func(t *testing.T) {
+			pks, signatures, ms := grothBatch(4)
+			_, first := groth.g1.(*FP256BN.ECP)
+
+			var expected string
+			switch tc {
+			case Single:
+				pks, signatures, ms = pks[:1], signatures[:1], ms[:1]
+			case Empty:
+				pks, signatures, ms = nil, nil, nil
+			case WrongMessage:
+				ms[2] = []interface{}{StringToECPb("wrong", first), ms[2][1], ms[2][2]}
+				expected = "signature 2"
+			case WrongPK:
+				pks[1] = pks[0]
+				expected = "signature 1"
+			case TamperedS:
+				signatures[3].s = pointMultiply(signatures[3].s, FP256BN.NewBIGint(0x13))
+				expected = "signature 3"
+			case SwappedTs:
+				// equations of one signature are combined with different exponents
+				signatures[0].ts[1], signatures[0].ts[2] = signatures[0].ts[2], signatures[0].ts[1]
+				ms[0][1], ms[0][2] = ms[0][2], ms[0][1]
+				expected = "signature 0"
+			case LengthsDiffer:
+				ms = ms[:3]
+				expected = "must be equal"
+			case InvalidPoint:
+				signatures[2].r = pointMultiply(signatures[2].r, FP256BN.NewBIGint(0))
+				expected = "invalid"
+			}
+
+			e := groth.VerifyBatch(pks, signatures, ms)
+			if expected == "" {
+				assert.NilError(t, e)
+			} else {
+				assert.ErrorContains(t, e, expected)
+			}
+		})
+	}
+}
+
 // Benchmarks
 
 func BenchmarkGroth(b *testing.B) {
@@ -316,6 +490,7 @@ func BenchmarkGroth(b *testing.B) {
 				benchmarkGrothGenerate,
 				benchmarkGrothSign,
 				benchmarkGrothVerify,
+				benchmarkGrothVerifyBatch,
 				benchmarkGrothRandomize,
 			} {
 				b.Run(funcToString(reflect.ValueOf(benchmark)), benchmark)
@@ -352,3 +527,25 @@ func benchmarkGrothRandomize(b *testing.B) {
 		groth.Randomize(signature, nil)
 	}
 }
+
+func benchmarkGrothVerifyBatch(b *testing.B) {
+	for _, N := range []int{1, 10, 50} {
+		pks, signatures, ms := grothBatch(N)
+
+		b.Run(fmt.Sprintf("N=%d", N), func(b *testing.B) {
+			b.Run("sequential", func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					for i := range signatures {
+						groth.Verify(pks[i], signatures[i], ms[i])
+					}
+				}
+			})
+
+			b.Run("batch", func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					groth.VerifyBatch(pks, signatures, ms)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package dac
+
+import (
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Domain separation tags of the points this library hashes to the curve.
+// Points hashed under different tags are independent, even for the same message.
+const (
+	_DSTScope    = "DAC-LIB-V01-FP256BN-G1-SCOPE"
+	_DSTNymBase  = "DAC-LIB-V01-FP256BN-NYM-BASE"
+	_DSTPedersen = "DAC-LIB-V01-FP256BN-G1-PEDERSEN"
+)
+
+// HashToG1 hashes the message to a point of G1 whose discrete logarithm is unknown.
+// dst is the domain separation tag, it must be unique to the application and to the purpose of the point.
+// The point is found by try-and-increment on the x-coordinate derived from the tag and the message,
+// so the running time depends on the input: do not hash secrets with it.
+func HashToG1(dst string, message []byte) *FP256BN.ECP {
+	return FP256BN.ECP_mapit(hashToField(dst, message))
+}
+
+// HashToG2 is HashToG1 for G2.
+// The point found by try-and-increment is mapped to the prime-order subgroup by clearing the cofactor.
+func HashToG2(dst string, message []byte) *FP256BN.ECP2 {
+	return FP256BN.ECP2_mapit(hashToField(dst, message))
+}
+
+// HashToCurve is HashToG1 (if first) or HashToG2
+func HashToCurve(dst string, message []byte, first bool) interface{} {
+	if first {
+		return HashToG1(dst, message)
+	}
+	return HashToG2(dst, message)
+}
+
+// GenerateNymBase derives the pseudonym base h from a public label (e.g. the name of the deployment).
+// Anyone can recompute it, and nobody knows its discrete logarithm to the generator,
+// which the pseudonyms (see GenerateNymKeys) rely on to hide the secret key.
+// first defines the group of h.
+func GenerateNymBase(label string, first bool) (h interface{}) {
+	return HashToCurve(_DSTNymBase, []byte(label), first)
+}
+
+// AttributeFromString encodes a string as a point on the curve: the generator to the power of the hash of the string.
+// The discrete logarithm of the point is public, which is fine for attributes,
+// but the point must never serve as a pseudonym base or a commitment generator, use HashToCurve for those.
+func AttributeFromString(message string, first bool) interface{} {
+	a := StringValue(message)
+
+	if first {
+		return FP256BN.ECP_generator().Mul(a)
+	}
+	return FP256BN.ECP2_generator().Mul(a)
+}
+
+// StringValue is the exponent of the attribute encoding the string (see AttributeFromString),
+// the prover supplies it in AttributeValues and the verifier lists it in set predicates
+func StringValue(message string) *FP256BN.BIG {
+	return sha3(FP256BN.NewBIGints(FP256BN.CURVE_Order), []byte(message))
+}
+
+// hashToField derives the starting x-coordinate from the tag and the message (both length-prefixed)
+func hashToField(dst string, message []byte) []byte {
+	t := &transcript{}
+	t.append("dst", []byte(dst))
+	t.append("message", message)
+
+	return t.digest()
+}
@@ -0,0 +1,100 @@
+package dac
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Tests
+
+func TestHash(t *testing.T) {
+	for _, first := range []bool{true, false} {
+		t.Run(fmt.Sprintf("g%d", map[bool]int{true: 1, false: 2}[first]), func(t *testing.T) {
+			for _, test := range []func(*testing.T, bool){
+				testHashToCurveValid,
+				testHashToCurveDeterministic,
+				testHashToCurveSeparation,
+				testHashNymBase,
+			} {
+				t.Run(funcToString(reflect.ValueOf(test)), func(t *testing.T) { test(t, first) })
+			}
+		})
+	}
+
+	t.Run("attribute encoding", testHashAttributeFromString)
+}
+
+func testHashToCurveValid(t *testing.T, first bool) {
+	for _, message := range []string{"", "a", "hello world", string(make([]byte, 1000))} {
+		point := HashToCurve("DST", []byte(message), first)
+
+		// includes the subgroup check for G2
+		assert.NilError(t, validatePointInGroup(point, first))
+		assert.Check(t, !pointEqual(point, generatorSameGroup(point)))
+	}
+}
+
+func testHashToCurveDeterministic(t *testing.T, first bool) {
+	assert.Check(t, pointEqual(HashToCurve("DST", []byte("hello"), first), HashToCurve("DST", []byte("hello"), first)))
+}
+
+func testHashToCurveSeparation(t *testing.T, first bool) {
+	point := HashToCurve("DST", []byte("hello"), first)
+
+	// another message
+	assert.Check(t, !pointEqual(point, HashToCurve("DST", []byte("hello!"), first)))
+	// another tag
+	assert.Check(t, !pointEqual(point, HashToCurve("DST2", []byte("hello"), first)))
+	// tag and message are not simply concatenated
+	assert.Check(t, !pointEqual(point, HashToCurve("DSTh", []byte("ello"), first)))
+	// not the naive encoding
+	assert.Check(t, !pointEqual(point, AttributeFromString("hello", first)))
+}
+
+func testHashNymBase(t *testing.T, first bool) {
+	prg := getNewRand(SEED)
+
+	h := GenerateNymBase("deployment", first)
+	assert.Check(t, pointEqual(h, GenerateNymBase("deployment", first)))
+	assert.Check(t, !pointEqual(h, GenerateNymBase("another deployment", first)))
+	assert.Check(t, !pointEqual(h, HashToCurve(_DSTScope, []byte("deployment"), first)))
+
+	sk, _ := GenerateKeys(prg, 1)
+	skNym, pkNym := GenerateNymKeys(prg, sk, h)
+
+	signature := SignNym(prg, pkNym, skNym, sk, h, []byte("message"))
+	assert.NilError(t, signature.VerifyNym(h, pkNym, []byte("message")))
+}
+
+func testHashAttributeFromString(t *testing.T) {
+	for _, first := range []bool{true, false} {
+		a := AttributeFromString("hello", first)
+
+		exponent := sha3(FP256BN.NewBIGints(FP256BN.CURVE_Order), []byte("hello"))
+		assert.Check(t, pointEqual(a, pointMultiply(generatorSameGroup(a), exponent)))
+		assert.Check(t, bigEqual(StringValue("hello"), exponent))
+
+		// the deprecated name keeps the encoding
+		assert.Check(t, pointEqual(a, StringToECPb("hello", first)))
+	}
+
+	assert.Check(t, pointListEquals(ProduceAttributes(1, "hello"), []interface{}{AttributeFromString("hello", true)}))
+	assert.Check(t, pointListEquals(ProduceAttributes(2, "hello"), []interface{}{AttributeFromString("hello", false)}))
+}
+
+// Benchmarks
+
+func BenchmarkHash(b *testing.B) {
+	for _, first := range []bool{true, false} {
+		b.Run(fmt.Sprintf("g%d", map[bool]int{true: 1, false: 2}[first]), func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				HashToCurve("DST", []byte(fmt.Sprintf("message %d", n)), first)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package dac
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// membershipProof proves that the exponent x of the attribute committed in com = g^x * h^r is one of the values of the set,
+// or none of them, with com linked to the attribute's response in the credentials proof through resR.
+// For membership, it is an OR-proof of com / g^v = h^r with a challenge and a response per value, the challenges sum up to c.
+// For non-membership, it is a proof of (x - v)^-1 for each value: com / g^v to its power times h to some power is g.
+type membershipProof struct {
+	com  *FP256BN.ECP
+	c    []*FP256BN.BIG
+	z    []*FP256BN.BIG
+	zB   []*FP256BN.BIG
+	resR *FP256BN.BIG
+}
+
+// membershipProver holds the state of the membership proof generation between the commitments and the responses
+type membershipProver struct {
+	negated bool
+	member  int
+	r       *FP256BN.BIG
+	rhoR    *FP256BN.BIG
+	ws      []*FP256BN.BIG
+	fakeC   []*FP256BN.BIG
+	fakeZ   []*FP256BN.BIG
+	as      []*FP256BN.BIG
+	bs      []*FP256BN.BIG
+	wBs     []*FP256BN.BIG
+
+	com   *FP256BN.ECP
+	ts    []*FP256BN.ECP
+	tLink *FP256BN.ECP
+}
+
+// membershipCommit commits to x and to the branches of the proof.
+// rhoX is the randomness of x shared with the other parts of the proof.
+// Returns error if x is not in the set (or is in it, for non-membership).
+func membershipCommit(prg *amcl.RAND, m Membership, x *FP256BN.BIG, rhoX *FP256BN.BIG) (prover *membershipProver, e error) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	g := FP256BN.ECP_generator()
+
+	member := -1
+	for index, value := range m.Set {
+		if bigEqual(x, value) {
+			member = index
+			break
+		}
+	}
+	if !m.Negated && member < 0 {
+		return nil, fmt.Errorf("attribute (%d, %d) is not in the set", m.I, m.J)
+	}
+	if m.Negated && member >= 0 {
+		return nil, fmt.Errorf("attribute (%d, %d) is in the set", m.I, m.J)
+	}
+
+	prover = &membershipProver{
+		negated: m.Negated,
+		member:  member,
+		r:       FP256BN.Randomnum(q, prg),
+		rhoR:    FP256BN.Randomnum(q, prg),
+		ts:      make([]*FP256BN.ECP, len(m.Set)),
+	}
+
+	prover.com = g.Mul2(x, _PedersenBase, prover.r)
+	prover.tLink = g.Mul2(rhoX, _PedersenBase, prover.rhoR)
+
+	if !m.Negated {
+		prover.ws = make([]*FP256BN.BIG, len(m.Set))
+		prover.fakeC = make([]*FP256BN.BIG, len(m.Set))
+		prover.fakeZ = make([]*FP256BN.BIG, len(m.Set))
+
+		for index, value := range m.Set {
+			if index == member {
+				prover.ws[index] = FP256BN.Randomnum(q, prg)
+				prover.ts[index] = _PedersenBase.Mul(prover.ws[index])
+				continue
+			}
+
+			// the branches of the other values are simulated
+			prover.fakeC[index] = FP256BN.Randomnum(q, prg)
+			prover.fakeZ[index] = FP256BN.Randomnum(q, prg)
+			prover.ts[index] = _PedersenBase.Mul2(prover.fakeZ[index], membershipShifted(prover.com, value), bigNegate(prover.fakeC[index], q))
+		}
+
+		return
+	}
+
+	prover.as = make([]*FP256BN.BIG, len(m.Set))
+	prover.bs = make([]*FP256BN.BIG, len(m.Set))
+	prover.ws = make([]*FP256BN.BIG, len(m.Set))
+	prover.wBs = make([]*FP256BN.BIG, len(m.Set))
+
+	for index, value := range m.Set {
+		// (com / g^v)^a * h^b = g for a = (x - v)^-1 and b = -r * a
+		prover.as[index] = bigInverse(bigMinusMod(x, value, q), q)
+		prover.bs[index] = bigNegate(FP256BN.Modmul(prover.r, prover.as[index], q), q)
+
+		prover.ws[index] = FP256BN.Randomnum(q, prg)
+		prover.wBs[index] = FP256BN.Randomnum(q, prg)
+		prover.ts[index] = membershipShifted(prover.com, value).Mul2(prover.ws[index], _PedersenBase, prover.wBs[index])
+	}
+
+	return
+}
+
+// respond computes the responses of the membership proof for the challenge c
+func (prover *membershipProver) respond(c *FP256BN.BIG) (proof membershipProof) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	n := len(prover.ts)
+
+	proof.com = prover.com
+	proof.z = make([]*FP256BN.BIG, n)
+
+	respond := func(w *FP256BN.BIG, c *FP256BN.BIG, secret *FP256BN.BIG) (z *FP256BN.BIG) {
+		z = FP256BN.Modmul(c, secret, q)
+		z = z.Plus(w)
+		z.Mod(q)
+
+		return
+	}
+
+	if !prover.negated {
+		proof.c = make([]*FP256BN.BIG, n)
+
+		// the real branch gets the rest of the challenge
+		realC := FP256BN.NewBIGcopy(c)
+		for index := 0; index < n; index++ {
+			if index != prover.member {
+				proof.c[index], proof.z[index] = prover.fakeC[index], prover.fakeZ[index]
+				realC = bigMinusMod(realC, prover.fakeC[index], q)
+			}
+		}
+		proof.c[prover.member] = realC
+		proof.z[prover.member] = respond(prover.ws[prover.member], realC, prover.r)
+	} else {
+		proof.zB = make([]*FP256BN.BIG, n)
+
+		for index := 0; index < n; index++ {
+			proof.z[index] = respond(prover.ws[index], c, prover.as[index])
+			proof.zB[index] = respond(prover.wBs[index], c, prover.bs[index])
+		}
+	}
+
+	proof.resR = respond(prover.rhoR, c, prover.r)
+
+	return
+}
+
+// commitments recomputes the commitments of the membership proof from the challenge c and the response resX of the attribute's exponent.
+// Returns error if the challenges of the branches do not sum up to c.
+func (proof *membershipProof) commitments(m Membership, c *FP256BN.BIG, resX *FP256BN.BIG) (ts []*FP256BN.ECP, tLink *FP256BN.ECP, e error) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	g := FP256BN.ECP_generator()
+
+	ts = make([]*FP256BN.ECP, len(m.Set))
+
+	if !m.Negated {
+		sum := FP256BN.NewBIGint(0)
+		for index, value := range m.Set {
+			ts[index] = _PedersenBase.Mul2(proof.z[index], membershipShifted(proof.com, value), bigNegate(proof.c[index], q))
+
+			sum = sum.Plus(proof.c[index])
+			sum.Mod(q)
+		}
+		if !bigEqual(sum, c) {
+			return nil, nil, fmt.Errorf("challenges of the set membership of attribute (%d, %d) do not sum up to c", m.I, m.J)
+		}
+	} else {
+		cNeg := bigNegate(c, q)
+		for index, value := range m.Set {
+			ts[index] = membershipShifted(proof.com, value).Mul2(proof.z[index], _PedersenBase, proof.zB[index])
+			ts[index].Add(g.Mul(cNeg))
+		}
+	}
+
+	tLink = g.Mul2(resX, _PedersenBase, proof.resR)
+	tLink.Add(proof.com.Mul(bigNegate(c, q)))
+
+	return
+}
+
+// validate checks that the membership proof has all the components for the set
+func (proof *membershipProof) validate(m Membership) (e error) {
+	n := len(m.Set)
+
+	if m.Negated && (proof.c != nil || len(proof.z) != n || len(proof.zB) != n) ||
+		!m.Negated && (len(proof.c) != n || len(proof.z) != n || proof.zB != nil) {
+		return fmt.Errorf("set membership proof for attribute (%d, %d) does not fit the set of %d values (negated: %t)", m.I, m.J, n, m.Negated)
+	}
+	if proof.resR == nil {
+		return fmt.Errorf("set membership proof for attribute (%d, %d) is incomplete", m.I, m.J)
+	}
+	if e = ValidatePoint(proof.com); e != nil {
+		return fmt.Errorf("set membership proof for attribute (%d, %d): %v", m.I, m.J, e)
+	}
+	for index := 0; index < n; index++ {
+		if proof.z[index] == nil || !m.Negated && proof.c[index] == nil || m.Negated && proof.zB[index] == nil {
+			return fmt.Errorf("set membership proof for attribute (%d, %d): value %d is incomplete", m.I, m.J, index)
+		}
+	}
+
+	return
+}
+
+// membershipShifted computes com / g^v, which is a commitment to 0 if com commits to v
+func membershipShifted(com *FP256BN.ECP, v *FP256BN.BIG) (shifted *FP256BN.ECP) {
+	shifted = FP256BN.ECP_generator().Mul(v)
+	shifted.Neg()
+	shifted.Add(com)
+
+	return
+}
+
+func appendMembership(t *transcript, m Membership, com *FP256BN.ECP, ts []*FP256BN.ECP, tLink *FP256BN.ECP) {
+	t.append("i", []byte(strconv.Itoa(m.I)))
+	t.append("j", []byte(strconv.Itoa(m.J)))
+	t.append("negated", []byte(strconv.FormatBool(m.Negated)))
+	t.append("set", []byte(strconv.Itoa(len(m.Set))))
+	for index, value := range m.Set {
+		t.appendBig("value", value)
+		t.appendPoint("t", ts[index])
+	}
+	t.appendPoint("com", com)
+	t.appendPoint("tLink", tLink)
+}
+
+type membershipProofMarshal struct {
+	Com  []byte
+	C    [][]byte
+	Z    [][]byte
+	ZB   [][]byte
+	ResR []byte
+}
+
+func (proof *membershipProof) marshal() (marshal membershipProofMarshal) {
+	marshal.Com = PointToBytes(proof.com)
+	marshal.C = bigsToBytes(proof.c)
+	marshal.Z = bigsToBytes(proof.z)
+	marshal.ZB = bigsToBytes(proof.zB)
+	marshal.ResR = bigToBytes(proof.resR)
+
+	return
+}
+
+func (marshal membershipProofMarshal) unmarshal() (proof membershipProof, e error) {
+	com, e := pointFromBytesInGroup(marshal.Com, true, false)
+	if e != nil {
+		return proof, fmt.Errorf("com: %v", e)
+	}
+	proof.com = com.(*FP256BN.ECP)
+
+	// an empty list stands for a missing one, validate tells if it fits the set
+	if len(marshal.C) > 0 {
+		if proof.c, e = bigsFromBytes(marshal.C...); e != nil {
+			return proof, fmt.Errorf("c: %v", e)
+		}
+	}
+	if proof.z, e = bigsFromBytes(marshal.Z...); e != nil {
+		return proof, fmt.Errorf("z: %v", e)
+	}
+	if len(marshal.ZB) > 0 {
+		if proof.zB, e = bigsFromBytes(marshal.ZB...); e != nil {
+			return proof, fmt.Errorf("zB: %v", e)
+		}
+	}
+	if proof.resR, e = bigFromBytes(marshal.ResR); e != nil {
+		return proof, fmt.Errorf("resR: %v", e)
+	}
+
+	return
+}
+
+func bigsToBytes(as []*FP256BN.BIG) (result [][]byte) {
+	result = make([][]byte, len(as))
+	for index, a := range as {
+		result[index] = bigToBytes(a)
+	}
+
+	return
+}
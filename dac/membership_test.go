@@ -0,0 +1,180 @@
+package dac
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// helper that proves the membership of x and recomputes the commitments the way the verifier does;
+// returns whether they match those of the prover
+func membershipRoundTrip(prg *amcl.RAND, m Membership, x *FP256BN.BIG) (matches bool, e error) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	rhoX := FP256BN.Randomnum(q, prg)
+	prover, e := membershipCommit(prg, m, x, rhoX)
+	if e != nil {
+		return
+	}
+
+	c := FP256BN.Randomnum(q, prg)
+	proof := prover.respond(c)
+	if e = proof.validate(m); e != nil {
+		return
+	}
+
+	resX := FP256BN.Modmul(c, x, q)
+	resX = resX.Plus(rhoX)
+	resX.Mod(q)
+
+	ts, tLink, e := proof.commitments(m, c, resX)
+	if e != nil {
+		return
+	}
+	for index := range ts {
+		if !ts[index].Equals(prover.ts[index]) {
+			return false, nil
+		}
+	}
+
+	return tLink.Equals(prover.tLink), nil
+}
+
+func membershipSet(values ...string) (set []*FP256BN.BIG) {
+	for _, value := range values {
+		set = append(set, StringValue(value))
+	}
+
+	return
+}
+
+// Tests
+
+func TestMembership(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testMembershipRoundTrip,
+		testMembershipWrongValue,
+		testMembershipTampered,
+		testMembershipMarshal,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testMembershipRoundTrip(t *testing.T) {
+	set := membershipSet("US", "CA", "MX")
+
+	for _, negated := range []bool{false, true} {
+		for _, value := range []string{"US", "CA", "MX", "FR"} {
+			t.Run(fmt.Sprintf("negated=%t %s", negated, value), func(t *testing.T) {
+				prg := getNewRand(SEED)
+
+				matches, e := membershipRoundTrip(prg, Membership{Position{1, 0}, set, negated}, StringValue(value))
+				if negated == (value != "FR") {
+					assert.ErrorContains(t, e, "attribute (1, 0) is")
+					return
+				}
+				assert.NilError(t, e)
+				assert.Check(t, matches)
+			})
+		}
+	}
+
+	// a set of one value
+	matches, e := membershipRoundTrip(getNewRand(SEED), MemberOf(Position{1, 0}, NumericValue(7)), NumericValue(7))
+	assert.NilError(t, e)
+	assert.Check(t, matches)
+}
+
+// the commitments do not match if the response is for another exponent
+func testMembershipWrongValue(t *testing.T) {
+	for _, negated := range []bool{false, true} {
+		prg := getNewRand(SEED)
+		q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+		m := Membership{Position{1, 0}, membershipSet("US", "CA"), negated}
+		x := map[bool]*FP256BN.BIG{false: StringValue("US"), true: StringValue("FR")}[negated]
+		rhoX := FP256BN.Randomnum(q, prg)
+
+		prover, e := membershipCommit(prg, m, x, rhoX)
+		assert.NilError(t, e)
+
+		c := FP256BN.Randomnum(q, prg)
+		proof := prover.respond(c)
+
+		resX := FP256BN.Modmul(c, StringValue("CA"), q)
+		resX = resX.Plus(rhoX)
+		resX.Mod(q)
+
+		_, tLink, e := proof.commitments(m, c, resX)
+		assert.NilError(t, e)
+		assert.Check(t, !tLink.Equals(prover.tLink))
+	}
+}
+
+func testMembershipTampered(t *testing.T) {
+	prg := getNewRand(SEED)
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	m := MemberOf(Position{1, 0}, membershipSet("US", "CA")...)
+	prover, e := membershipCommit(prg, m, StringValue("CA"), FP256BN.Randomnum(q, prg))
+	assert.NilError(t, e)
+
+	c := FP256BN.Randomnum(q, prg)
+	proof := prover.respond(c)
+
+	// the challenges must sum up to c
+	tampered := proof
+	tampered.c = []*FP256BN.BIG{proof.c[0], FP256BN.NewBIGint(0x13)}
+	_, _, e = tampered.commitments(m, c, FP256BN.NewBIGint(0x13))
+	assert.ErrorContains(t, e, "do not sum up to c")
+
+	// the proof does not fit another set
+	assert.ErrorContains(t, proof.validate(MemberOf(Position{1, 0}, membershipSet("US")...)), "does not fit the set of 1 values")
+	assert.ErrorContains(t, proof.validate(NotMemberOf(Position{1, 0}, membershipSet("US", "CA")...)), "negated: true")
+
+	tampered = proof
+	tampered.z = []*FP256BN.BIG{proof.z[0], nil}
+	assert.ErrorContains(t, tampered.validate(m), "value 1 is incomplete")
+
+	tampered = proof
+	tampered.com = FP256BN.NewECP()
+	assert.ErrorContains(t, tampered.validate(m), "infinity")
+
+	tampered = proof
+	tampered.resR = nil
+	assert.ErrorContains(t, tampered.validate(m), "incomplete")
+}
+
+func testMembershipMarshal(t *testing.T) {
+	for _, negated := range []bool{false, true} {
+		prg := getNewRand(SEED)
+		q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+		m := Membership{Position{1, 0}, membershipSet("US", "CA"), negated}
+		x := map[bool]*FP256BN.BIG{false: StringValue("US"), true: StringValue("FR")}[negated]
+
+		prover, e := membershipCommit(prg, m, x, FP256BN.Randomnum(q, prg))
+		assert.NilError(t, e)
+		proof := prover.respond(FP256BN.Randomnum(q, prg))
+
+		recovered, e := proof.marshal().unmarshal()
+		assert.NilError(t, e)
+		assert.NilError(t, recovered.validate(m))
+		assert.DeepEqual(t, recovered.marshal(), proof.marshal())
+
+		marshal := proof.marshal()
+		marshal.Com = PointToBytes(FP256BN.ECP2_generator())
+		_, e = marshal.unmarshal()
+		assert.ErrorContains(t, e, "com")
+
+		marshal = proof.marshal()
+		marshal.Z[0] = orderBytes()
+		_, e = marshal.unmarshal()
+		assert.ErrorContains(t, e, "z: ")
+	}
+}
@@ -0,0 +1,305 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// SystemParameters bundles all public setup values the provers and the verifiers have to agree on.
+// Revocation and auditing values are optional, leave them nil if the features are not used.
+// Obtain it with MakeSystemParameters or ParseSystemParameters, both of which validate the values;
+// the routines that accept SystemParameters assume it is valid.
+// The ...WithParameters routines bind the proofs to the fingerprint of the parameters and to the caller's context
+// (e.g. an application or a channel name); their verifiers report a proof generated for different parameters as such
+// and require the context the proof was generated with.
+type SystemParameters struct {
+	// AuthorityPK is the public key of the root authority (level 0, in G2)
+	AuthorityPK PK
+	// GrothYs are the y-values for credential links: index 0 for even levels (G2), index 1 for odd levels (G1)
+	GrothYs [][]interface{}
+	// H is the base for pseudonyms
+	H interface{}
+
+	// RevocationPK is the public key of the revocation authority, in the group of H
+	RevocationPK PK
+	// RevocationYs are the y-values of non-revocation signatures, in the group opposite to that of H
+	RevocationYs []interface{}
+
+	// AuditorPK is the public key of the auditor, in the group of H
+	AuditorPK PK
+}
+
+// MakeSystemParameters creates and validates the system parameters.
+// revocationPK, revocationYs and auditorPK may be nil.
+func MakeSystemParameters(authorityPK PK, grothYs [][]interface{}, h interface{}, revocationPK PK, revocationYs []interface{}, auditorPK PK) (params *SystemParameters, e error) {
+	params = &SystemParameters{
+		AuthorityPK:  authorityPK,
+		GrothYs:      grothYs,
+		H:            h,
+		RevocationPK: revocationPK,
+		RevocationYs: revocationYs,
+		AuditorPK:    auditorPK,
+	}
+
+	if e = params.Validate(); e != nil {
+		return nil, e
+	}
+
+	return
+}
+
+// Validate checks that all points are valid (see ValidatePoint) and are in their prescribed groups
+func (params *SystemParameters) Validate() (e error) {
+	if e = validatePointInGroup(params.AuthorityPK, false); e != nil {
+		return fmt.Errorf("SystemParameters: authority public key: %v", e)
+	}
+
+	if len(params.GrothYs) != 2 || len(params.GrothYs[0]) == 0 || len(params.GrothYs[1]) == 0 {
+		return fmt.Errorf("SystemParameters: Groth y-values must be given for both groups")
+	}
+	for index, y := range params.GrothYs[0] {
+		if e = validatePointInGroup(y, false); e != nil {
+			return fmt.Errorf("SystemParameters: Groth y-value %d for even levels: %v", index, e)
+		}
+	}
+	for index, y := range params.GrothYs[1] {
+		if e = validatePointInGroup(y, true); e != nil {
+			return fmt.Errorf("SystemParameters: Groth y-value %d for odd levels: %v", index, e)
+		}
+	}
+
+	if e = ValidatePoint(params.H); e != nil {
+		return fmt.Errorf("SystemParameters: h: %v", e)
+	}
+	_, hFirst := params.H.(*FP256BN.ECP)
+
+	if (params.RevocationPK == nil) != (len(params.RevocationYs) == 0) {
+		return fmt.Errorf("SystemParameters: revocation public key and y-values must be given together")
+	}
+	if params.RevocationPK != nil {
+		if e = validatePointInGroup(params.RevocationPK, hFirst); e != nil {
+			return fmt.Errorf("SystemParameters: revocation public key: %v", e)
+		}
+		// the non-revocation signature signs the user's public key and the epoch
+		if len(params.RevocationYs) < 2 {
+			return fmt.Errorf("SystemParameters: at least 2 revocation y-values are required, got %d", len(params.RevocationYs))
+		}
+		for index, y := range params.RevocationYs {
+			if e = validatePointInGroup(y, !hFirst); e != nil {
+				return fmt.Errorf("SystemParameters: revocation y-value %d: %v", index, e)
+			}
+		}
+	}
+
+	if params.AuditorPK != nil {
+		if e = validatePointInGroup(params.AuditorPK, hFirst); e != nil {
+			return fmt.Errorf("SystemParameters: auditor public key: %v", e)
+		}
+	}
+
+	return
+}
+
+// validatePointInGroup is ValidatePoint that also ensures the point is in the expected group
+func validatePointInGroup(g interface{}, first bool) (e error) {
+	if e = ValidatePoint(g); e != nil {
+		return
+	}
+	if _, isFirst := g.(*FP256BN.ECP); isFirst != first {
+		return fmt.Errorf("point is expected to be in %s", map[bool]string{true: "ECP", false: "ECP2"}[first])
+	}
+
+	return
+}
+
+type systemParametersMarshal struct {
+	AuthorityPK  []byte
+	GrothYsEven  [][]byte
+	GrothYsOdd   [][]byte
+	H            []byte
+	RevocationPK []byte
+	RevocationYs [][]byte
+	AuditorPK    []byte
+}
+
+// ToBytes produces the canonical encoding of the system parameters (ASN1 with canonical point encodings)
+func (params *SystemParameters) ToBytes() (result []byte) {
+	var marshal systemParametersMarshal
+
+	pointsToBytes := func(gs []interface{}) (result [][]byte) {
+		result = make([][]byte, len(gs))
+		for index, g := range gs {
+			result[index] = PointToBytes(g)
+		}
+		return
+	}
+
+	marshal.AuthorityPK = PointToBytes(params.AuthorityPK)
+	if len(params.GrothYs) == 2 {
+		marshal.GrothYsEven = pointsToBytes(params.GrothYs[0])
+		marshal.GrothYsOdd = pointsToBytes(params.GrothYs[1])
+	}
+	marshal.H = PointToBytes(params.H)
+	marshal.RevocationPK = PointToBytes(params.RevocationPK)
+	marshal.RevocationYs = pointsToBytes(params.RevocationYs)
+	marshal.AuditorPK = PointToBytes(params.AuditorPK)
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParseSystemParameters un-marshals and validates the system parameters
+func ParseSystemParameters(input []byte) (params *SystemParameters, e error) {
+	var marshal systemParametersMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseSystemParameters: %v", e)
+	}
+
+	params = &SystemParameters{GrothYs: make([][]interface{}, 2)}
+
+	if params.AuthorityPK, e = PointFromBytes(marshal.AuthorityPK); e != nil {
+		return nil, fmt.Errorf("ParseSystemParameters: authority public key: %v", e)
+	}
+	if params.GrothYs[0], e = pointsFromBytesInGroup(marshal.GrothYsEven, false, false); e != nil {
+		return nil, fmt.Errorf("ParseSystemParameters: Groth y-values for even levels: %v", e)
+	}
+	if params.GrothYs[1], e = pointsFromBytesInGroup(marshal.GrothYsOdd, true, false); e != nil {
+		return nil, fmt.Errorf("ParseSystemParameters: Groth y-values for odd levels: %v", e)
+	}
+	if params.H, e = PointFromBytes(marshal.H); e != nil {
+		return nil, fmt.Errorf("ParseSystemParameters: h: %v", e)
+	}
+	if params.RevocationPK, e = PointFromBytes(marshal.RevocationPK); e != nil {
+		return nil, fmt.Errorf("ParseSystemParameters: revocation public key: %v", e)
+	}
+	if len(marshal.RevocationYs) > 0 {
+		params.RevocationYs = make([]interface{}, len(marshal.RevocationYs))
+		for index := range marshal.RevocationYs {
+			if params.RevocationYs[index], e = PointFromBytes(marshal.RevocationYs[index]); e != nil {
+				return nil, fmt.Errorf("ParseSystemParameters: revocation y-value %d: %v", index, e)
+			}
+		}
+	}
+	if params.AuditorPK, e = PointFromBytes(marshal.AuditorPK); e != nil {
+		return nil, fmt.Errorf("ParseSystemParameters: auditor public key: %v", e)
+	}
+
+	if e = params.Validate(); e != nil {
+		return nil, e
+	}
+
+	return
+}
+
+// Fingerprint is a stable 32-byte hash of the canonical encoding of the system parameters.
+// Proofs generated with the parameters are bound to it.
+func (params *SystemParameters) Fingerprint() []byte {
+	t := newTranscript(_ProtocolSystemParameters, binding{})
+	t.append("parameters", params.ToBytes())
+
+	return t.digest()
+}
+
+// Equals checks the equality of two sets of system parameters
+func (params *SystemParameters) Equals(other *SystemParameters) bool {
+	return bytesEqual(params.ToBytes(), other.ToBytes())
+}
+
+// fingerprintFromBytes validates the (optional) marshalled fingerprint
+func fingerprintFromBytes(bytes []byte) ([]byte, error) {
+	if len(bytes) == 0 {
+		return nil, nil
+	}
+	if len(bytes) != 32 {
+		return nil, fmt.Errorf("fingerprint must be 32 bytes long, got %d", len(bytes))
+	}
+
+	return bytes, nil
+}
+
+// checkFingerprint reports a mismatch between the parameters the proof was generated for and those of the verifier
+func (params *SystemParameters) checkFingerprint(fingerprint []byte) error {
+	expected := params.Fingerprint()
+	if len(fingerprint) == 0 {
+		return fmt.Errorf("proof is not bound to system parameters (expected fingerprint %x)", expected)
+	}
+	if !bytesEqual(fingerprint, expected) {
+		return fmt.Errorf("system parameters mismatch: proof was generated for parameters with fingerprint %x, verifier uses %x", fingerprint, expected)
+	}
+
+	return nil
+}
+
+// Credentials
+
+// ProveWithParameters is Prove that takes the public values from the system parameters (see SystemParameters).
+// Secret key is that of the last level, h and skNym should be received with GenerateNymKeys using params.H.
+func (creds *Credentials) ProveWithParameters(prg *amcl.RAND, sk SK, params *SystemParameters, context string, D Indices, m []byte, skNym SK) (proof Proof, e error) {
+
+	return creds.prove(prg, sk, params.AuthorityPK, D, m, params.GrothYs, params.H, skNym, binding{params.Fingerprint(), context}, false)
+}
+
+// ProveBatchableWithParameters is ProveWithParameters that attaches the commitments like ProveBatchable.
+// Set the context in BatchProof to verify the proof with VerifyProofBatch.
+func (creds *Credentials) ProveBatchableWithParameters(prg *amcl.RAND, sk SK, params *SystemParameters, context string, D Indices, m []byte, skNym SK) (proof Proof, e error) {
+	return creds.prove(prg, sk, params.AuthorityPK, D, m, params.GrothYs, params.H, skNym, binding{params.Fingerprint(), context}, true)
+}
+
+// VerifyProofWithParameters is VerifyProof that takes the public values from the system parameters (see SystemParameters).
+func (proof *Proof) VerifyProofWithParameters(params *SystemParameters, context string, pkNym PK, D Indices, m []byte) (e error) {
+	if e = params.checkFingerprint(proof.fingerprint); e != nil {
+		return fmt.Errorf("VerifyProof: %v", e)
+	}
+
+	return proof.verifyProof(params.AuthorityPK, params.GrothYs, params.H, pkNym, D, m, context)
+}
+
+// Revocation
+
+// RevocationProveWithParameters is RevocationProve that takes the public values from the system parameters (see SystemParameters).
+func RevocationProveWithParameters(prg *amcl.RAND, signature GrothSignature, sk SK, skNym SK, epoch *FP256BN.BIG, params *SystemParameters, context string) (proof RevocationProof, e error) {
+	if params.RevocationPK == nil {
+		return proof, fmt.Errorf("RevocationProve: system parameters do not include revocation values")
+	}
+
+	return revocationProveBound(prg, signature, sk, skNym, epoch, params.H, params.RevocationYs, binding{params.Fingerprint(), context}, nil, nil), nil
+}
+
+// VerifyWithParameters is Verify that takes the public values from the system parameters (see SystemParameters).
+func (proof *RevocationProof) VerifyWithParameters(pkNym PK, epoch *FP256BN.BIG, params *SystemParameters, context string) (e error) {
+	if params.RevocationPK == nil {
+		return fmt.Errorf("RevocationProof.Verify: system parameters do not include revocation values")
+	}
+	if e = params.checkFingerprint(proof.fingerprint); e != nil {
+		return fmt.Errorf("RevocationProof.Verify: %v", e)
+	}
+
+	return proof.verify(pkNym, epoch, params.H, params.RevocationPK, params.RevocationYs, context, nil, nil)
+}
+
+// Auditing
+
+// AuditingProveWithParameters is AuditingProve that takes the public values from the system parameters (see SystemParameters).
+func AuditingProveWithParameters(prg *amcl.RAND, encryption AuditingEncryption, pk PK, sk SK, pkNym PK, skNym SK, r *FP256BN.BIG, params *SystemParameters, context string) (proof AuditingProof, e error) {
+	if params.AuditorPK == nil {
+		return proof, fmt.Errorf("AuditingProve: system parameters do not include the auditor public key")
+	}
+
+	return auditingProveBound(prg, encryption, pk, sk, pkNym, skNym, params.AuditorPK, r, params.H, binding{params.Fingerprint(), context}, nil), nil
+}
+
+// VerifyWithParameters is Verify that takes the public values from the system parameters (see SystemParameters).
+func (proof *AuditingProof) VerifyWithParameters(encryption AuditingEncryption, pkNym PK, params *SystemParameters, context string) (e error) {
+	if params.AuditorPK == nil {
+		return fmt.Errorf("AuditingProof.Verify: system parameters do not include the auditor public key")
+	}
+	if e = params.checkFingerprint(proof.fingerprint); e != nil {
+		return fmt.Errorf("AuditingProof.Verify: %v", e)
+	}
+
+	return proof.verify(encryption, pkNym, params.AuditorPK, params.H, context, nil)
+}
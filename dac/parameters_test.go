@@ -0,0 +1,341 @@
+package dac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// helper that generates system parameters with h in G1 along with the revocation and auditor secret keys
+func generateSystemParameters(prg *amcl.RAND) (params *SystemParameters, revocationSk SK, auditorSk SK) {
+	const YsNum = 10
+
+	_, authorityPK := GenerateKeys(prg, 0)
+
+	grothYs := make([][]interface{}, 2)
+	grothYs[0] = GenerateYs(false, YsNum, prg)
+	grothYs[1] = GenerateYs(true, YsNum, prg)
+
+	h := FP256BN.ECP_generator().Mul(FP256BN.Randomnum(FP256BN.NewBIGints(FP256BN.CURVE_Order), prg))
+
+	revocationYs := GenerateYs(false, 2, prg)
+	revocationSk, revocationPK := MakeGroth(prg, false, revocationYs).Generate()
+
+	auditorSk, auditorPK := GenerateKeys(prg, 1)
+
+	params, e := MakeSystemParameters(authorityPK, grothYs, h, revocationPK, revocationYs, auditorPK)
+	if e != nil {
+		panic(e)
+	}
+
+	return
+}
+
+// helper that generates system parameters for the credentials issued by the authority pk with grothYs
+func generateSystemParametersFor(pk PK, grothYs [][]interface{}) (params *SystemParameters) {
+	params, _, _ = generateSystemParameters(getNewRand(SEED))
+	params.AuthorityPK, params.GrothYs = pk, grothYs
+	if e := params.Validate(); e != nil {
+		panic(e)
+	}
+
+	return
+}
+
+// Tests
+
+func TestParameters(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testParametersValidate,
+		testParametersMarshal,
+		testParametersParseRejectsMalformed,
+		testParametersFingerprint,
+		testParametersProof,
+		testParametersProofBatch,
+		testParametersRevocation,
+		testParametersAuditing,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testParametersValidate(t *testing.T) {
+	type TestCase string
+	const (
+		Correct            TestCase = "correct"
+		NoOptional         TestCase = "no revocation and auditing"
+		AuthorityInG1      TestCase = "authority key in G1"
+		MissingYs          TestCase = "Groth y-values missing"
+		YsWrongGroup       TestCase = "Groth y-value in wrong group"
+		InvalidH           TestCase = "h not in subgroup"
+		RevocationNoYs     TestCase = "revocation key without y-values"
+		RevocationFewYs    TestCase = "too few revocation y-values"
+		RevocationYsGroup  TestCase = "revocation y-value in wrong group"
+		RevocationPKGroup  TestCase = "revocation key in wrong group"
+		AuditorWrongGroup  TestCase = "auditor key in wrong group"
+		AuditorNotOnCurve  TestCase = "auditor key at infinity"
+		AuthorityWrongType TestCase = "authority key of wrong type"
+	)
+
+	for _, tc := range []TestCase{Correct, NoOptional, AuthorityInG1, MissingYs, YsWrongGroup, InvalidH, RevocationNoYs, RevocationFewYs, RevocationYsGroup, RevocationPKGroup, AuditorWrongGroup, AuditorNotOnCurve, AuthorityWrongType} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+			params, _, _ := generateSystemParameters(prg)
+
+			g1 := FP256BN.ECP_generator().Mul(FP256BN.NewBIGint(0x13))
+			g2 := FP256BN.ECP2_generator().Mul(FP256BN.NewBIGint(0x13))
+
+			switch tc {
+			case NoOptional:
+				params.RevocationPK, params.RevocationYs, params.AuditorPK = nil, nil, nil
+			case AuthorityInG1:
+				params.AuthorityPK = g1
+			case MissingYs:
+				params.GrothYs = params.GrothYs[:1]
+			case YsWrongGroup:
+				params.GrothYs[1][3] = g2
+			case InvalidH:
+				params.H = twistPoint()
+			case RevocationNoYs:
+				params.RevocationYs = nil
+			case RevocationFewYs:
+				params.RevocationYs = params.RevocationYs[:1]
+			case RevocationYsGroup:
+				params.RevocationYs[1] = g1
+			case RevocationPKGroup:
+				params.RevocationPK = g2
+			case AuditorWrongGroup:
+				params.AuditorPK = g2
+			case AuditorNotOnCurve:
+				params.AuditorPK = FP256BN.NewECP()
+			case AuthorityWrongType:
+				params.AuthorityPK = FP256BN.NewBIGint(0x13)
+			}
+
+			e := params.Validate()
+			if tc == Correct || tc == NoOptional {
+				assert.NilError(t, e)
+			} else {
+				assert.ErrorContains(t, e, "SystemParameters")
+			}
+		})
+	}
+}
+
+func testParametersMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	params, _, _ := generateSystemParameters(prg)
+
+	recovered, e := ParseSystemParameters(params.ToBytes())
+	assert.NilError(t, e)
+	assert.Check(t, params.Equals(recovered))
+	assert.Check(t, bytesEqual(params.Fingerprint(), recovered.Fingerprint()))
+
+	params.RevocationPK, params.RevocationYs, params.AuditorPK = nil, nil, nil
+
+	recovered, e = ParseSystemParameters(params.ToBytes())
+	assert.NilError(t, e)
+	assert.Check(t, params.Equals(recovered))
+	assert.Check(t, recovered.RevocationPK == nil && recovered.RevocationYs == nil && recovered.AuditorPK == nil)
+}
+
+func testParametersParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed  TestCase = "malformed ASN1"
+		NoH        TestCase = "h missing"
+		OffCurve   TestCase = "authority key not on curve"
+		WrongGroup TestCase = "Groth y-value in wrong group"
+		Twist      TestCase = "revocation y-value not in subgroup"
+	)
+
+	prg := getNewRand(SEED)
+	params, _, _ := generateSystemParameters(prg)
+
+	for _, tc := range []TestCase{Malformed, NoH, OffCurve, WrongGroup, Twist} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal systemParametersMarshal
+			bytes := remarshal(t, params.ToBytes(), &marshal, func() {
+				switch tc {
+				case NoH:
+					marshal.H = nil
+				case OffCurve:
+					marshal.AuthorityPK = offCurveBytes(false)
+				case WrongGroup:
+					marshal.GrothYsEven[0] = pointBytes(true)
+				case Twist:
+					marshal.RevocationYs[0] = PointToBytes(twistPoint())
+				}
+			})
+
+			if tc == Malformed {
+				bytes = append(bytes, 0x13)
+			}
+
+			_, e := ParseSystemParameters(bytes)
+			assert.ErrorContains(t, e, "SystemParameters")
+		})
+	}
+}
+
+func testParametersFingerprint(t *testing.T) {
+	params, _, _ := generateSystemParameters(getNewRand(SEED))
+	same, _, _ := generateSystemParameters(getNewRand(SEED))
+	other, _, _ := generateSystemParameters(getNewRand(SEED + 1))
+
+	assert.Equal(t, len(params.Fingerprint()), 32)
+	assert.Check(t, bytesEqual(params.Fingerprint(), same.Fingerprint()))
+	assert.Check(t, !bytesEqual(params.Fingerprint(), other.Fingerprint()))
+
+	// any component changes the fingerprint
+	fingerprint := params.Fingerprint()
+	params.AuditorPK = other.AuditorPK
+	assert.Check(t, !bytesEqual(params.Fingerprint(), fingerprint))
+}
+
+func testParametersProof(t *testing.T) {
+	prg := getNewRand(SEED + 1)
+
+	creds, sk, pk, ys, _, _, _, _ := generateChain(3, 2)
+
+	params, _, _ := generateSystemParameters(getNewRand(SEED))
+	params.AuthorityPK, params.GrothYs = pk, ys
+	assert.NilError(t, params.Validate())
+
+	skNym, pkNym := GenerateNymKeys(prg, sk, params.H)
+	D := Indices{{1, 1, creds.Attributes[1][1]}}
+	m := []byte("message")
+
+	proof, e := creds.ProveWithParameters(prg, sk, params, "context", D, m, skNym)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyProofWithParameters(params, "context", pkNym, D, m))
+	assert.ErrorContains(t, proof.VerifyProofWithParameters(params, "other", pkNym, D, m), "failed")
+
+	// fingerprint survives marshalling
+	recovered, e := ParseProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.Check(t, recovered.Equals(proof))
+	assert.NilError(t, recovered.VerifyProofWithParameters(params, "context", pkNym, D, m))
+
+	// the verifier uses different parameters
+	other, _, _ := generateSystemParameters(getNewRand(SEED + 2))
+	other.AuthorityPK, other.GrothYs, other.H = params.AuthorityPK, params.GrothYs, params.H
+	assert.ErrorContains(t, proof.VerifyProofWithParameters(other, "context", pkNym, D, m), "mismatch")
+
+	// the proof is not bound to parameters
+	unbound, _ := creds.Prove(prg, sk, params.AuthorityPK, D, m, params.GrothYs, params.H, skNym)
+	assert.NilError(t, unbound.VerifyProof(params.AuthorityPK, params.GrothYs, params.H, pkNym, D, m))
+	assert.ErrorContains(t, unbound.VerifyProofWithParameters(params, "context", pkNym, D, m), "not bound")
+
+	// fingerprint is covered by the challenge
+	proof.fingerprint = nil
+	assert.ErrorContains(t, proof.VerifyProof(params.AuthorityPK, params.GrothYs, params.H, pkNym, D, m), "failed")
+}
+
+// proofs bound to a context are folded into the combined check of VerifyProofBatch
+func testParametersProofBatch(t *testing.T) {
+	prg := getNewRand(SEED + 1)
+
+	creds, sk, pk, ys, _, _, _, _ := generateChain(3, 2)
+	params := generateSystemParametersFor(pk, ys)
+
+	skNym, pkNym := GenerateNymKeys(prg, sk, params.H)
+	D := Indices{{1, 1, creds.Attributes[1][1]}}
+	m := []byte("message")
+
+	proof, e := creds.ProveBatchableWithParameters(prg, sk, params, "context", D, m, skNym)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.VerifyProofWithParameters(params, "context", pkNym, D, m))
+
+	authorityPK, _ := toG2("pk", params.AuthorityPK)
+	grothParams, _ := TypedGrothParams(params.GrothYs)
+	base, _ := TypedPoint(params.H)
+	batched, e := proof.batch(G2PublicKey{authorityPK}, grothParams, base, pkNym, D, m, "context")
+	assert.NilError(t, e)
+	assert.Check(t, batchedValid([]*proofBatch{batched}))
+
+	invalid, e := VerifyProofBatch(params.AuthorityPK, params.GrothYs, params.H, []BatchProof{{&proof, pkNym, D, m, "context"}})
+	assert.NilError(t, e)
+	assert.Equal(t, len(invalid), 0)
+
+	invalid, e = VerifyProofBatch(params.AuthorityPK, params.GrothYs, params.H, []BatchProof{{&proof, pkNym, D, m, "other"}})
+	assert.ErrorContains(t, e, "failed")
+	assert.DeepEqual(t, invalid, []int{0})
+}
+
+func testParametersRevocation(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	params, revocationSk, _ := generateSystemParameters(prg)
+
+	epoch := FP256BN.NewBIGint(0x13)
+	userSk, userPk := GenerateKeys(prg, 0)
+	skNym, pkNym := GenerateNymKeys(prg, userSk, params.H)
+
+	signature := SignNonRevoke(prg, revocationSk, userPk, epoch, params.RevocationYs)
+
+	proof, e := RevocationProveWithParameters(prg, signature, userSk, skNym, epoch, params, "context")
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyWithParameters(pkNym, epoch, params, "context"))
+	assert.ErrorContains(t, proof.VerifyWithParameters(pkNym, epoch, params, "other"), "later")
+
+	recovered, e := ParseRevocationProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.VerifyWithParameters(pkNym, epoch, params, "context"))
+
+	other := *params
+	other.AuditorPK = nil
+	assert.ErrorContains(t, proof.VerifyWithParameters(pkNym, epoch, &other, "context"), "mismatch")
+
+	unbound := RevocationProve(prg, signature, userSk, skNym, epoch, params.H, params.RevocationYs)
+	assert.ErrorContains(t, unbound.VerifyWithParameters(pkNym, epoch, params, "context"), "not bound")
+
+	other.RevocationPK, other.RevocationYs = nil, nil
+	_, e = RevocationProveWithParameters(prg, signature, userSk, skNym, epoch, &other, "context")
+	assert.ErrorContains(t, e, "revocation values")
+
+	proof.fingerprint = nil
+	assert.ErrorContains(t, proof.Verify(pkNym, epoch, params.H, params.RevocationPK, params.RevocationYs), "later")
+}
+
+func testParametersAuditing(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	params, _, auditorSk := generateSystemParameters(prg)
+
+	userSk, userPk := GenerateKeys(prg, 1)
+	skNym, pkNym := GenerateNymKeys(prg, userSk, params.H)
+
+	encryption, r := AuditingEncrypt(prg, params.AuditorPK, userPk)
+	assert.Check(t, pointEqual(encryption.AuditingDecrypt(auditorSk), userPk))
+
+	proof, e := AuditingProveWithParameters(prg, encryption, userPk, userSk, pkNym, skNym, r, params, "context")
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyWithParameters(encryption, pkNym, params, "context"))
+	assert.ErrorContains(t, proof.VerifyWithParameters(encryption, pkNym, params, "other"), "failed")
+
+	recovered, e := ParseAuditingProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.VerifyWithParameters(encryption, pkNym, params, "context"))
+
+	other := *params
+	other.RevocationPK, other.RevocationYs = nil, nil
+	assert.ErrorContains(t, proof.VerifyWithParameters(encryption, pkNym, &other, "context"), "mismatch")
+
+	unbound := AuditingProve(prg, encryption, userPk, userSk, pkNym, skNym, params.AuditorPK, r, params.H)
+	assert.ErrorContains(t, unbound.VerifyWithParameters(encryption, pkNym, params, "context"), "not bound")
+
+	other.AuditorPK = nil
+	_, e = AuditingProveWithParameters(prg, encryption, userPk, userSk, pkNym, skNym, r, &other, "context")
+	assert.ErrorContains(t, e, "auditor public key")
+
+	proof.fingerprint = nil
+	assert.ErrorContains(t, proof.Verify(encryption, pkNym, params.AuditorPK, params.H), "failed")
+}
@@ -0,0 +1,474 @@
+package dac
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+)
+
+// Policy states what a verifier requires from a presentation: credentials under one of the trusted roots
+// with exactly ChainLength levels following the schema, the attributes to disclose and the constraints on the hidden ones.
+// Attributes are addressed by their schema paths, e.g. "level1.role".
+// The presentation discloses the reserved schema attribute of every level (see Schema.Attribute),
+// so that only credentials delegated with the schema satisfy the policy.
+// The proof signs the hash of the policy (see Policy.Hash), so that a fresh Nonce makes the proof good for one session only.
+// Policies travel as JSON, see ParsePolicy and Policy.ToJSON.
+type Policy struct {
+	Schema Schema
+	// TrustedRoots are the public keys of the trusted authorities encoded with PointToBytes
+	TrustedRoots [][]byte
+	ChainLength  int
+	Disclose     []string     `json:",omitempty"`
+	Constraints  []Constraint `json:",omitempty"`
+	Nonce        []byte       `json:",omitempty"`
+}
+
+// Constraint restricts the hidden attribute at Path, each of its fields that is set is a predicate:
+// Min and Max bound int64 and timestamp attributes (inclusive), OneOf and NoneOf list the allowed and the forbidden values,
+// EqualTo is the path of another hidden attribute that has to be equal to this one.
+type Constraint struct {
+	Path    string
+	Min     *Value  `json:",omitempty"`
+	Max     *Value  `json:",omitempty"`
+	OneOf   []Value `json:",omitempty"`
+	NoneOf  []Value `json:",omitempty"`
+	EqualTo string  `json:",omitempty"`
+}
+
+// PolicyProof is the presentation for a policy: the proof of the credentials and the predicates,
+// the typed values of the disclosed attributes, the root the credentials are under and the holder's pseudonym
+type PolicyProof struct {
+	Root   []byte
+	PkNym  PK
+	Values DisclosedValues
+	Proof  PredicateProof
+}
+
+// PolicyCheck is the outcome of one requirement of the policy, Error is nil if it is met
+type PolicyCheck struct {
+	Requirement string
+	Error       error
+}
+
+// PolicyResult is the outcome of verifying a presentation against a policy.
+// Values holds the disclosed values keyed by their paths, they are trustworthy only if Err returns nil.
+type PolicyResult struct {
+	Root   []byte
+	Values map[string]Value
+	Checks []PolicyCheck
+}
+
+// Err returns the errors of the failed checks combined, or nil if all checks passed
+func (result *PolicyResult) Err() error {
+	var failures []string
+	for _, check := range result.Checks {
+		if check.Error != nil {
+			failures = append(failures, check.Requirement+": "+check.Error.Error())
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%s", strings.Join(failures, "; "))
+}
+
+// ParsePolicy un-marshals the policy from JSON and validates it
+func ParsePolicy(data []byte) (policy *Policy, e error) {
+	policy = &Policy{}
+	if e = json.Unmarshal(data, policy); e != nil {
+		return nil, fmt.Errorf("ParsePolicy: %v", e)
+	}
+	if e = policy.Validate(); e != nil {
+		return nil, fmt.Errorf("ParsePolicy: %v", e)
+	}
+
+	return
+}
+
+// ToJSON marshals the policy to JSON
+func (policy *Policy) ToJSON() ([]byte, error) {
+	return json.MarshalIndent(policy, "", "\t")
+}
+
+// Hash is the digest of the policy, it is the message the presentation signs
+func (policy *Policy) Hash() []byte {
+	data, _ := json.Marshal(policy)
+
+	t := &transcript{}
+	t.append("policy", data)
+
+	return t.digest()
+}
+
+// Validate checks that the policy is consistent: the schema is valid, the roots are G2 points,
+// the paths exist within the chain length and the constraints fit the types of their attributes
+func (policy *Policy) Validate() (e error) {
+	if e = policy.Schema.validate(); e != nil {
+		return
+	}
+	if len(policy.TrustedRoots) == 0 {
+		return fmt.Errorf("policy has no trusted roots")
+	}
+	for index, root := range policy.TrustedRoots {
+		if _, e = pointFromBytesInGroup(root, false, false); e != nil {
+			return fmt.Errorf("trusted root %d: %v", index, e)
+		}
+	}
+	if policy.ChainLength < 1 || policy.ChainLength > len(policy.Schema.Levels) {
+		return fmt.Errorf("chain length must be between 1 and %d, got %d", len(policy.Schema.Levels), policy.ChainLength)
+	}
+
+	disclosed := make(map[string]bool)
+	for _, path := range policy.Disclose {
+		if _, e = policy.position(path); e != nil {
+			return
+		}
+		if disclosed[path] {
+			return fmt.Errorf("%s is disclosed twice", path)
+		}
+		disclosed[path] = true
+	}
+
+	for _, constraint := range policy.Constraints {
+		if e = policy.validateConstraint(constraint, disclosed); e != nil {
+			return fmt.Errorf("constraint on %s: %v", constraint.Path, e)
+		}
+	}
+
+	return
+}
+
+// Prove builds the presentation for the policy from the credentials.
+// values are the values of the holder's attributes keyed by their paths,
+// they have to include the disclosed attributes and those with Min, Max, OneOf or NoneOf constraints.
+// The pseudonym of the presentation is derived from sk and skNym, see GenerateNymKeys.
+func (policy *Policy) Prove(prg *amcl.RAND, creds *Credentials, sk SK, skNym SK, values map[string]Value, grothYs [][]interface{}, h interface{}) (proof PolicyProof, e error) {
+	if e = policy.Validate(); e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+	if e = ValidatePoint(h); e != nil {
+		return proof, fmt.Errorf("Policy.Prove: h: %v", e)
+	}
+	if e = policy.Schema.Check(creds); e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+	if L := len(creds.signatures) - 1; L != policy.ChainLength {
+		return proof, fmt.Errorf("Policy.Prove: credentials have %d levels, policy requires %d", L, policy.ChainLength)
+	}
+
+	proof.Root = PointToBytes(creds.publicKeys[0])
+	if _, e = policy.root(proof.Root); e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+
+	disclosed := make(map[string]Value)
+	for _, path := range policy.Disclose {
+		value, ok := values[path]
+		if !ok {
+			return proof, fmt.Errorf("Policy.Prove: value of %s is missing", path)
+		}
+		disclosed[path] = value
+	}
+	if proof.Values, e = policy.Schema.DiscloseValuesByName(disclosed); e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+	D, e := proof.Values.Indices()
+	if e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+	D = append(D, policy.Schema.indices(policy.ChainLength)...)
+	if e = creds.checkDisclosed(D); e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+
+	predicates, e := policy.predicates()
+	if e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+
+	exponents := make(AttributeValues)
+	for _, constraint := range policy.Constraints {
+		if constraint.Min == nil && constraint.Max == nil && constraint.OneOf == nil && constraint.NoneOf == nil {
+			continue
+		}
+		value, ok := values[constraint.Path]
+		if !ok {
+			return proof, fmt.Errorf("Policy.Prove: value of %s is missing", constraint.Path)
+		}
+		position, _ := policy.position(constraint.Path)
+		if exponents[position], e = value.Exponent(); e != nil {
+			return proof, fmt.Errorf("Policy.Prove: %s: %v", constraint.Path, e)
+		}
+	}
+
+	proof.PkNym = productOfExponents(generatorSameGroup(h), sk, h, skNym)
+	proof.Proof, e = creds.ProveWithPredicates(prg, sk, creds.publicKeys[0], D, policy.Hash(), grothYs, h, skNym, predicates, exponents)
+	if e != nil {
+		return proof, fmt.Errorf("Policy.Prove: %v", e)
+	}
+
+	return
+}
+
+// Verify checks the presentation against the policy.
+// The result lists the outcome of every requirement, the returned error is result.Err().
+func (policy *Policy) Verify(proof *PolicyProof, grothYs [][]interface{}, h interface{}) (result *PolicyResult, e error) {
+	result = &PolicyResult{Root: proof.Root, Values: make(map[string]Value)}
+	check := func(requirement string, e error) bool {
+		result.Checks = append(result.Checks, PolicyCheck{requirement, e})
+		return e == nil
+	}
+
+	if !check("policy", policy.Validate()) {
+		return result, result.Err()
+	}
+
+	root, e := policy.root(proof.Root)
+	check("trusted root", e)
+
+	if L := len(proof.Proof.proof.resA) - 1; L != policy.ChainLength {
+		check("chain length", fmt.Errorf("credentials have %d levels, policy requires %d", L, policy.ChainLength))
+	} else {
+		check("chain length", nil)
+		check("schema", policy.checkLevels(proof))
+	}
+
+	for _, path := range policy.Disclose {
+		value, e := policy.Schema.ValueByName(proof.Values, path)
+		if check("disclosed "+path, e) {
+			result.Values[path] = value
+		}
+	}
+	if len(proof.Values) != len(policy.Disclose) {
+		check("disclosed", fmt.Errorf("%d values disclosed, policy requires %d", len(proof.Values), len(policy.Disclose)))
+	}
+
+	if root == nil || result.Err() != nil {
+		return result, result.Err()
+	}
+
+	D, e := proof.Values.Indices()
+	if check("disclosed values", e) {
+		// the reserved attributes come from the policy, the proof verifies only if the credentials have them
+		D = append(D, policy.Schema.indices(policy.ChainLength)...)
+		predicates, e := policy.predicates()
+		if check("constraints", e) {
+			check("proof", proof.Proof.Verify(root, grothYs, h, proof.PkNym, D, policy.Hash(), predicates))
+		}
+	}
+
+	return result, result.Err()
+}
+
+// root decodes the root if it is one of the trusted roots
+func (policy *Policy) root(root []byte) (pk PK, e error) {
+	for _, trusted := range policy.TrustedRoots {
+		if bytes.Equal(trusted, root) {
+			return pointFromBytesInGroup(root, false, false)
+		}
+	}
+
+	return nil, fmt.Errorf("root of the credentials is not trusted")
+}
+
+// checkLevels checks that each level of the proven credentials has as many attributes as the schema defines,
+// counting the reserved schema attribute
+func (policy *Policy) checkLevels(proof *PolicyProof) error {
+	for i := 1; i <= policy.ChainLength; i++ {
+		n := len(policy.Schema.Levels[i-1]) + 1
+		if len(proof.Proof.proof.resA[i]) != n {
+			return fmt.Errorf("level %d has %d attributes, schema %q expects %d", i, len(proof.Proof.proof.resA[i]), policy.Schema.Name, n)
+		}
+	}
+
+	return nil
+}
+
+// position resolves the path and checks that it is within the chain length
+func (policy *Policy) position(path string) (position Position, e error) {
+	if position, _, e = policy.Schema.Position(path); e != nil {
+		return
+	}
+	if position.I > policy.ChainLength {
+		return Position{}, fmt.Errorf("%s is beyond the chain length %d", path, policy.ChainLength)
+	}
+
+	return
+}
+
+// validateConstraint checks that the constraint refers to a hidden attribute and fits its type
+func (policy *Policy) validateConstraint(constraint Constraint, disclosed map[string]bool) (e error) {
+	if _, e = policy.position(constraint.Path); e != nil {
+		return
+	}
+	if disclosed[constraint.Path] {
+		return fmt.Errorf("attribute is disclosed, check its value instead")
+	}
+	_, kind, _ := policy.Schema.Position(constraint.Path)
+
+	if constraint.Min == nil && constraint.Max == nil && constraint.OneOf == nil && constraint.NoneOf == nil && constraint.EqualTo == "" {
+		return fmt.Errorf("constraint is empty")
+	}
+
+	for _, bound := range []*Value{constraint.Min, constraint.Max} {
+		if bound == nil {
+			continue
+		}
+		if kind != TypeInt64 && kind != TypeTimestamp {
+			return fmt.Errorf("Min and Max apply to int64 and timestamp attributes, got %s", kind)
+		}
+		if bound.Type() != kind {
+			return fmt.Errorf("bound must be of type %s, got %s", kind, bound.Type())
+		}
+	}
+	if constraint.Min != nil && constraint.Max != nil && boundOf(*constraint.Min) > boundOf(*constraint.Max) {
+		return fmt.Errorf("Min is greater than Max")
+	}
+
+	for _, set := range [][]Value{constraint.OneOf, constraint.NoneOf} {
+		if set != nil && len(set) == 0 {
+			return fmt.Errorf("set of values is empty")
+		}
+		for _, value := range set {
+			if value.Type() != kind {
+				return fmt.Errorf("value %s must be of type %s, got %s", value, kind, value.Type())
+			}
+			if e = value.validate(); e != nil {
+				return
+			}
+		}
+	}
+
+	if constraint.EqualTo != "" {
+		if _, e = policy.position(constraint.EqualTo); e != nil {
+			return
+		}
+		if disclosed[constraint.EqualTo] {
+			return fmt.Errorf("%s is disclosed, check its value instead", constraint.EqualTo)
+		}
+		if _, other, _ := policy.Schema.Position(constraint.EqualTo); other != kind {
+			return fmt.Errorf("%s must be of type %s, got %s", constraint.EqualTo, kind, other)
+		}
+	}
+
+	return
+}
+
+// predicates translates the constraints of the (valid) policy to the predicates
+func (policy *Policy) predicates() (predicates Predicates, e error) {
+	for _, constraint := range policy.Constraints {
+		position, _ := policy.position(constraint.Path)
+
+		if constraint.Min != nil || constraint.Max != nil {
+			r := Range{Position: position}
+			if constraint.Min != nil {
+				min := boundOf(*constraint.Min)
+				r.Min = &min
+			}
+			if constraint.Max != nil {
+				max := boundOf(*constraint.Max)
+				r.Max = &max
+			}
+			predicates.Ranges = append(predicates.Ranges, r)
+		}
+
+		for _, set := range []struct {
+			values  []Value
+			negated bool
+		}{{constraint.OneOf, false}, {constraint.NoneOf, true}} {
+			if set.values == nil {
+				continue
+			}
+			membership := Membership{Position: position, Negated: set.negated}
+			for _, value := range set.values {
+				exponent, e := value.Exponent()
+				if e != nil {
+					return Predicates{}, e
+				}
+				membership.Set = append(membership.Set, exponent)
+			}
+			predicates.Memberships = append(predicates.Memberships, membership)
+		}
+
+		if constraint.EqualTo != "" {
+			other, _ := policy.position(constraint.EqualTo)
+			predicates.Equalities = append(predicates.Equalities, Equality{A: position, B: other})
+		}
+	}
+
+	return
+}
+
+// boundOf is the integer a bound of an int64 or a timestamp attribute encodes
+func boundOf(value Value) int64 {
+	if t, e := value.AsTimestamp(); e == nil {
+		return t.Unix()
+	}
+	x, _ := value.AsInt64()
+
+	return x
+}
+
+type policyProofMarshal struct {
+	Root   []byte
+	PkNym  []byte
+	Values []disclosedValueMarshal
+	Proof  []byte
+}
+
+// ToBytes marshals the presentation using ASN1 encoding
+func (proof *PolicyProof) ToBytes() (result []byte) {
+	var marshal policyProofMarshal
+
+	marshal.Root = proof.Root
+	marshal.PkNym = PointToBytes(proof.PkNym)
+	marshal.Values = make([]disclosedValueMarshal, len(proof.Values))
+	for index, value := range proof.Values {
+		marshal.Values[index] = disclosedValueMarshal{value.I, value.J, value.Value.marshal()}
+	}
+	marshal.Proof = proof.Proof.ToBytes()
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParsePolicyProof un-marshals and validates the presentation using ASN1 encoding.
+// Whether it fits a policy is checked by Policy.Verify.
+func ParsePolicyProof(input []byte) (proof *PolicyProof, e error) {
+	var marshal policyProofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParsePolicyProof: %v", e)
+	}
+
+	proof = &PolicyProof{Root: marshal.Root}
+
+	if proof.PkNym, e = PointFromBytes(marshal.PkNym); e != nil {
+		return nil, fmt.Errorf("ParsePolicyProof: invalid pkNym: %v", e)
+	}
+	if proof.PkNym == nil {
+		return nil, fmt.Errorf("ParsePolicyProof: pkNym is missing")
+	}
+
+	proof.Values = make(DisclosedValues, len(marshal.Values))
+	for index, valueMarshal := range marshal.Values {
+		value, e := valueMarshal.Value.unmarshal()
+		if e != nil {
+			return nil, fmt.Errorf("ParsePolicyProof: value %d: %v", index, e)
+		}
+		proof.Values[index] = DisclosedValue{valueMarshal.I, valueMarshal.J, value}
+	}
+
+	predicateProof, e := ParsePredicateProof(marshal.Proof)
+	if e != nil {
+		return nil, fmt.Errorf("ParsePolicyProof: %v", e)
+	}
+	proof.Proof = *predicateProof
+
+	return
+}
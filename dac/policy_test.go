@@ -0,0 +1,483 @@
+package dac
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+// helper that makes a schema of an organization (level 1) and its members (level 2), who repeat the organization's name
+func policySchema() Schema {
+	schema, e := MakeSchema(
+		"membership",
+		[]Slot{{"org", TypeString}, {"founded", TypeTimestamp}},
+		[]Slot{{"org", TypeString}, {"role", TypeString}, {"age", TypeInt64}, {"admin", TypeBool}},
+	)
+	if e != nil {
+		panic(e)
+	}
+
+	return schema
+}
+
+// helper that makes the values of the levels of the chain of policyChain
+func policyLevels() []map[string]Value {
+	return []map[string]Value{
+		{"org": NewString("acme"), "founded": NewTimestamp(_Now)},
+		{"org": NewString("acme"), "role": NewString("engineer"), "age": NewInt64(25), "admin": NewBool(false)},
+	}
+}
+
+// helper that delegates a chain of 2 levels following policySchema, values are the holder's attribute values by path
+func policyChain() (creds *Credentials, sk SK, pk PK, ys [][]interface{}, skNym SK, pkNym PK, h interface{}, values map[string]Value) {
+	schema := policySchema()
+	creds, sk, pk, ys, skNym, pkNym, h, e := generateCustomChain(0, nil, chainOptions{schema: &schema, levels: policyLevels()})
+	if e != nil {
+		panic(e)
+	}
+
+	return creds, sk, pk, ys, skNym, pkNym, h, valuesByPath(policyLevels()...)
+}
+
+// helper that makes a policy the chain of policyChain satisfies
+func policyOf(pk PK) *Policy {
+	min := NewInt64(18)
+	max := NewTimestamp(_Now.Add(time.Hour))
+
+	return &Policy{
+		Schema:       policySchema(),
+		TrustedRoots: [][]byte{PointToBytes(levelGenerator(2)), PointToBytes(pk)},
+		ChainLength:  2,
+		Disclose:     []string{"level2.role"},
+		Constraints: []Constraint{
+			{Path: "level2.age", Min: &min},
+			{Path: "level1.founded", Max: &max},
+			{Path: "level2.org", OneOf: []Value{NewString("acme"), NewString("globex")}, EqualTo: "level1.org"},
+			{Path: "level2.admin", NoneOf: []Value{NewBool(true)}},
+		},
+		Nonce: []byte("nonce"),
+	}
+}
+
+// Tests
+
+func TestPolicy(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testPolicyValueJSON,
+		testPolicyJSON,
+		testPolicyValidate,
+		testPolicyHappyPath,
+		testPolicyProveErrors,
+		testPolicyVerifyFail,
+		testPolicySchemaBound,
+		testPolicyMarshal,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testPolicyValueJSON(t *testing.T) {
+	for value, expected := range map[*Value]string{
+		{TypeString, []byte("acme")}:                `{"Type":"string","Value":"acme"}`,
+		{TypeInt64, NewInt64(-25).payload}:          `{"Type":"int64","Value":-25}`,
+		{TypeBool, []byte{1}}:                       `{"Type":"bool","Value":true}`,
+		{TypeTimestamp, NewTimestamp(_Now).payload}: `{"Type":"timestamp","Value":"2020-01-01T00:00:00Z"}`,
+		{TypeBytes, []byte{0x13, 0x14}}:             `{"Type":"bytes","Value":"ExQ="}`,
+	} {
+		data, e := json.Marshal(value)
+		assert.NilError(t, e)
+		assert.Equal(t, string(data), expected)
+
+		var recovered Value
+		assert.NilError(t, json.Unmarshal(data, &recovered))
+		assert.Check(t, recovered.Equals(*value), "value %s", value)
+	}
+
+	for data, expected := range map[string]string{
+		`{"Type":"date","Value":"2020-01-01"}`:   `unknown value type "date"`,
+		`{"Value":"acme"}`:                       "value type is missing",
+		`{"Type":"int64","Value":"25"}`:          "int64 value: json: cannot unmarshal string",
+		`{"Type":"timestamp","Value":"today"}`:   "timestamp value: parsing time",
+		`{"Type":"bytes","Value":"not base64!"}`: "illegal base64 data",
+	} {
+		var value Value
+		assert.ErrorContains(t, json.Unmarshal([]byte(data), &value), expected)
+	}
+
+	_, e := json.Marshal(Value{TypeBool, []byte{2}})
+	assert.ErrorContains(t, e, "bool value must be 0 or 1, got 2")
+}
+
+func testPolicyJSON(t *testing.T) {
+	_, _, pk, _, _, _, _, _ := policyChain()
+	policy := policyOf(pk)
+
+	data, e := policy.ToJSON()
+	assert.NilError(t, e)
+
+	recovered, e := ParsePolicy(data)
+	assert.NilError(t, e)
+	assert.DeepEqual(t, recovered.Hash(), policy.Hash())
+
+	again, e := recovered.ToJSON()
+	assert.NilError(t, e)
+	assert.Equal(t, string(again), string(data))
+
+	// every part of the policy is in its hash
+	other := *policy
+	other.Nonce = []byte("another nonce")
+	assert.Check(t, string(other.Hash()) != string(policy.Hash()))
+	other = *policy
+	other.Disclose = nil
+	assert.Check(t, string(other.Hash()) != string(policy.Hash()))
+
+	_, e = ParsePolicy([]byte(`{"ChainLength": "two"}`))
+	assert.ErrorContains(t, e, "ParsePolicy: json: cannot unmarshal")
+
+	policy.ChainLength = 0
+	data, _ = policy.ToJSON()
+	_, e = ParsePolicy(data)
+	assert.ErrorContains(t, e, "ParsePolicy: chain length must be between 1 and 2, got 0")
+}
+
+func testPolicyValidate(t *testing.T) {
+	_, _, pk, _, _, _, _, _ := policyChain()
+
+	word := NewString("word")
+	number := NewInt64(25)
+	less := NewInt64(20)
+
+	for _, tc := range []struct {
+		tamper   func(policy *Policy)
+		expected string
+	}{
+		{func(policy *Policy) { policy.Schema.Levels[0][1].Name = "org" }, `attribute "org" repeats at level 1`},
+		{func(policy *Policy) { policy.TrustedRoots = nil }, "policy has no trusted roots"},
+		{func(policy *Policy) { policy.TrustedRoots[1] = PointToBytes(levelGenerator(1)) }, "trusted root 1: "},
+		{func(policy *Policy) { policy.ChainLength = 3 }, "chain length must be between 1 and 2, got 3"},
+		{func(policy *Policy) { policy.Disclose = []string{"level2.salary"} }, `schema "membership" has no attribute "salary" at level 2`},
+		{func(policy *Policy) { policy.Disclose = []string{"level2.role", "level2.role"} }, "level2.role is disclosed twice"},
+		{func(policy *Policy) {
+			policy.ChainLength = 1
+			policy.Constraints = nil
+		}, "level2.role is beyond the chain length 1"},
+		{func(policy *Policy) { policy.Constraints[0].Path = "level2.role" }, "constraint on level2.role: attribute is disclosed, check its value instead"},
+		{func(policy *Policy) { policy.Constraints[0] = Constraint{Path: "level2.age"} }, "constraint on level2.age: constraint is empty"},
+		{func(policy *Policy) { policy.Constraints[2].Min = &word }, "constraint on level2.org: Min and Max apply to int64 and timestamp attributes, got string"},
+		{func(policy *Policy) { policy.Constraints[1].Min = &number }, "constraint on level1.founded: bound must be of type timestamp, got int64"},
+		{func(policy *Policy) {
+			policy.Constraints[0].Min = &number
+			policy.Constraints[0].Max = &less
+		}, "constraint on level2.age: Min is greater than Max"},
+		{func(policy *Policy) { policy.Constraints[2].OneOf = []Value{} }, "constraint on level2.org: set of values is empty"},
+		{func(policy *Policy) { policy.Constraints[3].NoneOf = []Value{NewInt64(1)} }, "constraint on level2.admin: value 1 must be of type bool, got int64"},
+		{func(policy *Policy) { policy.Constraints[3].NoneOf = []Value{{TypeBool, []byte{2}}} }, "constraint on level2.admin: bool value must be 0 or 1, got 2"},
+		{func(policy *Policy) { policy.Constraints[2].EqualTo = "level2.role" }, "constraint on level2.org: level2.role is disclosed, check its value instead"},
+		{func(policy *Policy) { policy.Constraints[2].EqualTo = "level2.age" }, "constraint on level2.org: level2.age must be of type string, got int64"},
+		{func(policy *Policy) { policy.Constraints[2].EqualTo = "level3.org" }, `constraint on level2.org: schema "membership" has no level 3`},
+	} {
+		policy := policyOf(pk)
+		policy.Schema = policySchema()
+		tc.tamper(policy)
+
+		assert.ErrorContains(t, policy.Validate(), tc.expected)
+	}
+
+	assert.NilError(t, policyOf(pk).Validate())
+}
+
+func testPolicyHappyPath(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h, values := policyChain()
+
+	// the verifier sends the policy as JSON
+	data, e := policyOf(pk).ToJSON()
+	assert.NilError(t, e)
+
+	policy, e := ParsePolicy(data)
+	assert.NilError(t, e)
+
+	proof, e := policy.Prove(prg, creds, sk, skNym, values, ys, h)
+	assert.NilError(t, e)
+	// the pseudonym is derived from the secret keys
+	assert.Check(t, pointEqual(proof.PkNym, pkNym))
+
+	recovered, e := ParsePolicyProof(proof.ToBytes())
+	assert.NilError(t, e)
+
+	result, e := policy.Verify(recovered, ys, h)
+	assert.NilError(t, e)
+	assert.NilError(t, result.Err())
+
+	assert.DeepEqual(t, result.Root, PointToBytes(pk))
+	assert.Equal(t, len(result.Values), 1)
+	role, e := result.Values["level2.role"].AsString()
+	assert.NilError(t, e)
+	assert.Equal(t, role, "engineer")
+
+	var requirements []string
+	for _, check := range result.Checks {
+		assert.NilError(t, check.Error)
+		requirements = append(requirements, check.Requirement)
+	}
+	assert.DeepEqual(t, requirements, []string{"policy", "trusted root", "chain length", "schema", "disclosed level2.role", "disclosed values", "constraints", "proof"})
+
+	// nothing disclosed and no constraints
+	policy.Disclose, policy.Constraints = nil, nil
+	proof, e = policy.Prove(prg, creds, sk, skNym, map[string]Value{}, ys, h)
+	assert.NilError(t, e)
+	_, e = policy.Verify(&proof, ys, h)
+	assert.NilError(t, e)
+}
+
+func testPolicyProveErrors(t *testing.T) {
+	type TestCase string
+	const (
+		Invalid        TestCase = "invalid policy"
+		Untrusted      TestCase = "untrusted root"
+		ChainLength    TestCase = "chain length"
+		OtherSchema    TestCase = "another schema"
+		MissingValue   TestCase = "disclosed value missing"
+		WrongValue     TestCase = "disclosed value wrong"
+		WrongType      TestCase = "disclosed value of wrong type"
+		MissingBounded TestCase = "constrained value missing"
+		Unmet          TestCase = "constraint unmet"
+		NotEqual       TestCase = "equality unmet"
+		MissingH       TestCase = "h missing"
+	)
+
+	for _, tc := range []TestCase{Invalid, Untrusted, ChainLength, OtherSchema, MissingValue, WrongValue, WrongType, MissingBounded, Unmet, NotEqual, MissingH} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, pk, ys, skNym, _, h, values := policyChain()
+			policy := policyOf(pk)
+
+			var expected string
+			switch tc {
+			case Invalid:
+				policy.TrustedRoots = nil
+				expected = "policy has no trusted roots"
+			case Untrusted:
+				policy.TrustedRoots = policy.TrustedRoots[:1]
+				expected = "root of the credentials is not trusted"
+			case ChainLength:
+				policy.ChainLength = 1
+				policy.Disclose, policy.Constraints = nil, nil
+				expected = "credentials have 2 levels, policy requires 1"
+			case OtherSchema:
+				policy.Schema.Name = "another"
+				expected = `Schema.Check: credentials do not follow schema "another"`
+			case MissingValue:
+				delete(values, "level2.role")
+				expected = "value of level2.role is missing"
+			case WrongValue:
+				values["level2.role"] = NewString("manager")
+				expected = "disclosed value of attribute (2, 1) does not match the attribute"
+			case WrongType:
+				values["level2.role"] = NewBytes([]byte("engineer"))
+				expected = "level2.role must be of type string, got bytes"
+			case MissingBounded:
+				delete(values, "level2.age")
+				expected = "value of level2.age is missing"
+			case Unmet:
+				min := NewInt64(30)
+				policy.Constraints[0].Min = &min
+				expected = "attribute (2, 2) is out of the bound"
+			case NotEqual:
+				policy.Constraints[2].EqualTo = "level2.role"
+				policy.Disclose = nil
+				expected = "attributes (2, 0) and (2, 1) are not equal"
+			case MissingH:
+				h = nil
+				expected = "h: value of type <nil> is not an ECP or ECP2 point"
+			}
+
+			_, e := policy.Prove(prg, creds, sk, skNym, values, ys, h)
+			assert.ErrorContains(t, e, "Policy.Prove: ")
+			assert.ErrorContains(t, e, expected)
+		})
+	}
+}
+
+func testPolicyVerifyFail(t *testing.T) {
+	type TestCase string
+	const (
+		Untrusted   TestCase = "untrusted root"
+		Nonce       TestCase = "another nonce"
+		ChainLength TestCase = "chain length"
+		Dropped     TestCase = "disclosed value dropped"
+		Extra       TestCase = "extra value disclosed"
+		Changed     TestCase = "disclosed value changed"
+		Retyped     TestCase = "disclosed value retyped"
+		Constraint  TestCase = "constraint tightened"
+		Pseudonym   TestCase = "pseudonym"
+		Invalid     TestCase = "invalid policy"
+	)
+
+	for _, tc := range []TestCase{Untrusted, Nonce, ChainLength, Dropped, Extra, Changed, Retyped, Constraint, Pseudonym, Invalid} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, pk, ys, skNym, _, h, values := policyChain()
+			policy := policyOf(pk)
+
+			proof, e := policy.Prove(prg, creds, sk, skNym, values, ys, h)
+			assert.NilError(t, e)
+
+			var requirement, expected string
+			switch tc {
+			case Untrusted:
+				policy.TrustedRoots = policy.TrustedRoots[:1]
+				requirement, expected = "trusted root", "root of the credentials is not trusted"
+			case Nonce:
+				policy.Nonce = []byte("another nonce")
+				requirement, expected = "proof", "PredicateProof.Verify: verification failed"
+			case ChainLength:
+				policy.Schema.Levels = append(policy.Schema.Levels, []Slot{{"role", TypeString}})
+				policy.ChainLength = 3
+				requirement, expected = "chain length", "credentials have 2 levels, policy requires 3"
+			case Dropped:
+				proof.Values = nil
+				requirement, expected = "disclosed level2.role", "ValueByName: level2.role is not disclosed"
+			case Extra:
+				proof.Values = append(proof.Values, NewInt64(25).Disclose(2, 2))
+				requirement, expected = "disclosed", "2 values disclosed, policy requires 1"
+			case Changed:
+				proof.Values[0].Value = NewString("manager")
+				requirement, expected = "proof", "PredicateProof.Verify: verification failed"
+			case Retyped:
+				proof.Values[0].Value = NewBytes([]byte("engineer"))
+				requirement, expected = "disclosed level2.role", "ValueByName: level2.role must be of type string, got bytes"
+			case Constraint:
+				min := NewInt64(21)
+				policy.Constraints[0].Min = &min
+				requirement, expected = "proof", "PredicateProof.Verify: verification failed"
+			case Pseudonym:
+				proof.PkNym = pk
+				requirement, expected = "proof", ""
+			case Invalid:
+				policy.ChainLength = 0
+				requirement, expected = "policy", "chain length must be between"
+			}
+
+			result, e := policy.Verify(&proof, ys, h)
+			assert.Assert(t, e != nil)
+			assert.ErrorContains(t, result.Err(), requirement+": "+expected)
+
+			failed := false
+			for _, check := range result.Checks {
+				if check.Requirement == requirement {
+					failed = check.Error != nil
+				}
+			}
+			assert.Check(t, failed)
+		})
+	}
+}
+
+func testPolicySchemaBound(t *testing.T) {
+	levels := policyLevels()
+	renamed := policySchema()
+	renamed.Name = "another"
+
+	for name, tc := range map[string]struct {
+		// the holder's credentials under the same root, and the reserved attributes it discloses
+		creds       func() (*Credentials, SK, SK, PK)
+		reserved    func(creds *Credentials) Indices
+		requirement string
+		expected    string
+	}{
+		"schema swapped": {
+			func() (*Credentials, SK, SK, PK) {
+				creds, sk, _, _, skNym, pkNym, _, e := generateCustomChain(0, nil, chainOptions{schema: &renamed, levels: levels})
+				assert.NilError(t, e)
+				return creds, sk, skNym, pkNym
+			},
+			func(creds *Credentials) Indices {
+				return Indices{{1, 2, creds.Attributes[1][2]}, {2, 4, creds.Attributes[2][4]}}
+			},
+			"proof", "PredicateProof.Verify: verification failed",
+		},
+		"schema stripped": {
+			func() (*Credentials, SK, SK, PK) {
+				// the same values encoded without the schema attribute
+				attributes := make(map[Position]interface{})
+				for L, level := range levels {
+					encoded, e := policySchema().Encode(L+1, level)
+					assert.NilError(t, e)
+					for j, attribute := range encoded {
+						attributes[Position{L + 1, j}] = attribute.Untyped()
+					}
+				}
+				creds, sk, _, _, skNym, pkNym, _, e := generateCustomChain(2, []int{0, 2, 4}, chainOptions{attributes: attributes})
+				assert.NilError(t, e)
+
+				return creds, sk, skNym, pkNym
+			},
+			func(creds *Credentials) Indices { return Indices{} },
+			"schema", `level 1 has 2 attributes, schema "membership" expects 3`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			_, _, pk, ys, _, _, h, _ := policyChain()
+			policy := policyOf(pk)
+			policy.Constraints = nil
+
+			creds, sk, skNym, pkNym := tc.creds()
+			assert.ErrorContains(t, policy.Schema.Check(creds), "Schema.Check: ")
+
+			// the holder skips the schema check of Policy.Prove and proves its own attributes
+			values, e := policy.Schema.DiscloseValuesByName(map[string]Value{"level2.role": NewString("engineer")})
+			assert.NilError(t, e)
+			D, e := values.Indices()
+			assert.NilError(t, e)
+			D = append(D, tc.reserved(creds)...)
+
+			predicateProof, e := creds.ProveWithPredicates(prg, sk, pk, D, policy.Hash(), ys, h, skNym, Predicates{}, AttributeValues{})
+			assert.NilError(t, e)
+			proof := PolicyProof{PointToBytes(pk), pkNym, values, predicateProof}
+
+			result, e := policy.Verify(&proof, ys, h)
+			assert.Assert(t, e != nil)
+			assert.ErrorContains(t, result.Err(), tc.requirement+": "+tc.expected)
+		})
+	}
+}
+
+func testPolicyMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, _, h, values := policyChain()
+	policy := policyOf(pk)
+
+	proof, e := policy.Prove(prg, creds, sk, skNym, values, ys, h)
+	assert.NilError(t, e)
+
+	recovered, e := ParsePolicyProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.DeepEqual(t, recovered.ToBytes(), proof.ToBytes())
+
+	_, e = ParsePolicyProof(append(proof.ToBytes(), 0x13))
+	assert.ErrorContains(t, e, "trailing")
+
+	var marshal policyProofMarshal
+	for _, tc := range []struct {
+		tamper   func()
+		expected string
+	}{
+		{func() { marshal.PkNym = nil }, "ParsePolicyProof: pkNym is missing"},
+		{func() { marshal.PkNym = marshal.PkNym[1:] }, "ParsePolicyProof: invalid pkNym"},
+		{func() { marshal.Values[0].Value.Type = 0x13 }, "ParsePolicyProof: value 0: unknown value type 19"},
+		{func() { marshal.Proof = marshal.Proof[1:] }, "ParsePolicyProof: ParsePredicateProof"},
+	} {
+		_, e = ParsePolicyProof(remarshal(t, proof.ToBytes(), &marshal, tc.tamper))
+		assert.ErrorContains(t, e, tc.expected)
+	}
+}
@@ -0,0 +1,492 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strconv"
+	"time"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// _RangeBits is the bit length of the differences proven non-negative, enough for any difference of 64-bit integers
+const _RangeBits = 64
+
+// Predicates are the statements about hidden attributes proven along with the credentials, see ProveWithPredicates.
+// The attributes the predicates are about must have the form g^x with x known to the prover (see AttributeValues).
+type Predicates struct {
+	// Expiry, if set, states that the expiry attribute is later than the given time
+	Expiry *Expiry
+	// Ranges state that the numeric attributes are within the bounds
+	Ranges []Range
+	// Memberships state that the attributes are (or are not) among the public values
+	Memberships []Membership
+	// Equalities state that pairs of attributes are equal, they need no values
+	Equalities []Equality
+}
+
+// Expiry states that the hidden expiry attribute (see ExpiryAttribute) at Position is later than Now,
+// with a precision of one second
+type Expiry struct {
+	Position
+	Now time.Time
+}
+
+// Range states that the hidden numeric attribute (see NumericAttribute) at Position is within [Min, Max].
+// A nil bound is not checked, see AtLeast, AtMost and Between.
+type Range struct {
+	Position
+	Min *int64
+	Max *int64
+}
+
+// Membership states that the exponent of the hidden attribute at Position is one of Set
+// (or, if Negated, none of them), see MemberOf and NotMemberOf.
+// The values are the exponents of the attributes, e.g. StringValue or NumericValue.
+type Membership struct {
+	Position
+	Set     []*FP256BN.BIG
+	Negated bool
+}
+
+// AttributeValues are the exponents of the hidden attributes (the attribute is g^value) keyed by their positions.
+// The prover supplies the values of the attributes its predicates are about, e.g. ExpiryValue of the expiry.
+type AttributeValues map[Position]*FP256BN.BIG
+
+// PredicateProof is a credentials proof along with the proofs of the predicates over its hidden attributes.
+// Both parts are under a single challenge.
+// The exponent x of each attribute the predicates are about is proven with the response resX,
+// which shares the randomness of the attribute in the credentials proof, so that resA = g^resX.
+type PredicateProof struct {
+	proof       Proof
+	resX        []*FP256BN.BIG
+	ranges      []rangeProof
+	memberships []membershipProof
+}
+
+// NumericValue encodes the integer x as the exponent x + 2^64 of a numeric attribute.
+// The shift keeps every exponent positive and below q, so that any integer (0 included) encodes to a valid point,
+// and it cancels out in the differences the range proofs are about.
+func NumericValue(x int64) *FP256BN.BIG {
+	var raw [_BIGByteLength]byte
+
+	// for a negative x, x + 2^64 is its two's complement read as unsigned
+	if x >= 0 {
+		raw[_BIGByteLength-9] = 1
+	}
+	binary.BigEndian.PutUint64(raw[_BIGByteLength-8:], uint64(x))
+
+	return FP256BN.FromBytes(raw[:])
+}
+
+// NumericAttribute encodes the integer as an attribute of level L: g^NumericValue(x).
+// Range predicates prove bounds on such attributes.
+func NumericAttribute(L int, x int64) interface{} {
+	return pointMultiply(levelGenerator(L), NumericValue(x))
+}
+
+// AtLeast states that the numeric attribute at the position is at least min
+func AtLeast(position Position, min int64) Range {
+	return Range{position, &min, nil}
+}
+
+// AtMost states that the numeric attribute at the position is at most max
+func AtMost(position Position, max int64) Range {
+	return Range{position, nil, &max}
+}
+
+// Between states that the numeric attribute at the position is within [min, max]
+func Between(position Position, min int64, max int64) Range {
+	return Range{position, &min, &max}
+}
+
+// MemberOf states that the attribute at the position is one of the values
+func MemberOf(position Position, set ...*FP256BN.BIG) Membership {
+	return Membership{position, set, false}
+}
+
+// NotMemberOf states that the attribute at the position is none of the values
+func NotMemberOf(position Position, set ...*FP256BN.BIG) Membership {
+	return Membership{position, set, true}
+}
+
+// ExpiryValue encodes the expiry time as the number of seconds since the Unix epoch
+func ExpiryValue(expiry time.Time) *FP256BN.BIG {
+	return NumericValue(expiry.Unix())
+}
+
+// ExpiryAttribute encodes the expiry time as a numeric attribute of level L.
+// Delegate it like any other attribute; the holder proves it has not expired with the Expiry predicate.
+func ExpiryAttribute(L int, expiry time.Time) interface{} {
+	return NumericAttribute(L, expiry.Unix())
+}
+
+// ProveWithPredicates is Prove that also proves the predicates over the hidden attributes.
+// values must hold the exponents of the attributes the predicates are about.
+// Verify the proof with PredicateProof.Verify.
+func (creds *Credentials) ProveWithPredicates(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK, predicates Predicates, values AttributeValues) (proof PredicateProof, e error) {
+	return creds.proveWithPredicates(prg, sk, pk, D, m, grothYs, h, skNym, predicates, values, binding{})
+}
+
+// ProveWithPredicatesAndParameters is ProveWithPredicates that takes the public values from the system parameters (see SystemParameters).
+// Verify the proof with PredicateProof.VerifyWithParameters.
+func (creds *Credentials) ProveWithPredicatesAndParameters(prg *amcl.RAND, sk SK, params *SystemParameters, context string, D Indices, m []byte, skNym SK, predicates Predicates, values AttributeValues) (proof PredicateProof, e error) {
+	return creds.proveWithPredicates(prg, sk, params.AuthorityPK, D, m, params.GrothYs, params.H, skNym, predicates, values, binding{params.Fingerprint(), context})
+}
+
+func (creds *Credentials) proveWithPredicates(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK, predicates Predicates, values AttributeValues, b binding) (proof PredicateProof, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if e = predicates.validate(); e != nil {
+		return proof, fmt.Errorf("ProveWithPredicates: %v", e)
+	}
+	positions := predicates.positions()
+	if e = checkHiddenPositions("attribute", positions, D, creds.Attributes); e != nil {
+		return proof, fmt.Errorf("ProveWithPredicates: %v", e)
+	}
+	for _, position := range positions {
+		value := values[position]
+		if value == nil {
+			return proof, fmt.Errorf("ProveWithPredicates: value of attribute (%d, %d) is missing", position.I, position.J)
+		}
+		if !pointEqual(pointMultiply(levelGenerator(position.I), value), creds.Attributes[position.I][position.J]) {
+			return proof, fmt.Errorf("ProveWithPredicates: value of attribute (%d, %d) does not match the attribute", position.I, position.J)
+		}
+	}
+
+	if e = checkEqualities(predicates.Equalities, [][][]interface{}{creds.Attributes}, func(int) Indices { return D }); e != nil {
+		return proof, fmt.Errorf("ProveWithPredicates: %v", e)
+	}
+	if e = checkEqualitiesHold(predicates.Equalities, [][][]interface{}{creds.Attributes}); e != nil {
+		return proof, fmt.Errorf("ProveWithPredicates: %v", e)
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	prover, e := creds.proveCommitSharing(prg, D, grothYs, h, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg), sharedRandomness(prg, predicates.Equalities, 1)[0])
+	if e != nil {
+		return
+	}
+
+	bounds := predicates.bounds()
+	rangeProvers := make([]*rangeProver, len(bounds))
+	for index, b := range bounds {
+		if rangeProvers[index], e = rangeCommit(prg, b, values[b.Position], prover.rhoA[b.I][b.J]); e != nil {
+			return proof, fmt.Errorf("ProveWithPredicates: %v", e)
+		}
+	}
+
+	membershipProvers := make([]*membershipProver, len(predicates.Memberships))
+	for index, membership := range predicates.Memberships {
+		if membershipProvers[index], e = membershipCommit(prg, membership, values[membership.Position], prover.rhoA[membership.I][membership.J]); e != nil {
+			return proof, fmt.Errorf("ProveWithPredicates: %v", e)
+		}
+	}
+
+	t := newTranscript(_ProtocolPredicates, b)
+	appendCommitments(t, grothYs, pk, h, prover.rPrime, prover.coms, prover.comNym, D)
+	appendPredicates(t, positions)
+	for index, b := range bounds {
+		appendRange(t, b, rangeProvers[index].coms, rangeProvers[index].t0, rangeProvers[index].t1, rangeProvers[index].tLink)
+	}
+	for index, membership := range predicates.Memberships {
+		appendMembership(t, membership, membershipProvers[index].com, membershipProvers[index].ts, membershipProvers[index].tLink)
+	}
+	appendEqualities(t, predicates.Equalities)
+	t.append("m", m)
+
+	c := t.challenge(q)
+
+	proof.proof = prover.respond(c, sk, skNym)
+	proof.proof.fingerprint = b.fingerprint
+
+	proof.resX = make([]*FP256BN.BIG, len(positions))
+	for index, position := range positions {
+		proof.resX[index] = FP256BN.Modmul(c, values[position], q)
+		proof.resX[index] = proof.resX[index].Plus(prover.rhoA[position.I][position.J])
+		proof.resX[index].Mod(q)
+	}
+
+	proof.ranges = make([]rangeProof, len(bounds))
+	for index := range bounds {
+		proof.ranges[index] = rangeProvers[index].respond(c)
+	}
+
+	proof.memberships = make([]membershipProof, len(predicates.Memberships))
+	for index := range predicates.Memberships {
+		proof.memberships[index] = membershipProvers[index].respond(c)
+	}
+
+	return
+}
+
+// Verify checks the credentials proof like VerifyProof and that the hidden attributes satisfy the predicates.
+// The predicates must be the same as in the generation.
+func (proof *PredicateProof) Verify(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, predicates Predicates) (e error) {
+	return proof.verify(pk, grothYs, h, pkNym, D, m, predicates, "")
+}
+
+// VerifyWithParameters is Verify that takes the public values from the system parameters (see SystemParameters).
+func (proof *PredicateProof) VerifyWithParameters(params *SystemParameters, context string, pkNym PK, D Indices, m []byte, predicates Predicates) (e error) {
+	if e = params.checkFingerprint(proof.proof.fingerprint); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: %v", e)
+	}
+
+	return proof.verify(params.AuthorityPK, params.GrothYs, params.H, pkNym, D, m, predicates, context)
+}
+
+// verify is Verify for the proof bound to the context (and to the fingerprint it carries)
+func (proof *PredicateProof) verify(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, predicates Predicates, context string) (e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if e = predicates.validate(); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: %v", e)
+	}
+	if e = proof.proof.validate(); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: invalid proof: %v", e)
+	}
+	if e = ValidatePoint(pkNym); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: invalid pkNym: %v", e)
+	}
+	if e = D.validate(); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: %v", e)
+	}
+
+	positions := predicates.positions()
+	if e = checkHiddenPositions("attribute", positions, D, proof.proof.resA); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: %v", e)
+	}
+	if len(proof.resX) != len(positions) {
+		return fmt.Errorf("PredicateProof.Verify: %d responses for %d attributes", len(proof.resX), len(positions))
+	}
+
+	bounds := predicates.bounds()
+	if len(proof.ranges) != len(bounds) {
+		return fmt.Errorf("PredicateProof.Verify: %d range proofs for %d bounds", len(proof.ranges), len(bounds))
+	}
+	for index, b := range bounds {
+		if e = proof.ranges[index].validate(b); e != nil {
+			return fmt.Errorf("PredicateProof.Verify: %v", e)
+		}
+	}
+
+	if len(proof.memberships) != len(predicates.Memberships) {
+		return fmt.Errorf("PredicateProof.Verify: %d set membership proofs for %d sets", len(proof.memberships), len(predicates.Memberships))
+	}
+	for index, membership := range predicates.Memberships {
+		if e = proof.memberships[index].validate(membership); e != nil {
+			return fmt.Errorf("PredicateProof.Verify: %v", e)
+		}
+	}
+
+	if e = checkEqualities(predicates.Equalities, [][][]interface{}{proof.proof.resA}, func(int) Indices { return D }); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: %v", e)
+	}
+	if e = checkEqualitiesHold(predicates.Equalities, [][][]interface{}{proof.proof.resA}); e != nil {
+		return fmt.Errorf("PredicateProof.Verify: verification failed at %v", e)
+	}
+
+	// resA = g^rhoA * (g^x)^c = g^resX
+	resX := make(map[Position]*FP256BN.BIG, len(positions))
+	for index, position := range positions {
+		if !pointEqual(pointMultiply(levelGenerator(position.I), proof.resX[index]), proof.proof.resA[position.I][position.J]) {
+			return fmt.Errorf("PredicateProof.Verify: verification failed at resA == g^resX for attribute (%d, %d)", position.I, position.J)
+		}
+		resX[position] = proof.resX[index]
+	}
+
+	credsComs, comNym, e := proof.proof.commitments(pk, grothYs, h, pkNym, D)
+	if e != nil {
+		return
+	}
+
+	t := newTranscript(_ProtocolPredicates, binding{proof.proof.fingerprint, context})
+	appendCommitments(t, grothYs, pk, h, proof.proof.rPrime, credsComs, comNym, D)
+	appendPredicates(t, positions)
+	for index, b := range bounds {
+		t0, t1, tLink := proof.ranges[index].commitments(b, proof.proof.c, resX[b.Position])
+		appendRange(t, b, proof.ranges[index].coms, t0, t1, tLink)
+	}
+	for index, membership := range predicates.Memberships {
+		ts, tLink, e := proof.memberships[index].commitments(membership, proof.proof.c, resX[membership.Position])
+		if e != nil {
+			return fmt.Errorf("PredicateProof.Verify: %v", e)
+		}
+		appendMembership(t, membership, proof.memberships[index].com, ts, tLink)
+	}
+	appendEqualities(t, predicates.Equalities)
+	t.append("m", m)
+
+	if !bigEqual(proof.proof.c, t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order))) {
+		return fmt.Errorf("PredicateProof.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// positions returns the distinct positions of the attributes the predicates are about, in the order of the predicates
+func (predicates Predicates) positions() (positions []Position) {
+	seen := make(map[Position]bool)
+	add := func(position Position) {
+		if !seen[position] {
+			seen[position] = true
+			positions = append(positions, position)
+		}
+	}
+
+	if predicates.Expiry != nil {
+		add(predicates.Expiry.Position)
+	}
+	for _, r := range predicates.Ranges {
+		add(r.Position)
+	}
+	for _, membership := range predicates.Memberships {
+		add(membership.Position)
+	}
+
+	return
+}
+
+// validate ensures that each range has a bound and is not empty
+func (predicates Predicates) validate() (e error) {
+	for _, r := range predicates.Ranges {
+		if r.Min == nil && r.Max == nil {
+			return fmt.Errorf("range of attribute (%d, %d) has no bounds", r.I, r.J)
+		}
+		if r.Min != nil && r.Max != nil && *r.Min > *r.Max {
+			return fmt.Errorf("range of attribute (%d, %d) is empty", r.I, r.J)
+		}
+	}
+	for _, equality := range predicates.Equalities {
+		if equality.CredentialA != 0 || equality.CredentialB != 0 {
+			return fmt.Errorf("equality of attributes (%d, %d) and (%d, %d) refers to other credentials", equality.A.I, equality.A.J, equality.B.I, equality.B.J)
+		}
+	}
+	for _, membership := range predicates.Memberships {
+		if len(membership.Set) == 0 {
+			return fmt.Errorf("set of attribute (%d, %d) is empty", membership.I, membership.J)
+		}
+		for index, value := range membership.Set {
+			if value == nil {
+				return fmt.Errorf("set of attribute (%d, %d): value %d is missing", membership.I, membership.J, index)
+			}
+		}
+	}
+
+	return
+}
+
+// bounds translates the predicates to the bounds proven with range proofs
+func (predicates Predicates) bounds() (bounds []bound) {
+	if predicates.Expiry != nil {
+		// expiry > now is expiry - (now + 1) >= 0
+		bounds = append(bounds, bound{predicates.Expiry.Position, false, NumericValue(predicates.Expiry.Now.Unix() + 1), _RangeBits})
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	for _, r := range predicates.Ranges {
+		// x - min and max - x both below 2^bits, with 2^bits > max - min, is enough for x in [min, max]
+		length := _RangeBits
+		if r.Min != nil && r.Max != nil {
+			length = bits.Len64(uint64(*r.Max - *r.Min))
+			if length == 0 {
+				length = 1
+			}
+		}
+
+		if r.Min != nil {
+			bounds = append(bounds, bound{r.Position, false, NumericValue(*r.Min), length})
+		}
+		if r.Max != nil {
+			// max - x = -x - (-max)
+			bounds = append(bounds, bound{r.Position, true, bigNegate(NumericValue(*r.Max), q), length})
+		}
+	}
+
+	return
+}
+
+func appendPredicates(t *transcript, positions []Position) {
+	t.append("positions", []byte(strconv.Itoa(len(positions))))
+	for _, position := range positions {
+		t.append("i", []byte(strconv.Itoa(position.I)))
+		t.append("j", []byte(strconv.Itoa(position.J)))
+	}
+}
+
+type predicateProofMarshal struct {
+	Proof       []byte
+	ResX        [][]byte
+	Ranges      []rangeProofMarshal
+	Memberships []membershipProofMarshal
+}
+
+// ToBytes marshals the proof using ASN1 encoding
+func (proof *PredicateProof) ToBytes() (result []byte) {
+	var marshal predicateProofMarshal
+
+	marshal.Proof = proof.proof.ToBytes()
+	marshal.ResX = make([][]byte, len(proof.resX))
+	for index, res := range proof.resX {
+		marshal.ResX[index] = bigToBytes(res)
+	}
+	marshal.Ranges = make([]rangeProofMarshal, len(proof.ranges))
+	for index := range proof.ranges {
+		marshal.Ranges[index] = proof.ranges[index].marshal()
+	}
+	marshal.Memberships = make([]membershipProofMarshal, len(proof.memberships))
+	for index := range proof.memberships {
+		marshal.Memberships[index] = proof.memberships[index].marshal()
+	}
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParsePredicateProof un-marshals and validates the proof using ASN1 encoding.
+// Whether the proof fits the predicates is checked by PredicateProof.Verify.
+func ParsePredicateProof(input []byte) (proof *PredicateProof, e error) {
+	var marshal predicateProofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParsePredicateProof: %v", e)
+	}
+
+	credsProof, e := ParseProof(marshal.Proof)
+	if e != nil {
+		return nil, fmt.Errorf("ParsePredicateProof: %v", e)
+	}
+
+	proof = &PredicateProof{proof: *credsProof}
+
+	if proof.resX, e = bigsFromBytes(marshal.ResX...); e != nil {
+		return nil, fmt.Errorf("ParsePredicateProof: %v", e)
+	}
+
+	proof.ranges = make([]rangeProof, len(marshal.Ranges))
+	for index, rangeMarshal := range marshal.Ranges {
+		if proof.ranges[index], e = rangeMarshal.unmarshal(); e != nil {
+			return nil, fmt.Errorf("ParsePredicateProof: range proof %d: %v", index, e)
+		}
+	}
+
+	proof.memberships = make([]membershipProof, len(marshal.Memberships))
+	for index, membershipMarshal := range marshal.Memberships {
+		if proof.memberships[index], e = membershipMarshal.unmarshal(); e != nil {
+			return nil, fmt.Errorf("ParsePredicateProof: set membership proof %d: %v", index, e)
+		}
+	}
+
+	return
+}
@@ -0,0 +1,512 @@
+package dac
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+var _Now = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Tests
+
+func TestPredicates(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testPredicatesExpiry,
+		testPredicatesExpired,
+		testPredicatesParameters,
+		testPredicatesVerificationFail,
+		testPredicatesValueErrors,
+		testPredicatesRange,
+		testPredicatesZero,
+		testPredicatesRangeOutOfBounds,
+		testPredicatesRangeErrors,
+		testPredicatesMembership,
+		testPredicatesMembershipFail,
+		testPredicatesMarshal,
+		testPredicatesParseRejectsMalformed,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testPredicatesExpiry(t *testing.T) {
+	// the expiry in G1 (level 1) and in G2 (level 2)
+	for _, position := range []Position{{1, 1}, {2, 0}} {
+		t.Run(strconv.Itoa(position.I), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			expiry := _Now.Add(time.Hour)
+			creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{position: ExpiryAttribute(position.I, expiry)})
+
+			D := Indices{{1, 0, creds.Attributes[1][0]}}
+			m := []byte("message")
+			predicates := Predicates{Expiry: &Expiry{position, _Now}}
+
+			proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, AttributeValues{position: ExpiryValue(expiry)})
+			assert.NilError(t, e)
+
+			assert.NilError(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates))
+
+			// a second before the expiry is still fine, the expiry itself is not
+			predicates.Expiry.Now = expiry.Add(-time.Second)
+			proof, e = creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, AttributeValues{position: ExpiryValue(expiry)})
+			assert.NilError(t, e)
+			assert.NilError(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates))
+		})
+	}
+}
+
+func testPredicatesExpired(t *testing.T) {
+	for _, now := range []time.Time{_Now, _Now.Add(time.Second), _Now.Add(24 * time.Hour)} {
+		prg := getNewRand(SEED)
+
+		creds, sk, pk, ys, skNym, _, h := generateChainWith(SEED, 1, 2, map[Position]interface{}{{1, 0}: ExpiryAttribute(1, _Now)})
+
+		_, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, Predicates{Expiry: &Expiry{Position{1, 0}, now}}, AttributeValues{{1, 0}: ExpiryValue(_Now)})
+		assert.ErrorContains(t, e, "attribute (1, 0) is out of the bound")
+	}
+}
+
+func testPredicatesParameters(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, _, _, _ := generateChainWith(SEED, 2, 2, map[Position]interface{}{{2, 1}: NumericAttribute(2, 25)})
+	params := generateSystemParametersFor(pk, ys)
+	skNym, pkNym := GenerateNymKeys(prg, sk, params.H)
+
+	D := Indices{}
+	m := []byte("message")
+	predicates := Predicates{Ranges: []Range{AtLeast(Position{2, 1}, 18)}}
+	values := AttributeValues{{2, 1}: NumericValue(25)}
+
+	proof, e := creds.ProveWithPredicatesAndParameters(prg, sk, params, "context", D, m, skNym, predicates, values)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyWithParameters(params, "context", pkNym, D, m, predicates))
+	assert.ErrorContains(t, proof.VerifyWithParameters(params, "other", pkNym, D, m, predicates), "verification failed")
+	assert.ErrorContains(t, proof.Verify(pk, ys, params.H, pkNym, D, m, predicates), "verification failed")
+
+	recovered, e := ParsePredicateProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.VerifyWithParameters(params, "context", pkNym, D, m, predicates))
+
+	other, _, _ := generateSystemParameters(getNewRand(SEED + 2))
+	other.AuthorityPK, other.GrothYs, other.H = params.AuthorityPK, params.GrothYs, params.H
+	assert.ErrorContains(t, proof.VerifyWithParameters(other, "context", pkNym, D, m, predicates), "mismatch")
+
+	unbound, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, params.H, skNym, predicates, values)
+	assert.NilError(t, e)
+	assert.ErrorContains(t, unbound.VerifyWithParameters(params, "context", pkNym, D, m, predicates), "not bound")
+}
+
+func testPredicatesVerificationFail(t *testing.T) {
+	type TestCase string
+	const (
+		WrongMessage  TestCase = "wrong message"
+		LaterNow      TestCase = "later current time"
+		Moved         TestCase = "position changed"
+		Disclosed     TestCase = "attribute disclosed"
+		ResX          TestCase = "response of the value"
+		BitCommitment TestCase = "bit commitment"
+		BitResponse   TestCase = "bit response"
+		Dropped       TestCase = "range proof dropped"
+		NoPredicates  TestCase = "predicates dropped"
+		CredsDetached TestCase = "credentials proof detached"
+	)
+
+	for _, tc := range []TestCase{WrongMessage, LaterNow, Moved, Disclosed, ResX, BitCommitment, BitResponse, Dropped, NoPredicates, CredsDetached} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			expiry := _Now.Add(time.Hour)
+			creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{{2, 1}: ExpiryAttribute(2, expiry)})
+
+			D := Indices{}
+			m := []byte("message")
+			predicates := Predicates{Expiry: &Expiry{Position{2, 1}, _Now}}
+
+			proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, AttributeValues{{2, 1}: ExpiryValue(expiry)})
+			assert.NilError(t, e)
+
+			expected := "verification failed"
+			switch tc {
+			case WrongMessage:
+				m = []byte("another message")
+			case LaterNow:
+				predicates.Expiry.Now = expiry.Add(time.Hour)
+			case Moved:
+				predicates.Expiry.Position = Position{2, 0}
+				expected = "resA == g^resX"
+			case Disclosed:
+				D = Indices{{2, 1, creds.Attributes[2][1]}}
+				expected = "is disclosed"
+			case ResX:
+				proof.resX[0] = FP256BN.NewBIGint(0x13)
+				expected = "resA == g^resX"
+			case BitCommitment:
+				proof.ranges[0].coms[3] = FP256BN.ECP_generator()
+			case BitResponse:
+				proof.ranges[0].z0[5] = FP256BN.NewBIGint(0x13)
+			case Dropped:
+				proof.ranges = nil
+				expected = "0 range proofs for 1 bounds"
+			case NoPredicates:
+				predicates = Predicates{}
+				expected = "1 responses for 0 attributes"
+			case CredsDetached:
+				e = proof.proof.VerifyProof(pk, ys, h, pkNym, D, m)
+				assert.ErrorContains(t, e, "verification failed")
+				return
+			}
+
+			assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates), expected)
+		})
+	}
+}
+
+func testPredicatesValueErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	expiry := _Now.Add(time.Hour)
+	creds, sk, pk, ys, skNym, _, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{{1, 0}: ExpiryAttribute(1, expiry)})
+
+	for _, tc := range []struct {
+		D        Indices
+		position Position
+		values   AttributeValues
+		expected string
+	}{
+		{Indices{}, Position{1, 0}, AttributeValues{}, "value of attribute (1, 0) is missing"},
+		{Indices{}, Position{1, 0}, AttributeValues{{1, 0}: ExpiryValue(expiry.Add(time.Second))}, "does not match"},
+		{Indices{}, Position{1, 1}, AttributeValues{{1, 1}: ExpiryValue(expiry)}, "does not match"},
+		{Indices{{1, 0, creds.Attributes[1][0]}}, Position{1, 0}, AttributeValues{{1, 0}: ExpiryValue(expiry)}, "(1, 0) is disclosed"},
+		{Indices{}, Position{3, 0}, AttributeValues{}, "(3, 0) does not exist"},
+	} {
+		_, e := creds.ProveWithPredicates(prg, sk, pk, tc.D, []byte("message"), ys, h, skNym, Predicates{Expiry: &Expiry{tc.position, _Now}}, tc.values)
+		assert.ErrorContains(t, e, tc.expected)
+	}
+}
+
+func testPredicatesRange(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 3, map[Position]interface{}{
+		{1, 0}: NumericAttribute(1, 25),
+		{2, 1}: NumericAttribute(2, 3),
+		{2, 2}: NumericAttribute(2, -50),
+	})
+	values := AttributeValues{
+		{1, 0}: NumericValue(25),
+		{2, 1}: NumericValue(3),
+		{2, 2}: NumericValue(-50),
+	}
+
+	D := Indices{{2, 0, creds.Attributes[2][0]}}
+	m := []byte("message")
+
+	for name, ranges := range map[string][]Range{
+		"at least":            {AtLeast(Position{1, 0}, 18)},
+		"at least exactly":    {AtLeast(Position{1, 0}, 25)},
+		"at most":             {AtMost(Position{1, 0}, 65)},
+		"between":             {Between(Position{2, 1}, 1, 5)},
+		"between exactly":     {Between(Position{2, 1}, 3, 3)},
+		"between wide":        {Between(Position{1, 0}, math.MinInt64, math.MaxInt64)},
+		"negative":            {Between(Position{2, 2}, -100, 0)},
+		"several":             {AtLeast(Position{1, 0}, 18), Between(Position{2, 1}, 1, 5), AtMost(Position{2, 2}, -1)},
+		"same attribute":      {AtLeast(Position{1, 0}, 18), AtMost(Position{1, 0}, 30)},
+		"limits of the range": {AtLeast(Position{2, 2}, math.MinInt64), AtMost(Position{2, 2}, math.MaxInt64)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			predicates := Predicates{Ranges: ranges}
+
+			proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, values)
+			assert.NilError(t, e)
+
+			assert.NilError(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates))
+		})
+	}
+
+	t.Run("with expiry", func(t *testing.T) {
+		expiry := _Now.Add(time.Hour)
+		creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 1, 2, map[Position]interface{}{
+			{1, 0}: NumericAttribute(1, 25),
+			{1, 1}: ExpiryAttribute(1, expiry),
+		})
+
+		predicates := Predicates{Expiry: &Expiry{Position{1, 1}, _Now}, Ranges: []Range{AtLeast(Position{1, 0}, 18)}}
+		values := AttributeValues{{1, 0}: NumericValue(25), {1, 1}: ExpiryValue(expiry)}
+
+		proof, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, m, ys, h, skNym, predicates, values)
+		assert.NilError(t, e)
+
+		assert.NilError(t, proof.Verify(pk, ys, h, pkNym, Indices{}, m, predicates))
+	})
+}
+
+func testPredicatesZero(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	epoch := time.Unix(0, 0)
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{
+		{1, 0}: NumericAttribute(1, 0),
+		{2, 1}: ExpiryAttribute(2, epoch),
+	})
+
+	// zero and the epoch encode to valid points, so the credentials verify and survive marshalling
+	assert.NilError(t, creds.Verify(sk, pk, ys))
+	recovered, e := ParseCredentials(creds.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.Verify(sk, pk, ys))
+
+	for _, x := range []int64{0, 1, -1, math.MinInt64, math.MaxInt64} {
+		assert.Check(t, !bigEqual(NumericValue(x), FP256BN.NewBIG()), "value %d", x)
+	}
+	assert.Check(t, !bigEqual(NumericValue(-1), NumericValue(math.MaxInt64)))
+
+	predicates := Predicates{
+		Expiry:      &Expiry{Position{2, 1}, epoch.Add(-time.Second)},
+		Ranges:      []Range{Between(Position{1, 0}, 0, 0), AtLeast(Position{1, 0}, -1), AtMost(Position{1, 0}, 0)},
+		Memberships: []Membership{MemberOf(Position{1, 0}, NumericValue(0))},
+	}
+	values := AttributeValues{{1, 0}: NumericValue(0), {2, 1}: ExpiryValue(epoch)}
+
+	proof, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, predicates, values)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.Verify(pk, ys, h, pkNym, Indices{}, []byte("message"), predicates))
+
+	_, e = creds.ProveWithPredicates(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, Predicates{Ranges: []Range{AtLeast(Position{1, 0}, 1)}}, values)
+	assert.ErrorContains(t, e, "attribute (1, 0) is out of the bound")
+}
+
+func testPredicatesRangeOutOfBounds(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 1, 2, map[Position]interface{}{{1, 0}: NumericAttribute(1, 17)})
+	values := AttributeValues{{1, 0}: NumericValue(17)}
+	m := []byte("message")
+
+	for _, r := range []Range{
+		AtLeast(Position{1, 0}, 18),
+		AtMost(Position{1, 0}, 16),
+		Between(Position{1, 0}, 18, 65),
+		Between(Position{1, 0}, 0, 16),
+		Between(Position{1, 0}, 16, 16),
+	} {
+		_, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, m, ys, h, skNym, Predicates{Ranges: []Range{r}}, values)
+		assert.ErrorContains(t, e, "attribute (1, 0) is out of the bound")
+	}
+
+	// the proof of one range does not pass for another
+	proof, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, m, ys, h, skNym, Predicates{Ranges: []Range{Between(Position{1, 0}, 0, 17)}}, values)
+	assert.NilError(t, e)
+
+	for _, r := range []Range{Between(Position{1, 0}, 1, 17), Between(Position{1, 0}, 0, 18), Between(Position{1, 0}, 18, 35)} {
+		assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, Indices{}, m, Predicates{Ranges: []Range{r}}), "verification failed")
+	}
+	assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, Indices{}, m, Predicates{Ranges: []Range{AtLeast(Position{1, 0}, 0)}}), "2 range proofs for 1 bounds")
+}
+
+func testPredicatesRangeErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 1, 2, map[Position]interface{}{{1, 0}: NumericAttribute(1, 17)})
+	values := AttributeValues{{1, 0}: NumericValue(17)}
+
+	for _, tc := range []struct {
+		r        Range
+		expected string
+	}{
+		{Range{Position: Position{1, 0}}, "range of attribute (1, 0) has no bounds"},
+		{Between(Position{1, 0}, 18, 17), "range of attribute (1, 0) is empty"},
+	} {
+		predicates := Predicates{Ranges: []Range{tc.r}}
+
+		_, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, predicates, values)
+		assert.ErrorContains(t, e, tc.expected)
+
+		var proof PredicateProof
+		assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, Indices{}, []byte("message"), predicates), tc.expected)
+	}
+}
+
+func testPredicatesMembership(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	// the country in G1 (level 1) and the department in G2 (level 2)
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{
+		{1, 0}: ProduceAttributes(1, "CA")[0],
+		{2, 1}: ProduceAttributes(2, "engineering")[0],
+	})
+	values := AttributeValues{
+		{1, 0}: StringValue("CA"),
+		{2, 1}: StringValue("engineering"),
+	}
+	countries := membershipSet("US", "CA", "MX")
+
+	D := Indices{{1, 1, creds.Attributes[1][1]}}
+	m := []byte("message")
+
+	for name, memberships := range map[string][]Membership{
+		"member":     {MemberOf(Position{1, 0}, countries...)},
+		"not member": {NotMemberOf(Position{2, 1}, membershipSet("sales", "legal")...)},
+		"both":       {MemberOf(Position{2, 1}, membershipSet("engineering", "research")...), NotMemberOf(Position{1, 0}, membershipSet("FR", "DE")...)},
+		"same attribute": {
+			MemberOf(Position{1, 0}, countries...),
+			NotMemberOf(Position{1, 0}, membershipSet("US")...),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			predicates := Predicates{Memberships: memberships}
+
+			proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, values)
+			assert.NilError(t, e)
+
+			assert.NilError(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates))
+
+			recovered, e := ParsePredicateProof(proof.ToBytes())
+			assert.NilError(t, e)
+			assert.NilError(t, recovered.Verify(pk, ys, h, pkNym, D, m, predicates))
+		})
+	}
+}
+
+func testPredicatesMembershipFail(t *testing.T) {
+	type TestCase string
+	const (
+		AnotherSet    TestCase = "another set"
+		Reordered     TestCase = "set reordered"
+		Negated       TestCase = "negated"
+		Challenge     TestCase = "branch challenge"
+		Commitment    TestCase = "commitment"
+		Dropped       TestCase = "set membership proof dropped"
+		EmptySet      TestCase = "empty set"
+		NotInSet      TestCase = "value not in the set"
+		InSet         TestCase = "value in the set"
+		CredsDetached TestCase = "credentials proof detached"
+	)
+
+	for _, tc := range []TestCase{AnotherSet, Reordered, Negated, Challenge, Commitment, Dropped, EmptySet, NotInSet, InSet, CredsDetached} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 1, 2, map[Position]interface{}{{1, 0}: ProduceAttributes(1, "CA")[0]})
+			values := AttributeValues{{1, 0}: StringValue("CA")}
+
+			D := Indices{}
+			m := []byte("message")
+			predicates := Predicates{Memberships: []Membership{MemberOf(Position{1, 0}, membershipSet("US", "CA", "MX")...)}}
+
+			proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, values)
+			assert.NilError(t, e)
+
+			expected := "verification failed"
+			switch tc {
+			case AnotherSet:
+				predicates.Memberships[0].Set = membershipSet("US", "FR", "MX")
+			case Reordered:
+				predicates.Memberships[0].Set = membershipSet("CA", "US", "MX")
+			case Negated:
+				predicates.Memberships[0].Negated = true
+				expected = "does not fit the set"
+			case Challenge:
+				proof.memberships[0].c[0] = FP256BN.NewBIGint(0x13)
+				expected = "do not sum up to c"
+			case Commitment:
+				proof.memberships[0].com = FP256BN.ECP_generator()
+			case Dropped:
+				proof.memberships = nil
+				expected = "0 set membership proofs for 1 sets"
+			case EmptySet:
+				predicates.Memberships[0].Set = nil
+				expected = "set of attribute (1, 0) is empty"
+			case NotInSet:
+				predicates.Memberships[0].Set = membershipSet("US", "MX")
+				_, e = creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, values)
+				assert.ErrorContains(t, e, "attribute (1, 0) is not in the set")
+				return
+			case InSet:
+				predicates.Memberships[0].Negated = true
+				_, e = creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, values)
+				assert.ErrorContains(t, e, "attribute (1, 0) is in the set")
+				return
+			case CredsDetached:
+				e = proof.proof.VerifyProof(pk, ys, h, pkNym, D, m)
+				assert.ErrorContains(t, e, "verification failed")
+				return
+			}
+
+			assert.ErrorContains(t, proof.Verify(pk, ys, h, pkNym, D, m, predicates), expected)
+		})
+	}
+}
+
+func testPredicatesMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	expiry := _Now.Add(time.Hour)
+	creds, sk, pk, ys, skNym, pkNym, h := generateChainWith(SEED, 2, 2, map[Position]interface{}{{1, 0}: ExpiryAttribute(1, expiry)})
+
+	D := Indices{{2, 0, creds.Attributes[2][0]}}
+	m := []byte("message")
+	predicates := Predicates{Expiry: &Expiry{Position{1, 0}, _Now}}
+
+	proof, e := creds.ProveWithPredicates(prg, sk, pk, D, m, ys, h, skNym, predicates, AttributeValues{{1, 0}: ExpiryValue(expiry)})
+	assert.NilError(t, e)
+
+	recovered, e := ParsePredicateProof(proof.ToBytes())
+	assert.NilError(t, e)
+
+	assert.NilError(t, recovered.Verify(pk, ys, h, pkNym, D, m, predicates))
+	assert.DeepEqual(t, recovered.ToBytes(), proof.ToBytes())
+}
+
+func testPredicatesParseRejectsMalformed(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	expiry := _Now.Add(time.Hour)
+	creds, sk, pk, ys, skNym, _, h := generateChainWith(SEED, 1, 2, map[Position]interface{}{{1, 0}: ExpiryAttribute(1, expiry)})
+
+	proof, e := creds.ProveWithPredicates(prg, sk, pk, Indices{}, []byte("message"), ys, h, skNym, Predicates{Expiry: &Expiry{Position{1, 0}, _Now}}, AttributeValues{{1, 0}: ExpiryValue(expiry)})
+	assert.NilError(t, e)
+
+	type TestCase string
+	const (
+		Trailing TestCase = "trailing bytes"
+		Proof    TestCase = "credentials proof"
+		ResX     TestCase = "response of the value"
+		Range    TestCase = "range proof"
+	)
+
+	for _, tc := range []TestCase{Trailing, Proof, ResX, Range} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal predicateProofMarshal
+			var expected string
+			input := remarshal(t, proof.ToBytes(), &marshal, func() {
+				switch tc {
+				case Proof:
+					marshal.Proof = marshal.Proof[1:]
+					expected = "ParseProof"
+				case ResX:
+					marshal.ResX[0] = orderBytes()
+					expected = "order"
+				case Range:
+					marshal.Ranges[0].C0 = marshal.Ranges[0].C0[1:]
+					expected = "range proof 0: lengths"
+				}
+			})
+			if tc == Trailing {
+				input = append(input, 0x13)
+				expected = "trailing"
+			}
+
+			_, e := ParsePredicateProof(input)
+			assert.ErrorContains(t, e, expected)
+		})
+	}
+}
@@ -30,6 +30,12 @@ func GenerateNymKeys(prg *amcl.RAND, sk SK, h interface{}) (skNym SK, pkNym PK)
 
 // SignNym generates a proof of knowledge of pseudonym's secret key sk and randomness skNym
 func SignNym(prg *amcl.RAND, pkNym PK, skNym SK, sk SK, h interface{}, m []byte) (signature NymSignature) {
+	return SignNymWithContext(prg, pkNym, skNym, sk, h, m, "")
+}
+
+// SignNymWithContext is SignNym that binds the signature to the caller-supplied context.
+// The same context has to be supplied to VerifyNymWithContext.
+func SignNymWithContext(prg *amcl.RAND, pkNym PK, skNym SK, sk SK, h interface{}, m []byte, context string) (signature NymSignature) {
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 	g := generatorSameGroup(h)
 
@@ -38,7 +44,7 @@ func SignNym(prg *amcl.RAND, pkNym PK, skNym SK, sk SK, h interface{}, m []byte)
 
 	signature.commitment = productOfExponents(g, t1, h, t2)
 
-	c := hashNym(q, signature.commitment, pkNym, m)
+	c := hashNym(q, context, h, signature.commitment, pkNym, m)
 
 	signature.resSk = FP256BN.Modmul(sk, c, q).Plus(t1)
 	signature.resSk.Mod(q)
@@ -51,10 +57,19 @@ func SignNym(prg *amcl.RAND, pkNym PK, skNym SK, sk SK, h interface{}, m []byte)
 
 // VerifyNym verifies the proof of knowledge of pseudonym's secret key sk and randomness skNym
 func (signature *NymSignature) VerifyNym(h interface{}, pkNym PK, m []byte) (e error) {
+	return signature.VerifyNymWithContext(h, pkNym, m, "")
+}
+
+// VerifyNymWithContext is VerifyNym for the signature bound to the context
+func (signature *NymSignature) VerifyNymWithContext(h interface{}, pkNym PK, m []byte, context string) (e error) {
+	if e = validatePoints(signature.commitment, pkNym); e != nil {
+		return fmt.Errorf("VerifyNym: invalid commitment or pkNym: %v", e)
+	}
+
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 	g := generatorSameGroup(h)
 
-	c := hashNym(q, signature.commitment, pkNym, m)
+	c := hashNym(q, context, h, signature.commitment, pkNym, m)
 
 	LHS := pointMultiply(pkNym, c)
 	pointAdd(LHS, signature.commitment)
@@ -68,13 +83,15 @@ func (signature *NymSignature) VerifyNym(h interface{}, pkNym PK, m []byte) (e e
 	return
 }
 
-func hashNym(q *FP256BN.BIG, commitment interface{}, pkNym PK, m []byte) *FP256BN.BIG {
-	var raw []byte
-	raw = append(raw, PointToBytes(commitment)...)
-	raw = append(raw, PointToBytes(pkNym)...)
-	raw = append(raw, m...)
+func hashNym(q *FP256BN.BIG, context string, h interface{}, commitment interface{}, pkNym PK, m []byte) *FP256BN.BIG {
+	t := newTranscript(_ProtocolNym, binding{context: context})
 
-	return sha3(q, raw)
+	t.appendPoint("h", h)
+	t.appendPoint("commitment", commitment)
+	t.appendPoint("pkNym", pkNym)
+	t.append("m", m)
+
+	return t.challenge(q)
 }
 
 type nymSignatureMarshal struct {
@@ -97,17 +114,35 @@ func (signature *NymSignature) ToBytes() (result []byte) {
 }
 
 // NymSignatureFromBytes un-marshals the NIZK object using ASN1 encoding
+// Panics if the input is malformed, see ParseNymSignature for a version that returns error.
 func NymSignatureFromBytes(input []byte) (signature *NymSignature) {
+	signature, e := ParseNymSignature(input)
+	if e != nil {
+		panic("un-marshalling nym-signature failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseNymSignature un-marshals and validates the NIZK object using ASN1 encoding
+func ParseNymSignature(input []byte) (signature *NymSignature, e error) {
 	var marshal nymSignatureMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling nym-signature failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseNymSignature: %v", e)
 	}
 
 	signature = &NymSignature{}
 
-	signature.commitment, _ = PointFromBytes(marshal.Commitment)
-	signature.resSk = FP256BN.FromBytes(marshal.ResSk)
-	signature.resSkNym = FP256BN.FromBytes(marshal.ResSkNym)
+	if signature.commitment, _, e = requiredPointFromBytes(marshal.Commitment); e != nil {
+		return nil, fmt.Errorf("ParseNymSignature: commitment: %v", e)
+	}
+
+	scalars, e := bigsFromBytes(marshal.ResSk, marshal.ResSkNym)
+	if e != nil {
+		return nil, fmt.Errorf("ParseNymSignature: %v", e)
+	}
+	signature.resSk = scalars[0]
+	signature.resSkNym = scalars[1]
 
 	return
 }
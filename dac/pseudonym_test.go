@@ -54,7 +54,11 @@ func TestNym(t *testing.T) {
 				testNymVerifyNoCrash,
 				testNymVerifyTamperedSignature,
 				testNymVerifyWrongMessage,
+				testNymVerifyWrongContext,
 				testNymUnMarshalingFail,
+				testNymParse,
+				testNymParseRejectsMalformed,
+				testNymRejectsInvalidPoints,
 			} {
 				t.Run(funcToString(reflect.ValueOf(test)), test)
 			}
@@ -131,6 +135,18 @@ func testNymVerifyWrongMessage(t *testing.T) {
 	assert.ErrorContains(t, verifyError, "verification")
 }
 
+// signature is bound to the context
+func testNymVerifyWrongContext(t *testing.T) {
+	sk, h, prg := generateCredKeys()
+	skNym, pkNym := GenerateNymKeys(prg, sk, h)
+
+	signature := SignNymWithContext(prg, pkNym, skNym, sk, h, []byte("Message"), "a")
+
+	assert.NilError(t, signature.VerifyNymWithContext(h, pkNym, []byte("Message"), "a"))
+	assert.ErrorContains(t, signature.VerifyNymWithContext(h, pkNym, []byte("Message"), "b"), "verification")
+	assert.ErrorContains(t, signature.VerifyNym(h, pkNym, []byte("Message")), "verification")
+}
+
 // verify rejects tampered signature
 func testNymVerifyTamperedSignature(t *testing.T) {
 	type TestCase string
@@ -232,6 +248,80 @@ func testNymUnMarshalingFail(t *testing.T) {
 	NymSignatureFromBytes([]byte{0x13})
 }
 
+// parsing yields the original object
+func testNymParse(t *testing.T) {
+	sk, h, prg := generateCredKeys()
+	skNym, pkNym := GenerateNymKeys(prg, sk, h)
+	signature := SignNym(prg, pkNym, skNym, sk, h, []byte("Message"))
+
+	recovered, e := ParseNymSignature(signature.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Check(t, signature.equals(recovered))
+}
+
+// parsing rejects malformed input without panicking
+func testNymParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed   TestCase = "malformed ASN1"
+		OffCurve    TestCase = "commitment not on curve"
+		Infinity    TestCase = "commitment at infinity"
+		Missing     TestCase = "commitment missing"
+		LargeScalar TestCase = "scalar not reduced"
+	)
+
+	sk, h, prg := generateCredKeys()
+	skNym, pkNym := GenerateNymKeys(prg, sk, h)
+	signature := SignNym(prg, pkNym, skNym, sk, h, []byte("Message"))
+
+	for _, tc := range []TestCase{Malformed, OffCurve, Infinity, Missing, LargeScalar} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal nymSignatureMarshal
+			bytes := remarshal(t, signature.ToBytes(), &marshal, func() {
+				switch tc {
+				case OffCurve:
+					marshal.Commitment = offCurveBytes(hFirst)
+				case Infinity:
+					marshal.Commitment = infinityBytes(hFirst)
+				case Missing:
+					marshal.Commitment = nil
+				case LargeScalar:
+					marshal.ResSkNym = orderBytes()
+				}
+			})
+
+			if tc == Malformed {
+				bytes = bytes[1:]
+			}
+
+			_, e := ParseNymSignature(bytes)
+			assert.ErrorContains(t, e, "ParseNymSignature")
+		})
+	}
+}
+
+// verification rejects points outside of the prime-order subgroups
+func testNymRejectsInvalidPoints(t *testing.T) {
+	sk, h, prg := generateCredKeys()
+	skNym, pkNym := GenerateNymKeys(prg, sk, h)
+
+	signature := SignNym(prg, pkNym, skNym, sk, h, []byte("Message"))
+
+	for name, point := range invalidPointsLike(h) {
+		t.Run("commitment "+name, func(t *testing.T) {
+			tampered := signature
+			tampered.commitment = point
+
+			assert.ErrorContains(t, tampered.VerifyNym(h, pkNym, []byte("Message")), "invalid")
+		})
+
+		t.Run("pkNym "+name, func(t *testing.T) {
+			assert.ErrorContains(t, signature.VerifyNym(h, point, []byte("Message")), "invalid")
+		})
+	}
+}
+
 // Benchmarks
 
 func BenchmarkNym(b *testing.B) {
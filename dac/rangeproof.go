@@ -0,0 +1,298 @@
+package dac
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// _PedersenBase is the second generator of the Pedersen commitments in G1,
+// nobody knows its discrete logarithm to the generator
+var _PedersenBase = HashToG1(_DSTPedersen, []byte("h"))
+
+// bound is the statement that sign*x - offset is in [0, 2^bits),
+// where x is the exponent of the hidden attribute at Position (the attribute is g^x) and sign is -1 if negative.
+// x >= a is {negative: false, offset: a}, x <= b is {negative: true, offset: -b}.
+type bound struct {
+	Position
+	negative bool
+	offset   *FP256BN.BIG
+	bits     int
+}
+
+// rangeProof proves a bound by committing to the bits of sign*x - offset.
+// Each bit commitment comes with a proof that it commits to 0 or 1 (c1 = c - c0),
+// and the weighted sum of the commitments is linked to the attribute's exponent through resR.
+type rangeProof struct {
+	coms []*FP256BN.ECP
+	c0   []*FP256BN.BIG
+	z0   []*FP256BN.BIG
+	z1   []*FP256BN.BIG
+	resR *FP256BN.BIG
+}
+
+// rangeProver holds the state of the range proof generation between the commitments and the responses
+type rangeProver struct {
+	bits  []bool
+	rs    []*FP256BN.BIG
+	ws    []*FP256BN.BIG
+	fakeC []*FP256BN.BIG
+	fakeZ []*FP256BN.BIG
+	r     *FP256BN.BIG
+	rhoR  *FP256BN.BIG
+
+	coms  []*FP256BN.ECP
+	t0    []*FP256BN.ECP
+	t1    []*FP256BN.ECP
+	tLink *FP256BN.ECP
+}
+
+// rangeCommit commits to the bits of sign*x - offset.
+// rhoX is the randomness of x shared with the other parts of the proof.
+// Returns error if the value is out of the bound.
+func rangeCommit(prg *amcl.RAND, b bound, x *FP256BN.BIG, rhoX *FP256BN.BIG) (prover *rangeProver, e error) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	g := FP256BN.ECP_generator()
+
+	delta := b.apply(x)
+	if FP256BN.Comp(delta, powerOfTwo(b.bits)) >= 0 {
+		return nil, fmt.Errorf("attribute (%d, %d) is out of the bound", b.I, b.J)
+	}
+	raw := bigToBytes(delta)
+
+	prover = &rangeProver{
+		bits:  make([]bool, b.bits),
+		rs:    make([]*FP256BN.BIG, b.bits),
+		ws:    make([]*FP256BN.BIG, b.bits),
+		fakeC: make([]*FP256BN.BIG, b.bits),
+		fakeZ: make([]*FP256BN.BIG, b.bits),
+		coms:  make([]*FP256BN.ECP, b.bits),
+		t0:    make([]*FP256BN.ECP, b.bits),
+		t1:    make([]*FP256BN.ECP, b.bits),
+		r:     FP256BN.NewBIGint(0),
+		rhoR:  FP256BN.Randomnum(q, prg),
+	}
+
+	for l := 0; l < b.bits; l++ {
+		prover.bits[l] = (raw[_BIGByteLength-1-l/8]>>(l%8))&1 == 1
+		prover.rs[l] = FP256BN.Randomnum(q, prg)
+		prover.ws[l] = FP256BN.Randomnum(q, prg)
+		prover.fakeC[l] = FP256BN.Randomnum(q, prg)
+		prover.fakeZ[l] = FP256BN.Randomnum(q, prg)
+
+		prover.coms[l] = _PedersenBase.Mul(prover.rs[l])
+		if prover.bits[l] {
+			prover.coms[l].Add(g)
+
+			// the first branch (commitment to 0) is simulated
+			prover.t0[l] = _PedersenBase.Mul2(prover.fakeZ[l], prover.coms[l], bigNegate(prover.fakeC[l], q))
+			prover.t1[l] = _PedersenBase.Mul(prover.ws[l])
+		} else {
+			// the second branch (commitment to 1) is simulated
+			prover.t0[l] = _PedersenBase.Mul(prover.ws[l])
+			prover.t1[l] = _PedersenBase.Mul2(prover.fakeZ[l], bitShifted(prover.coms[l]), bigNegate(prover.fakeC[l], q))
+		}
+
+		// r = sum 2^l * r_l
+		prover.r = prover.r.Plus(FP256BN.Modmul(powerOfTwo(l), prover.rs[l], q))
+		prover.r.Mod(q)
+	}
+
+	prover.tLink = b.base().Mul2(rhoX, _PedersenBase, prover.rhoR)
+
+	return
+}
+
+// respond computes the responses of the range proof for the challenge c
+func (prover *rangeProver) respond(c *FP256BN.BIG) (proof rangeProof) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	bits := len(prover.bits)
+
+	proof.coms = prover.coms
+	proof.c0 = make([]*FP256BN.BIG, bits)
+	proof.z0 = make([]*FP256BN.BIG, bits)
+	proof.z1 = make([]*FP256BN.BIG, bits)
+
+	for l := 0; l < bits; l++ {
+		// the real branch gets the rest of the challenge
+		realC := bigMinusMod(c, prover.fakeC[l], q)
+		realZ := FP256BN.Modmul(realC, prover.rs[l], q)
+		realZ = realZ.Plus(prover.ws[l])
+		realZ.Mod(q)
+
+		if prover.bits[l] {
+			proof.c0[l], proof.z0[l], proof.z1[l] = prover.fakeC[l], prover.fakeZ[l], realZ
+		} else {
+			proof.c0[l], proof.z0[l], proof.z1[l] = realC, realZ, prover.fakeZ[l]
+		}
+	}
+
+	proof.resR = FP256BN.Modmul(c, prover.r, q)
+	proof.resR = proof.resR.Plus(prover.rhoR)
+	proof.resR.Mod(q)
+
+	return
+}
+
+// commitments recomputes the commitments of the range proof from the challenge c and the response resX of the attribute's exponent
+func (proof *rangeProof) commitments(b bound, c *FP256BN.BIG, resX *FP256BN.BIG) (t0 []*FP256BN.ECP, t1 []*FP256BN.ECP, tLink *FP256BN.ECP) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	bits := len(proof.coms)
+
+	t0 = make([]*FP256BN.ECP, bits)
+	t1 = make([]*FP256BN.ECP, bits)
+
+	// y = prod com_l^(2^l) = g^(sign*x - offset) * h^r, computed by Horner's rule
+	y := FP256BN.NewECP()
+	for l := bits - 1; l >= 0; l-- {
+		y.Add(y)
+		y.Add(proof.coms[l])
+
+		c1 := bigMinusMod(c, proof.c0[l], q)
+
+		t0[l] = _PedersenBase.Mul2(proof.z0[l], proof.coms[l], bigNegate(proof.c0[l], q))
+		t1[l] = _PedersenBase.Mul2(proof.z1[l], bitShifted(proof.coms[l]), bigNegate(c1, q))
+	}
+
+	// y * g^offset = (g^sign)^x * h^r
+	y.Add(FP256BN.ECP_generator().Mul(b.offset))
+
+	tLink = b.base().Mul2(resX, _PedersenBase, proof.resR)
+	tLink.Add(y.Mul(bigNegate(c, q)))
+
+	return
+}
+
+// validate checks that the range proof has all the components for the bound
+func (proof *rangeProof) validate(b bound) (e error) {
+	if len(proof.coms) != b.bits || len(proof.c0) != b.bits || len(proof.z0) != b.bits || len(proof.z1) != b.bits {
+		return fmt.Errorf("range proof for attribute (%d, %d) must have %d bits", b.I, b.J, b.bits)
+	}
+	if proof.resR == nil {
+		return fmt.Errorf("range proof for attribute (%d, %d) is incomplete", b.I, b.J)
+	}
+	for l := 0; l < b.bits; l++ {
+		if e = ValidatePoint(proof.coms[l]); e != nil {
+			return fmt.Errorf("range proof for attribute (%d, %d): bit %d: %v", b.I, b.J, l, e)
+		}
+		if proof.c0[l] == nil || proof.z0[l] == nil || proof.z1[l] == nil {
+			return fmt.Errorf("range proof for attribute (%d, %d): bit %d is incomplete", b.I, b.J, l)
+		}
+	}
+
+	return
+}
+
+// apply computes sign*x - offset
+func (b bound) apply(x *FP256BN.BIG) *FP256BN.BIG {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	if b.negative {
+		x = bigNegate(x, q)
+	}
+	return bigMinusMod(x, b.offset, q)
+}
+
+// base is g^sign
+func (b bound) base() *FP256BN.ECP {
+	g := FP256BN.ECP_generator()
+	if b.negative {
+		g.Neg()
+	}
+	return g
+}
+
+// bitShifted computes com / g, which is a commitment to 0 if com commits to 1
+func bitShifted(com *FP256BN.ECP) (shifted *FP256BN.ECP) {
+	shifted = FP256BN.NewECP()
+	shifted.Copy(com)
+	shifted.Sub(FP256BN.ECP_generator())
+
+	return
+}
+
+// powerOfTwo computes 2^l for l < 256
+func powerOfTwo(l int) *FP256BN.BIG {
+	raw := make([]byte, _BIGByteLength)
+	raw[_BIGByteLength-1-l/8] = 1 << uint(l%8)
+
+	return FP256BN.FromBytes(raw)
+}
+
+func appendRange(t *transcript, b bound, coms []*FP256BN.ECP, t0 []*FP256BN.ECP, t1 []*FP256BN.ECP, tLink *FP256BN.ECP) {
+	t.append("i", []byte(strconv.Itoa(b.I)))
+	t.append("j", []byte(strconv.Itoa(b.J)))
+	t.append("negative", []byte(strconv.FormatBool(b.negative)))
+	t.appendBig("offset", b.offset)
+	t.append("bits", []byte(strconv.Itoa(b.bits)))
+	for l := range coms {
+		t.appendPoint("com", coms[l])
+		t.appendPoint("t0", t0[l])
+		t.appendPoint("t1", t1[l])
+	}
+	t.appendPoint("tLink", tLink)
+}
+
+type rangeProofMarshal struct {
+	Coms [][]byte
+	C0   [][]byte
+	Z0   [][]byte
+	Z1   [][]byte
+	ResR []byte
+}
+
+func (proof *rangeProof) marshal() (marshal rangeProofMarshal) {
+	marshal.Coms = make([][]byte, len(proof.coms))
+	marshal.C0 = make([][]byte, len(proof.c0))
+	marshal.Z0 = make([][]byte, len(proof.z0))
+	marshal.Z1 = make([][]byte, len(proof.z1))
+
+	for l := range proof.coms {
+		marshal.Coms[l] = PointToBytes(proof.coms[l])
+	}
+	for l := range proof.c0 {
+		marshal.C0[l] = bigToBytes(proof.c0[l])
+	}
+	for l := range proof.z0 {
+		marshal.Z0[l] = bigToBytes(proof.z0[l])
+	}
+	for l := range proof.z1 {
+		marshal.Z1[l] = bigToBytes(proof.z1[l])
+	}
+	marshal.ResR = bigToBytes(proof.resR)
+
+	return
+}
+
+func (marshal rangeProofMarshal) unmarshal() (proof rangeProof, e error) {
+	bits := len(marshal.Coms)
+	if len(marshal.C0) != bits || len(marshal.Z0) != bits || len(marshal.Z1) != bits {
+		return proof, fmt.Errorf("lengths of the components do not agree")
+	}
+
+	coms, e := pointsFromBytesInGroup(marshal.Coms, true, false)
+	if e != nil {
+		return proof, fmt.Errorf("coms: %v", e)
+	}
+	proof.coms = make([]*FP256BN.ECP, bits)
+	for l := range coms {
+		proof.coms[l] = coms[l].(*FP256BN.ECP)
+	}
+
+	if proof.c0, e = bigsFromBytes(marshal.C0...); e != nil {
+		return proof, fmt.Errorf("c0: %v", e)
+	}
+	if proof.z0, e = bigsFromBytes(marshal.Z0...); e != nil {
+		return proof, fmt.Errorf("z0: %v", e)
+	}
+	if proof.z1, e = bigsFromBytes(marshal.Z1...); e != nil {
+		return proof, fmt.Errorf("z1: %v", e)
+	}
+	if proof.resR, e = bigFromBytes(marshal.ResR); e != nil {
+		return proof, fmt.Errorf("resR: %v", e)
+	}
+
+	return
+}
@@ -0,0 +1,197 @@
+package dac
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// helper that proves the bound for x and recomputes the commitments the way the verifier does;
+// returns whether they match those of the prover
+func rangeRoundTrip(prg *amcl.RAND, b bound, x *FP256BN.BIG) (matches bool, e error) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	rhoX := FP256BN.Randomnum(q, prg)
+	prover, e := rangeCommit(prg, b, x, rhoX)
+	if e != nil {
+		return
+	}
+
+	c := FP256BN.Randomnum(q, prg)
+	proof := prover.respond(c)
+
+	resX := FP256BN.Modmul(c, x, q)
+	resX = resX.Plus(rhoX)
+	resX.Mod(q)
+
+	t0, t1, tLink := proof.commitments(b, c, resX)
+	for l := range t0 {
+		if !t0[l].Equals(prover.t0[l]) || !t1[l].Equals(prover.t1[l]) {
+			return false, nil
+		}
+	}
+
+	return tLink.Equals(prover.tLink), nil
+}
+
+// Tests
+
+func TestRangeProof(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testRangeProofBounds,
+		testRangeProofOutOfBound,
+		testRangeProofWrongX,
+		testRangeProofValidate,
+		testRangeProofMarshal,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testRangeProofBounds(t *testing.T) {
+	for _, tc := range []struct {
+		negative bool
+		offset   int64
+		bits     int
+		x        int64
+	}{
+		// x - 10 in [0, 256)
+		{false, 10, 8, 10},
+		{false, 10, 8, 11},
+		{false, 10, 8, 265},
+		// 10 - x in [0, 256)
+		{true, -10, 8, 10},
+		{true, -10, 8, -245},
+		// negative values
+		{false, -100, 8, -100},
+		{false, -100, 8, 0},
+		// bit lengths not divisible by 8
+		{false, 0, 1, 1},
+		{false, 0, 3, 7},
+		{false, 0, 13, 8191},
+	} {
+		t.Run(fmt.Sprintf("negative=%t offset=%d bits=%d x=%d", tc.negative, tc.offset, tc.bits, tc.x), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			matches, e := rangeRoundTrip(prg, bound{Position{1, 0}, tc.negative, bigFromInt64(tc.offset), tc.bits}, bigFromInt64(tc.x))
+			assert.NilError(t, e)
+			assert.Check(t, matches)
+		})
+	}
+}
+
+func testRangeProofOutOfBound(t *testing.T) {
+	for _, tc := range []struct {
+		negative bool
+		offset   int64
+		bits     int
+		x        int64
+	}{
+		{false, 10, 8, 9},
+		{false, 10, 8, 266},
+		{true, -10, 8, 11},
+		{true, -10, 8, -246},
+		{false, 0, 1, 2},
+		{false, 0, 13, 8192},
+	} {
+		t.Run(fmt.Sprintf("negative=%t offset=%d bits=%d x=%d", tc.negative, tc.offset, tc.bits, tc.x), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			_, e := rangeRoundTrip(prg, bound{Position{1, 2}, tc.negative, bigFromInt64(tc.offset), tc.bits}, bigFromInt64(tc.x))
+			assert.ErrorContains(t, e, "attribute (1, 2) is out of the bound")
+		})
+	}
+}
+
+// the commitments do not match if the response is for another exponent
+func testRangeProofWrongX(t *testing.T) {
+	prg := getNewRand(SEED)
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	b := bound{Position{1, 0}, false, bigFromInt64(10), 8}
+	rhoX := FP256BN.Randomnum(q, prg)
+
+	prover, e := rangeCommit(prg, b, bigFromInt64(20), rhoX)
+	assert.NilError(t, e)
+
+	c := FP256BN.Randomnum(q, prg)
+	proof := prover.respond(c)
+
+	resX := FP256BN.Modmul(c, bigFromInt64(21), q)
+	resX = resX.Plus(rhoX)
+	resX.Mod(q)
+
+	_, _, tLink := proof.commitments(b, c, resX)
+	assert.Check(t, !tLink.Equals(prover.tLink))
+
+	// a commitment to 2 cannot pass for a bit
+	proof.coms[0].Add(FP256BN.ECP_generator())
+	t0, t1, _ := proof.commitments(b, c, resX)
+	assert.Check(t, !t0[0].Equals(prover.t0[0]) && !t1[0].Equals(prover.t1[0]))
+}
+
+func testRangeProofValidate(t *testing.T) {
+	prg := getNewRand(SEED)
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	b := bound{Position{1, 0}, false, bigFromInt64(10), 8}
+	prover, e := rangeCommit(prg, b, bigFromInt64(20), FP256BN.Randomnum(q, prg))
+	assert.NilError(t, e)
+	proof := prover.respond(FP256BN.Randomnum(q, prg))
+
+	assert.NilError(t, proof.validate(b))
+
+	assert.ErrorContains(t, proof.validate(bound{Position{1, 0}, false, bigFromInt64(10), 16}), "must have 16 bits")
+
+	tampered := proof
+	tampered.z1 = append([]*FP256BN.BIG{nil}, proof.z1[1:]...)
+	assert.ErrorContains(t, tampered.validate(b), "bit 0 is incomplete")
+
+	tampered = proof
+	tampered.coms = append([]*FP256BN.ECP{FP256BN.NewECP()}, proof.coms[1:]...)
+	assert.ErrorContains(t, tampered.validate(b), "infinity")
+
+	tampered = proof
+	tampered.resR = nil
+	assert.ErrorContains(t, tampered.validate(b), "incomplete")
+}
+
+func testRangeProofMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	b := bound{Position{1, 0}, false, bigFromInt64(10), 8}
+	prover, e := rangeCommit(prg, b, bigFromInt64(20), FP256BN.Randomnum(q, prg))
+	assert.NilError(t, e)
+	proof := prover.respond(FP256BN.Randomnum(q, prg))
+
+	recovered, e := proof.marshal().unmarshal()
+	assert.NilError(t, e)
+
+	for l := range proof.coms {
+		assert.Check(t, recovered.coms[l].Equals(proof.coms[l]))
+		assert.Check(t, bigEqual(recovered.c0[l], proof.c0[l]))
+		assert.Check(t, bigEqual(recovered.z0[l], proof.z0[l]))
+		assert.Check(t, bigEqual(recovered.z1[l], proof.z1[l]))
+	}
+	assert.Check(t, bigEqual(recovered.resR, proof.resR))
+
+	marshal := proof.marshal()
+	marshal.Z0 = marshal.Z0[1:]
+	_, e = marshal.unmarshal()
+	assert.ErrorContains(t, e, "lengths")
+
+	marshal = proof.marshal()
+	marshal.Coms[0] = PointToBytes(FP256BN.ECP2_generator())
+	_, e = marshal.unmarshal()
+	assert.ErrorContains(t, e, "coms")
+
+	marshal = proof.marshal()
+	marshal.ResR = nil
+	_, e = marshal.unmarshal()
+	assert.ErrorContains(t, e, "resR")
+}
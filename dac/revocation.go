@@ -18,6 +18,9 @@ type RevocationProof struct {
 	res4   *FP256BN.BIG
 	rPrime interface{}
 	sPrime interface{}
+
+	// fingerprint of the system parameters the proof is bound to (empty if not bound)
+	fingerprint []byte
 }
 
 // SignNonRevoke generates a Groth signature of user's public key along with the epoch.
@@ -37,6 +40,50 @@ func SignNonRevoke(prg *amcl.RAND, sk SK, userPk PK, epoch *FP256BN.BIG, ys []in
 
 // RevocationProve generates a NIZK of the Groth signature of user's public key along with the epoch
 func RevocationProve(prg *amcl.RAND, signature GrothSignature, sk SK, skNym SK, epoch *FP256BN.BIG, h interface{}, ys []interface{}) (proof RevocationProof) {
+	return revocationProveBound(prg, signature, sk, skNym, epoch, h, ys, binding{}, nil, nil)
+}
+
+// RevocationProveWithMessage is RevocationProve that also signs the message m and the (optional) verifier's nonce,
+// so that the proof cannot be replayed with another transaction that uses the same pseudonym and epoch.
+// Verify the proof with VerifyWithMessage.
+func RevocationProveWithMessage(prg *amcl.RAND, signature GrothSignature, sk SK, skNym SK, epoch *FP256BN.BIG, h interface{}, ys []interface{}, m []byte, nonce []byte) (proof RevocationProof) {
+	return revocationProveBound(prg, signature, sk, skNym, epoch, h, ys, binding{}, m, nonce)
+}
+
+// revocationProveBound is RevocationProve that binds the proof to the system parameters fingerprint and the context,
+// and signs the message and the nonce (both may be empty)
+func revocationProveBound(prg *amcl.RAND, signature GrothSignature, sk SK, skNym SK, epoch *FP256BN.BIG, h interface{}, ys []interface{}, b binding, m []byte, nonce []byte) (proof RevocationProof) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	prover := revocationCommit(prg, signature, h, ys, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg))
+
+	t := newTranscript(_ProtocolRevocation, b)
+	appendRevocation(t, h, ys, prover.sigmaPrime.r, prover.sigmaPrime.s, prover.com1, prover.com2, prover.com3, epoch)
+	t.append("m", m)
+	t.append("nonce", nonce)
+
+	proof = prover.respond(t.challenge(q), sk, skNym)
+	proof.fingerprint = b.fingerprint
+
+	return
+}
+
+// revocationProver holds the state of the proof generation between the commitments and the responses
+type revocationProver struct {
+	sigmaPrime GrothSignature
+	r1         *FP256BN.BIG
+	r2         *FP256BN.BIG
+	r3         *FP256BN.BIG
+	r4         *FP256BN.BIG
+	com1       *FP256BN.FP12
+	com2       *FP256BN.FP12
+	com3       interface{}
+}
+
+// revocationCommit randomizes the signature and computes the commitments of the proof.
+// rSk and rNym are the randomness for the secret key and the pseudonym secret key,
+// a proof sharing them with other proofs under the same challenge shows that the secret keys are the same.
+func revocationCommit(prg *amcl.RAND, signature GrothSignature, h interface{}, ys []interface{}, rSk *FP256BN.BIG, rNym *FP256BN.BIG) (prover *revocationProver) {
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 
 	var g1, g2 interface{}
@@ -52,39 +99,90 @@ func RevocationProve(prg *amcl.RAND, signature GrothSignature, sk SK, skNym SK,
 	g1Neg := pointNegate(g1)
 
 	groth := MakeGroth(prg, first, ys)
-	sigmaPrime := groth.Randomize(signature, nil)
 
-	r1 := FP256BN.Randomnum(q, prg)
-	r2 := FP256BN.Randomnum(q, prg)
-	r3 := FP256BN.Randomnum(q, prg)
-	r4 := FP256BN.Randomnum(q, prg)
+	prover = &revocationProver{
+		sigmaPrime: groth.Randomize(signature, nil),
+		r1:         FP256BN.Randomnum(q, prg),
+		r2:         rSk,
+		r3:         FP256BN.Randomnum(q, prg),
+		r4:         rNym,
+	}
+
+	prover.com1 = FP256BN.Fexp(ate2(prover.sigmaPrime.r, pointMultiply(g2, prover.r1), g1Neg, pointMultiply(g2, prover.r2)))
+	prover.com2 = FP256BN.Fexp(ate(prover.sigmaPrime.r, pointMultiply(g2, prover.r3)))
+	prover.com3 = productOfExponents(g1, prover.r2, h, prover.r4)
+
+	return
+}
 
-	com1 := FP256BN.Fexp(ate2(sigmaPrime.r, pointMultiply(g2, r1), g1Neg, pointMultiply(g2, r2)))
-	com2 := FP256BN.Fexp(ate(sigmaPrime.r, pointMultiply(g2, r3)))
-	com3 := productOfExponents(g1, r2, h, r4)
+// respond computes the responses of the proof for the challenge c
+func (prover *revocationProver) respond(c *FP256BN.BIG, sk SK, skNym SK) (proof RevocationProof) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	g2 := generatorSameGroup(prover.sigmaPrime.ts[0])
 
-	proof.c = hashRevocation(q, h, sigmaPrime.r, sigmaPrime.s, com1, com2, com3, epoch)
+	proof.c = c
 
-	proof.res1 = productOfExponents(g2, r1, sigmaPrime.ts[0], proof.c)
+	proof.res1 = productOfExponents(g2, prover.r1, prover.sigmaPrime.ts[0], proof.c)
 
 	proof.res2 = FP256BN.Modmul(proof.c, sk, q)
-	proof.res2 = proof.res2.Plus(r2)
+	proof.res2 = proof.res2.Plus(prover.r2)
 	proof.res2.Mod(q)
 
-	proof.res3 = productOfExponents(g2, r3, sigmaPrime.ts[1], proof.c)
+	proof.res3 = productOfExponents(g2, prover.r3, prover.sigmaPrime.ts[1], proof.c)
 
 	proof.res4 = FP256BN.Modmul(proof.c, skNym, q)
-	proof.res4 = proof.res4.Plus(r4)
+	proof.res4 = proof.res4.Plus(prover.r4)
 	proof.res4.Mod(q)
 
-	proof.rPrime = sigmaPrime.r
-	proof.sPrime = sigmaPrime.s
+	proof.rPrime = prover.sigmaPrime.r
+	proof.sPrime = prover.sigmaPrime.s
 
 	return
 }
 
 // Verify validates the NIZK of the Groth signature of user's public key along with the epoch
 func (proof *RevocationProof) Verify(pkNym PK, epoch *FP256BN.BIG, h interface{}, pkRev PK, ys []interface{}) (e error) {
+	return proof.verify(pkNym, epoch, h, pkRev, ys, "", nil, nil)
+}
+
+// VerifyWithMessage validates the proof generated with RevocationProveWithMessage.
+// The message and the nonce have to be the ones the proof was generated with.
+func (proof *RevocationProof) VerifyWithMessage(pkNym PK, epoch *FP256BN.BIG, h interface{}, pkRev PK, ys []interface{}, m []byte, nonce []byte) (e error) {
+	return proof.verify(pkNym, epoch, h, pkRev, ys, "", m, nonce)
+}
+
+// verify is Verify for the proof bound to the context (and to the fingerprint it carries) that signs the message and the nonce
+func (proof *RevocationProof) verify(pkNym PK, epoch *FP256BN.BIG, h interface{}, pkRev PK, ys []interface{}, context string, m []byte, nonce []byte) (e error) {
+	com1, com2, com3, e := proof.commitments(pkNym, epoch, h, pkRev, ys)
+	if e != nil {
+		return
+	}
+
+	t := newTranscript(_ProtocolRevocation, binding{proof.fingerprint, context})
+	appendRevocation(t, h, ys, proof.rPrime, proof.sPrime, com1, com2, com3, epoch)
+	t.append("m", m)
+	t.append("nonce", nonce)
+
+	if !bigEqual(t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order)), proof.c) {
+		e = fmt.Errorf("RevocationProof.Verify: verification failed later at cPrime == c")
+	}
+
+	return
+}
+
+// commitments validates the proof and recomputes its commitments from the challenge and the responses
+func (proof *RevocationProof) commitments(pkNym PK, epoch *FP256BN.BIG, h interface{}, pkRev PK, ys []interface{}) (com1 *FP256BN.FP12, com2 *FP256BN.FP12, com3 interface{}, e error) {
+	return proof.commitmentsWithEpoch(pkNym, FP256BN.Modmul(proof.c, epoch, FP256BN.NewBIGints(FP256BN.CURVE_Order)), h, pkRev, ys)
+}
+
+// commitmentsWithEpoch is commitments where the epoch enters com2 as e(g1, g2)^-epochExponent.
+// For a public epoch the exponent is c * epoch, for a hidden one it is the response for the epoch.
+func (proof *RevocationProof) commitmentsWithEpoch(pkNym PK, epochExponent *FP256BN.BIG, h interface{}, pkRev PK, ys []interface{}) (com1 *FP256BN.FP12, com2 *FP256BN.FP12, com3 interface{}, e error) {
+	if e = validatePoints(proof.rPrime, proof.sPrime, proof.res1, proof.res3, pkNym); e != nil {
+		e = fmt.Errorf("RevocationProof.Verify: invalid proof or pkNym: %v", e)
+		return
+	}
+
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 
 	var g1, g2 interface{}
@@ -107,35 +205,28 @@ func (proof *RevocationProof) Verify(pkNym PK, epoch *FP256BN.BIG, h interface{}
 		return
 	}
 
-	com1 := FP256BN.Fexp(ate2(proof.rPrime, proof.res1, g1Neg, pointMultiply(g2, proof.res2)))
+	com1 = FP256BN.Fexp(ate2(proof.rPrime, proof.res1, g1Neg, pointMultiply(g2, proof.res2)))
 	com1.Mul(FP256BN.Fexp(ate(pointMultiply(pkRev, cNeg), ys[0])))
 
-	com2 := FP256BN.Fexp(ate2(proof.rPrime, proof.res3, pointMultiply(pkRev, cNeg), ys[1]))
-	com2.Mul(FP256BN.Fexp(ate(pointMultiply(g1, cNeg), pointMultiply(g2, epoch))))
+	com2 = FP256BN.Fexp(ate2(proof.rPrime, proof.res3, pointMultiply(pkRev, cNeg), ys[1]))
+	com2.Mul(FP256BN.Fexp(ate(pointMultiply(g1, bigNegate(epochExponent, q)), g2)))
 
-	com3 := productOfExponents(g1, proof.res2, h, proof.res4)
+	com3 = productOfExponents(g1, proof.res2, h, proof.res4)
 	pointAdd(com3, pointMultiply(pkNym, cNeg))
 
-	cPrime := hashRevocation(q, h, proof.rPrime, proof.sPrime, com1, com2, com3, epoch)
-
-	if !bigEqual(cPrime, proof.c) {
-		e = fmt.Errorf("RevocationProof.Verify: verification failed later at cPrime == c")
-	}
-
 	return
 }
 
-func hashRevocation(q *FP256BN.BIG, h, r, s interface{}, com1 *FP256BN.FP12, com2 *FP256BN.FP12, com3 interface{}, epoch *FP256BN.BIG) *FP256BN.BIG {
-	var raw []byte
-	raw = append(raw, PointToBytes(h)...)
-	raw = append(raw, PointToBytes(r)...)
-	raw = append(raw, PointToBytes(s)...)
-	raw = append(raw, fpToBytes(com1)...)
-	raw = append(raw, fpToBytes(com2)...)
-	raw = append(raw, PointToBytes(com3)...)
-	raw = append(raw, bigToBytes(epoch)...)
-
-	return sha3(q, raw)
+// appendRevocation adds the public values and the commitments of the revocation proof to the transcript
+func appendRevocation(t *transcript, h interface{}, ys []interface{}, r, s interface{}, com1 *FP256BN.FP12, com2 *FP256BN.FP12, com3 interface{}, epoch *FP256BN.BIG) {
+	t.appendPoint("h", h)
+	t.appendPoints("ys", ys)
+	t.appendPoint("rPrime", r)
+	t.appendPoint("sPrime", s)
+	t.appendFP("com1", com1)
+	t.appendFP("com2", com2)
+	t.appendPoint("com3", com3)
+	t.appendBig("epoch", epoch)
 }
 
 type revocationProofMarshal struct {
@@ -146,6 +237,8 @@ type revocationProofMarshal struct {
 	Res4   []byte
 	RPrime []byte
 	SPrime []byte
+
+	Fingerprint []byte `asn1:"optional"`
 }
 
 // ToBytes marshals the NIZK object using ASN1 encoding
@@ -159,6 +252,7 @@ func (proof *RevocationProof) ToBytes() (result []byte) {
 	marshal.Res4 = bigToBytes(proof.res4)
 	marshal.RPrime = PointToBytes(proof.rPrime)
 	marshal.SPrime = PointToBytes(proof.sPrime)
+	marshal.Fingerprint = proof.fingerprint
 
 	result, _ = asn1.Marshal(marshal)
 
@@ -166,21 +260,49 @@ func (proof *RevocationProof) ToBytes() (result []byte) {
 }
 
 // RevocationProofFromBytes un-marshals the NIZK object using ASN1 encoding
+// Panics if the input is malformed, see ParseRevocationProof for a version that returns error.
 func RevocationProofFromBytes(input []byte) (proof *RevocationProof) {
+	proof, e := ParseRevocationProof(input)
+	if e != nil {
+		panic("un-marshalling revocation proof failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseRevocationProof un-marshals and validates the NIZK object using ASN1 encoding
+func ParseRevocationProof(input []byte) (proof *RevocationProof, e error) {
 	var marshal revocationProofMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling schnorr signature failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseRevocationProof: %v", e)
 	}
 
 	proof = &RevocationProof{}
 
-	proof.c = FP256BN.FromBytes(marshal.C)
-	proof.res1, _ = PointFromBytes(marshal.Res1)
-	proof.res2 = FP256BN.FromBytes(marshal.Res2)
-	proof.res3, _ = PointFromBytes(marshal.Res3)
-	proof.res4 = FP256BN.FromBytes(marshal.Res4)
-	proof.rPrime, _ = PointFromBytes(marshal.RPrime)
-	proof.sPrime, _ = PointFromBytes(marshal.SPrime)
+	scalars, e := bigsFromBytes(marshal.C, marshal.Res2, marshal.Res4)
+	if e != nil {
+		return nil, fmt.Errorf("ParseRevocationProof: %v", e)
+	}
+	proof.c, proof.res2, proof.res4 = scalars[0], scalars[1], scalars[2]
+
+	if proof.fingerprint, e = fingerprintFromBytes(marshal.Fingerprint); e != nil {
+		return nil, fmt.Errorf("ParseRevocationProof: %v", e)
+	}
+
+	// R' is in the group of h, and S', res1 and res3 are in the opposite group
+	var first bool
+	if proof.rPrime, first, e = requiredPointFromBytes(marshal.RPrime); e != nil {
+		return nil, fmt.Errorf("ParseRevocationProof: rPrime: %v", e)
+	}
+	if proof.sPrime, e = pointFromBytesInGroup(marshal.SPrime, !first, false); e != nil {
+		return nil, fmt.Errorf("ParseRevocationProof: sPrime: %v", e)
+	}
+	if proof.res1, e = pointFromBytesInGroup(marshal.Res1, !first, false); e != nil {
+		return nil, fmt.Errorf("ParseRevocationProof: res1: %v", e)
+	}
+	if proof.res3, e = pointFromBytesInGroup(marshal.Res3, !first, false); e != nil {
+		return nil, fmt.Errorf("ParseRevocationProof: res3: %v", e)
+	}
 
 	return
 }
@@ -11,7 +11,8 @@ import (
 	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
 )
 
-func revocationProve(prg *amcl.RAND, t *testing.T) (pkNym interface{}, epoch *FP256BN.BIG, h interface{}, revokePk interface{}, ys []interface{}, proof RevocationProof) {
+// helper that issues a non-revocation signature for a fresh user
+func revocationSetup(prg *amcl.RAND, t *testing.T) (signature GrothSignature, userSk SK, skNym SK, pkNym interface{}, epoch *FP256BN.BIG, h interface{}, revokePk interface{}, ys []interface{}) {
 
 	const YsNum = 10
 
@@ -33,11 +34,18 @@ func revocationProve(prg *amcl.RAND, t *testing.T) (pkNym interface{}, epoch *FP
 
 	revokeSk, revokePk := groth.Generate()
 
-	signature := SignNonRevoke(prg, revokeSk, userPk, epoch, ys)
+	signature = SignNonRevoke(prg, revokeSk, userPk, epoch, ys)
 
 	assert.Check(t, groth.Verify(revokePk, signature, []interface{}{userPk, pointMultiply(g, epoch)}))
 
-	skNym, pkNym := GenerateNymKeys(prg, userSk, h)
+	skNym, pkNym = GenerateNymKeys(prg, userSk, h)
+
+	return
+}
+
+func revocationProve(prg *amcl.RAND, t *testing.T) (pkNym interface{}, epoch *FP256BN.BIG, h interface{}, revokePk interface{}, ys []interface{}, proof RevocationProof) {
+
+	signature, userSk, skNym, pkNym, epoch, h, revokePk, ys := revocationSetup(prg, t)
 
 	proof = RevocationProve(prg, signature, userSk, skNym, epoch, h, ys)
 
@@ -58,6 +66,10 @@ func TestRevocation(t *testing.T) {
 				testRevocationVerificationFailsLater,
 				testRevocationMarshal,
 				testRevocationUnMarshalFails,
+				testRevocationParse,
+				testRevocationParseRejectsMalformed,
+				testRevocationRejectsInvalidPoints,
+				testRevocationMessage,
 			} {
 				t.Run(funcToString(reflect.ValueOf(test)), test)
 			}
@@ -127,6 +139,144 @@ func testRevocationUnMarshalFails(t *testing.T) {
 	RevocationProofFromBytes([]byte{0x13})
 }
 
+// parsing yields the original object
+func testRevocationParse(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	pkNym, epoch, h, revokePk, ys, proof := revocationProve(prg, t)
+
+	recovered, e := ParseRevocationProof(proof.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Check(t, recovered.Verify(pkNym, epoch, h, revokePk, ys))
+}
+
+// parsing rejects malformed input without panicking
+func testRevocationParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed   TestCase = "malformed ASN1"
+		OffCurve    TestCase = "rPrime not on curve"
+		Infinity    TestCase = "sPrime at infinity"
+		WrongGroup  TestCase = "res1 in wrong group"
+		Missing     TestCase = "res3 missing"
+		LargeScalar TestCase = "scalar not reduced"
+	)
+
+	prg := getNewRand(SEED)
+
+	_, _, _, _, _, proof := revocationProve(prg, t)
+
+	for _, tc := range []TestCase{Malformed, OffCurve, Infinity, WrongGroup, Missing, LargeScalar} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal revocationProofMarshal
+			bytes := remarshal(t, proof.ToBytes(), &marshal, func() {
+				switch tc {
+				case OffCurve:
+					marshal.RPrime = offCurveBytes(hFirst)
+				case Infinity:
+					marshal.SPrime = infinityBytes(!hFirst)
+				case WrongGroup:
+					marshal.Res1 = pointBytes(hFirst)
+				case Missing:
+					marshal.Res3 = nil
+				case LargeScalar:
+					marshal.Res4 = orderBytes()
+				}
+			})
+
+			if tc == Malformed {
+				bytes = bytes[:len(bytes)/2]
+			}
+
+			_, e := ParseRevocationProof(bytes)
+			assert.ErrorContains(t, e, "ParseRevocationProof")
+		})
+	}
+}
+
+// verification rejects points outside of the prime-order subgroups
+func testRevocationRejectsInvalidPoints(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	pkNym, epoch, h, revokePk, ys, proof := revocationProve(prg, t)
+
+	for _, component := range []string{"rPrime", "sPrime", "res1", "res3", "pkNym"} {
+		points := invalidPointsLike(map[string]interface{}{"rPrime": proof.rPrime, "sPrime": proof.sPrime, "res1": proof.res1, "res3": proof.res3, "pkNym": pkNym}[component])
+		points["missing"] = nil
+		for name, point := range points {
+			t.Run(fmt.Sprintf("%s %s", component, name), func(t *testing.T) {
+				tampered := proof
+				tamperedPkNym := pkNym
+				switch component {
+				case "rPrime":
+					tampered.rPrime = point
+				case "sPrime":
+					tampered.sPrime = point
+				case "res1":
+					tampered.res1 = point
+				case "res3":
+					tampered.res3 = point
+				case "pkNym":
+					tamperedPkNym = point
+				}
+
+				e := tampered.Verify(tamperedPkNym, epoch, h, revokePk, ys)
+				assert.ErrorContains(t, e, "invalid")
+			})
+		}
+	}
+}
+
+// proof bound to a message and a nonce cannot be replayed with another message or nonce
+func testRevocationMessage(t *testing.T) {
+	type TestCase string
+	const (
+		Correct      TestCase = "correct"
+		NoNonce      TestCase = "no nonce"
+		WrongMessage TestCase = "wrong message"
+		WrongNonce   TestCase = "wrong nonce"
+		NoMessage    TestCase = "verified without message"
+		Unbound      TestCase = "proof without message"
+	)
+
+	for _, tc := range []TestCase{Correct, NoNonce, WrongMessage, WrongNonce, NoMessage, Unbound} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			signature, userSk, skNym, pkNym, epoch, h, revokePk, ys := revocationSetup(prg, t)
+
+			m, nonce := []byte("transaction"), []byte("nonce")
+			if tc == NoNonce {
+				nonce = nil
+			}
+
+			proof := RevocationProveWithMessage(prg, signature, userSk, skNym, epoch, h, ys, m, nonce)
+
+			var e error
+			switch tc {
+			case Correct, NoNonce:
+				e = proof.VerifyWithMessage(pkNym, epoch, h, revokePk, ys, m, nonce)
+			case WrongMessage:
+				e = proof.VerifyWithMessage(pkNym, epoch, h, revokePk, ys, []byte("another transaction"), nonce)
+			case WrongNonce:
+				e = proof.VerifyWithMessage(pkNym, epoch, h, revokePk, ys, m, []byte("another nonce"))
+			case NoMessage:
+				e = proof.Verify(pkNym, epoch, h, revokePk, ys)
+			case Unbound:
+				unbound := RevocationProve(prg, signature, userSk, skNym, epoch, h, ys)
+				e = unbound.VerifyWithMessage(pkNym, epoch, h, revokePk, ys, m, nonce)
+			}
+
+			if tc == Correct || tc == NoNonce {
+				assert.NilError(t, e)
+			} else {
+				assert.ErrorContains(t, e, "later")
+			}
+		})
+	}
+}
+
 // Benchmarks
 
 func BenchmarkRevocation(b *testing.B) {
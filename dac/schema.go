@@ -0,0 +1,272 @@
+package dac
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Slot names and types an attribute of a credentials level
+type Slot struct {
+	Name string
+	Type ValueType
+}
+
+// Schema names and types the attribute slots of each delegation level: Levels[0] describes level 1 and so on.
+// An attribute is addressed by its path "level<i>.<name>", e.g. "level1.role".
+// Credentials delegated with DelegateWithSchema carry the schema in a reserved attribute signed after the slots of each level
+// (see Schema.Attribute), so that the holder can neither strip nor swap it.
+type Schema struct {
+	Name   string
+	Levels [][]Slot
+}
+
+// MakeSchema makes a schema with the slots of levels 1, 2 and so on.
+// Returns error if a name is empty, contains a dot or repeats within a level, or a type is unknown.
+func MakeSchema(name string, levels ...[]Slot) (schema Schema, e error) {
+	schema = Schema{name, levels}
+	if e = schema.validate(); e != nil {
+		return Schema{}, fmt.Errorf("MakeSchema: %v", e)
+	}
+
+	return
+}
+
+// Hash is the digest of the schema, it covers the name of the schema and the names and types of all slots
+func (schema Schema) Hash() []byte {
+	t := &transcript{}
+	t.append("schema", []byte(schema.Name))
+	t.append("levels", []byte(strconv.Itoa(len(schema.Levels))))
+	for _, slots := range schema.Levels {
+		t.append("slots", []byte(strconv.Itoa(len(slots))))
+		for _, slot := range slots {
+			t.append("name", []byte(slot.Name))
+			t.append("type", []byte{byte(slot.Type)})
+		}
+	}
+
+	return t.digest()
+}
+
+// Attribute is the reserved attribute of level L that binds the credentials to the schema:
+// the generator of the level to the power of the hash of the schema.
+// DelegateWithSchema signs it after the slots of the level, and Policy proofs disclose it at every level.
+func (schema Schema) Attribute(L int) interface{} {
+	t := &transcript{}
+	t.append("schema", schema.Hash())
+
+	return pointMultiply(levelGenerator(L), t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order)))
+}
+
+// indices are the reserved attributes of levels 1 to L disclosed to prove the schema
+func (schema Schema) indices(L int) (D Indices) {
+	for i := 1; i <= L; i++ {
+		D = append(D, Index{i, len(schema.Levels[i-1]), schema.Attribute(i)})
+	}
+
+	return
+}
+
+// Position resolves the path "level<i>.<name>" to the position of the attribute and its type
+func (schema Schema) Position(path string) (position Position, kind ValueType, e error) {
+	dot := strings.Index(path, ".")
+	if dot < 0 || !strings.HasPrefix(path, "level") {
+		return Position{}, 0, fmt.Errorf("path %q is not of the form level<i>.<name>", path)
+	}
+
+	L, e := strconv.Atoi(path[len("level"):dot])
+	if e != nil || L < 1 {
+		return Position{}, 0, fmt.Errorf("path %q is not of the form level<i>.<name>", path)
+	}
+	if L > len(schema.Levels) {
+		return Position{}, 0, fmt.Errorf("schema %q has no level %d", schema.Name, L)
+	}
+
+	for j, slot := range schema.Levels[L-1] {
+		if slot.Name == path[dot+1:] {
+			return Position{L, j}, slot.Type, nil
+		}
+	}
+
+	return Position{}, 0, fmt.Errorf("schema %q has no attribute %q at level %d", schema.Name, path[dot+1:], L)
+}
+
+// Path is the path of the attribute at the position (the inverse of Schema.Position)
+func (schema Schema) Path(position Position) (string, error) {
+	if position.I < 1 || position.I > len(schema.Levels) || position.J < 0 || position.J >= len(schema.Levels[position.I-1]) {
+		return "", fmt.Errorf("schema %q has no attribute (%d, %d)", schema.Name, position.I, position.J)
+	}
+
+	return fmt.Sprintf("level%d.%s", position.I, schema.Levels[position.I-1][position.J].Name), nil
+}
+
+// Encode checks the values of level L against the schema and encodes them as the attributes in the order of the slots.
+// Every slot needs a value of its type, and every value needs a slot.
+func (schema Schema) Encode(L int, values map[string]Value) (attributes []Attribute, e error) {
+	if L < 1 || L > len(schema.Levels) {
+		return nil, fmt.Errorf("schema %q has no level %d", schema.Name, L)
+	}
+	slots := schema.Levels[L-1]
+
+	for name := range values {
+		if _, _, e = schema.Position(fmt.Sprintf("level%d.%s", L, name)); e != nil {
+			return nil, e
+		}
+	}
+
+	attributes = make([]Attribute, len(slots))
+	for j, slot := range slots {
+		value, ok := values[slot.Name]
+		if !ok {
+			return nil, fmt.Errorf("value of level%d.%s is missing", L, slot.Name)
+		}
+		if value.Type() != slot.Type {
+			return nil, fmt.Errorf("level%d.%s must be of type %s, got %s", L, slot.Name, slot.Type, value.Type())
+		}
+		if attributes[j], e = value.Attribute(L); e != nil {
+			return nil, fmt.Errorf("level%d.%s: %v", L, slot.Name, e)
+		}
+	}
+
+	return
+}
+
+// Check makes sure every level of the credentials has the attributes the schema defines followed by the schema's reserved attribute.
+// Note, this does not verify the signatures, use Credentials.Verify for that.
+func (schema Schema) Check(creds *Credentials) error {
+	if e := schema.check(creds); e != nil {
+		return fmt.Errorf("Schema.Check: %v", e)
+	}
+
+	return nil
+}
+
+func (schema Schema) check(creds *Credentials) error {
+	L := len(creds.Attributes) - 1
+	if L > len(schema.Levels) {
+		return fmt.Errorf("credentials have %d levels, schema %q has %d", L, schema.Name, len(schema.Levels))
+	}
+	for i := 1; i <= L; i++ {
+		n := len(schema.Levels[i-1])
+		if len(creds.Attributes[i]) != n+1 {
+			return fmt.Errorf("level %d has %d attributes, schema %q expects %d", i, len(creds.Attributes[i]), schema.Name, n+1)
+		}
+		if !pointEqual(creds.Attributes[i][n], schema.Attribute(i)) {
+			return fmt.Errorf("credentials do not follow schema %q", schema.Name)
+		}
+	}
+
+	return nil
+}
+
+// DiscloseByName builds the disclosure set of the attributes at the paths, e.g. "level1.role".
+// The credentials have to follow the schema.
+func (schema Schema) DiscloseByName(creds *Credentials, paths ...string) (D Indices, e error) {
+	if e = schema.Check(creds); e != nil {
+		return nil, fmt.Errorf("DiscloseByName: %v", e)
+	}
+
+	D = make(Indices, len(paths))
+	for index, path := range paths {
+		position, _, e := schema.Position(path)
+		if e != nil {
+			return nil, fmt.Errorf("DiscloseByName: %v", e)
+		}
+		if position.I >= len(creds.Attributes) {
+			return nil, fmt.Errorf("DiscloseByName: credentials have no level %d", position.I)
+		}
+		D[index] = Index{position.I, position.J, creds.Attributes[position.I][position.J]}
+	}
+
+	return
+}
+
+// DiscloseValuesByName builds the disclosed typed values (see ProveDisclosing) from the values keyed by their paths.
+// Every value has to be of the type of its slot.
+func (schema Schema) DiscloseValuesByName(values map[string]Value) (disclosed DisclosedValues, e error) {
+	for path, value := range values {
+		position, kind, e := schema.Position(path)
+		if e != nil {
+			return nil, fmt.Errorf("DiscloseValuesByName: %v", e)
+		}
+		if value.Type() != kind {
+			return nil, fmt.Errorf("DiscloseValuesByName: %s must be of type %s, got %s", path, kind, value.Type())
+		}
+		disclosed = append(disclosed, value.Disclose(position.I, position.J))
+	}
+
+	// the order of a map is random, while the transcript depends on the order of the disclosed attributes
+	sort.Slice(disclosed, func(a, b int) bool {
+		if disclosed[a].I != disclosed[b].I {
+			return disclosed[a].I < disclosed[b].I
+		}
+		return disclosed[a].J < disclosed[b].J
+	})
+
+	return
+}
+
+// ValueByName looks up the disclosed value at the path and checks that it is of the type of its slot
+func (schema Schema) ValueByName(disclosed DisclosedValues, path string) (value Value, e error) {
+	position, kind, e := schema.Position(path)
+	if e != nil {
+		return Value{}, fmt.Errorf("ValueByName: %v", e)
+	}
+
+	value, ok := disclosed.Value(position.I, position.J)
+	if !ok {
+		return Value{}, fmt.Errorf("ValueByName: %s is not disclosed", path)
+	}
+	if value.Type() != kind {
+		return Value{}, fmt.Errorf("ValueByName: %s must be of type %s, got %s", path, kind, value.Type())
+	}
+
+	return
+}
+
+// DelegateWithSchema is Delegate that checks the values of the new level against the schema, encodes them as the attributes
+// and appends the reserved attribute of the schema (see Schema.Attribute).
+// All levels of the credentials have to be delegated with the same schema.
+func (creds *Credentials) DelegateWithSchema(sk SK, publicKey PK, values map[string]Value, prg *amcl.RAND, grothYs [][]interface{}, schema Schema) (e error) {
+	if e = schema.validate(); e != nil {
+		return fmt.Errorf("DelegateWithSchema: %v", e)
+	}
+
+	L := len(creds.signatures)
+
+	if e = schema.check(creds); e != nil {
+		return fmt.Errorf("DelegateWithSchema: %v", e)
+	}
+
+	attributes, e := schema.Encode(L, values)
+	if e != nil {
+		return fmt.Errorf("DelegateWithSchema: %v", e)
+	}
+
+	return creds.Delegate(sk, publicKey, append(untypedAttributes(attributes), schema.Attribute(L)), prg, grothYs)
+}
+
+// validate checks the names and the types of the slots
+func (schema Schema) validate() error {
+	for i, slots := range schema.Levels {
+		names := make(map[string]bool)
+		for _, slot := range slots {
+			if slot.Name == "" || strings.Contains(slot.Name, ".") {
+				return fmt.Errorf("name %q of an attribute at level %d must be non-empty and have no dots", slot.Name, i+1)
+			}
+			if names[slot.Name] {
+				return fmt.Errorf("attribute %q repeats at level %d", slot.Name, i+1)
+			}
+			if !slot.Type.known() {
+				return fmt.Errorf("attribute %q at level %d is of unknown type %d", slot.Name, i+1, slot.Type)
+			}
+			names[slot.Name] = true
+		}
+	}
+
+	return nil
+}
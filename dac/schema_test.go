@@ -0,0 +1,357 @@
+package dac
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// helper that makes a schema of an organization (level 1), its members (level 2) and their guests (level 3)
+func schemaOrganization() Schema {
+	schema, e := MakeSchema(
+		"organization",
+		[]Slot{{"org", TypeString}, {"founded", TypeTimestamp}},
+		[]Slot{{"role", TypeString}, {"age", TypeInt64}, {"admin", TypeBool}},
+		[]Slot{{"role", TypeString}, {"age", TypeInt64}, {"admin", TypeBool}},
+	)
+	if e != nil {
+		panic(e)
+	}
+
+	return schema
+}
+
+// helper that keys the values of the levels (see chainOptions) by their paths
+func valuesByPath(levels ...map[string]Value) (values map[string]Value) {
+	values = make(map[string]Value)
+	for L, level := range levels {
+		for name, value := range level {
+			values["level"+strconv.Itoa(L+1)+"."+name] = value
+		}
+	}
+
+	return
+}
+
+// helper that delegates a chain of 2 levels following schemaOrganization
+func schemaChain() (creds *Credentials, sk SK, pk PK, ys [][]interface{}, skNym SK, pkNym PK, h interface{}) {
+	schema := schemaOrganization()
+	creds, sk, pk, ys, skNym, pkNym, h, e := generateCustomChain(0, nil, chainOptions{schema: &schema, levels: []map[string]Value{
+		{"org": NewString("acme"), "founded": NewTimestamp(_Now)},
+		{"role": NewString("engineer"), "age": NewInt64(25), "admin": NewBool(false)},
+	}})
+	if e != nil {
+		panic(e)
+	}
+
+	return
+}
+
+// Tests
+
+func TestSchema(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testSchemaMake,
+		testSchemaHash,
+		testSchemaPaths,
+		testSchemaJSON,
+		testSchemaDelegate,
+		testSchemaDelegateErrors,
+		testSchemaMarshal,
+		testSchemaDisclose,
+		testSchemaDiscloseValues,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testSchemaMake(t *testing.T) {
+	for _, tc := range []struct {
+		levels   [][]Slot
+		expected string
+	}{
+		{[][]Slot{{{"", TypeString}}}, `name "" of an attribute at level 1 must be non-empty and have no dots`},
+		{[][]Slot{{}, {{"a.b", TypeString}}}, `name "a.b" of an attribute at level 2 must be non-empty and have no dots`},
+		{[][]Slot{{{"role", TypeString}, {"role", TypeInt64}}}, `attribute "role" repeats at level 1`},
+		{[][]Slot{{{"role", 0x13}}}, `attribute "role" at level 1 is of unknown type 19`},
+	} {
+		_, e := MakeSchema("schema", tc.levels...)
+		assert.ErrorContains(t, e, "MakeSchema: "+tc.expected)
+	}
+
+	// the same name at different levels is fine
+	_, e := MakeSchema("schema", []Slot{{"role", TypeString}}, []Slot{{"role", TypeString}})
+	assert.NilError(t, e)
+}
+
+func testSchemaHash(t *testing.T) {
+	schema := schemaOrganization()
+	assert.Equal(t, len(schema.Hash()), 32)
+	assert.DeepEqual(t, schema.Hash(), schemaOrganization().Hash())
+
+	for _, other := range []Schema{
+		{"another", schema.Levels},
+		{schema.Name, schema.Levels[:2]},
+		{schema.Name, [][]Slot{schema.Levels[1], schema.Levels[0], schema.Levels[2]}},
+		{schema.Name, [][]Slot{schema.Levels[0], {{"role", TypeString}, {"age", TypeTimestamp}, {"admin", TypeBool}}, schema.Levels[2]}},
+		{schema.Name, [][]Slot{schema.Levels[0], {{"role", TypeString}, {"years", TypeInt64}, {"admin", TypeBool}}, schema.Levels[2]}},
+		{schema.Name, [][]Slot{append(append([]Slot{}, schema.Levels[0]...), schema.Levels[1][0]), schema.Levels[1][1:], schema.Levels[2]}},
+	} {
+		assert.Check(t, string(other.Hash()) != string(schema.Hash()))
+	}
+}
+
+func testSchemaPaths(t *testing.T) {
+	schema := schemaOrganization()
+
+	position, kind, e := schema.Position("level2.age")
+	assert.NilError(t, e)
+	assert.Equal(t, position, Position{2, 1})
+	assert.Equal(t, kind, TypeInt64)
+
+	path, e := schema.Path(position)
+	assert.NilError(t, e)
+	assert.Equal(t, path, "level2.age")
+
+	for path, expected := range map[string]string{
+		"role":          `path "role" is not of the form level<i>.<name>`,
+		"level.role":    `path "level.role" is not of the form level<i>.<name>`,
+		"level0.role":   `path "level0.role" is not of the form level<i>.<name>`,
+		"leveltwo.role": `path "leveltwo.role" is not of the form level<i>.<name>`,
+		"item2.role":    `path "item2.role" is not of the form level<i>.<name>`,
+		"level4.role":   `schema "organization" has no level 4`,
+		"level1.role":   `schema "organization" has no attribute "role" at level 1`,
+	} {
+		_, _, e := schema.Position(path)
+		assert.ErrorContains(t, e, expected)
+	}
+
+	for _, position := range []Position{{0, 0}, {4, 0}, {1, 2}, {2, -1}} {
+		_, e := schema.Path(position)
+		assert.ErrorContains(t, e, "has no attribute")
+	}
+}
+
+func testSchemaJSON(t *testing.T) {
+	schema := schemaOrganization()
+
+	data, e := json.Marshal(schema)
+	assert.NilError(t, e)
+	assert.Check(t, json.Valid(data))
+	assert.Assert(t, string(data) != "")
+
+	var recovered Schema
+	assert.NilError(t, json.Unmarshal(data, &recovered))
+	assert.DeepEqual(t, recovered.Hash(), schema.Hash())
+
+	data, e = json.Marshal(Slot{"expiry", TypeTimestamp})
+	assert.NilError(t, e)
+	assert.Equal(t, string(data), `{"Name":"expiry","Type":"timestamp"}`)
+
+	var slot Slot
+	e = json.Unmarshal([]byte(`{"Name":"expiry","Type":"date"}`), &slot)
+	assert.ErrorContains(t, e, `unknown value type "date"`)
+
+	_, e = json.Marshal(Slot{"expiry", 0x13})
+	assert.ErrorContains(t, e, "unknown value type 19")
+}
+
+func testSchemaDelegate(t *testing.T) {
+	creds, sk, pk, ys, _, _, _ := schemaChain()
+
+	assert.NilError(t, creds.Verify(sk, pk, ys))
+
+	schema := schemaOrganization()
+	assert.NilError(t, schema.Check(creds))
+
+	// the attributes follow the order of the slots, and the schema's attribute follows them
+	assert.Check(t, pointEqual(creds.Attributes[1][0], AttributeFromString("acme", true)))
+	assert.Check(t, pointEqual(creds.Attributes[2][1], NumericAttribute(2, 25)))
+	admin, _ := NewBool(false).Attribute(2)
+	assert.Check(t, pointEqual(creds.Attributes[2][2], admin.Untyped()))
+	assert.Check(t, pointEqual(creds.Attributes[1][2], schema.Attribute(1)))
+	assert.Check(t, pointEqual(creds.Attributes[2][3], schema.Attribute(2)))
+
+	other, _ := MakeSchema("another", schema.Levels...)
+	assert.Check(t, !pointEqual(other.Attribute(1), schema.Attribute(1)))
+	assert.ErrorContains(t, other.Check(creds), `Schema.Check: credentials do not follow schema "another"`)
+
+	// the same values delegated without the schema do not follow it
+	plain, _, _, _, _, _, _ := generateChainWith(SEED, 1, 3, map[Position]interface{}{})
+	assert.ErrorContains(t, schema.Check(plain), `Schema.Check: credentials do not follow schema "organization"`)
+	plain, _, _, _, _, _, _ = generateChainWith(SEED, 1, 2, map[Position]interface{}{})
+	assert.ErrorContains(t, schema.Check(plain), `Schema.Check: level 1 has 2 attributes, schema "organization" expects 3`)
+
+	// a chain extended past the schema or with other attributes
+	prg := getNewRand(SEED)
+	_, pki := GenerateKeys(prg, 3)
+	assert.NilError(t, creds.Delegate(sk, pki, ProduceAttributes(3, "extra"), prg, ys))
+	assert.ErrorContains(t, schema.Check(creds), `Schema.Check: level 3 has 1 attributes, schema "organization" expects 4`)
+
+	short, _ := MakeSchema(schema.Name, schema.Levels[:2]...)
+	assert.ErrorContains(t, short.Check(creds), `Schema.Check: credentials have 3 levels, schema "organization" has 2`)
+}
+
+func testSchemaDelegateErrors(t *testing.T) {
+	type TestCase string
+	const (
+		Missing      TestCase = "value missing"
+		Extra        TestCase = "extra value"
+		WrongType    TestCase = "wrong type"
+		Invalid      TestCase = "invalid value"
+		NoLevel      TestCase = "level beyond the schema"
+		OtherSchema  TestCase = "another schema"
+		NoSchema     TestCase = "chain without schema"
+		BrokenSchema TestCase = "invalid schema"
+	)
+
+	for _, tc := range []TestCase{Missing, Extra, WrongType, Invalid, NoLevel, OtherSchema, NoSchema, BrokenSchema} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, _, ys, _, _, _ := schemaChain()
+			schema := schemaOrganization()
+
+			L := 3
+			values := map[string]Value{"role": NewString("intern"), "age": NewInt64(20), "admin": NewBool(false)}
+
+			var expected string
+			switch tc {
+			case Missing:
+				delete(values, "age")
+				expected = "value of level3.age is missing"
+			case Extra:
+				values["salary"] = NewInt64(100)
+				expected = `schema "organization" has no attribute "salary" at level 3`
+			case WrongType:
+				values["age"] = NewString("20")
+				expected = "level3.age must be of type int64, got string"
+			case Invalid:
+				values["admin"] = Value{TypeBool, []byte{2}}
+				expected = "level3.admin: Value.Exponent: bool value must be 0 or 1, got 2"
+			case NoLevel:
+				prg = getNewRand(SEED + 1)
+				ski, pki := GenerateKeys(prg, L)
+				assert.NilError(t, creds.DelegateWithSchema(sk, pki, values, prg, ys, schema))
+				sk, L = ski, L+1
+				expected = `schema "organization" has no level 4`
+			case OtherSchema:
+				schema.Name = "another"
+				expected = `credentials do not follow schema "another"`
+			case NoSchema:
+				creds, sk, _, ys, _, _, _ = generateChainWith(SEED, 2, 3, map[Position]interface{}{})
+				expected = `credentials do not follow schema "organization"`
+			case BrokenSchema:
+				schema.Levels[2] = []Slot{{"role", TypeString}, {"role", TypeString}}
+				expected = `attribute "role" repeats at level 3`
+			}
+
+			_, pki := GenerateKeys(prg, L)
+			before := creds.ToBytes()
+
+			e := creds.DelegateWithSchema(sk, pki, values, prg, ys, schema)
+			assert.ErrorContains(t, e, "DelegateWithSchema: "+expected)
+			assert.DeepEqual(t, creds.ToBytes(), before)
+		})
+	}
+}
+
+func testSchemaMarshal(t *testing.T) {
+	creds, sk, pk, ys, _, _, _ := schemaChain()
+
+	recovered, e := ParseCredentials(creds.ToBytes())
+	assert.NilError(t, e)
+	assert.Check(t, recovered.Equals(creds))
+	assert.NilError(t, schemaOrganization().Check(recovered))
+
+	// the schema travels in the signed attributes, they cannot be dropped or swapped
+	var marshal credentialsMarshal
+	_, e = ParseCredentials(remarshal(t, creds.ToBytes(), &marshal, func() { marshal.Attributes[2] = marshal.Attributes[2][:3] }))
+	assert.ErrorContains(t, e, "ParseCredentials: signatures[2] must sign the public key and all attributes")
+
+	other, _ := MakeSchema("another", schemaOrganization().Levels...)
+	swapped, e := ParseCredentials(remarshal(t, creds.ToBytes(), &marshal, func() { marshal.Attributes[2][3] = PointToBytes(other.Attribute(2)) }))
+	assert.NilError(t, e)
+	assert.ErrorContains(t, swapped.Verify(sk, pk, ys), "")
+}
+
+func testSchemaDisclose(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := schemaChain()
+	schema := schemaOrganization()
+
+	D, e := schema.DiscloseByName(creds, "level1.org", "level2.role")
+	assert.NilError(t, e)
+	assert.Equal(t, len(D), 2)
+	assert.Equal(t, D[1].I, 2)
+	assert.Equal(t, D[1].J, 0)
+	assert.Check(t, pointEqual(D[1].Attribute, AttributeFromString("engineer", false)))
+
+	proof, e := creds.Prove(prg, sk, pk, D, []byte("message"), ys, h, skNym)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.VerifyProof(pk, ys, h, pkNym, D, []byte("message")))
+
+	_, e = schema.DiscloseByName(creds, "level2.salary")
+	assert.ErrorContains(t, e, `DiscloseByName: schema "organization" has no attribute "salary" at level 2`)
+
+	_, e = schema.DiscloseByName(creds, "level3.role")
+	assert.ErrorContains(t, e, "DiscloseByName: credentials have no level 3")
+
+	other, _ := MakeSchema("another", schema.Levels...)
+	_, e = other.DiscloseByName(creds, "level1.org")
+	assert.ErrorContains(t, e, "DiscloseByName: Schema.Check: credentials do not follow schema")
+}
+
+func testSchemaDiscloseValues(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h := schemaChain()
+	schema := schemaOrganization()
+
+	disclosed, e := schema.DiscloseValuesByName(map[string]Value{
+		"level2.role":    NewString("engineer"),
+		"level1.founded": NewTimestamp(_Now),
+		"level1.org":     NewString("acme"),
+	})
+	assert.NilError(t, e)
+	expected := DisclosedValues{
+		NewString("acme").Disclose(1, 0),
+		NewTimestamp(_Now).Disclose(1, 1),
+		NewString("engineer").Disclose(2, 0),
+	}
+	assert.Equal(t, len(disclosed), len(expected))
+	for index := range expected {
+		assert.Equal(t, disclosed[index].I, expected[index].I)
+		assert.Equal(t, disclosed[index].J, expected[index].J)
+		assert.Check(t, disclosed[index].Value.Equals(expected[index].Value))
+	}
+
+	proof, e := creds.ProveDisclosing(prg, sk, pk, disclosed, []byte("message"), ys, h, skNym)
+	assert.NilError(t, e)
+	assert.NilError(t, proof.Verify(pk, ys, h, pkNym, []byte("message")))
+
+	value, e := schema.ValueByName(proof.Values, "level2.role")
+	assert.NilError(t, e)
+	role, _ := value.AsString()
+	assert.Equal(t, role, "engineer")
+
+	_, e = schema.ValueByName(proof.Values, "level2.age")
+	assert.ErrorContains(t, e, "ValueByName: level2.age is not disclosed")
+
+	_, e = schema.ValueByName(proof.Values, "level2.salary")
+	assert.ErrorContains(t, e, `ValueByName: schema "organization" has no attribute "salary" at level 2`)
+
+	proof.Values[0].Value = NewBytes([]byte("acme"))
+	_, e = schema.ValueByName(proof.Values, "level1.org")
+	assert.ErrorContains(t, e, "ValueByName: level1.org must be of type string, got bytes")
+
+	_, e = schema.DiscloseValuesByName(map[string]Value{"level2.age": NewString("25")})
+	assert.ErrorContains(t, e, "DiscloseValuesByName: level2.age must be of type int64, got string")
+
+	_, e = schema.DiscloseValuesByName(map[string]Value{"level4.age": NewInt64(25)})
+	assert.ErrorContains(t, e, `DiscloseValuesByName: schema "organization" has no level 4`)
+}
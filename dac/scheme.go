@@ -2,6 +2,8 @@ package dac
 
 import (
 	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
 
@@ -26,6 +28,11 @@ type Proof struct {
 	resCpk []interface{}
 	resCsk *FP256BN.BIG
 	resNym *FP256BN.BIG
+
+	// fingerprint of the system parameters the proof is bound to (empty if not bound)
+	fingerprint []byte
+	// commitments of the proof, only carried by proofs made with ProveBatchable (nil otherwise)
+	coms [][]*FP256BN.FP12
 }
 
 // GenerateKeys generates a key pair for the authority (Level-0 issuer)
@@ -57,7 +64,7 @@ func ProduceAttributes(L int, inputs ...string) (attributes []interface{}) {
 	first := L%2 == 1
 	attributes = make([]interface{}, len(inputs))
 	for index, value := range inputs {
-		attributes[index] = StringToECPb(value, first)
+		attributes[index] = AttributeFromString(value, first)
 	}
 
 	return
@@ -65,8 +72,53 @@ func ProduceAttributes(L int, inputs ...string) (attributes []interface{}) {
 
 // Delegate extends credentials by a single link.
 // Needs secret key of the delegator, public key and attributes of the delegatee.
-// Returns error if exception / panic occurred (perhaps due to wrong type of curve)
+// The public key and the attributes are converted to the typed ones of the new level,
+// and the link is added with DelegateG1 or DelegateG2.
+// Returns error if they are not in the group of the new level.
 func (creds *Credentials) Delegate(sk SK, publicKey PK, attributes []interface{}, prg *amcl.RAND, grothYs [][]interface{}) (e error) {
+	params, e := TypedGrothParams(grothYs)
+	if e != nil {
+		return fmt.Errorf("Delegate: %v", e)
+	}
+
+	L := len(creds.signatures)
+
+	if LevelInG1(L) {
+		pk, e := toG1(fmt.Sprintf("public key for level %d", L), publicKey)
+		if e != nil {
+			return fmt.Errorf("Delegate: %v", e)
+		}
+		typed := make([]G1Attribute, len(attributes))
+		for index, attribute := range attributes {
+			g, e := toG1(fmt.Sprintf("attribute %d for level %d", index, L), attribute)
+			if e != nil {
+				return fmt.Errorf("Delegate: %v", e)
+			}
+			typed[index] = G1Attribute{g}
+		}
+
+		return creds.DelegateG1(sk, G1PublicKey{pk}, typed, prg, params)
+	}
+
+	pk, e := toG2(fmt.Sprintf("public key for level %d", L), publicKey)
+	if e != nil {
+		return fmt.Errorf("Delegate: %v", e)
+	}
+	typed := make([]G2Attribute, len(attributes))
+	for index, attribute := range attributes {
+		g, e := toG2(fmt.Sprintf("attribute %d for level %d", index, L), attribute)
+		if e != nil {
+			return fmt.Errorf("Delegate: %v", e)
+		}
+		typed[index] = G2Attribute{g}
+	}
+
+	return creds.DelegateG2(sk, G2PublicKey{pk}, typed, prg, params)
+}
+
+// delegate signs the link of the next level, the arguments are assumed to be in the groups of the level.
+// Returns error if exception / panic occurred.
+func (creds *Credentials) delegate(sk SK, publicKey PK, attributes []interface{}, prg *amcl.RAND, grothYs [][]interface{}) (e error) {
 	defer func() {
 		if r := recover(); r != nil {
 			e = r.(error)
@@ -89,7 +141,22 @@ func (creds *Credentials) Delegate(sk SK, publicKey PK, attributes []interface{}
 // Verify checks the validity of the credentils.
 // Note, this has nothing to do with the NIZK proof.
 // If verification fails, returns error describing the failed stage.
+// The arguments are converted to the typed ones, see VerifyTyped.
 func (creds *Credentials) Verify(sk SK, authorityPK PK, grothYs [][]interface{}) (e error) {
+	pk, e := toG2("trusted authority's public key", authorityPK)
+	if e != nil {
+		return fmt.Errorf("Verify: %v", e)
+	}
+	params, e := TypedGrothParams(grothYs)
+	if e != nil {
+		return fmt.Errorf("Verify: %v", e)
+	}
+
+	return creds.VerifyTyped(sk, G2PublicKey{pk}, params)
+}
+
+// verify checks the signatures of all links and the secret key of the last level
+func (creds *Credentials) verify(sk SK, authorityPK PK, grothYs [][]interface{}) (e error) {
 	defer func() {
 		if r := recover(); r != nil {
 			e = r.(error)
@@ -105,7 +172,27 @@ func (creds *Credentials) Verify(sk SK, authorityPK PK, grothYs [][]interface{})
 		return fmt.Errorf("trusted authority's public key and credentials' top-level public key do not match")
 	}
 
+	// all levels are checked with a single randomized product of pairings,
+	// and only if it fails, the levels are verified one by one to find the failing one
+	var args []*eArg
+	batchFailed := false
 	for index := L - 1; index > 0; index-- {
+		levelArgs, levelResult := MakeGroth(nil, index%2 == 1, grothYs[index%2]).batchArgs(
+			creds.publicKeys[index-1],
+			creds.signatures[index],
+			append([]interface{}{creds.publicKeys[index]}, creds.Attributes[index]...),
+		)
+		if levelResult != nil {
+			return fmt.Errorf("verification failed for L = %d", index)
+		}
+		args = append(args, levelArgs...)
+	}
+	if len(args) > 0 {
+		result := eProduct(args...)
+		batchFailed = result == nil || !result.Isunity()
+	}
+
+	for index := L - 1; batchFailed && index > 0; index-- {
 		siblings := MakeSiblings(nil, index%2 == 1, grothYs[index%2])
 		levelResult := siblings.VerifyGroth(
 			creds.publicKeys[index-1],
@@ -116,6 +203,9 @@ func (creds *Credentials) Verify(sk SK, authorityPK PK, grothYs [][]interface{})
 			return fmt.Errorf("verification failed for L = %d", index)
 		}
 	}
+	if batchFailed {
+		return fmt.Errorf("verification failed")
+	}
 
 	if !VerifyKeyPair(sk, creds.publicKeys[len(creds.publicKeys)-1]) {
 		return fmt.Errorf("supplied secret key does not match credentials' bottom-level public key")
@@ -132,13 +222,103 @@ func (creds *Credentials) Verify(sk SK, authorityPK PK, grothYs [][]interface{})
 // D is a set of disclosed attributes (with their 'coordinates' and values).
 // D can be empty, then no attributes will be disclosed.
 // h and skNym should be received with GenerateNymKeys.
+// The arguments are converted to the typed ones, see ProveTyped.
 func (creds *Credentials) Prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK) (proof Proof, e error) {
+	authorityPK, e := toG2("trusted authority's public key", pk)
+	if e != nil {
+		return proof, fmt.Errorf("Prove: %v", e)
+	}
+	params, e := TypedGrothParams(grothYs)
+	if e != nil {
+		return proof, fmt.Errorf("Prove: %v", e)
+	}
+	base, e := TypedPoint(h)
+	if e != nil {
+		return proof, fmt.Errorf("Prove: h: %v", e)
+	}
+
+	return creds.ProveTyped(prg, sk, G2PublicKey{authorityPK}, D, m, params, base, skNym)
+}
+
+// ProveBatchable is Prove that also attaches the commitments to the proof.
+// VerifyProof checks such a proof as usual, while VerifyProofBatch folds its pairings
+// into the single product of pairings of the whole batch.
+// The proof is larger by one element of GT per attribute plus two per level.
+func (creds *Credentials) ProveBatchable(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK) (proof Proof, e error) {
+	authorityPK, e := toG2("trusted authority's public key", pk)
+	if e != nil {
+		return proof, fmt.Errorf("ProveBatchable: %v", e)
+	}
+	params, e := TypedGrothParams(grothYs)
+	if e != nil {
+		return proof, fmt.Errorf("ProveBatchable: %v", e)
+	}
+	base, e := TypedPoint(h)
+	if e != nil {
+		return proof, fmt.Errorf("ProveBatchable: h: %v", e)
+	}
+
+	return creds.proveTyped(prg, sk, G2PublicKey{authorityPK}, D, m, params, base, skNym, true)
+}
+
+// prove is Prove that binds the proof to the system parameters fingerprint and the context.
+// The commitments are attached to the proof if batchable is set.
+func (creds *Credentials) prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK, b binding, batchable bool) (proof Proof, e error) {
 	defer func() {
 		if r := recover(); r != nil {
 			e = r.(error)
 		}
 	}()
 
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	prover, e := creds.proveCommit(prg, D, grothYs, h, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg))
+	if e != nil {
+		return
+	}
+
+	// line 31
+	t := newTranscript(_ProtocolCredentials, b)
+	appendCommitments(t, grothYs, pk, h, prover.rPrime, prover.coms, prover.comNym, D)
+	t.append("m", m)
+
+	proof = prover.respond(t.challenge(q), sk, skNym)
+	proof.fingerprint = b.fingerprint
+	if batchable {
+		proof.coms = prover.coms
+	}
+
+	return
+}
+
+// credentialsProver holds the state of the proof generation between the commitments and the responses
+type credentialsProver struct {
+	creds  *Credentials
+	D      Indices
+	n      []int
+	rPrime []interface{}
+	sPrime []interface{}
+	tPrime [][]interface{}
+	rhoS   []*FP256BN.BIG
+	rhoT   [][]*FP256BN.BIG
+	rhoA   [][]*FP256BN.BIG
+	rhoCpk []*FP256BN.BIG
+	rhoNym *FP256BN.BIG
+	coms   [][]*FP256BN.FP12
+	comNym interface{}
+}
+
+// proveCommit randomizes the signatures and computes the commitments of the proof (lines 2-30).
+// rhoSk and rhoNym are the randomness for the secret key and the pseudonym secret key,
+// a proof sharing them with other proofs under the same challenge shows that the secret keys are the same.
+func (creds *Credentials) proveCommit(prg *amcl.RAND, D Indices, grothYs [][]interface{}, h interface{}, rhoSk *FP256BN.BIG, rhoNym *FP256BN.BIG) (prover *credentialsProver, e error) {
+	return creds.proveCommitSharing(prg, D, grothYs, h, rhoSk, rhoNym, nil)
+}
+
+// proveCommitSharing is proveCommit with the given randomness of some hidden attributes.
+// Attributes sharing the randomness get the same responses resA (or responses with the same exponent, across the groups)
+// if and only if they are equal, which proves their equality.
+func (creds *Credentials) proveCommitSharing(prg *amcl.RAND, D Indices, grothYs [][]interface{}, h interface{}, rhoSk *FP256BN.BIG, rhoNym *FP256BN.BIG, rhoShared map[Position]*FP256BN.BIG) (prover *credentialsProver, e error) {
 	L := len(creds.signatures) - 1
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 
@@ -148,7 +328,7 @@ func (creds *Credentials) Prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byt
 	}
 
 	rhoSigma := make([]*FP256BN.BIG, L+1)
-	proof.rPrime = make([]interface{}, L+1)
+	rPrime := make([]interface{}, L+1)
 	sPrime := make([]interface{}, L+1)
 	tPrime := make([][]interface{}, L+1)
 
@@ -156,7 +336,7 @@ func (creds *Credentials) Prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byt
 	for i := 1; i <= L; i++ {
 		// line 3
 		rhoSigma[i] = FP256BN.Randomnum(q, prg)
-		proof.rPrime[i] = pointMultiply(creds.signatures[i].r, rhoSigma[i])
+		rPrime[i] = pointMultiply(creds.signatures[i].r, rhoSigma[i])
 
 		rhoSigmaInv := FP256BN.NewBIGcopy(rhoSigma[i])
 		rhoSigmaInv.Invmodp(q)
@@ -175,18 +355,25 @@ func (creds *Credentials) Prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byt
 	rhoT := make([][]*FP256BN.BIG, L+1)
 	rhoA := make([][]*FP256BN.BIG, L+1)
 	rhoCpk := make([]*FP256BN.BIG, L+1)
-	rhoNym := FP256BN.Randomnum(q, prg)
 
 	for i := 1; i <= L; i++ {
 		rhoS[i] = FP256BN.Randomnum(q, prg)
-		rhoCpk[i] = FP256BN.Randomnum(q, prg)
+		if i != L {
+			rhoCpk[i] = FP256BN.Randomnum(q, prg)
+		} else {
+			rhoCpk[i] = rhoSk
+		}
 
 		rhoT[i] = make([]*FP256BN.BIG, n[i]+1)
 		rhoA[i] = make([]*FP256BN.BIG, n[i])
 
 		for j := 0; j < n[i]; j++ {
 			rhoT[i][j] = FP256BN.Randomnum(q, prg)
-			rhoA[i][j] = FP256BN.Randomnum(q, prg)
+			if rho, shared := rhoShared[Position{i, j}]; shared {
+				rhoA[i][j] = rho
+			} else {
+				rhoA[i][j] = FP256BN.Randomnum(q, prg)
+			}
 		}
 		rhoT[i][n[i]] = FP256BN.Randomnum(q, prg)
 	}
@@ -262,8 +449,33 @@ func (creds *Credentials) Prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byt
 	g := generatorSameGroup(h)
 	comNym := productOfExponents(g, rhoCpk[L], h, rhoNym)
 
-	// line 31
-	proof.c = hashCommitments(grothYs, pk, proof.rPrime, coms, comNym, D, m, q)
+	prover = &credentialsProver{
+		creds:  creds,
+		D:      D,
+		n:      n,
+		rPrime: rPrime,
+		sPrime: sPrime,
+		tPrime: tPrime,
+		rhoS:   rhoS,
+		rhoT:   rhoT,
+		rhoA:   rhoA,
+		rhoCpk: rhoCpk,
+		rhoNym: rhoNym,
+		coms:   coms,
+		comNym: comNym,
+	}
+
+	return
+}
+
+// respond computes the responses of the proof for the challenge c (lines 32-47)
+func (prover *credentialsProver) respond(c *FP256BN.BIG, sk SK, skNym SK) (proof Proof) {
+	creds := prover.creds
+	L := len(prover.n) - 1
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	proof.c = c
+	proof.rPrime = prover.rPrime
 
 	// line 32 / 41
 	proof.resS = make([]interface{}, L+1)
@@ -280,32 +492,32 @@ func (creds *Credentials) Prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byt
 		}
 
 		// line 33 / 42
-		proof.resS[i] = productOfExponents(g, rhoS[i], sPrime[i], proof.c)
+		proof.resS[i] = productOfExponents(g, prover.rhoS[i], prover.sPrime[i], proof.c)
 		if i != L {
-			proof.resCpk[i] = productOfExponents(g, rhoCpk[i], creds.publicKeys[i], proof.c)
+			proof.resCpk[i] = productOfExponents(g, prover.rhoCpk[i], creds.publicKeys[i], proof.c)
 		} else {
 			proof.resCsk = FP256BN.Modmul(proof.c, sk, q)
-			proof.resCsk = proof.resCsk.Plus(rhoCpk[L])
+			proof.resCsk = proof.resCsk.Plus(prover.rhoCpk[L])
 			proof.resCsk.Mod(q)
 
 			proof.resNym = FP256BN.Modmul(proof.c, skNym, q)
-			proof.resNym = proof.resNym.Plus(rhoNym)
+			proof.resNym = proof.resNym.Plus(prover.rhoNym)
 			proof.resNym.Mod(q)
 		}
 
 		// line 34 / 43
-		proof.resT[i] = make([]interface{}, n[i]+1)
-		for j := 0; j < n[i]+1; j++ {
+		proof.resT[i] = make([]interface{}, prover.n[i]+1)
+		for j := 0; j < prover.n[i]+1; j++ {
 			// line 35 / 44
-			proof.resT[i][j] = productOfExponents(g, rhoT[i][j], tPrime[i][j], proof.c)
+			proof.resT[i][j] = productOfExponents(g, prover.rhoT[i][j], prover.tPrime[i][j], proof.c)
 		}
 
 		// line 37 / 46
-		proof.resA[i] = make([]interface{}, n[i])
-		for j := 0; j < n[i]; j++ {
-			if D.contains(i, j) == nil {
+		proof.resA[i] = make([]interface{}, prover.n[i])
+		for j := 0; j < prover.n[i]; j++ {
+			if prover.D.contains(i, j) == nil {
 				// line 38 / 47
-				proof.resA[i][j] = productOfExponents(g, rhoA[i][j], creds.Attributes[i][j], proof.c)
+				proof.resA[i][j] = productOfExponents(g, prover.rhoA[i][j], creds.Attributes[i][j], proof.c)
 			}
 		}
 	}
@@ -320,13 +532,106 @@ func (creds *Credentials) Prove(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byt
 // h and pkNym should be received with GenerateNymKeys.
 // D is a set of disclosed attributes (with their 'coordinates' and values).
 // D has to exactly correspond to the one used in generation.
+// The arguments are converted to the typed ones, see VerifyProofTyped.
 func (proof *Proof) VerifyProof(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte) (e error) {
+	return proof.verifyProofUntyped(pk, grothYs, h, pkNym, D, m, "")
+}
+
+// verifyProofUntyped is VerifyProof for the proof bound to the context
+func (proof *Proof) verifyProofUntyped(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, context string) (e error) {
+	authorityPK, e := toG2("trusted authority's public key", pk)
+	if e != nil {
+		return fmt.Errorf("VerifyProof: %v", e)
+	}
+	params, e := TypedGrothParams(grothYs)
+	if e != nil {
+		return fmt.Errorf("VerifyProof: %v", e)
+	}
+	base, e := TypedPoint(h)
+	if e != nil {
+		return fmt.Errorf("VerifyProof: h: %v", e)
+	}
+	typedPkNym, e := TypedPoint(pkNym)
+	if e != nil {
+		return fmt.Errorf("VerifyProof: invalid pkNym: %v", e)
+	}
+
+	return proof.verifyProofTyped(G2PublicKey{authorityPK}, params, base, typedPkNym, D, m, context)
+}
+
+// verifyProof is VerifyProof for the proof bound to the context (and to the fingerprint it carries)
+func (proof *Proof) verifyProof(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, context string) (e error) {
 	defer func() {
 		if r := recover(); r != nil {
 			e = r.(error)
 		}
 	}()
 
+	if e = proof.checkInputs(pkNym, D); e != nil {
+		return
+	}
+
+	coms, comNym, e := proof.commitments(pk, grothYs, h, pkNym, D)
+	if e != nil {
+		return
+	}
+
+	// line 25
+	t := newTranscript(_ProtocolCredentials, binding{proof.fingerprint, context})
+	appendCommitments(t, grothYs, pk, h, proof.rPrime, coms, comNym, D)
+	t.append("m", m)
+
+	if !bigEqual(proof.c, t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order))) {
+		return fmt.Errorf("proof verification failed")
+	}
+
+	return
+}
+
+// checkInputs validates the proof, pkNym and D before the commitments are recomputed
+func (proof *Proof) checkInputs(pkNym PK, D Indices) (e error) {
+	if e = proof.validate(); e != nil {
+		return fmt.Errorf("VerifyProof: invalid proof: %v", e)
+	}
+	if e = ValidatePoint(pkNym); e != nil {
+		return fmt.Errorf("VerifyProof: invalid pkNym: %v", e)
+	}
+	if e = D.validate(); e != nil {
+		return fmt.Errorf("VerifyProof: %v", e)
+	}
+
+	return
+}
+
+// commitments recomputes the commitments of the proof from its challenge and responses (lines 3-24).
+// The proof, pkNym and D are assumed to be validated.
+func (proof *Proof) commitments(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices) (coms [][]*FP256BN.FP12, comNym interface{}, e error) {
+	eComputer, e := proof.equations(pk, grothYs, D)
+	if e != nil {
+		return
+	}
+
+	coms, e = eComputer.compute()
+	if e != nil {
+		return
+	}
+
+	return coms, proof.commitmentNym(h, pkNym), nil
+}
+
+// commitmentNym recomputes the commitment of the pseudonym from the challenge and responses
+func (proof *Proof) commitmentNym(h interface{}, pkNym PK) (comNym interface{}) {
+	g := generatorSameGroup(h)
+	comNym = productOfExponents(g, proof.resCsk, h, proof.resNym)
+	pointSubtract(comNym, pointMultiply(pkNym, proof.c))
+
+	return
+}
+
+// equations enqueues the products of pairings that recompute the commitments of the proof (lines 3-24),
+// the product for coms[i][j] is enqueued at (i, j).
+// The proof and D are assumed to be validated.
+func (proof *Proof) equations(pk PK, grothYs [][]interface{}, D Indices) (eComputer *eProductComputer, e error) {
 	L := len(proof.resA) - 1
 	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
 
@@ -337,12 +642,7 @@ func (proof *Proof) VerifyProof(pk PK, grothYs [][]interface{}, h interface{}, p
 		total += len(proof.resA[i]) + 2
 	}
 
-	coms := make([][]*FP256BN.FP12, L+1)
-	for i := 1; i <= L; i++ {
-		coms[i] = make([]*FP256BN.FP12, n[i]+2)
-	}
-
-	eComputer := makeEProductComputer(total)
+	eComputer = makeEProductComputer(total)
 
 	cNeg := bigNegate(proof.c, q)
 
@@ -359,8 +659,6 @@ func (proof *Proof) VerifyProof(pk PK, grothYs [][]interface{}, h interface{}, p
 		g1Neg = pointNegate(g1)
 		g2Neg = pointNegate(g2)
 
-		coms[i] = make([]*FP256BN.FP12, n[i]+2)
-
 		// line 4
 		e1com1 := &eArg{proof.resS[i], proof.rPrime[i], nil}
 		var e2com1 *eArg
@@ -412,6 +710,10 @@ func (proof *Proof) VerifyProof(pk PK, grothYs [][]interface{}, h interface{}, p
 				eComputer.enqueue(i, j, e1com, e2com, e3com, e4com)
 			} else {
 				// line 10
+				if proof.resA[i][j] == nil {
+					e = fmt.Errorf("VerifyProof: response for the hidden attribute (%d, %d) is missing", i, j)
+					return
+				}
 				e1com := &eArg{proof.resT[i][j+1], proof.rPrime[i], nil}
 				e2com := &eArg{proof.resA[i][j], g2Neg, nil}
 				var e3com *eArg
@@ -427,50 +729,222 @@ func (proof *Proof) VerifyProof(pk PK, grothYs [][]interface{}, h interface{}, p
 		}
 	}
 
-	coms, e = eComputer.compute()
+	return
+}
+
+// BatchProof is a proof along with the values it is verified against in VerifyProofBatch.
+// Context is the one the proof was bound to with ProveWithParameters (empty otherwise).
+type BatchProof struct {
+	Proof   *Proof
+	PkNym   PK
+	D       Indices
+	M       []byte
+	Context string
+}
+
+// VerifyProofBatch verifies many proofs generated under the same authority's public key, grothYs and h.
+// Returns the indices of invalid proofs (in increasing order) and an error describing the first of them,
+// or nil and nil if all proofs are valid.
+// The challenge of a proof made with ProveBatchable is checked against the commitments it carries,
+// and all equations of all such proofs are combined with random 128-bit exponents
+// into a single product of pairings with one final exponentiation.
+// If the combined check fails, those proofs are verified one by one to identify the invalid ones.
+// The other proofs, and those with a commitment outside GT, are verified one by one right away.
+// Per-proof work runs concurrently (at most Workers at a time, all at once if Workers is 0).
+func VerifyProofBatch(pk PK, grothYs [][]interface{}, h interface{}, proofs []BatchProof) (invalid []int, e error) {
+	errors := make([]error, len(proofs))
+	batched := make([]*proofBatch, len(proofs))
+
+	// the arguments that fail conversion are reported by VerifyProof for every proof
+	authorityPK, pe := toG2("trusted authority's public key", pk)
+	params, ge := TypedGrothParams(grothYs)
+	base, he := TypedPoint(h)
+	batchable := pe == nil && ge == nil && he == nil
+
+	concurrently(len(proofs), func(i int) {
+		if proofs[i].Proof == nil {
+			errors[i] = fmt.Errorf("proof is missing")
+			return
+		}
+		if batchable && proofs[i].Proof.coms != nil {
+			// a proof that cannot be batched is verified on its own to get the precise error
+			batched[i], _ = proofs[i].Proof.batch(G2PublicKey{authorityPK}, params, base, proofs[i].PkNym, proofs[i].D, proofs[i].M, proofs[i].Context)
+		}
+	})
+
+	if !batchedValid(batched) {
+		for i := range batched {
+			batched[i] = nil
+		}
+	}
+
+	concurrently(len(proofs), func(i int) {
+		if proofs[i].Proof != nil && batched[i] == nil {
+			errors[i] = proofs[i].Proof.verifyProofUntyped(pk, grothYs, h, proofs[i].PkNym, proofs[i].D, proofs[i].M, proofs[i].Context)
+		}
+	})
+
+	for i, err := range errors {
+		if err != nil {
+			invalid = append(invalid, i)
+		}
+	}
+	if len(invalid) > 0 {
+		e = fmt.Errorf("VerifyProofBatch: %d of %d proofs are invalid, proof %d: %v", len(invalid), len(proofs), invalid[0], errors[invalid[0]])
+	}
+
+	return
+}
+
+// proofBatch is the randomized combination of the equations of one proof.
+// The product of the pairings of args equals com if the proof is valid.
+type proofBatch struct {
+	args map[string]*eArg
+	com  *FP256BN.FP12
+}
+
+// batch checks the challenge of the proof against the commitments it carries
+// and combines its equations with random exponents, see VerifyProofBatch.
+// The typed arguments are checked as in VerifyProofTyped.
+// The commitments have to be in GT, otherwise a factor of small order could cancel out in the combined check.
+func (proof *Proof) batch(pk G2PublicKey, params GrothParams, h Point, pkNym PK, D Indices, m []byte, context string) (batch *proofBatch, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			batch, e = nil, r.(error)
+		}
+	}()
+
+	typedPkNym, e := TypedPoint(pkNym)
 	if e != nil {
+		return nil, fmt.Errorf("invalid pkNym: %v", e)
+	}
+	if e = proof.checkTyped(pk, params, h, typedPkNym, D); e != nil {
+		return
+	}
+	if e = proof.checkInputs(pkNym, D); e != nil {
+		return
+	}
+	if e = proof.checkCommitments(); e != nil {
 		return
 	}
 
-	g := generatorSameGroup(h)
-	comNym := productOfExponents(g, proof.resCsk, h, proof.resNym)
-	pointSubtract(comNym, pointMultiply(pkNym, proof.c))
+	grothYs := params.Untyped()
+	eComputer, e := proof.equations(pk.Untyped(), grothYs, D)
+	if e != nil {
+		return
+	}
 
-	// line 25
-	cPrime := hashCommitments(grothYs, pk, proof.rPrime, coms, comNym, D, m, q)
+	t := newTranscript(_ProtocolCredentials, binding{proof.fingerprint, context})
+	appendCommitments(t, grothYs, pk.Untyped(), h.Untyped(), proof.rPrime, proof.coms, proof.commitmentNym(h.Untyped(), pkNym), D)
+	t.append("m", m)
 
-	if !bigEqual(proof.c, cPrime) {
-		return fmt.Errorf("proof verification failed")
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	if !bigEqual(proof.c, t.challenge(q)) {
+		return nil, fmt.Errorf("proof verification failed")
+	}
+	for _, equation := range eComputer.queue {
+		if !fpInGroup(proof.coms[equation.i][equation.j]) {
+			return nil, fmt.Errorf("commitment %d-%d is not in GT", equation.i, equation.j)
+		}
 	}
 
+	batch = &proofBatch{args: make(map[string]*eArg)}
+	bases := make([]*FP256BN.FP12, 0, len(eComputer.queue))
+	exponents := make([]*FP256BN.BIG, 0, len(eComputer.queue))
+	for _, equation := range eComputer.queue {
+		r := randomSmallBig()
+		bases = append(bases, proof.coms[equation.i][equation.j])
+		exponents = append(exponents, r)
+
+		for _, arg := range equation.args {
+			if arg == nil {
+				continue
+			}
+			exponent := r
+			if arg.c != nil {
+				exponent = FP256BN.Modmul(r, arg.c, q)
+			}
+			batch.add(arg.b, pointMultiply(arg.a, exponent))
+		}
+	}
+	batch.com = fpProductOfPowers(bases, exponents)
+
 	return
 }
 
-func hashCommitments(grothYs [][]interface{}, pk PK, rPrime []interface{}, coms [][]*FP256BN.FP12, comNym interface{}, D Indices, m []byte, q *FP256BN.BIG) *FP256BN.BIG {
-
-	var raw []byte
+// add accumulates e(a, b), the arguments sharing b are merged into one pairing
+func (batch *proofBatch) add(b interface{}, a interface{}) {
+	key := string(PointToBytes(b))
+	if arg, ok := batch.args[key]; ok {
+		pointAdd(arg.a, a)
+	} else {
+		batch.args[key] = &eArg{a, b, nil}
+	}
+}
 
-	for i := 0; i < len(grothYs); i++ {
-		for j := 0; j < len(grothYs[i%2]); j++ {
-			raw = append(raw, PointToBytes(grothYs[i%2][j])...)
+// batchedValid checks all batched proofs with a single product of pairings.
+// Returns true if there are none.
+func batchedValid(batched []*proofBatch) bool {
+	combined := &proofBatch{args: make(map[string]*eArg)}
+	com := FP256BN.NewFP12int(1)
+	for _, batch := range batched {
+		if batch == nil {
+			continue
 		}
-	}
-	raw = append(raw, PointToBytes(pk)...)
-	for i := 0; i < len(rPrime); i++ {
-		if rPrime[i] != nil {
-			raw = append(raw, PointToBytes(rPrime[i])...)
+		for _, arg := range batch.args {
+			combined.add(arg.b, arg.a)
 		}
+		com.Mul(batch.com)
+	}
+
+	if len(combined.args) == 0 {
+		return true
 	}
+
+	args := make([]*eArg, 0, len(combined.args))
+	for _, arg := range combined.args {
+		args = append(args, arg)
+	}
+	result := eProduct(args...)
+
+	return result != nil && result.Equals(com)
+}
+
+// concurrently runs task for 0 <= i < n, at most Workers at a time (all at once if Workers is 0)
+func concurrently(n int, task func(i int)) {
+	workers := int(Workers)
+	if workers < 1 || workers > n {
+		workers = n
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for worker := 0; worker < workers; worker++ {
+		go func(worker int) {
+			defer wg.Done()
+
+			for i := worker; i < n; i += workers {
+				task(i)
+			}
+		}(worker)
+	}
+	wg.Wait()
+}
+
+// appendCommitments adds the public values and the commitments of the credentials proof to the transcript
+func appendCommitments(t *transcript, grothYs [][]interface{}, pk PK, h interface{}, rPrime []interface{}, coms [][]*FP256BN.FP12, comNym interface{}, D Indices) {
+	for i := 0; i < len(grothYs); i++ {
+		t.appendPoints("grothYs", grothYs[i])
+	}
+	t.appendPoint("pk", pk)
+	t.appendPoint("h", h)
+	t.appendPoints("rPrime", rPrime)
 	for i := 0; i < len(coms); i++ {
+		t.append("level", []byte(strconv.Itoa(i)))
 		for j := 0; j < len(coms[i]); j++ {
-			if coms[i][j] != nil {
-				raw = append(raw, fpToBytes(coms[i][j])...)
-			}
+			t.appendFP("com", coms[i][j])
 		}
 	}
-	raw = append(raw, PointToBytes(comNym)...)
-	raw = append(raw, D.hash()...)
-	raw = append(raw, m...)
-
-	return sha3(q, raw)
+	t.appendPoint("comNym", comNym)
+	D.appendTo(t)
 }
@@ -27,49 +27,104 @@ type proofMarshal struct {
 	ResCpk [][]byte
 	ResCsk []byte
 	ResNym []byte
+
+	Fingerprint []byte     `asn1:"optional"`
+	Coms        [][][]byte `asn1:"optional"`
 }
 
 // ProofFromBytes un-marshals the proof
+// Panics if the input is malformed, see ParseProof for a version that returns error.
 func ProofFromBytes(input []byte) (proof *Proof) {
-	var marshal proofMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling proof failed")
+	proof, e := ParseProof(input)
+	if e != nil {
+		panic("un-marshalling proof failed: " + e.Error())
 	}
 
-	proof = &Proof{}
+	return
+}
 
-	proof.c = FP256BN.FromBytes(marshal.C)
-	proof.resCsk = FP256BN.FromBytes(marshal.ResCsk)
-	proof.resNym = FP256BN.FromBytes(marshal.ResNym)
+// ParseProof un-marshals and validates the proof.
+// Besides the validity of each point and scalar, it checks that the proof is well-formed,
+// i.e. lengths of the components agree and each point is in the group prescribed by its level.
+func ParseProof(input []byte) (proof *Proof, e error) {
+	var marshal proofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseProof: %v", e)
+	}
 
-	proof.rPrime = make([]interface{}, len(marshal.RPrime))
-	for i := 0; i < len(marshal.RPrime); i++ {
-		proof.rPrime[i], _ = PointFromBytes(marshal.RPrime[i])
+	L := len(marshal.ResA) - 1
+	if L < 1 {
+		return nil, fmt.Errorf("ParseProof: proof must have at least one level")
+	}
+	if len(marshal.RPrime) != L+1 || len(marshal.ResS) != L+1 || len(marshal.ResT) != L+1 || len(marshal.ResCpk) != L+1 {
+		return nil, fmt.Errorf("ParseProof: lengths of the components do not agree with L = %d", L)
+	}
+	if len(marshal.RPrime[0]) != 0 || len(marshal.ResS[0]) != 0 || len(marshal.ResT[0]) != 0 || len(marshal.ResA[0]) != 0 || len(marshal.ResCpk[0]) != 0 {
+		return nil, fmt.Errorf("ParseProof: components for level 0 must be empty")
 	}
 
-	proof.resS = make([]interface{}, len(marshal.ResS))
-	for i := 0; i < len(marshal.ResS); i++ {
-		proof.resS[i], _ = PointFromBytes(marshal.ResS[i])
+	proof = &Proof{}
+
+	scalars, e := bigsFromBytes(marshal.C, marshal.ResCsk, marshal.ResNym)
+	if e != nil {
+		return nil, fmt.Errorf("ParseProof: %v", e)
 	}
+	proof.c, proof.resCsk, proof.resNym = scalars[0], scalars[1], scalars[2]
 
-	proof.resCpk = make([]interface{}, len(marshal.ResCpk))
-	for i := 0; i < len(marshal.ResCpk); i++ {
-		proof.resCpk[i], _ = PointFromBytes(marshal.ResCpk[i])
+	if proof.fingerprint, e = fingerprintFromBytes(marshal.Fingerprint); e != nil {
+		return nil, fmt.Errorf("ParseProof: %v", e)
 	}
 
-	proof.resT = make([][]interface{}, len(marshal.ResT))
-	for i := 0; i < len(marshal.ResT); i++ {
-		proof.resT[i] = make([]interface{}, len(marshal.ResT[i]))
-		for j := 0; j < len(marshal.ResT[i]); j++ {
-			proof.resT[i][j], _ = PointFromBytes(marshal.ResT[i][j])
+	proof.rPrime = make([]interface{}, L+1)
+	proof.resS = make([]interface{}, L+1)
+	proof.resT = make([][]interface{}, L+1)
+	proof.resA = make([][]interface{}, L+1)
+	proof.resCpk = make([]interface{}, L+1)
+
+	for i := 1; i <= L; i++ {
+		first := i%2 == 1
+
+		if proof.rPrime[i], e = pointFromBytesInGroup(marshal.RPrime[i], !first, false); e != nil {
+			return nil, fmt.Errorf("ParseProof: rPrime[%d]: %v", i, e)
+		}
+		if proof.resS[i], e = pointFromBytesInGroup(marshal.ResS[i], first, false); e != nil {
+			return nil, fmt.Errorf("ParseProof: resS[%d]: %v", i, e)
+		}
+		// the bottom level key is proven by resCsk
+		if proof.resCpk[i], e = pointFromBytesInGroup(marshal.ResCpk[i], first, i == L); e != nil {
+			return nil, fmt.Errorf("ParseProof: resCpk[%d]: %v", i, e)
+		}
+		if i == L && proof.resCpk[i] != nil {
+			return nil, fmt.Errorf("ParseProof: resCpk[%d] must be empty for the last level", i)
+		}
+		if len(marshal.ResT[i]) != len(marshal.ResA[i])+1 {
+			return nil, fmt.Errorf("ParseProof: resT[%d] must have exactly one more element than resA[%d]", i, i)
+		}
+		if proof.resT[i], e = pointsFromBytesInGroup(marshal.ResT[i], first, false); e != nil {
+			return nil, fmt.Errorf("ParseProof: resT[%d]: %v", i, e)
+		}
+		// resA is empty for disclosed attributes
+		if proof.resA[i], e = pointsFromBytesInGroup(marshal.ResA[i], first, true); e != nil {
+			return nil, fmt.Errorf("ParseProof: resA[%d]: %v", i, e)
 		}
 	}
 
-	proof.resA = make([][]interface{}, len(marshal.ResA))
-	for i := 0; i < len(marshal.ResA); i++ {
-		proof.resA[i] = make([]interface{}, len(marshal.ResA[i]))
-		for j := 0; j < len(marshal.ResA[i]); j++ {
-			proof.resA[i][j], _ = PointFromBytes(marshal.ResA[i][j])
+	// commitments are only carried by batchable proofs, absent ones are empty
+	if len(marshal.Coms) > 0 {
+		proof.coms = make([][]*FP256BN.FP12, len(marshal.Coms))
+		for i := 0; i < len(marshal.Coms); i++ {
+			proof.coms[i] = make([]*FP256BN.FP12, len(marshal.Coms[i]))
+			for j := 0; j < len(marshal.Coms[i]); j++ {
+				if len(marshal.Coms[i][j]) == 0 {
+					continue
+				}
+				if proof.coms[i][j], e = fpFromBytes(marshal.Coms[i][j]); e != nil {
+					return nil, fmt.Errorf("ParseProof: coms[%d][%d]: %v", i, j, e)
+				}
+			}
+		}
+		if e = proof.checkCommitments(); e != nil {
+			return nil, fmt.Errorf("ParseProof: %v", e)
 		}
 	}
 
@@ -81,6 +136,7 @@ func (proof *Proof) ToBytes() (result []byte) {
 	var marshal proofMarshal
 
 	marshal.C = bigToBytes(proof.c)
+	marshal.Fingerprint = proof.fingerprint
 	marshal.ResCsk = bigToBytes(proof.resCsk)
 	marshal.ResNym = bigToBytes(proof.resNym)
 
@@ -115,6 +171,18 @@ func (proof *Proof) ToBytes() (result []byte) {
 		}
 	}
 
+	if proof.coms != nil {
+		marshal.Coms = make([][][]byte, len(proof.coms))
+		for i := 0; i < len(proof.coms); i++ {
+			marshal.Coms[i] = make([][]byte, len(proof.coms[i]))
+			for j := 0; j < len(proof.coms[i]); j++ {
+				if proof.coms[i][j] != nil {
+					marshal.Coms[i][j] = fpToBytes(proof.coms[i][j])
+				}
+			}
+		}
+	}
+
 	result, _ = asn1.Marshal(marshal)
 
 	return
@@ -135,6 +203,10 @@ func (proof *Proof) Equals(other Proof) (result bool) {
 		return
 	}
 
+	if !bytesEqual(proof.fingerprint, other.fingerprint) {
+		return
+	}
+
 	if !pointListEquals(proof.rPrime, other.rPrime) {
 		return
 	}
@@ -155,9 +227,94 @@ func (proof *Proof) Equals(other Proof) (result bool) {
 		return
 	}
 
+	if len(proof.coms) != len(other.coms) {
+		return
+	}
+	for i := 0; i < len(proof.coms); i++ {
+		if len(proof.coms[i]) != len(other.coms[i]) {
+			return
+		}
+		for j := 0; j < len(proof.coms[i]); j++ {
+			if !fpEqual(proof.coms[i][j], other.coms[i][j]) {
+				return
+			}
+		}
+	}
+
 	return true
 }
 
+// validate makes sure the proof has the shape of a proof for L levels and runs ValidatePoint on its points.
+// The entries at level 0, resCpk at level L and the responses for the disclosed attributes are absent (nil).
+func (proof *Proof) validate() (e error) {
+	L := len(proof.rPrime) - 1
+	if L < 1 || len(proof.resS) != L+1 || len(proof.resCpk) != L+1 || len(proof.resT) != L+1 || len(proof.resA) != L+1 {
+		return fmt.Errorf("proof does not have the same number of levels in all of its components")
+	}
+
+	if e = validatePoints(proof.rPrime[1:]...); e != nil {
+		return fmt.Errorf("rPrime: %v", e)
+	}
+	if e = validatePoints(proof.resS[1:]...); e != nil {
+		return fmt.Errorf("resS: %v", e)
+	}
+	if e = validatePoints(proof.resCpk[1:L]...); e != nil {
+		return fmt.Errorf("resCpk: %v", e)
+	}
+	for i := 1; i <= L; i++ {
+		if len(proof.resT[i]) != len(proof.resA[i])+1 {
+			return fmt.Errorf("resT[%d] must have one entry more than resA[%d]", i, i)
+		}
+		if e = validatePoints(proof.resT[i]...); e != nil {
+			return fmt.Errorf("resT[%d]: %v", i, e)
+		}
+		if e = validateOptionalPoints(proof.resA[i]...); e != nil {
+			return fmt.Errorf("resA[%d]: %v", i, e)
+		}
+	}
+
+	return
+}
+
+// checkCommitments makes sure the commitments carried by the proof have the shape of the recomputed ones,
+// i.e. coms[i][j] is present exactly for 1 <= i <= L and j <= len(resA[i]) + 1, in L+1 rows of equal length.
+func (proof *Proof) checkCommitments() (e error) {
+	L := len(proof.resA) - 1
+	width := 0
+	for i := 1; i <= L; i++ {
+		if len(proof.resA[i])+2 > width {
+			width = len(proof.resA[i]) + 2
+		}
+	}
+
+	if len(proof.coms) != L+1 {
+		return fmt.Errorf("commitments must have %d levels, not %d", L+1, len(proof.coms))
+	}
+	for i := 0; i <= L; i++ {
+		if len(proof.coms[i]) != width {
+			return fmt.Errorf("coms[%d] must have %d entries, not %d", i, width, len(proof.coms[i]))
+		}
+		for j := 0; j < width; j++ {
+			if expected := i != 0 && j < len(proof.resA[i])+2; expected != (proof.coms[i][j] != nil) {
+				return fmt.Errorf("coms[%d][%d] must be %s", i, j, map[bool]string{true: "present", false: "empty"}[expected])
+			}
+		}
+	}
+
+	return
+}
+
+// validate runs ValidatePoint on all disclosed attributes
+func (indices Indices) validate() (e error) {
+	for _, ij := range indices {
+		if e = ValidatePoint(ij.Attribute); e != nil {
+			return fmt.Errorf("invalid disclosed attribute (%d, %d): %v", ij.I, ij.J, e)
+		}
+	}
+
+	return
+}
+
 type credentialsMarshal struct {
 	Signatures []grothSignatureMarshal
 	Attributes [][][]byte
@@ -165,30 +322,69 @@ type credentialsMarshal struct {
 }
 
 // CredentialsFromBytes un-marshals the credentials object using ASN1 encoding
+// Panics if the input is malformed, see ParseCredentials for a version that returns error.
 func CredentialsFromBytes(input []byte) (creds *Credentials) {
+	creds, e := ParseCredentials(input)
+	if e != nil {
+		panic("un-marshalling creds failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseCredentials un-marshals and validates the credentials object using ASN1 encoding.
+// Besides the validity of each point, it checks that the credentials are well-formed,
+// i.e. lengths of the components agree and each point is in the group prescribed by its level.
+// Note, this does not verify the signatures, use Credentials.Verify for that.
+func ParseCredentials(input []byte) (creds *Credentials, e error) {
 	var marshal credentialsMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling creds failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseCredentials: %v", e)
+	}
+
+	L := len(marshal.PublicKeys) - 1
+	if L < 0 {
+		return nil, fmt.Errorf("ParseCredentials: empty credentials")
+	}
+	if len(marshal.Signatures) != L+1 || len(marshal.Attributes) != L+1 {
+		return nil, fmt.Errorf("ParseCredentials: lengths of the components do not agree with L = %d", L)
+	}
+	root := marshal.Signatures[0]
+	if len(root.R) != 0 || len(root.S) != 0 || len(root.Ts) != 0 || len(marshal.Attributes[0]) != 0 {
+		return nil, fmt.Errorf("ParseCredentials: signature and attributes for level 0 must be empty")
 	}
 
 	creds = &Credentials{}
 
-	creds.signatures = make([]GrothSignature, len(marshal.Signatures))
-	for i := 0; i < len(marshal.Signatures); i++ {
-		creds.signatures[i] = *marshal.Signatures[i].toGrothSignature()
-	}
+	creds.signatures = make([]GrothSignature, L+1)
+	creds.publicKeys = make([]PK, L+1)
+	creds.Attributes = make([][]interface{}, L+1)
 
-	creds.publicKeys = make([]interface{}, len(marshal.PublicKeys))
-	for i := 0; i < len(marshal.PublicKeys); i++ {
-		creds.publicKeys[i], _ = PointFromBytes(marshal.PublicKeys[i])
+	if creds.publicKeys[0], e = pointFromBytesInGroup(marshal.PublicKeys[0], false, false); e != nil {
+		return nil, fmt.Errorf("ParseCredentials: publicKeys[0]: %v", e)
 	}
 
-	creds.Attributes = make([][]interface{}, len(marshal.Attributes))
-	for i := 0; i < len(marshal.Attributes); i++ {
-		creds.Attributes[i] = make([]interface{}, len(marshal.Attributes[i]))
-		for j := 0; j < len(marshal.Attributes[i]); j++ {
-			creds.Attributes[i][j], _ = PointFromBytes(marshal.Attributes[i][j])
+	for i := 1; i <= L; i++ {
+		first := i%2 == 1
+
+		if creds.publicKeys[i], e = pointFromBytesInGroup(marshal.PublicKeys[i], first, false); e != nil {
+			return nil, fmt.Errorf("ParseCredentials: publicKeys[%d]: %v", i, e)
+		}
+		if creds.Attributes[i], e = pointsFromBytesInGroup(marshal.Attributes[i], first, false); e != nil {
+			return nil, fmt.Errorf("ParseCredentials: Attributes[%d]: %v", i, e)
 		}
+
+		signature, e := marshal.Signatures[i].toGrothSignature()
+		if e != nil {
+			return nil, fmt.Errorf("ParseCredentials: signatures[%d]: %v", i, e)
+		}
+		if _, rFirst := signature.r.(*FP256BN.ECP); rFirst == first {
+			return nil, fmt.Errorf("ParseCredentials: signatures[%d] is in the wrong group", i)
+		}
+		if len(signature.ts) != len(creds.Attributes[i])+1 {
+			return nil, fmt.Errorf("ParseCredentials: signatures[%d] must sign the public key and all attributes", i)
+		}
+		creds.signatures[i] = *signature
 	}
 
 	return
@@ -260,7 +456,7 @@ func (indices Indices) Swap(i, j int) {
 	indices[i], indices[j] = indices[j], indices[i]
 }
 func (indices Indices) Less(i, j int) bool {
-	return indices[i].I < indices[j].I || indices[i].J < indices[j].J
+	return indices[i].I < indices[j].I || (indices[i].I == indices[j].I && indices[i].J < indices[j].J)
 }
 
 func (indices Indices) contains(i, j int) (attribute interface{}) {
@@ -272,18 +468,18 @@ func (indices Indices) contains(i, j int) (attribute interface{}) {
 	return
 }
 
-func (indices Indices) hash() (result []byte) {
+// appendTo adds the disclosed attributes to the transcript in the canonical (sorted) order
+func (indices Indices) appendTo(t *transcript) {
 	d := make(Indices, len(indices))
 	copy(d, indices)
 	sort.Sort(d)
 
+	t.append("disclosed", []byte(strconv.Itoa(len(d))))
 	for i := 0; i < len(d); i++ {
-		result = append(result, []byte(strconv.Itoa(d[i].I))...)
-		result = append(result, []byte(strconv.Itoa(d[i].J))...)
-		result = append(result, PointToBytes(d[i].Attribute)...)
+		t.append("i", []byte(strconv.Itoa(d[i].I)))
+		t.append("j", []byte(strconv.Itoa(d[i].J)))
+		t.appendPoint("attribute", d[i].Attribute)
 	}
-
-	return result
 }
 
 type eProductComputer struct {
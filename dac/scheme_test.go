@@ -29,15 +29,31 @@ const SEED = 0x13
 
 // helper that constructs a valid credential chain of L levels with n attributes per level
 func generateChain(L int, n int) (creds *Credentials, sk SK, pk PK, ys [][]interface{}, skNym SK, pkNym PK, h interface{}, e error) {
-	absL := L
-	if absL < 0 {
-		absL = -absL
+	return generateCustomChain(L, levelSizes(L, n), chainOptions{})
+}
+
+// helper that constructs a chain of L levels with n attributes per level,
+// where the attributes at the given positions are replaced with the given ones (see chainOptions)
+func generateChainWith(seed byte, L int, n int, attributes map[Position]interface{}) (creds *Credentials, sk SK, pk PK, ys [][]interface{}, skNym SK, pkNym PK, h interface{}) {
+	creds, sk, pk, ys, skNym, pkNym, h, e := generateCustomChain(L, levelSizes(L, n), chainOptions{seed: seed, attributes: attributes})
+	if e != nil {
+		panic(e)
+	}
+
+	return
+}
+
+// helper that makes the numbers of attributes of generateCustomChain, n for each of the |L| levels
+func levelSizes(L int, n int) (ns []int) {
+	if L < 0 {
+		L = -L
 	}
-	ns := make([]int, absL+1)
-	for i := 1; i <= absL; i++ {
+	ns = make([]int, L+1)
+	for i := 1; i <= L; i++ {
 		ns[i] = n
 	}
-	return generateCustomChain(L, ns)
+
+	return
 }
 
 // helper that constructs the chain, generates the proof and verifies
@@ -84,33 +100,59 @@ func marshal(L int, n int, attributes AttributesCase) (marshaled []byte) {
 	return proof.ToBytes()
 }
 
-func generateCustomChain(L int, n []int) (creds *Credentials, sk SK, pk PK, ys [][]interface{}, skNym SK, pkNym PK, h interface{}, e error) {
-	const YsNum = 10
-
-	prg := getNewRand(SEED)
+// chainOptions customize the chains of generateCustomChain
+type chainOptions struct {
+	// seed of the keys (SEED if 0), ys and h are the same for any seed
+	seed byte
+	// attributes replacing the generated ones at their positions, either untyped points or typed Values
+	attributes map[Position]interface{}
+	// if set, the levels are delegated with the schema (see DelegateWithSchema),
+	// levels[i-1] are the values of level i, and L and n are ignored
+	schema *Schema
+	levels []map[string]Value
+}
 
-	// Level-0 creds
-	sk, pk = GenerateKeys(prg, 0)
-	creds = MakeCredentials(pk)
+// helper that constructs a valid credential chain of L levels with n[i] attributes at level i;
+// h is in G2 if L is negative
+func generateCustomChain(L int, n []int, options chainOptions) (creds *Credentials, sk SK, pk PK, ys [][]interface{}, skNym SK, pkNym PK, h interface{}, e error) {
+	const YsNum = 10
 
+	// the public parameters come from their own seed
+	prg := getNewRand(SEED + 0x80)
 	ys = make([][]interface{}, 2)
 	ys[0] = GenerateYs(false, YsNum, prg)
 	ys[1] = GenerateYs(true, YsNum, prg)
-	h = FP256BN.ECP_generator().Mul(FP256BN.Randomnum(FP256BN.NewBIGints(FP256BN.CURVE_Order), prg))
-	// little hack to generate h in different group
+	h = GenerateNymBase("test chains", L >= 0)
 	if L < 0 {
-		h = FP256BN.ECP2_generator().Mul(FP256BN.Randomnum(FP256BN.NewBIGints(FP256BN.CURVE_Order), prg))
 		L = -L
 	}
+	if options.schema != nil {
+		L = len(options.levels)
+	}
+
+	seed := options.seed
+	if seed == 0 {
+		seed = SEED
+	}
+	prg = getNewRand(seed)
+
+	// Level-0 creds
+	sk, pk = GenerateKeys(prg, 0)
+	creds = MakeCredentials(pk)
 
 	for index := 1; index <= L; index++ {
 		// Level-index creds
 		ski, pki := GenerateKeys(prg, index)
-		var ai []interface{}
-		for j := 0; j < n[index]; j++ {
-			ai = append(ai, ProduceAttributes(index, "attribute-"+strconv.Itoa(index)+"-"+strconv.Itoa(j))...)
+		if options.schema != nil {
+			e = creds.DelegateWithSchema(sk, pki, options.levels[index-1], prg, ys, *options.schema)
+		} else {
+			var ai []interface{}
+			if ai, e = levelAttributes(index, n[index], options.attributes); e != nil {
+				return
+			}
+			e = creds.Delegate(sk, pki, ai, prg, ys)
 		}
-		if e = creds.Delegate(sk, pki, ai, prg, ys); e != nil {
+		if e != nil {
 			return
 		}
 		sk = ski
@@ -121,6 +163,26 @@ func generateCustomChain(L int, n []int) (creds *Credentials, sk SK, pk PK, ys [
 	return
 }
 
+// helper that makes the n attributes of level L for generateCustomChain
+func levelAttributes(L int, n int, attributes map[Position]interface{}) (ai []interface{}, e error) {
+	for j := 0; j < n; j++ {
+		switch attribute := attributes[Position{L, j}].(type) {
+		case nil:
+			ai = append(ai, ProduceAttributes(L, "attribute-"+strconv.Itoa(L)+"-"+strconv.Itoa(j))...)
+		case Value:
+			encoded, e := attribute.Attribute(L)
+			if e != nil {
+				return nil, e
+			}
+			ai = append(ai, encoded.Untyped())
+		default:
+			ai = append(ai, attribute)
+		}
+	}
+
+	return
+}
+
 // Tests
 
 func TestHappyPath(t *testing.T) {
@@ -608,7 +670,7 @@ func TestSchemeUserErrors(t *testing.T) {
 
 		e := creds.Delegate(sk, pki, ai, prg, ys)
 
-		assert.ErrorContains(t, e, "interface conversion")
+		assert.ErrorContains(t, e, "attribute 0 for level 1 must be in G1, got G2")
 	})
 
 	t.Run("verify", func(t *testing.T) {
@@ -619,7 +681,7 @@ func TestSchemeUserErrors(t *testing.T) {
 
 		e := creds.Verify(sk, pk, ys)
 
-		assert.ErrorContains(t, e, "interface conversion")
+		assert.ErrorContains(t, e, "trusted authority's public key must be in G2, got G1")
 
 		creds, sk, pk, ys, _, _, _, _ = generateChain(3, 2)
 
@@ -639,7 +701,7 @@ func TestSchemeUserErrors(t *testing.T) {
 
 		_, e := creds.Prove(prg, sk, pk, Indices{}, []byte("Hello"), ys, h, skNym)
 
-		assert.ErrorContains(t, e, "error")
+		assert.ErrorContains(t, e, "y-value 0 for even levels must be in G2")
 	})
 
 	t.Run("prove", func(t *testing.T) {
@@ -662,7 +724,7 @@ func TestSchemeUserErrors(t *testing.T) {
 
 		e := proof.VerifyProof(pk, ys, h, pkNym, Indices{}, []byte("Hello"))
 
-		assert.ErrorContains(t, e, "error")
+		assert.ErrorContains(t, e, "y-value 0 for odd levels must be in G1")
 	})
 
 	t.Run("verify proof", func(t *testing.T) {
@@ -674,7 +736,7 @@ func TestSchemeUserErrors(t *testing.T) {
 
 		e := proof.VerifyProof(pk, ys, h, pkNym, Indices{}, []byte("Hello"))
 
-		assert.ErrorContains(t, e, "index out of range")
+		assert.ErrorContains(t, e, "invalid proof")
 	})
 }
 
@@ -843,6 +905,382 @@ func TestSchemeHInGTwo(t *testing.T) {
 	}
 }
 
+// parsing credentials yields the original object or a descriptive error
+func TestSchemeCredentialsParse(t *testing.T) {
+	type TestCase string
+	const (
+		Correct        TestCase = "correct"
+		Malformed      TestCase = "malformed ASN1"
+		WrongLength    TestCase = "lengths disagree"
+		LevelZero      TestCase = "level 0 not empty"
+		RootKey        TestCase = "root key in wrong group"
+		OffCurve       TestCase = "public key not on curve"
+		WrongAttribute TestCase = "attribute in wrong group"
+		WrongSignature TestCase = "signature in wrong group"
+		FewTs          TestCase = "too few ts"
+	)
+
+	creds, _, _, _, _, _, _, _ := generateChain(3, 2)
+
+	for _, tc := range []TestCase{Correct, Malformed, WrongLength, LevelZero, RootKey, OffCurve, WrongAttribute, WrongSignature, FewTs} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal credentialsMarshal
+			bytes := remarshal(t, creds.ToBytes(), &marshal, func() {
+				switch tc {
+				case WrongLength:
+					marshal.PublicKeys = marshal.PublicKeys[:2]
+				case LevelZero:
+					marshal.Attributes[0] = [][]byte{pointBytes(false)}
+				case RootKey:
+					marshal.PublicKeys[0] = pointBytes(true)
+				case OffCurve:
+					marshal.PublicKeys[2] = offCurveBytes(false)
+				case WrongAttribute:
+					marshal.Attributes[1][0] = pointBytes(false)
+				case WrongSignature:
+					marshal.Signatures[2].R = pointBytes(false)
+				case FewTs:
+					marshal.Signatures[1].Ts = marshal.Signatures[1].Ts[:1]
+				}
+			})
+
+			if tc == Malformed {
+				bytes = bytes[:len(bytes)-3]
+			}
+
+			recovered, e := ParseCredentials(bytes)
+			if tc == Correct {
+				assert.NilError(t, e)
+				assert.Check(t, creds.Equals(recovered))
+			} else {
+				assert.ErrorContains(t, e, "ParseCredentials")
+			}
+		})
+	}
+}
+
+// parsing proof yields the original object or a descriptive error
+func TestSchemeProofParse(t *testing.T) {
+	type TestCase string
+	const (
+		Correct     TestCase = "correct"
+		Malformed   TestCase = "malformed ASN1"
+		NoLevels    TestCase = "no levels"
+		WrongLength TestCase = "lengths disagree"
+		LevelZero   TestCase = "level 0 not empty"
+		OffCurve    TestCase = "rPrime not on curve"
+		Infinity    TestCase = "resS at infinity"
+		WrongGroup  TestCase = "resT in wrong group"
+		LastCpk     TestCase = "resCpk for last level"
+		FewResT     TestCase = "too few resT"
+		LargeScalar TestCase = "scalar not reduced"
+		Batchable   TestCase = "batchable"
+		ComsMissing TestCase = "commitment missing"
+		ComsExtra   TestCase = "commitment for level 0"
+		ComsLarge   TestCase = "commitment not reduced"
+		ComsZero    TestCase = "commitment is zero"
+	)
+
+	prg := getNewRand(SEED + 1)
+
+	creds, sk, pk, ys, skNym, _, h, _ := generateChain(3, 2)
+	D := Indices{{1, 1, creds.Attributes[1][1]}}
+	plain, _ := creds.Prove(prg, sk, pk, D, []byte("message"), ys, h, skNym)
+	batchable, _ := creds.ProveBatchable(prg, sk, pk, D, []byte("message"), ys, h, skNym)
+
+	for _, tc := range []TestCase{Correct, Malformed, NoLevels, WrongLength, LevelZero, OffCurve, Infinity, WrongGroup, LastCpk, FewResT, LargeScalar, Batchable, ComsMissing, ComsExtra, ComsLarge, ComsZero} {
+		t.Run(string(tc), func(t *testing.T) {
+			proof := plain
+			if tc == Batchable || strings.HasPrefix(string(tc), "commitment") {
+				proof = batchable
+			}
+
+			var marshal proofMarshal
+			bytes := remarshal(t, proof.ToBytes(), &marshal, func() {
+				switch tc {
+				case NoLevels:
+					marshal.RPrime = marshal.RPrime[:1]
+					marshal.ResS = marshal.ResS[:1]
+					marshal.ResT = marshal.ResT[:1]
+					marshal.ResA = marshal.ResA[:1]
+					marshal.ResCpk = marshal.ResCpk[:1]
+				case WrongLength:
+					marshal.ResS = marshal.ResS[:2]
+				case LevelZero:
+					marshal.ResS[0] = pointBytes(false)
+				case OffCurve:
+					marshal.RPrime[1] = offCurveBytes(false)
+				case Infinity:
+					marshal.ResS[2] = infinityBytes(false)
+				case WrongGroup:
+					marshal.ResT[1][0] = pointBytes(false)
+				case LastCpk:
+					marshal.ResCpk[3] = pointBytes(true)
+				case FewResT:
+					marshal.ResT[2] = marshal.ResT[2][:1]
+				case LargeScalar:
+					marshal.ResCsk = orderBytes()
+				case ComsMissing:
+					marshal.Coms[2][1] = nil
+				case ComsExtra:
+					marshal.Coms[0][1] = marshal.Coms[1][1]
+				case ComsLarge:
+					for i := 0; i < _BIGByteLength; i++ {
+						marshal.Coms[1][0][i] = 0xFF
+					}
+				case ComsZero:
+					marshal.Coms[3][2] = make([]byte, _FP12ByteLength)
+				}
+			})
+
+			if tc == Malformed {
+				bytes = append(bytes, 0x00)
+			}
+
+			recovered, e := ParseProof(bytes)
+			if tc == Correct || tc == Batchable {
+				assert.NilError(t, e)
+				assert.Check(t, recovered.Equals(proof))
+			} else {
+				assert.ErrorContains(t, e, "ParseProof")
+				assert.Check(t, !strings.Contains(e.Error(), "<nil>"))
+			}
+			if tc == LastCpk {
+				assert.ErrorContains(t, e, "resCpk[3] must be empty for the last level")
+			}
+		})
+	}
+}
+
+// proof verification rejects points outside of the prime-order subgroups
+func TestSchemeVerifyProofInvalidPoints(t *testing.T) {
+	prg := getNewRand(SEED + 1)
+
+	creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(3, 2)
+	D := Indices{{1, 1, creds.Attributes[1][1]}}
+	proof, _ := creds.Prove(prg, sk, pk, D, []byte("message"), ys, h, skNym)
+
+	assert.NilError(t, proof.VerifyProof(pk, ys, h, pkNym, D, []byte("message")))
+
+	for _, component := range []string{"rPrime", "resS", "resT", "resA", "resCpk", "pkNym", "attribute"} {
+		var like interface{}
+		switch component {
+		case "rPrime":
+			like = proof.rPrime[1]
+		case "resS":
+			like = proof.resS[2]
+		case "resT":
+			like = proof.resT[3][1]
+		case "resA":
+			like = proof.resA[2][0]
+		case "resCpk":
+			like = proof.resCpk[2]
+		case "pkNym":
+			like = pkNym
+		case "attribute":
+			like = D[0].Attribute
+		}
+
+		for name, point := range invalidPointsLike(like) {
+			t.Run(fmt.Sprintf("%s %s", component, name), func(t *testing.T) {
+				tampered := ProofFromBytes(proof.ToBytes())
+				tamperedPkNym := pkNym
+				tamperedD := Indices{D[0]}
+				switch component {
+				case "rPrime":
+					tampered.rPrime[1] = point
+				case "resS":
+					tampered.resS[2] = point
+				case "resT":
+					tampered.resT[3][1] = point
+				case "resA":
+					tampered.resA[2][0] = point
+				case "resCpk":
+					tampered.resCpk[2] = point
+				case "pkNym":
+					tamperedPkNym = point
+				case "attribute":
+					tamperedD[0].Attribute = point
+				}
+
+				e := tampered.VerifyProof(pk, ys, h, tamperedPkNym, tamperedD, []byte("message"))
+				assert.ErrorContains(t, e, "invalid")
+			})
+		}
+	}
+}
+
+// helper that generates a batch of valid proofs for the same credentials, made with ProveBatchable if batchable
+func generateProofBatch(prg *amcl.RAND, N int, batchable bool) (batch []BatchProof, pk PK, ys [][]interface{}, h interface{}) {
+	creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(2, 2)
+
+	for i := 0; i < N; i++ {
+		D := Indices{{1, 1, creds.Attributes[1][1]}}
+		m := []byte(fmt.Sprintf("message %d", i))
+		prove := creds.Prove
+		if batchable {
+			prove = creds.ProveBatchable
+		}
+		proof, _ := prove(prg, sk, pk, D, m, ys, h, skNym)
+
+		batch = append(batch, BatchProof{&proof, pkNym, D, m, ""})
+	}
+
+	return
+}
+
+// helper that makes a batchable proof for the credentials of generateProofBatch
+// with the commitment multiplied by factor, which the responses do not recompute, but that the challenge is computed over,
+// so that the proof passes the challenge check of the batch but not its equations
+func forgeCommitment(prg *amcl.RAND, entry BatchProof, factor func(coms [][]*FP256BN.FP12) *FP256BN.FP12) *Proof {
+	creds, sk, pk, ys, skNym, _, h, _ := generateChain(2, 2)
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	prover, _ := creds.proveCommit(prg, entry.D, ys, h, FP256BN.Randomnum(q, prg), FP256BN.Randomnum(q, prg))
+	prover.coms[1][0].Mul(factor(prover.coms))
+
+	t := newTranscript(_ProtocolCredentials, binding{})
+	appendCommitments(t, ys, pk, h, prover.rPrime, prover.coms, prover.comNym, entry.D)
+	t.append("m", entry.M)
+
+	proof := prover.respond(t.challenge(q), sk, skNym)
+	proof.coms = prover.coms
+
+	return &proof
+}
+
+// factors for forgeCommitment: another commitment (in GT) and -1 (of order 2, outside GT)
+var (
+	otherCommitment = func(coms [][]*FP256BN.FP12) *FP256BN.FP12 { return coms[1][1] }
+	minusOne        = func([][]*FP256BN.FP12) *FP256BN.FP12 {
+		raw := make([]byte, _FP12ByteLength)
+		modulus := FP256BN.NewBIGints(FP256BN.Modulus)
+		modulus.Minus(FP256BN.NewBIGint(1)).ToBytes(raw[:_BIGByteLength])
+		x, _ := fpFromBytes(raw)
+		return x
+	}
+)
+
+// batch verification accepts valid proofs and reports the invalid ones
+func TestSchemeVerifyProofBatch(t *testing.T) {
+	type TestCase string
+	const (
+		AllValid     TestCase = "all valid"
+		Empty        TestCase = "empty batch"
+		WrongMessage TestCase = "wrong message"
+		Tampered     TestCase = "tampered proofs"
+		Missing      TestCase = "proof missing"
+		OneWorker    TestCase = "one worker"
+		Mixed        TestCase = "some proofs not batchable"
+		Commitments  TestCase = "commitments replaced"
+		Forged       TestCase = "forged commitment"
+		OutsideGT    TestCase = "commitment outside GT"
+	)
+
+	prg := getNewRand(SEED + 1)
+
+	for _, batchable := range []bool{false, true} {
+		batch, pk, ys, h := generateProofBatch(prg, 5, batchable)
+
+		for _, tc := range []TestCase{AllValid, Empty, WrongMessage, Tampered, Missing, OneWorker, Mixed, Commitments, Forged, OutsideGT} {
+			if !batchable && (tc == Mixed || tc == Commitments || tc == Forged || tc == OutsideGT) {
+				continue
+			}
+
+			t.Run(fmt.Sprintf("batchable=%t %s", batchable, tc), func(t *testing.T) {
+				tampered := make([]BatchProof, len(batch))
+				copy(tampered, batch)
+
+				var expected []int
+				switch tc {
+				case Empty:
+					tampered = nil
+				case WrongMessage:
+					tampered[2].M = []byte("wrong")
+					expected = []int{2}
+				case Tampered:
+					for _, i := range []int{1, 4} {
+						proof := ProofFromBytes(batch[i].Proof.ToBytes())
+						proof.resCsk = FP256BN.NewBIGint(0x13)
+						tampered[i].Proof = proof
+					}
+					expected = []int{1, 4}
+				case Missing:
+					tampered[0].Proof = nil
+					expected = []int{0}
+				case OneWorker:
+					Workers = 1
+					defer func() { Workers = 0 }()
+					tampered[3].D = Indices{}
+					expected = []int{3}
+				case Mixed:
+					for _, i := range []int{0, 3} {
+						proof := ProofFromBytes(batch[i].Proof.ToBytes())
+						proof.coms = nil
+						tampered[i].Proof = proof
+					}
+					tampered[3].M = []byte("wrong")
+					expected = []int{3}
+				case Commitments:
+					// a valid proof with the commitments of another one is verified on its own
+					proof := ProofFromBytes(batch[1].Proof.ToBytes())
+					proof.coms = batch[2].Proof.coms
+					tampered[1].Proof = proof
+				case Forged:
+					tampered[2].Proof = forgeCommitment(prg, batch[2], otherCommitment)
+					expected = []int{2}
+				case OutsideGT:
+					tampered[4].Proof = forgeCommitment(prg, batch[4], minusOne)
+					expected = []int{4}
+				}
+
+				invalid, e := VerifyProofBatch(pk, ys, h, tampered)
+				if expected == nil {
+					assert.NilError(t, e)
+					assert.Equal(t, len(invalid), 0)
+				} else {
+					assert.ErrorContains(t, e, fmt.Sprintf("proof %d", expected[0]))
+					assert.DeepEqual(t, invalid, expected)
+				}
+			})
+		}
+	}
+}
+
+// batchable proofs pass the single combined check, a forged commitment fails it
+func TestSchemeVerifyProofBatchCombined(t *testing.T) {
+	prg := getNewRand(SEED + 1)
+	batch, pk, ys, h := generateProofBatch(prg, 3, true)
+
+	authorityPK, _ := toG2("pk", pk)
+	params, _ := TypedGrothParams(ys)
+	base, _ := TypedPoint(h)
+
+	batched := func(entries []BatchProof) (result []*proofBatch) {
+		for _, entry := range entries {
+			combined, e := entry.Proof.batch(G2PublicKey{authorityPK}, params, base, entry.PkNym, entry.D, entry.M, entry.Context)
+			assert.NilError(t, e)
+			result = append(result, combined)
+		}
+		return
+	}
+
+	assert.Check(t, batchedValid(batched(batch)))
+	assert.Check(t, batchedValid(nil))
+
+	forged := append([]BatchProof{}, batch...)
+	forged[1].Proof = forgeCommitment(prg, batch[1], otherCommitment)
+	assert.Check(t, !batchedValid(batched(forged)))
+
+	outside := forgeCommitment(prg, batch[0], minusOne)
+	_, e := outside.batch(G2PublicKey{authorityPK}, params, base, batch[0].PkNym, batch[0].D, batch[0].M, batch[0].Context)
+	assert.ErrorContains(t, e, "commitment 1-0 is not in GT")
+
+	plain, _, _, _ := generateProofBatch(prg, 1, false)
+	_, e = plain[0].Proof.batch(G2PublicKey{authorityPK}, params, base, plain[0].PkNym, plain[0].D, plain[0].M, plain[0].Context)
+	assert.ErrorContains(t, e, "commitments must have")
+}
+
 // un-marshaling failure properly reported (panic)
 func TestSchemeCredentialsUnMarshalingFail(t *testing.T) {
 	defer func() {
@@ -940,6 +1378,38 @@ func BenchmarkSchemeVerifyProof(b *testing.B) {
 	}
 }
 
+func BenchmarkSchemeVerifyProofBatch(b *testing.B) {
+
+	prg := getNewRand(SEED + 1)
+
+	for _, N := range []int{1, 5, 10, 20} {
+		batch, pk, ys, h := generateProofBatch(prg, N, false)
+		batchable, _, _, _ := generateProofBatch(prg, N, true)
+
+		b.Run(fmt.Sprintf("N=%d", N), func(b *testing.B) {
+			b.Run("sequential", func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					for _, entry := range batch {
+						entry.Proof.VerifyProof(pk, ys, h, entry.PkNym, entry.D, entry.M)
+					}
+				}
+			})
+
+			b.Run("batch", func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					VerifyProofBatch(pk, ys, h, batch)
+				}
+			})
+
+			b.Run("batchable", func(b *testing.B) {
+				for n := 0; n < b.N; n++ {
+					VerifyProofBatch(pk, ys, h, batchable)
+				}
+			})
+		})
+	}
+}
+
 func BenchmarkSchemeForPaper(b *testing.B) {
 
 	prg := getNewRand(SEED + 1)
@@ -1129,7 +1599,7 @@ func BenchmarkSchemeAgainsOriginalPaper(b *testing.B) {
 			} {
 				b.Run(fmt.Sprintf("n1=%d n2=%d", c.n1, c.n2), func(b *testing.B) {
 
-					creds, sk, pk, ys, skNym, pkNym, h, _ := generateCustomChain(L, []int{0, c.n1, c.n2})
+					creds, sk, pk, ys, skNym, pkNym, h, _ := generateCustomChain(L, []int{0, c.n1, c.n2}, chainOptions{})
 
 					m := []byte("Message")
 					if prove {
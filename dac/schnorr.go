@@ -48,6 +48,12 @@ func (schnorr *Schnorr) Generate() (sk SK, pk PK) {
 
 // Sign signs the message given by points on the curve (ECP or ECP2)
 func (schnorr *Schnorr) Sign(sk *FP256BN.BIG, m []byte) (signature SchnorrSignature) {
+	return schnorr.SignWithContext(sk, m, "")
+}
+
+// SignWithContext is Sign for the signature bound to the context (e.g. an application or a channel name).
+// Verify the signature with VerifyWithContext.
+func (schnorr *Schnorr) SignWithContext(sk *FP256BN.BIG, m []byte, context string) (signature SchnorrSignature) {
 
 	// k <- Zq
 	k := FP256BN.Randomnum(schnorr.q, schnorr.prg)
@@ -55,8 +61,8 @@ func (schnorr *Schnorr) Sign(sk *FP256BN.BIG, m []byte) (signature SchnorrSignat
 	// r := g^k
 	r := pointMultiply(schnorr.g, k)
 
-	// e := H(r, m)
-	signature.e = schnorr.hash(r, m)
+	// e := H(pk, r, m)
+	signature.e = schnorr.hash(context, pointMultiply(schnorr.g, sk), r, m)
 
 	// s := k + sk * e
 	signature.s = k.Plus(FP256BN.Modmul(sk, signature.e, schnorr.q))
@@ -68,8 +74,14 @@ func (schnorr *Schnorr) Sign(sk *FP256BN.BIG, m []byte) (signature SchnorrSignat
 // Verify verifies the signature.
 // Returns nil if verification is successful.
 func (schnorr *Schnorr) Verify(pk PK, signature SchnorrSignature, m []byte) (e error) {
+	return schnorr.VerifyWithContext(pk, signature, m, "")
+}
+
+// VerifyWithContext verifies the signature generated with SignWithContext.
+// The context has to be the one the signature was generated with.
+func (schnorr *Schnorr) VerifyWithContext(pk PK, signature SchnorrSignature, m []byte, context string) (e error) {
 	rv := productOfExponents(schnorr.g, signature.s, pointNegate(pk), signature.e)
-	ev := schnorr.hash(rv, m)
+	ev := schnorr.hash(context, pk, rv, m)
 
 	if !bigEqual(ev, signature.e) {
 		return fmt.Errorf("verification failed")
@@ -77,17 +89,16 @@ func (schnorr *Schnorr) Verify(pk PK, signature SchnorrSignature, m []byte) (e e
 	return
 }
 
-func (schnorr *Schnorr) hash(r interface{}, m []byte) *FP256BN.BIG {
-	// r || m to bytes
-	var raw []byte
-	var rBytes []byte
-
-	rBytes = PointToBytes(r)
+// hash binds the challenge to the public key, so that a signature cannot be re-targeted to another key
+func (schnorr *Schnorr) hash(context string, pk PK, r interface{}, m []byte) *FP256BN.BIG {
+	t := newTranscript(_ProtocolSchnorr, binding{context: context})
 
-	raw = append(raw, rBytes[:]...)
-	raw = append(raw, m[:]...)
+	t.appendPoint("g", schnorr.g)
+	t.appendPoint("pk", pk)
+	t.appendPoint("r", r)
+	t.append("m", m)
 
-	return sha3(schnorr.q, raw)
+	return t.challenge(schnorr.q)
 }
 
 type schnorrSignatureMarshal struct {
@@ -108,16 +119,32 @@ func (signature *SchnorrSignature) ToBytes() (result []byte) {
 }
 
 // SchnorrSignatureFromBytes un-marshals the NIZK object using ASN1 encoding
+// Panics if the input is malformed, see ParseSchnorrSignature for a version that returns error.
 func SchnorrSignatureFromBytes(input []byte) (signature *SchnorrSignature) {
+	signature, e := ParseSchnorrSignature(input)
+	if e != nil {
+		panic("un-marshalling schnorr signature failed: " + e.Error())
+	}
+
+	return
+}
+
+// ParseSchnorrSignature un-marshals and validates the NIZK object using ASN1 encoding
+func ParseSchnorrSignature(input []byte) (signature *SchnorrSignature, e error) {
 	var marshal schnorrSignatureMarshal
-	if rest, err := asn1.Unmarshal(input, &marshal); len(rest) != 0 || err != nil {
-		panic("un-marshalling schnorr signature failed")
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseSchnorrSignature: %v", e)
 	}
 
-	signature = &SchnorrSignature{}
+	scalars, e := bigsFromBytes(marshal.S, marshal.E)
+	if e != nil {
+		return nil, fmt.Errorf("ParseSchnorrSignature: %v", e)
+	}
 
-	signature.s = FP256BN.FromBytes(marshal.S)
-	signature.e = FP256BN.FromBytes(marshal.E)
+	signature = &SchnorrSignature{
+		s: scalars[0],
+		e: scalars[1],
+	}
 
 	return
 }
@@ -34,8 +34,12 @@ func TestSchnorr(t *testing.T) {
 				testSchnorrVerifyCorrect,
 				testSchnorrVerifyTamperedSignature,
 				testSchnorrVerifyWrongMessage,
+				testSchnorrContext,
+				testSchnorrKeyBound,
 				testSchnorrMarshal,
 				testSchnorrUnMarshalFails,
+				testSchnorrParse,
+				testSchnorrParseRejectsMalformed,
 			} {
 				t.Run(funcToString(reflect.ValueOf(test)), test)
 			}
@@ -135,6 +139,33 @@ func testSchnorrVerifyTamperedSignature(t *testing.T) {
 	assert.ErrorContains(t, rETampered, "")
 }
 
+// signature bound to a context verifies only with that context
+func testSchnorrContext(t *testing.T) {
+	m := []byte("Message")
+
+	sk, pk := schnorr.Generate()
+
+	signature := schnorr.SignWithContext(sk, m, "channel")
+
+	assert.NilError(t, schnorr.VerifyWithContext(pk, signature, m, "channel"))
+	assert.ErrorContains(t, schnorr.VerifyWithContext(pk, signature, m, "another channel"), "verification failed")
+	assert.ErrorContains(t, schnorr.Verify(pk, signature, m), "verification failed")
+}
+
+// the challenge covers the public key
+func testSchnorrKeyBound(t *testing.T) {
+	m := []byte("Message")
+
+	sk, pk := schnorr.Generate()
+	_, other := schnorr.Generate()
+
+	r := pointMultiply(schnorr.g, FP256BN.NewBIGint(0x13))
+	assert.Check(t, !bigEqual(schnorr.hash("", pk, r, m), schnorr.hash("", other, r, m)))
+
+	signature := schnorr.Sign(sk, m)
+	assert.ErrorContains(t, schnorr.Verify(other, signature, m), "verification failed")
+}
+
 // marshaling and un-marshaling yields the original object
 func testSchnorrMarshal(t *testing.T) {
 
@@ -162,6 +193,57 @@ func testSchnorrUnMarshalFails(t *testing.T) {
 	SchnorrSignatureFromBytes([]byte{0x13})
 }
 
+// parsing yields the original object
+func testSchnorrParse(t *testing.T) {
+	m := []byte("Message")
+
+	sk, pk := schnorr.Generate()
+	signature := schnorr.Sign(sk, m)
+
+	recovered, e := ParseSchnorrSignature(signature.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Check(t, schnorr.Verify(pk, *recovered, m))
+}
+
+// parsing rejects malformed input without panicking
+func testSchnorrParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed   TestCase = "malformed ASN1"
+		Trailing    TestCase = "trailing bytes"
+		ShortScalar TestCase = "short scalar"
+		LargeScalar TestCase = "scalar not reduced"
+	)
+
+	sk, _ := schnorr.Generate()
+	signature := schnorr.Sign(sk, []byte("Message"))
+
+	for _, tc := range []TestCase{Malformed, Trailing, ShortScalar, LargeScalar} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal schnorrSignatureMarshal
+			bytes := remarshal(t, signature.ToBytes(), &marshal, func() {
+				switch tc {
+				case ShortScalar:
+					marshal.S = marshal.S[1:]
+				case LargeScalar:
+					marshal.E = orderBytes()
+				}
+			})
+
+			switch tc {
+			case Malformed:
+				bytes = bytes[:len(bytes)-1]
+			case Trailing:
+				bytes = append(bytes, 0x13)
+			}
+
+			_, e := ParseSchnorrSignature(bytes)
+			assert.ErrorContains(t, e, "ParseSchnorrSignature")
+		})
+	}
+}
+
 // Benchmarks
 
 func BenchmarkSchnorr(b *testing.B) {
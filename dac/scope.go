@@ -0,0 +1,130 @@
+package dac
+
+import (
+	"fmt"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// ScopeNym computes the scope-exclusive pseudonym of the user with the secret key sk of level L (the bottom level).
+// The pseudonym is deterministic: the same user always gets the same pseudonym within a scope,
+// while pseudonyms of the same user in different scopes are unlinkable.
+// The pseudonym is in the group of the public key of level L (G1 for odd L, G2 for even L),
+// so that no pairing relates it to that public key.
+func ScopeNym(sk SK, scope string, L int) (nym PK) {
+	return pointMultiply(scopeBase(scope, L), sk)
+}
+
+// ProveWithScope is Prove that also proves that the scope-exclusive pseudonym (see ScopeNym)
+// is computed with the same secret key as the credentials.
+// Returns the proof along with the pseudonym, verify them with VerifyWithScope.
+func (creds *Credentials) ProveWithScope(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK, scope string) (proof Proof, nym PK, e error) {
+	return creds.proveWithScope(prg, sk, pk, D, m, grothYs, h, skNym, scope, binding{})
+}
+
+// ProveWithScopeAndParameters is ProveWithScope that takes the public values from the system parameters (see SystemParameters).
+// Verify the proof with VerifyWithScopeAndParameters.
+func (creds *Credentials) ProveWithScopeAndParameters(prg *amcl.RAND, sk SK, params *SystemParameters, context string, D Indices, m []byte, skNym SK, scope string) (proof Proof, nym PK, e error) {
+	return creds.proveWithScope(prg, sk, params.AuthorityPK, D, m, params.GrothYs, params.H, skNym, scope, binding{params.Fingerprint(), context})
+}
+
+func (creds *Credentials) proveWithScope(prg *amcl.RAND, sk SK, pk PK, D Indices, m []byte, grothYs [][]interface{}, h interface{}, skNym SK, scope string, b binding) (proof Proof, nym PK, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	base := scopeBase(scope, len(creds.signatures)-1)
+	rhoSk := FP256BN.Randomnum(q, prg)
+
+	prover, e := creds.proveCommit(prg, D, grothYs, h, rhoSk, FP256BN.Randomnum(q, prg))
+	if e != nil {
+		return
+	}
+
+	nym = pointMultiply(base, sk)
+	// shares the randomness of the secret key with the credentials proof
+	comScope := pointMultiply(base, rhoSk)
+
+	t := newTranscript(_ProtocolScope, b)
+	appendCommitments(t, grothYs, pk, h, prover.rPrime, prover.coms, prover.comNym, D)
+	appendScope(t, scope, nym, comScope)
+	t.append("m", m)
+
+	proof = prover.respond(t.challenge(q), sk, skNym)
+	proof.fingerprint = b.fingerprint
+
+	return
+}
+
+// VerifyWithScope verifies the proof generated with ProveWithScope like VerifyProof,
+// and that nym is the scope-exclusive pseudonym of the same user for the scope.
+func (proof *Proof) VerifyWithScope(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, scope string, nym PK) (e error) {
+	return proof.verifyWithScope(pk, grothYs, h, pkNym, D, m, scope, nym, "")
+}
+
+// VerifyWithScopeAndParameters is VerifyWithScope that takes the public values from the system parameters (see SystemParameters).
+func (proof *Proof) VerifyWithScopeAndParameters(params *SystemParameters, context string, pkNym PK, D Indices, m []byte, scope string, nym PK) (e error) {
+	if e = params.checkFingerprint(proof.fingerprint); e != nil {
+		return fmt.Errorf("VerifyWithScope: %v", e)
+	}
+
+	return proof.verifyWithScope(params.AuthorityPK, params.GrothYs, params.H, pkNym, D, m, scope, nym, context)
+}
+
+// verifyWithScope is VerifyWithScope for the proof bound to the context (and to the fingerprint it carries)
+func (proof *Proof) verifyWithScope(pk PK, grothYs [][]interface{}, h interface{}, pkNym PK, D Indices, m []byte, scope string, nym PK, context string) (e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if e = proof.validate(); e != nil {
+		return fmt.Errorf("VerifyWithScope: invalid proof: %v", e)
+	}
+	if e = ValidatePoint(pkNym); e != nil {
+		return fmt.Errorf("VerifyWithScope: invalid pkNym: %v", e)
+	}
+	L := len(proof.resA) - 1
+	if e = validatePointInGroup(nym, L%2 == 1); e != nil {
+		return fmt.Errorf("VerifyWithScope: invalid scope pseudonym: %v", e)
+	}
+	if e = D.validate(); e != nil {
+		return fmt.Errorf("VerifyWithScope: %v", e)
+	}
+
+	coms, comNym, e := proof.commitments(pk, grothYs, h, pkNym, D)
+	if e != nil {
+		return
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	comScope := productOfExponents(scopeBase(scope, L), proof.resCsk, nym, bigNegate(proof.c, q))
+
+	t := newTranscript(_ProtocolScope, binding{proof.fingerprint, context})
+	appendCommitments(t, grothYs, pk, h, proof.rPrime, coms, comNym, D)
+	appendScope(t, scope, nym, comScope)
+	t.append("m", m)
+
+	if !bigEqual(proof.c, t.challenge(q)) {
+		return fmt.Errorf("VerifyWithScope: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// scopeBase hashes the scope to a point whose discrete logarithm is unknown,
+// in the group of the public key of level L
+func scopeBase(scope string, L int) interface{} {
+	return HashToCurve(_DSTScope, []byte(scope), L%2 == 1)
+}
+
+func appendScope(t *transcript, scope string, nym PK, comScope interface{}) {
+	t.append("scope", []byte(scope))
+	t.appendPoint("nym", nym)
+	t.appendPoint("comScope", comScope)
+}
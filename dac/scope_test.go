@@ -0,0 +1,211 @@
+package dac
+
+import (
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Tests
+
+func TestScope(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testScopeNymDeterministic,
+		testScopeBase,
+		testScopeNymUnlinkableToKey,
+		testScopeHappyPath,
+		testScopeParameters,
+		testScopeVerificationFail,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testScopeNymDeterministic(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	for _, L := range []int{1, 2} {
+		sk, _ := GenerateKeys(prg, L)
+		other, _ := GenerateKeys(prg, L)
+
+		assert.Check(t, pointEqual(ScopeNym(sk, "election-2020", L), ScopeNym(sk, "election-2020", L)))
+		assert.Check(t, !pointEqual(ScopeNym(sk, "election-2020", L), ScopeNym(sk, "election-2024", L)))
+		assert.Check(t, !pointEqual(ScopeNym(sk, "election-2020", L), ScopeNym(other, "election-2020", L)))
+		assert.NilError(t, validatePointInGroup(ScopeNym(sk, "", L), L%2 == 1))
+	}
+}
+
+func testScopeBase(t *testing.T) {
+	for _, L := range []int{1, 2} {
+		for _, scope := range []string{"", "a", "election-2020"} {
+			base := scopeBase(scope, L)
+
+			assert.NilError(t, validatePointInGroup(base, L%2 == 1))
+			assert.Check(t, !pointEqual(base, levelGenerator(L)))
+			// the base is not the naive encoding of the scope
+			assert.Check(t, !pointEqual(base, AttributeFromString(scope, L%2 == 1)))
+		}
+	}
+}
+
+// whoever knows the bottom-level public key cannot relate it to the pseudonym with a pairing,
+// e(nym, g) == e(base, pk) (g of the other group), as it could if the base were in the other group
+func testScopeNymUnlinkableToKey(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	for _, L := range []int{1, 2, 3, 4} {
+		sk, pk := GenerateKeys(prg, L)
+		g := levelGenerator(L + 1)
+
+		nym := ScopeNym(sk, "election-2020", L)
+		linked := eProduct(&eArg{nym, g, nil}, &eArg{pointNegate(scopeBase("election-2020", L)), pk, nil})
+		assert.Check(t, linked == nil || !linked.Isunity(), "L=%d", L)
+
+		// the check the base in the other group would allow
+		otherBase := scopeBase("election-2020", L+1)
+		linked = eProduct(&eArg{pointMultiply(otherBase, sk), levelGenerator(L), nil}, &eArg{pointNegate(otherBase), pk, nil})
+		assert.Check(t, linked != nil && linked.Isunity(), "L=%d", L)
+	}
+}
+
+func testScopeHappyPath(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	for _, L := range []int{1, 2, -2} {
+		creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(L, 2)
+		if L < 0 {
+			L = -L
+		}
+
+		D := Indices{{L, 1, creds.Attributes[L][1]}}
+		m := []byte("ballot")
+
+		proof, nym, e := creds.ProveWithScope(prg, sk, pk, D, m, ys, h, skNym, "election-2020")
+		assert.NilError(t, e)
+		assert.Check(t, pointEqual(nym, ScopeNym(sk, "election-2020", L)))
+
+		assert.NilError(t, proof.VerifyWithScope(pk, ys, h, pkNym, D, m, "election-2020", nym))
+
+		recovered, e := ParseProof(proof.ToBytes())
+		assert.NilError(t, e)
+		assert.NilError(t, recovered.VerifyWithScope(pk, ys, h, pkNym, D, m, "election-2020", nym))
+
+		// the same user gets the same pseudonym with a fresh proof and a fresh pkNym
+		skNym2, pkNym2 := GenerateNymKeys(prg, sk, h)
+		proof2, nym2, e := creds.ProveWithScope(prg, sk, pk, D, m, ys, h, skNym2, "election-2020")
+		assert.NilError(t, e)
+		assert.Check(t, pointEqual(nym, nym2))
+		assert.NilError(t, proof2.VerifyWithScope(pk, ys, h, pkNym2, D, m, "election-2020", nym2))
+	}
+}
+
+// proof bound to the system parameters and a context verifies only with them
+func testScopeParameters(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, _, _, _, _ := generateChain(2, 2)
+	params := generateSystemParametersFor(pk, ys)
+	skNym, pkNym := GenerateNymKeys(prg, sk, params.H)
+
+	D := Indices{{2, 1, creds.Attributes[2][1]}}
+	m := []byte("ballot")
+
+	proof, nym, e := creds.ProveWithScopeAndParameters(prg, sk, params, "context", D, m, skNym, "election-2020")
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyWithScopeAndParameters(params, "context", pkNym, D, m, "election-2020", nym))
+	assert.ErrorContains(t, proof.VerifyWithScopeAndParameters(params, "other", pkNym, D, m, "election-2020", nym), "verification failed")
+	assert.ErrorContains(t, proof.VerifyWithScope(pk, ys, params.H, pkNym, D, m, "election-2020", nym), "verification failed")
+
+	recovered, e := ParseProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.NilError(t, recovered.VerifyWithScopeAndParameters(params, "context", pkNym, D, m, "election-2020", nym))
+
+	other, _, _ := generateSystemParameters(getNewRand(SEED + 2))
+	other.AuthorityPK, other.GrothYs, other.H = params.AuthorityPK, params.GrothYs, params.H
+	assert.ErrorContains(t, proof.VerifyWithScopeAndParameters(other, "context", pkNym, D, m, "election-2020", nym), "mismatch")
+
+	unbound, nym, e := creds.ProveWithScope(prg, sk, pk, D, m, ys, params.H, skNym, "election-2020")
+	assert.NilError(t, e)
+	assert.ErrorContains(t, unbound.VerifyWithScopeAndParameters(params, "context", pkNym, D, m, "election-2020", nym), "not bound")
+}
+
+func testScopeVerificationFail(t *testing.T) {
+	type TestCase string
+	const (
+		WrongScope    TestCase = "wrong scope"
+		OtherScopeNym TestCase = "nym of another scope"
+		OtherUserNym  TestCase = "nym of another user"
+		WrongMessage  TestCase = "wrong message"
+		Unscoped      TestCase = "verified as plain proof"
+		PlainProof    TestCase = "plain proof"
+		MissingNym    TestCase = "missing nym"
+		WrongGroupNym TestCase = "nym in G1"
+	)
+
+	for _, tc := range []TestCase{WrongScope, OtherScopeNym, OtherUserNym, WrongMessage, Unscoped, PlainProof, MissingNym, WrongGroupNym} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(2, 2)
+
+			D := Indices{}
+			m := []byte("ballot")
+			scope := "election-2020"
+
+			proof, nym, e := creds.ProveWithScope(prg, sk, pk, D, m, ys, h, skNym, scope)
+			assert.NilError(t, e)
+
+			expected := "verification failed"
+			switch tc {
+			case WrongScope:
+				scope = "election-2024"
+			case OtherScopeNym:
+				nym = ScopeNym(sk, "election-2024", 2)
+			case OtherUserNym:
+				other, _ := GenerateKeys(prg, 2)
+				nym = ScopeNym(other, scope, 2)
+			case WrongMessage:
+				m = []byte("another ballot")
+			case Unscoped:
+				assert.ErrorContains(t, proof.VerifyProof(pk, ys, h, pkNym, D, m), "verification failed")
+				return
+			case PlainProof:
+				proof, e = creds.Prove(prg, sk, pk, D, m, ys, h, skNym)
+				assert.NilError(t, e)
+			case MissingNym:
+				nym = nil
+				expected = "invalid scope pseudonym"
+			case WrongGroupNym:
+				nym = FP256BN.ECP_generator().Mul(sk)
+				expected = "invalid scope pseudonym"
+			}
+
+			assert.ErrorContains(t, proof.VerifyWithScope(pk, ys, h, pkNym, D, m, scope, nym), expected)
+		})
+	}
+}
+
+// Benchmarks
+
+func BenchmarkScope(b *testing.B) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, ys, skNym, pkNym, h, _ := generateChain(2, 2)
+	proof, nym, _ := creds.ProveWithScope(prg, sk, pk, Indices{}, []byte("ballot"), ys, h, skNym, "election-2020")
+
+	b.Run("prove", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_, _, _ = creds.ProveWithScope(prg, sk, pk, Indices{}, []byte("ballot"), ys, h, skNym, "election-2020")
+		}
+	})
+
+	b.Run("verify", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_ = proof.VerifyWithScope(pk, ys, h, pkNym, Indices{}, []byte("ballot"), "election-2020", nym)
+		}
+	})
+}
@@ -54,6 +54,11 @@ func (siblings *Siblings) VerifyGroth(pk PK, sigma GrothSignature, m []interface
 	return siblings.groth.Verify(pk, sigma, m)
 }
 
+// VerifyGrothBatch is wrapper around Groth.VerifyBatch
+func (siblings *Siblings) VerifyGrothBatch(pks []PK, sigmas []GrothSignature, ms [][]interface{}) error {
+	return siblings.groth.VerifyBatch(pks, sigmas, ms)
+}
+
 // VerifySchnorr is wrapper around Schnorr.Verify
 func (siblings *Siblings) VerifySchnorr(pk PK, sigma SchnorrSignature, m []byte) error {
 	return siblings.schnorr.Verify(pk, sigma, m)
@@ -27,6 +27,7 @@ func TestSiblings(t *testing.T) {
 			for _, test := range []func(*testing.T){
 				testSiblingsSchnorr,
 				testSiblingsGroth,
+				testSiblingsGrothBatch,
 			} {
 				t.Run(funcToString(reflect.ValueOf(test)), test)
 			}
@@ -61,3 +62,15 @@ func testSiblingsGroth(t *testing.T) {
 
 	assert.Check(t, result)
 }
+
+// make sure wrapper works for Groth batch verification
+func testSiblingsGrothBatch(t *testing.T) {
+	sk, pk := siblings.Generate()
+
+	sigma := siblings.SignGroth(sk, grothMessage)
+	sigmaPrime := siblings.RandomizeGroth(sigma, nil)
+
+	result := siblings.VerifyGrothBatch([]PK{pk, pk}, []GrothSignature{sigma, sigmaPrime}, [][]interface{}{grothMessage, grothMessage})
+
+	assert.Check(t, result)
+}
@@ -0,0 +1,265 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"strconv"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// AuditorShare is the share of the auditor secret key held by one of the auditors.
+// Index is the point at which the sharing polynomial is evaluated (from 1 to n).
+type AuditorShare struct {
+	Index int
+	Sk    SK
+}
+
+// PartialDecryption is the share of the decryption of an auditing encryption produced by one of the auditors,
+// along with the proof that it is computed with the auditor's share of the key.
+type PartialDecryption struct {
+	index int
+	d     interface{}
+	proof equalityProof
+}
+
+// equalityProof is a Chaum-Pedersen NIZK that log_g(y) == log_u(v)
+type equalityProof struct {
+	c   *FP256BN.BIG
+	res *FP256BN.BIG
+}
+
+// DealAuditorKey generates the auditor key pair and splits the secret key with Shamir secret sharing into n shares,
+// any threshold of which can decrypt an auditing encryption.
+// first defines the group of the key, it must be the group of h and of the users' public keys.
+// Returns the public key (used as usual with AuditingEncrypt, AuditingProve and AuditingProof.Verify),
+// the shares to be handed to the auditors and the public verification keys of the shares.
+// The dealer is trusted, it learns the secret key and must erase it along with the shares.
+func DealAuditorKey(prg *amcl.RAND, threshold int, n int, first bool) (pk PK, shares []AuditorShare, verificationKeys []PK, e error) {
+	if threshold < 1 || threshold > n {
+		return nil, nil, nil, fmt.Errorf("DealAuditorKey: threshold (%d) must be between 1 and the number of auditors (%d)", threshold, n)
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	g := map[bool]interface{}{true: FP256BN.ECP_generator(), false: FP256BN.ECP2_generator()}[first]
+
+	// f(x) = a_0 + a_1 x + ... + a_{t-1} x^{t-1}, a_0 is the secret key
+	coefficients := make([]*FP256BN.BIG, threshold)
+	for index := range coefficients {
+		coefficients[index] = FP256BN.Randomnum(q, prg)
+	}
+
+	pk = pointMultiply(g, coefficients[0])
+
+	shares = make([]AuditorShare, n)
+	verificationKeys = make([]PK, n)
+	for index := 0; index < n; index++ {
+		x := FP256BN.NewBIGint(index + 1)
+
+		sk := FP256BN.NewBIGcopy(coefficients[threshold-1])
+		for k := threshold - 2; k >= 0; k-- {
+			sk = FP256BN.Modmul(sk, x, q)
+			sk = sk.Plus(coefficients[k])
+			sk.Mod(q)
+		}
+
+		shares[index] = AuditorShare{Index: index + 1, Sk: sk}
+		verificationKeys[index] = pointMultiply(g, sk)
+	}
+
+	return
+}
+
+// PartialDecrypt produces the share of the decryption with the auditor's share of the key
+// along with the proof that the same share underlies the auditor's verification key.
+func (encryption *AuditingEncryption) PartialDecrypt(prg *amcl.RAND, share AuditorShare) (partial PartialDecryption) {
+	g := generatorSameGroup(encryption.enc2)
+
+	partial.index = share.Index
+	partial.d = pointMultiply(encryption.enc2, share.Sk)
+
+	t := partialDecryptionTranscript(encryption, partial.index)
+	partial.proof = proveEquality(prg, t, g, pointMultiply(g, share.Sk), encryption.enc2, partial.d, share.Sk)
+
+	return
+}
+
+// Index returns the index of the share the partial decryption is produced with
+func (partial *PartialDecryption) Index() int {
+	return partial.index
+}
+
+// Verify checks that the partial decryption of the encryption is produced with the share
+// that corresponds to the verification key (the one of the auditor with the partial decryption's index)
+func (partial *PartialDecryption) Verify(encryption AuditingEncryption, verificationKey PK) (e error) {
+	if e = validateSameGroup(encryption.enc2, encryption.enc1, partial.d, verificationKey); e != nil {
+		return fmt.Errorf("PartialDecryption.Verify: invalid encryption, partial decryption or verification key: %v", e)
+	}
+
+	g := generatorSameGroup(encryption.enc2)
+
+	t := partialDecryptionTranscript(&encryption, partial.index)
+	if !partial.proof.verify(t, g, verificationKey, encryption.enc2, partial.d) {
+		return fmt.Errorf("PartialDecryption.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// CombinePartialDecryptions recovers the user's public key from the partial decryptions.
+// Partial decryptions that do not verify against their verification keys (indexed from 1), or repeat an index, are skipped.
+// Returns error if fewer than threshold valid partial decryptions remain.
+func (encryption *AuditingEncryption) CombinePartialDecryptions(threshold int, partials []PartialDecryption, verificationKeys []PK) (plaintext interface{}, e error) {
+	var valid []PartialDecryption
+	seen := make(map[int]bool)
+
+	for _, partial := range partials {
+		if len(valid) == threshold {
+			break
+		}
+		if partial.index < 1 || partial.index > len(verificationKeys) || seen[partial.index] {
+			continue
+		}
+		if partial.Verify(*encryption, verificationKeys[partial.index-1]) != nil {
+			continue
+		}
+
+		seen[partial.index] = true
+		valid = append(valid, partial)
+	}
+
+	if threshold < 1 || len(valid) < threshold {
+		return nil, fmt.Errorf("CombinePartialDecryptions: %d valid partial decryptions, %d required", len(valid), threshold)
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	// enc2^sk = prod d_i^lambda_i, where lambda_i are the Lagrange coefficients at 0
+	var mask interface{}
+	for i, partial := range valid {
+		lambda := FP256BN.NewBIGint(1)
+		for j, other := range valid {
+			if i == j {
+				continue
+			}
+			xj := FP256BN.NewBIGint(other.index)
+			denominator := bigMinusMod(FP256BN.NewBIGint(other.index), FP256BN.NewBIGint(partial.index), q)
+
+			lambda = FP256BN.Modmul(lambda, xj, q)
+			lambda = FP256BN.Modmul(lambda, bigInverse(denominator, q), q)
+		}
+
+		term := pointMultiply(partial.d, lambda)
+		if mask == nil {
+			mask = term
+		} else {
+			pointAdd(mask, term)
+		}
+	}
+
+	plaintext = pointMultiply(encryption.enc1, FP256BN.NewBIGint(1))
+	pointSubtract(plaintext, mask)
+
+	return
+}
+
+func partialDecryptionTranscript(encryption *AuditingEncryption, index int) (t *transcript) {
+	t = newTranscript(_ProtocolPartialDecryption, binding{})
+
+	t.append("index", []byte(strconv.Itoa(index)))
+	t.appendPoint("enc1", encryption.enc1)
+	t.appendPoint("enc2", encryption.enc2)
+
+	return
+}
+
+// proveEquality proves that x is the discrete logarithm of both y = g^x and v = u^x.
+// The transcript should already hold the statement the proof is bound to.
+func proveEquality(prg *amcl.RAND, t *transcript, g, y, u, v interface{}, x *FP256BN.BIG) (proof equalityProof) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	r := FP256BN.Randomnum(q, prg)
+
+	appendEquality(t, g, y, u, v, pointMultiply(g, r), pointMultiply(u, r))
+	proof.c = t.challenge(q)
+
+	proof.res = FP256BN.Modmul(proof.c, x, q)
+	proof.res = proof.res.Plus(r)
+	proof.res.Mod(q)
+
+	return
+}
+
+// verify checks the proof that log_g(y) == log_u(v) against the transcript it was generated with
+func (proof *equalityProof) verify(t *transcript, g, y, u, v interface{}) bool {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	cNeg := bigNegate(proof.c, q)
+
+	com1 := productOfExponents(g, proof.res, y, cNeg)
+	com2 := productOfExponents(u, proof.res, v, cNeg)
+
+	appendEquality(t, g, y, u, v, com1, com2)
+
+	return bigEqual(t.challenge(q), proof.c)
+}
+
+func appendEquality(t *transcript, g, y, u, v, com1, com2 interface{}) {
+	t.appendPoint("g", g)
+	t.appendPoint("y", y)
+	t.appendPoint("u", u)
+	t.appendPoint("v", v)
+	t.appendPoint("com1", com1)
+	t.appendPoint("com2", com2)
+}
+
+type partialDecryptionMarshal struct {
+	Index int
+	D     []byte
+	C     []byte
+	Res   []byte
+}
+
+// ToBytes marshals the partial decryption using ASN1 encoding
+func (partial *PartialDecryption) ToBytes() (result []byte) {
+	var marshal partialDecryptionMarshal
+
+	marshal.Index = partial.index
+	marshal.D = PointToBytes(partial.d)
+	marshal.C = bigToBytes(partial.proof.c)
+	marshal.Res = bigToBytes(partial.proof.res)
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParsePartialDecryption un-marshals and validates the partial decryption using ASN1 encoding
+func ParsePartialDecryption(input []byte) (partial *PartialDecryption, e error) {
+	var marshal partialDecryptionMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParsePartialDecryption: %v", e)
+	}
+
+	if marshal.Index < 1 {
+		return nil, fmt.Errorf("ParsePartialDecryption: index %d must be positive", marshal.Index)
+	}
+
+	d, _, e := requiredPointFromBytes(marshal.D)
+	if e != nil {
+		return nil, fmt.Errorf("ParsePartialDecryption: d: %v", e)
+	}
+
+	scalars, e := bigsFromBytes(marshal.C, marshal.Res)
+	if e != nil {
+		return nil, fmt.Errorf("ParsePartialDecryption: %v", e)
+	}
+
+	partial = &PartialDecryption{
+		index: marshal.Index,
+		d:     d,
+		proof: equalityProof{scalars[0], scalars[1]},
+	}
+
+	return
+}
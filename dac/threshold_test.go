@@ -0,0 +1,244 @@
+package dac
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+)
+
+const (
+	_ThresholdT = 3
+	_ThresholdN = 5
+)
+
+// helper that deals a threshold auditor key and encrypts a user's public key under it
+func thresholdEncrypt(prg *amcl.RAND) (h interface{}, userSk SK, userPk PK, audPk PK, shares []AuditorShare, verificationKeys []PK, encryption AuditingEncryption, r SK) {
+	h = getH(prg)
+
+	userSk, userPk = GenerateKeys(prg, map[bool]int{true: 1, false: 2}[hFirst])
+
+	audPk, shares, verificationKeys, e := DealAuditorKey(prg, _ThresholdT, _ThresholdN, hFirst)
+	if e != nil {
+		panic(e)
+	}
+
+	encryption, r = AuditingEncrypt(prg, audPk, userPk)
+
+	return
+}
+
+// Tests
+
+func TestThreshold(t *testing.T) {
+	for _, first := range []bool{true, false} {
+
+		hFirst = first
+
+		t.Run(fmt.Sprintf("h in g%d", map[bool]int{true: 1, false: 2}[first]), func(t *testing.T) {
+			for _, test := range []func(*testing.T){
+				testThresholdHappyPath,
+				testThresholdAnySubset,
+				testThresholdTooFewShares,
+				testThresholdTamperedPartial,
+				testThresholdAuditingProof,
+				testThresholdMarshal,
+				testThresholdParseRejectsMalformed,
+			} {
+				t.Run(funcToString(reflect.ValueOf(test)), test)
+			}
+		})
+	}
+
+	t.Run("deal errors", testThresholdDealErrors)
+}
+
+func testThresholdHappyPath(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, _, userPk, _, shares, verificationKeys, encryption, _ := thresholdEncrypt(prg)
+
+	var partials []PartialDecryption
+	for _, share := range shares[:_ThresholdT] {
+		partial := encryption.PartialDecrypt(prg, share)
+		assert.NilError(t, partial.Verify(encryption, verificationKeys[share.Index-1]))
+		partials = append(partials, partial)
+	}
+
+	plaintext, e := encryption.CombinePartialDecryptions(_ThresholdT, partials, verificationKeys)
+	assert.NilError(t, e)
+	assert.Check(t, pointEqual(plaintext, userPk))
+}
+
+func testThresholdAnySubset(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, _, userPk, _, shares, verificationKeys, encryption, _ := thresholdEncrypt(prg)
+
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var partials []PartialDecryption
+		for _, index := range subset {
+			partials = append(partials, encryption.PartialDecrypt(prg, shares[index]))
+		}
+
+		plaintext, e := encryption.CombinePartialDecryptions(_ThresholdT, partials, verificationKeys)
+		assert.NilError(t, e)
+		assert.Check(t, pointEqual(plaintext, userPk), "subset %v", subset)
+	}
+}
+
+func testThresholdTooFewShares(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, _, _, _, shares, verificationKeys, encryption, _ := thresholdEncrypt(prg)
+
+	partials := []PartialDecryption{
+		encryption.PartialDecrypt(prg, shares[0]),
+		encryption.PartialDecrypt(prg, shares[3]),
+		// duplicates do not count
+		encryption.PartialDecrypt(prg, shares[3]),
+	}
+
+	_, e := encryption.CombinePartialDecryptions(_ThresholdT, partials, verificationKeys)
+	assert.ErrorContains(t, e, "2 valid partial decryptions, 3 required")
+}
+
+func testThresholdTamperedPartial(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, _, userPk, _, shares, verificationKeys, encryption, _ := thresholdEncrypt(prg)
+
+	var partials []PartialDecryption
+	for _, share := range shares {
+		partials = append(partials, encryption.PartialDecrypt(prg, share))
+	}
+
+	// wrong share
+	partials[0].d = pointMultiply(partials[0].d, SK(randomSmallBig()))
+	assert.ErrorContains(t, partials[0].Verify(encryption, verificationKeys[0]), "verification failed")
+
+	// valid share of another auditor
+	partials[1].index = 3
+	assert.ErrorContains(t, partials[1].Verify(encryption, verificationKeys[2]), "verification failed")
+
+	// valid share for another encryption
+	other, _ := AuditingEncrypt(prg, encryption.enc1, userPk)
+	assert.ErrorContains(t, partials[2].Verify(other, verificationKeys[2]), "verification failed")
+
+	// the tampered shares are skipped, the remaining ones suffice
+	plaintext, e := encryption.CombinePartialDecryptions(_ThresholdT, partials, verificationKeys)
+	assert.NilError(t, e)
+	assert.Check(t, pointEqual(plaintext, userPk))
+
+	_, e = encryption.CombinePartialDecryptions(_ThresholdT, partials[:4], verificationKeys)
+	assert.ErrorContains(t, e, "2 valid partial decryptions")
+}
+
+func testThresholdAuditingProof(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	h, userSk, userPk, audPk, _, _, encryption, r := thresholdEncrypt(prg)
+
+	proof, pkNym := auditingProve(prg, userSk, h, encryption, userPk, audPk, r)
+
+	assert.Check(t, proof.Verify(encryption, pkNym, audPk, h))
+}
+
+func testThresholdMarshal(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, _, _, _, shares, verificationKeys, encryption, _ := thresholdEncrypt(prg)
+
+	partial := encryption.PartialDecrypt(prg, shares[2])
+
+	recovered, e := ParsePartialDecryption(partial.ToBytes())
+	assert.NilError(t, e)
+
+	assert.Equal(t, recovered.Index(), 3)
+	assert.Check(t, pointEqual(recovered.d, partial.d))
+	assert.Check(t, bigEqual(recovered.proof.c, partial.proof.c))
+	assert.Check(t, bigEqual(recovered.proof.res, partial.proof.res))
+	assert.NilError(t, recovered.Verify(encryption, verificationKeys[2]))
+}
+
+func testThresholdParseRejectsMalformed(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, _, _, _, shares, _, encryption, _ := thresholdEncrypt(prg)
+
+	valid := encryption.PartialDecrypt(prg, shares[0])
+
+	type TestCase string
+	const (
+		Empty     TestCase = "empty input"
+		Trailing  TestCase = "trailing bytes"
+		NoIndex   TestCase = "non-positive index"
+		NoPoint   TestCase = "missing point"
+		BadScalar TestCase = "scalar out of range"
+	)
+
+	for _, tc := range []TestCase{Empty, Trailing, NoIndex, NoPoint, BadScalar} {
+		t.Run(string(tc), func(t *testing.T) {
+			var input []byte
+			var marshal partialDecryptionMarshal
+
+			switch tc {
+			case Empty:
+				input = []byte{}
+			case Trailing:
+				input = append(valid.ToBytes(), 0x13)
+			case NoIndex:
+				input = remarshal(t, valid.ToBytes(), &marshal, func() { marshal.Index = 0 })
+			case NoPoint:
+				input = remarshal(t, valid.ToBytes(), &marshal, func() { marshal.D = []byte{} })
+			case BadScalar:
+				input = remarshal(t, valid.ToBytes(), &marshal, func() { marshal.Res = make([]byte, 40) })
+			}
+
+			_, e := ParsePartialDecryption(input)
+			assert.ErrorContains(t, e, "ParsePartialDecryption")
+		})
+	}
+}
+
+func testThresholdDealErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	for _, params := range [][2]int{{0, 3}, {4, 3}, {-1, -1}} {
+		_, _, _, e := DealAuditorKey(prg, params[0], params[1], true)
+		assert.ErrorContains(t, e, "threshold")
+	}
+
+	// 1-of-1 is the plain auditor key
+	pk, shares, _, e := DealAuditorKey(prg, 1, 1, true)
+	assert.NilError(t, e)
+	assert.Check(t, pointEqual(pointMultiply(generatorSameGroup(pk), shares[0].Sk), pk))
+}
+
+// Benchmarks
+
+func BenchmarkThreshold(b *testing.B) {
+	prg := getNewRand(SEED)
+
+	_, _, _, _, shares, verificationKeys, encryption, _ := thresholdEncrypt(prg)
+
+	var partials []PartialDecryption
+	for _, share := range shares[:_ThresholdT] {
+		partials = append(partials, encryption.PartialDecrypt(prg, share))
+	}
+
+	b.Run("partial decrypt", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			encryption.PartialDecrypt(prg, shares[0])
+		}
+	})
+
+	b.Run("combine", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			_, _ = encryption.CombinePartialDecryptions(_ThresholdT, partials, verificationKeys)
+		}
+	})
+}
@@ -0,0 +1,261 @@
+package dac
+
+import (
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// TransactionProof is a NIZK that combines the credentials proof, the non-revocation proof and the auditing proof
+// under a single challenge, which also signs the transaction payload.
+// The three parts share the responses for the user's secret key and the pseudonym secret key,
+// which shows that the same secret key underlies the credentials, the non-revocation signature and the auditing encryption.
+type TransactionProof struct {
+	c           *FP256BN.BIG
+	credentials Proof
+	revocation  RevocationProof
+	auditing    AuditingProof
+
+	// fingerprint of the system parameters the proof is bound to
+	fingerprint []byte
+}
+
+// transactionCommitments holds the commitments of the three parts of a transaction proof
+type transactionCommitments struct {
+	rPrime []interface{}
+	coms   [][]*FP256BN.FP12
+	comNym interface{}
+
+	revocationR    interface{}
+	revocationS    interface{}
+	revocationCom1 *FP256BN.FP12
+	revocationCom2 *FP256BN.FP12
+	revocationCom3 interface{}
+
+	auditingCom1 interface{}
+	auditingCom2 interface{}
+	auditingCom3 interface{}
+}
+
+// ProveTransaction generates a transaction proof.
+// Secret key is that of the last level, it has to be the one signed by the revocation authority for the epoch (nonRevoke)
+// and encrypted under the auditor's public key with randomness r (encryption).
+// skNym should be received with GenerateNymKeys using params.H.
+// D is a set of disclosed attributes, payload is the transaction signed by the proof.
+// The system parameters must include the revocation values and the auditor public key.
+func (creds *Credentials) ProveTransaction(prg *amcl.RAND, sk SK, skNym SK, params *SystemParameters, context string, D Indices, nonRevoke GrothSignature, epoch *FP256BN.BIG, encryption AuditingEncryption, r *FP256BN.BIG, payload []byte) (proof TransactionProof, e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if params.RevocationPK == nil {
+		return proof, fmt.Errorf("ProveTransaction: system parameters do not include revocation values")
+	}
+	if params.AuditorPK == nil {
+		return proof, fmt.Errorf("ProveTransaction: system parameters do not include the auditor public key")
+	}
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	pkNym := productOfExponents(generatorSameGroup(params.H), sk, params.H, skNym)
+
+	// the same randomness for the secret keys in all three parts
+	rhoSk := FP256BN.Randomnum(q, prg)
+	rhoNym := FP256BN.Randomnum(q, prg)
+
+	credentialsProver, e := creds.proveCommit(prg, D, params.GrothYs, params.H, rhoSk, rhoNym)
+	if e != nil {
+		return proof, fmt.Errorf("ProveTransaction: %v", e)
+	}
+	revocationProver := revocationCommit(prg, nonRevoke, params.H, params.RevocationYs, rhoSk, rhoNym)
+	auditingProver := auditingCommit(prg, params.AuditorPK, params.H, rhoSk, rhoNym)
+
+	coms := &transactionCommitments{
+		rPrime:         credentialsProver.rPrime,
+		coms:           credentialsProver.coms,
+		comNym:         credentialsProver.comNym,
+		revocationR:    revocationProver.sigmaPrime.r,
+		revocationS:    revocationProver.sigmaPrime.s,
+		revocationCom1: revocationProver.com1,
+		revocationCom2: revocationProver.com2,
+		revocationCom3: revocationProver.com3,
+		auditingCom1:   auditingProver.com1,
+		auditingCom2:   auditingProver.com2,
+		auditingCom3:   auditingProver.com3,
+	}
+
+	proof.fingerprint = params.Fingerprint()
+	proof.c = coms.challenge(binding{proof.fingerprint, context}, params, pkNym, D, epoch, encryption, payload)
+
+	proof.credentials = credentialsProver.respond(proof.c, sk, skNym)
+	proof.revocation = revocationProver.respond(proof.c, sk, skNym)
+	proof.auditing = auditingProver.respond(proof.c, sk, skNym, r)
+
+	return
+}
+
+// Verify validates the transaction proof.
+// pkNym is the pseudonym the transaction is made under, D has to exactly correspond to the one used in generation,
+// epoch, encryption, payload and context have to be the ones the proof was generated with.
+func (proof *TransactionProof) Verify(params *SystemParameters, context string, pkNym PK, D Indices, epoch *FP256BN.BIG, encryption AuditingEncryption, payload []byte) (e error) {
+	defer func() {
+		if r := recover(); r != nil {
+			e = r.(error)
+		}
+	}()
+
+	if params.RevocationPK == nil {
+		return fmt.Errorf("TransactionProof.Verify: system parameters do not include revocation values")
+	}
+	if params.AuditorPK == nil {
+		return fmt.Errorf("TransactionProof.Verify: system parameters do not include the auditor public key")
+	}
+	if e = params.checkFingerprint(proof.fingerprint); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: %v", e)
+	}
+	if e = proof.linked(); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: %v", e)
+	}
+
+	if e = proof.credentials.validate(); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: invalid credentials proof: %v", e)
+	}
+	if e = ValidatePoint(pkNym); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: invalid pkNym: %v", e)
+	}
+	if e = D.validate(); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: %v", e)
+	}
+
+	coms := &transactionCommitments{
+		rPrime:      proof.credentials.rPrime,
+		revocationR: proof.revocation.rPrime,
+		revocationS: proof.revocation.sPrime,
+	}
+
+	if coms.coms, coms.comNym, e = proof.credentials.commitments(params.AuthorityPK, params.GrothYs, params.H, pkNym, D); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: %v", e)
+	}
+	if coms.revocationCom1, coms.revocationCom2, coms.revocationCom3, e = proof.revocation.commitments(pkNym, epoch, params.H, params.RevocationPK, params.RevocationYs); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: %v", e)
+	}
+	if coms.auditingCom1, coms.auditingCom2, coms.auditingCom3, e = proof.auditing.commitments(encryption, pkNym, params.AuditorPK, params.H); e != nil {
+		return fmt.Errorf("TransactionProof.Verify: %v", e)
+	}
+
+	cPrime := coms.challenge(binding{proof.fingerprint, context}, params, pkNym, D, epoch, encryption, payload)
+
+	if !bigEqual(cPrime, proof.c) {
+		return fmt.Errorf("TransactionProof.Verify: verification failed at cPrime == c")
+	}
+
+	return
+}
+
+// linked checks that the parts share the challenge and the responses for the secret keys
+func (proof *TransactionProof) linked() error {
+	for _, c := range []*FP256BN.BIG{proof.credentials.c, proof.revocation.c, proof.auditing.c} {
+		if !bigEqual(c, proof.c) {
+			return fmt.Errorf("parts of the proof have different challenges")
+		}
+	}
+
+	if !bigEqual(proof.credentials.resCsk, proof.revocation.res2) || !bigEqual(proof.credentials.resCsk, proof.auditing.res1) {
+		return fmt.Errorf("parts of the proof are not linked by the same secret key")
+	}
+	if !bigEqual(proof.credentials.resNym, proof.revocation.res4) || !bigEqual(proof.credentials.resNym, proof.auditing.res3) {
+		return fmt.Errorf("parts of the proof are not linked by the same pseudonym secret key")
+	}
+
+	return nil
+}
+
+// challenge computes the single challenge over the commitments of all parts and the payload
+func (coms *transactionCommitments) challenge(b binding, params *SystemParameters, pkNym PK, D Indices, epoch *FP256BN.BIG, encryption AuditingEncryption, payload []byte) *FP256BN.BIG {
+	t := newTranscript(_ProtocolTransaction, b)
+
+	t.append("part", []byte(_ProtocolCredentials))
+	appendCommitments(t, params.GrothYs, params.AuthorityPK, params.H, coms.rPrime, coms.coms, coms.comNym, D)
+
+	t.append("part", []byte(_ProtocolRevocation))
+	appendRevocation(t, params.H, params.RevocationYs, coms.revocationR, coms.revocationS, coms.revocationCom1, coms.revocationCom2, coms.revocationCom3, epoch)
+
+	t.append("part", []byte(_ProtocolAuditing))
+	appendAuditing(t, params.AuditorPK, params.H, coms.auditingCom1, coms.auditingCom2, coms.auditingCom3, encryption, pkNym)
+
+	t.append("payload", payload)
+
+	return t.challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order))
+}
+
+type transactionProofMarshal struct {
+	C           []byte
+	Credentials []byte
+	Revocation  []byte
+	Auditing    []byte
+	Fingerprint []byte
+}
+
+// ToBytes marshals the transaction proof using ASN1 encoding
+func (proof *TransactionProof) ToBytes() (result []byte) {
+	var marshal transactionProofMarshal
+
+	marshal.C = bigToBytes(proof.c)
+	marshal.Credentials = proof.credentials.ToBytes()
+	marshal.Revocation = proof.revocation.ToBytes()
+	marshal.Auditing = proof.auditing.ToBytes()
+	marshal.Fingerprint = proof.fingerprint
+
+	result, _ = asn1.Marshal(marshal)
+
+	return
+}
+
+// ParseTransactionProof un-marshals and validates the transaction proof using ASN1 encoding
+func ParseTransactionProof(input []byte) (proof *TransactionProof, e error) {
+	var marshal transactionProofMarshal
+	if e = unmarshal(input, &marshal); e != nil {
+		return nil, fmt.Errorf("ParseTransactionProof: %v", e)
+	}
+
+	proof = &TransactionProof{}
+
+	if proof.c, e = bigFromBytes(marshal.C); e != nil {
+		return nil, fmt.Errorf("ParseTransactionProof: %v", e)
+	}
+
+	credentials, e := ParseProof(marshal.Credentials)
+	if e != nil {
+		return nil, fmt.Errorf("ParseTransactionProof: %v", e)
+	}
+	revocation, e := ParseRevocationProof(marshal.Revocation)
+	if e != nil {
+		return nil, fmt.Errorf("ParseTransactionProof: %v", e)
+	}
+	auditing, e := ParseAuditingProof(marshal.Auditing)
+	if e != nil {
+		return nil, fmt.Errorf("ParseTransactionProof: %v", e)
+	}
+	proof.credentials, proof.revocation, proof.auditing = *credentials, *revocation, *auditing
+
+	if proof.fingerprint, e = fingerprintFromBytes(marshal.Fingerprint); e != nil {
+		return nil, fmt.Errorf("ParseTransactionProof: %v", e)
+	}
+	if proof.fingerprint == nil {
+		return nil, fmt.Errorf("ParseTransactionProof: fingerprint is missing")
+	}
+
+	if e = proof.linked(); e != nil {
+		return nil, fmt.Errorf("ParseTransactionProof: %v", e)
+	}
+
+	return
+}
+
+// Equals checks the equality of two transaction proofs
+func (proof *TransactionProof) Equals(other *TransactionProof) bool {
+	return bytesEqual(proof.ToBytes(), other.ToBytes())
+}
@@ -0,0 +1,256 @@
+package dac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// transactionFixture holds everything needed to prove and verify a transaction
+type transactionFixture struct {
+	prg          *amcl.RAND
+	creds        *Credentials
+	sk           SK
+	skNym        SK
+	pkNym        PK
+	params       *SystemParameters
+	revocationSk SK
+	auditorSk    SK
+	D            Indices
+	nonRevoke    GrothSignature
+	epoch        *FP256BN.BIG
+	encryption   AuditingEncryption
+	r            *FP256BN.BIG
+	payload      []byte
+}
+
+// helper that issues credentials, a non-revocation signature and an auditing encryption for the same secret key
+func generateTransaction() (f *transactionFixture) {
+	f = &transactionFixture{prg: getNewRand(SEED + 1)}
+
+	var pk PK
+	var ys [][]interface{}
+	f.creds, f.sk, pk, ys, _, _, _, _ = generateChain(3, 2)
+
+	f.params, f.revocationSk, f.auditorSk = generateSystemParameters(getNewRand(SEED))
+	f.params.AuthorityPK, f.params.GrothYs = pk, ys
+
+	f.skNym, f.pkNym = GenerateNymKeys(f.prg, f.sk, f.params.H)
+	f.D = Indices{{1, 1, f.creds.Attributes[1][1]}}
+
+	// h is in G1, so the revocation authority signs the key in G2, and the auditor encrypts the key in G1
+	f.epoch = FP256BN.NewBIGint(0x13)
+	f.nonRevoke = SignNonRevoke(f.prg, f.revocationSk, FP256BN.ECP2_generator().Mul(f.sk), f.epoch, f.params.RevocationYs)
+	f.encryption, f.r = AuditingEncrypt(f.prg, f.params.AuditorPK, FP256BN.ECP_generator().Mul(f.sk))
+
+	f.payload = []byte("transaction payload")
+
+	return
+}
+
+func (f *transactionFixture) prove() (TransactionProof, error) {
+	return f.creds.ProveTransaction(f.prg, f.sk, f.skNym, f.params, "context", f.D, f.nonRevoke, f.epoch, f.encryption, f.r, f.payload)
+}
+
+func (f *transactionFixture) verify(proof TransactionProof) error {
+	return proof.Verify(f.params, "context", f.pkNym, f.D, f.epoch, f.encryption, f.payload)
+}
+
+// Tests
+
+func TestTransaction(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testTransactionCorrect,
+		testTransactionMarshal,
+		testTransactionWrongInputs,
+		testTransactionDifferentKeys,
+		testTransactionMixedParts,
+		testTransactionMissingParameters,
+		testTransactionParseRejectsMalformed,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testTransactionCorrect(t *testing.T) {
+	f := generateTransaction()
+
+	proof, e := f.prove()
+	assert.NilError(t, e)
+
+	assert.NilError(t, f.verify(proof))
+
+	// the encryption is honest
+	assert.Check(t, pointEqual(f.encryption.AuditingDecrypt(f.auditorSk), FP256BN.ECP_generator().Mul(f.sk)))
+}
+
+func testTransactionMarshal(t *testing.T) {
+	f := generateTransaction()
+
+	proof, _ := f.prove()
+
+	recovered, e := ParseTransactionProof(proof.ToBytes())
+	assert.NilError(t, e)
+	assert.Check(t, recovered.Equals(&proof))
+	assert.NilError(t, f.verify(*recovered))
+}
+
+func testTransactionWrongInputs(t *testing.T) {
+	type TestCase string
+	const (
+		WrongPayload    TestCase = "wrong payload"
+		WrongContext    TestCase = "wrong context"
+		WrongEpoch      TestCase = "wrong epoch"
+		WrongNym        TestCase = "wrong pseudonym"
+		WrongD          TestCase = "wrong disclosed attribute"
+		WrongEncryption TestCase = "wrong encryption"
+		WrongParameters TestCase = "wrong parameters"
+	)
+
+	f := generateTransaction()
+	proof, _ := f.prove()
+
+	for _, tc := range []TestCase{WrongPayload, WrongContext, WrongEpoch, WrongNym, WrongD, WrongEncryption, WrongParameters} {
+		t.Run(string(tc), func(t *testing.T) {
+			params, context, pkNym, D, epoch, encryption, payload := f.params, "context", f.pkNym, f.D, f.epoch, f.encryption, f.payload
+
+			expected := "failed"
+			switch tc {
+			case WrongPayload:
+				payload = []byte("another payload")
+			case WrongContext:
+				context = "another context"
+			case WrongEpoch:
+				epoch = FP256BN.NewBIGint(0x14)
+			case WrongNym:
+				_, pkNym = GenerateNymKeys(f.prg, f.sk, f.params.H)
+			case WrongD:
+				D = Indices{{1, 1, f.creds.Attributes[1][0]}}
+			case WrongEncryption:
+				encryption, _ = AuditingEncrypt(f.prg, f.params.AuditorPK, FP256BN.ECP_generator().Mul(f.sk))
+			case WrongParameters:
+				other := *f.params
+				other.AuditorPK = FP256BN.ECP_generator()
+				params = &other
+				expected = "mismatch"
+			}
+
+			assert.ErrorContains(t, proof.Verify(params, context, pkNym, D, epoch, encryption, payload), expected)
+		})
+	}
+}
+
+// the proof does not verify if the revocation signature or the encryption are for a different key
+func testTransactionDifferentKeys(t *testing.T) {
+	type TestCase string
+	const (
+		Revocation TestCase = "revocation signature for another key"
+		Auditing   TestCase = "encryption of another key"
+	)
+
+	for _, tc := range []TestCase{Revocation, Auditing} {
+		t.Run(string(tc), func(t *testing.T) {
+			f := generateTransaction()
+			other, _ := GenerateKeys(f.prg, 0)
+
+			switch tc {
+			case Revocation:
+				f.nonRevoke = SignNonRevoke(f.prg, f.revocationSk, FP256BN.ECP2_generator().Mul(other), f.epoch, f.params.RevocationYs)
+			case Auditing:
+				f.encryption, f.r = AuditingEncrypt(f.prg, f.params.AuditorPK, FP256BN.ECP_generator().Mul(other))
+			}
+
+			proof, e := f.prove()
+			assert.NilError(t, e)
+
+			assert.ErrorContains(t, f.verify(proof), "failed")
+		})
+	}
+}
+
+// parts of different transaction proofs cannot be combined
+func testTransactionMixedParts(t *testing.T) {
+	f := generateTransaction()
+
+	first, _ := f.prove()
+	second, _ := f.prove()
+
+	mixed := first
+	mixed.revocation = second.revocation
+	assert.ErrorContains(t, f.verify(mixed), "different challenges")
+
+	mixed = first
+	mixed.auditing.res1 = FP256BN.NewBIGint(0x13)
+	assert.ErrorContains(t, f.verify(mixed), "same secret key")
+
+	mixed = first
+	mixed.revocation.res4 = FP256BN.NewBIGint(0x13)
+	assert.ErrorContains(t, f.verify(mixed), "same pseudonym secret key")
+
+	// standalone parts are still bound to the transaction challenge
+	assert.Check(t, first.auditing.Verify(f.encryption, f.pkNym, f.params.AuditorPK, f.params.H) != nil)
+}
+
+func testTransactionMissingParameters(t *testing.T) {
+	f := generateTransaction()
+	proof, _ := f.prove()
+
+	params := *f.params
+	f.params = &params
+
+	params.AuditorPK = nil
+	_, e := f.prove()
+	assert.ErrorContains(t, e, "auditor public key")
+	assert.ErrorContains(t, f.verify(proof), "auditor public key")
+
+	params.RevocationPK, params.RevocationYs = nil, nil
+	_, e = f.prove()
+	assert.ErrorContains(t, e, "revocation values")
+	assert.ErrorContains(t, f.verify(proof), "revocation values")
+}
+
+func testTransactionParseRejectsMalformed(t *testing.T) {
+	type TestCase string
+	const (
+		Malformed      TestCase = "malformed ASN1"
+		NoFingerprint  TestCase = "fingerprint missing"
+		BadCredentials TestCase = "credentials proof malformed"
+		BadRevocation  TestCase = "revocation proof malformed"
+		BadAuditing    TestCase = "auditing proof malformed"
+		Unlinked       TestCase = "parts not linked"
+	)
+
+	f := generateTransaction()
+	proof, _ := f.prove()
+	other, _ := f.prove()
+
+	for _, tc := range []TestCase{Malformed, NoFingerprint, BadCredentials, BadRevocation, BadAuditing, Unlinked} {
+		t.Run(string(tc), func(t *testing.T) {
+			var marshal transactionProofMarshal
+			bytes := remarshal(t, proof.ToBytes(), &marshal, func() {
+				switch tc {
+				case NoFingerprint:
+					marshal.Fingerprint = []byte{}
+				case BadCredentials:
+					marshal.Credentials = []byte{0x13}
+				case BadRevocation:
+					marshal.Revocation = []byte{0x13}
+				case BadAuditing:
+					marshal.Auditing = []byte{0x13}
+				case Unlinked:
+					marshal.Auditing = other.auditing.ToBytes()
+				}
+			})
+
+			if tc == Malformed {
+				bytes = append(bytes, 0x13)
+			}
+
+			_, e := ParseTransactionProof(bytes)
+			assert.ErrorContains(t, e, "ParseTransactionProof")
+		})
+	}
+}
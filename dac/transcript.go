@@ -0,0 +1,109 @@
+package dac
+
+import (
+	"encoding/binary"
+	"strconv"
+
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// _TranscriptDomain separates the challenges of this library from those of any other protocol using the same hash
+const _TranscriptDomain = "github.com/dbogatov/dac-lib/transcript/v1"
+
+// Protocol names bound into the transcripts, so that a challenge of one protocol is never valid for another
+const (
+	_ProtocolCredentials       = "credentials"
+	_ProtocolRevocation        = "revocation"
+	_ProtocolAuditing          = "auditing"
+	_ProtocolNym               = "nym"
+	_ProtocolCredRequest       = "credential-request"
+	_ProtocolSchnorr           = "schnorr"
+	_ProtocolSystemParameters  = "system-parameters"
+	_ProtocolTransaction       = "transaction"
+	_ProtocolPartialDecryption = "partial-decryption"
+	_ProtocolDecryption        = "decryption"
+	_ProtocolEscrow            = "escrow"
+	_ProtocolScope             = "scope"
+	_ProtocolAccumulator       = "accumulator"
+	_ProtocolEpoch             = "epoch-announcement"
+	_ProtocolPredicates        = "predicates"
+	_ProtocolJoint             = "joint"
+	_ProtocolDisclosedValues   = "disclosed-values"
+)
+
+// transcript accumulates the values a Fiat-Shamir challenge is computed from.
+// Every value is appended with a label, and both are length-prefixed,
+// so that different sequences of values never produce the same transcript.
+type transcript struct {
+	raw []byte
+}
+
+// binding holds what a proof is bound to besides its statement
+type binding struct {
+	// fingerprint of the system parameters (empty if the proof is not bound to them)
+	fingerprint []byte
+	// context supplied by the caller, e.g. an application or a channel name (not serialized with the proof)
+	context string
+}
+
+// newTranscript starts a transcript bound to the library domain, the protocol name and the binding
+func newTranscript(protocol string, b binding) (t *transcript) {
+	t = &transcript{}
+
+	t.append("domain", []byte(_TranscriptDomain))
+	t.append("protocol", []byte(protocol))
+	t.append("parameters", b.fingerprint)
+	t.append("context", []byte(b.context))
+
+	return
+}
+
+// append adds a labelled, length-prefixed byte string
+func (t *transcript) append(label string, data []byte) {
+	var length [8]byte
+
+	binary.BigEndian.PutUint64(length[:], uint64(len(label)))
+	t.raw = append(t.raw, length[:]...)
+	t.raw = append(t.raw, label...)
+
+	binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+	t.raw = append(t.raw, length[:]...)
+	t.raw = append(t.raw, data...)
+}
+
+// appendPoint adds a point (nil point is encoded as an empty string)
+func (t *transcript) appendPoint(label string, g interface{}) {
+	t.append(label, PointToBytes(g))
+}
+
+// appendPoints adds a list of points, prefixed by its length
+func (t *transcript) appendPoints(label string, gs []interface{}) {
+	t.append(label, []byte(strconv.Itoa(len(gs))))
+	for _, g := range gs {
+		t.appendPoint(label, g)
+	}
+}
+
+// appendBig adds a scalar
+func (t *transcript) appendBig(label string, a *FP256BN.BIG) {
+	t.append(label, bigToBytes(a))
+}
+
+// appendFP adds an element of the target group (nil element is encoded as an empty string)
+func (t *transcript) appendFP(label string, p *FP256BN.FP12) {
+	if p == nil {
+		t.append(label, nil)
+		return
+	}
+	t.append(label, fpToBytes(p))
+}
+
+// challenge hashes the transcript to a scalar modulo q
+func (t *transcript) challenge(q *FP256BN.BIG) *FP256BN.BIG {
+	return sha3(q, t.raw)
+}
+
+// digest hashes the transcript to 32 bytes
+func (t *transcript) digest() []byte {
+	return sha3Bytes(t.raw)
+}
@@ -0,0 +1,138 @@
+package dac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// Tests
+
+func TestTranscript(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testTranscriptDeterministic,
+		testTranscriptUnambiguous,
+		testTranscriptBinding,
+		testTranscriptIndicesOrder,
+		testTranscriptCrossProtocol,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testTranscriptDeterministic(t *testing.T) {
+	build := func() *transcript {
+		tr := newTranscript(_ProtocolCredentials, binding{[]byte("fingerprint"), "context"})
+		tr.appendPoint("g", FP256BN.ECP_generator())
+		tr.appendPoints("ys", []interface{}{FP256BN.ECP2_generator(), nil})
+		tr.appendBig("a", FP256BN.NewBIGint(0x13))
+		tr.appendFP("f", nil)
+		return tr
+	}
+
+	assert.DeepEqual(t, build().raw, build().raw)
+	assert.Equal(t, len(build().digest()), 32)
+	assert.Check(t, FP256BN.Comp(build().challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order)), build().challenge(FP256BN.NewBIGints(FP256BN.CURVE_Order))) == 0)
+}
+
+func testTranscriptUnambiguous(t *testing.T) {
+	type TestCase string
+	const (
+		SplitData  TestCase = "data split differently"
+		SplitLabel TestCase = "label and data split differently"
+		Order      TestCase = "values reordered"
+		Count      TestCase = "point moved between lists"
+	)
+
+	for _, tc := range []TestCase{SplitData, SplitLabel, Order, Count} {
+		t.Run(string(tc), func(t *testing.T) {
+			first := newTranscript(_ProtocolCredentials, binding{})
+			second := newTranscript(_ProtocolCredentials, binding{})
+
+			g := FP256BN.ECP_generator()
+
+			switch tc {
+			case SplitData:
+				first.append("x", []byte("ab"))
+				first.append("x", []byte("c"))
+				second.append("x", []byte("a"))
+				second.append("x", []byte("bc"))
+			case SplitLabel:
+				first.append("ab", []byte("c"))
+				second.append("a", []byte("bc"))
+			case Order:
+				first.append("a", []byte("1"))
+				first.append("b", []byte("2"))
+				second.append("b", []byte("2"))
+				second.append("a", []byte("1"))
+			case Count:
+				first.appendPoints("ys", []interface{}{g, g})
+				first.appendPoints("ys", []interface{}{})
+				second.appendPoints("ys", []interface{}{g})
+				second.appendPoints("ys", []interface{}{g})
+			}
+
+			assert.Check(t, !bytesEqual(first.digest(), second.digest()))
+		})
+	}
+}
+
+func testTranscriptBinding(t *testing.T) {
+	digest := func(protocol string, b binding) []byte {
+		tr := newTranscript(protocol, b)
+		tr.append("m", []byte("message"))
+		return tr.digest()
+	}
+
+	base := digest(_ProtocolCredentials, binding{})
+
+	assert.Check(t, bytesEqual(base, digest(_ProtocolCredentials, binding{})))
+	assert.Check(t, !bytesEqual(base, digest(_ProtocolRevocation, binding{})))
+	assert.Check(t, !bytesEqual(base, digest(_ProtocolCredentials, binding{context: "context"})))
+	assert.Check(t, !bytesEqual(base, digest(_ProtocolCredentials, binding{fingerprint: []byte("fingerprint")})))
+	assert.Check(t, !bytesEqual(digest(_ProtocolCredentials, binding{context: "a"}), digest(_ProtocolCredentials, binding{context: "b"})))
+}
+
+func testTranscriptIndicesOrder(t *testing.T) {
+	attributes := ProduceAttributes(1, "a", "b", "c")
+	D := Indices{{1, 2, attributes[2]}, {1, 0, attributes[0]}, {2, 1, attributes[1]}}
+	shuffled := Indices{{2, 1, attributes[1]}, {1, 0, attributes[0]}, {1, 2, attributes[2]}}
+
+	first := newTranscript(_ProtocolCredentials, binding{})
+	D.appendTo(first)
+	second := newTranscript(_ProtocolCredentials, binding{})
+	shuffled.appendTo(second)
+
+	assert.Check(t, bytesEqual(first.digest(), second.digest()))
+
+	other := newTranscript(_ProtocolCredentials, binding{})
+	Indices{{1, 2, attributes[0]}, {1, 0, attributes[2]}, {2, 1, attributes[1]}}.appendTo(other)
+
+	assert.Check(t, !bytesEqual(first.digest(), other.digest()))
+}
+
+// the challenge of one protocol differs from that of another one over the same values
+func testTranscriptCrossProtocol(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	schnorr := MakeSchnorr(prg, true)
+	_, pk := schnorr.Generate()
+
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+	r := FP256BN.ECP_generator().Mul(FP256BN.NewBIGint(0x13))
+	m := []byte("message")
+
+	challenges := []*FP256BN.BIG{
+		schnorr.hash("", pk, r, m),
+		hashNym(q, "", schnorr.g, r, pk, m),
+		hashCredRequest(q, "", r, pk, m),
+	}
+
+	for i := range challenges {
+		for j := i + 1; j < len(challenges); j++ {
+			assert.Check(t, FP256BN.Comp(challenges[i], challenges[j]) != 0)
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package dac
+
+import (
+	"fmt"
+
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+)
+
+// Point is a typed group element, either *G1 or *G2.
+// Use Untyped to pass it to the routines that work with interface{} points.
+type Point interface {
+	// InG1 reports whether the point belongs to the first group
+	InG1() bool
+	// Untyped returns the underlying *FP256BN.ECP or *FP256BN.ECP2
+	Untyped() interface{}
+	// ToBytes encodes the point the same way as PointToBytes
+	ToBytes() []byte
+}
+
+// G1 is an element of the first group (ECP)
+type G1 struct {
+	point *FP256BN.ECP
+}
+
+// G2 is an element of the second group (ECP2)
+type G2 struct {
+	point *FP256BN.ECP2
+}
+
+// NewG1 wraps an ECP point
+func NewG1(point *FP256BN.ECP) *G1 {
+	return &G1{point}
+}
+
+// NewG2 wraps an ECP2 point
+func NewG2(point *FP256BN.ECP2) *G2 {
+	return &G2{point}
+}
+
+// InG1 is always true for G1
+func (g *G1) InG1() bool {
+	return true
+}
+
+// InG1 is always false for G2
+func (g *G2) InG1() bool {
+	return false
+}
+
+// Untyped returns the underlying ECP point (nil interface if the point is missing)
+func (g *G1) Untyped() interface{} {
+	if g == nil || g.point == nil {
+		return nil
+	}
+	return g.point
+}
+
+// Untyped returns the underlying ECP2 point (nil interface if the point is missing)
+func (g *G2) Untyped() interface{} {
+	if g == nil || g.point == nil {
+		return nil
+	}
+	return g.point
+}
+
+// ToBytes encodes the point, see PointToBytes
+func (g *G1) ToBytes() []byte {
+	return PointToBytes(g.Untyped())
+}
+
+// ToBytes encodes the point, see PointToBytes
+func (g *G2) ToBytes() []byte {
+	return PointToBytes(g.Untyped())
+}
+
+// ECP returns the underlying ECP point
+func (g *G1) ECP() *FP256BN.ECP {
+	return g.point
+}
+
+// ECP2 returns the underlying ECP2 point
+func (g *G2) ECP2() *FP256BN.ECP2 {
+	return g.point
+}
+
+// TypedPoint converts an untyped point (ECP or ECP2) to *G1 or *G2.
+// Returns error if the value is neither.
+func TypedPoint(g interface{}) (Point, error) {
+	switch point := g.(type) {
+	case *FP256BN.ECP:
+		if point != nil {
+			return NewG1(point), nil
+		}
+	case *FP256BN.ECP2:
+		if point != nil {
+			return NewG2(point), nil
+		}
+	}
+
+	return nil, fmt.Errorf("value of type %T is not an ECP or ECP2 point", g)
+}
+
+// ParsePoint un-marshals a typed point, see PointFromBytes.
+// Unlike PointFromBytes, empty input is an error.
+func ParsePoint(input []byte) (Point, error) {
+	g, _, e := requiredPointFromBytes(input)
+	if e != nil {
+		return nil, fmt.Errorf("ParsePoint: %v", e)
+	}
+
+	return TypedPoint(g)
+}
+
+func groupName(first bool) string {
+	return map[bool]string{true: "G1", false: "G2"}[first]
+}
+
+// Level parity
+
+// LevelInG1 reports whether the keys and attributes of level L are in G1.
+// Odd levels live in G1, even levels (including the root authority, level 0) live in G2.
+func LevelInG1(L int) bool {
+	return L%2 == 1
+}
+
+// groupOf names the group of an untyped point for the error messages
+func groupOf(g interface{}) string {
+	switch point := g.(type) {
+	case *FP256BN.ECP:
+		if point != nil {
+			return "G1"
+		}
+	case *FP256BN.ECP2:
+		if point != nil {
+			return "G2"
+		}
+	case nil:
+	default:
+		return fmt.Sprintf("%T", g)
+	}
+
+	return "nothing"
+}
+
+// toG1 converts an untyped point to *G1, what names the point in the error
+func toG1(what string, g interface{}) (*G1, error) {
+	if point, ok := g.(*FP256BN.ECP); ok && point != nil {
+		return NewG1(point), nil
+	}
+
+	return nil, fmt.Errorf("%s must be in G1, got %s", what, groupOf(g))
+}
+
+// toG2 converts an untyped point to *G2, what names the point in the error
+func toG2(what string, g interface{}) (*G2, error) {
+	if point, ok := g.(*FP256BN.ECP2); ok && point != nil {
+		return NewG2(point), nil
+	}
+
+	return nil, fmt.Errorf("%s must be in G2, got %s", what, groupOf(g))
+}
+
+// Keys
+
+// G1PublicKey is a public key of an odd level
+type G1PublicKey struct {
+	*G1
+}
+
+// G2PublicKey is a public key of an even level, including the root authority
+type G2PublicKey struct {
+	*G2
+}
+
+// GenerateG1Keys generates a key pair for an odd level
+func GenerateG1Keys(prg *amcl.RAND) (SK, G1PublicKey) {
+	sk, pk := GenerateKeys(prg, 1)
+	return sk, G1PublicKey{NewG1(pk.(*FP256BN.ECP))}
+}
+
+// GenerateG2Keys generates a key pair for an even level, including the root authority (level 0)
+func GenerateG2Keys(prg *amcl.RAND) (SK, G2PublicKey) {
+	sk, pk := GenerateKeys(prg, 0)
+	return sk, G2PublicKey{NewG2(pk.(*FP256BN.ECP2))}
+}
+
+// Attributes
+
+// G1Attribute is an attribute of an odd level
+type G1Attribute struct {
+	*G1
+}
+
+// G2Attribute is an attribute of an even level
+type G2Attribute struct {
+	*G2
+}
+
+// ProduceG1Attributes is ProduceAttributes for an odd level
+func ProduceG1Attributes(inputs ...string) (attributes []G1Attribute) {
+	attributes = make([]G1Attribute, len(inputs))
+	for index, value := range inputs {
+		attributes[index] = G1Attribute{NewG1(AttributeFromString(value, true).(*FP256BN.ECP))}
+	}
+
+	return
+}
+
+// ProduceG2Attributes is ProduceAttributes for an even level
+func ProduceG2Attributes(inputs ...string) (attributes []G2Attribute) {
+	attributes = make([]G2Attribute, len(inputs))
+	for index, value := range inputs {
+		attributes[index] = G2Attribute{NewG2(AttributeFromString(value, false).(*FP256BN.ECP2))}
+	}
+
+	return
+}
+
+// Disclose makes an Index to be used in Indices for disclosing the attribute at level i and position j
+func (attribute G1Attribute) Disclose(i, j int) Index {
+	return Index{i, j, attribute.Untyped()}
+}
+
+// Disclose makes an Index to be used in Indices for disclosing the attribute at level i and position j
+func (attribute G2Attribute) Disclose(i, j int) Index {
+	return Index{i, j, attribute.Untyped()}
+}
+
+// Attribute is an attribute of a level known only at run time, as encoded by Value.Attribute and Schema.Encode.
+// Use G1 or G2 to get the attribute of the level parity the typed API expects.
+type Attribute struct {
+	Point
+}
+
+// G1 returns the attribute as one of an odd level, or error if it is not in G1
+func (attribute Attribute) G1() (G1Attribute, error) {
+	g, e := toG1("attribute", attribute.untyped())
+	return G1Attribute{g}, e
+}
+
+// G2 returns the attribute as one of an even level, or error if it is not in G2
+func (attribute Attribute) G2() (G2Attribute, error) {
+	g, e := toG2("attribute", attribute.untyped())
+	return G2Attribute{g}, e
+}
+
+// Disclose makes an Index to be used in Indices for disclosing the attribute at level i and position j
+func (attribute Attribute) Disclose(i, j int) Index {
+	return Index{i, j, attribute.untyped()}
+}
+
+func (attribute Attribute) untyped() interface{} {
+	if attribute.Point == nil {
+		return nil
+	}
+	return attribute.Untyped()
+}
+
+func untypedAttributes(attributes []Attribute) (result []interface{}) {
+	result = make([]interface{}, len(attributes))
+	for index, attribute := range attributes {
+		result[index] = attribute.untyped()
+	}
+
+	return
+}
+
+// checkLevelGroups makes sure every disclosed attribute is in the group required by its level
+func (indices Indices) checkLevelGroups() (e error) {
+	for _, ij := range indices {
+		if ij.I < 1 {
+			return fmt.Errorf("disclosed attribute (%d, %d) refers to a level without attributes", ij.I, ij.J)
+		}
+		what := fmt.Sprintf("disclosed attribute %d for level %d", ij.J, ij.I)
+		if LevelInG1(ij.I) {
+			_, e = toG1(what, ij.Attribute)
+		} else {
+			_, e = toG2(what, ij.Attribute)
+		}
+		if e != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// Groth parameters
+
+// GrothParams holds the y-values of Groth signatures.
+// Links of odd levels are signed with the G1 values, those of even levels with the G2 values.
+// Each list must hold at least one more value than the number of attributes of the corresponding levels.
+type GrothParams struct {
+	G1 []*G1
+	G2 []*G2
+}
+
+// GenerateGrothParams generates n y-values for each group, see GenerateYs
+func GenerateGrothParams(prg *amcl.RAND, n int) (params GrothParams) {
+	for _, y := range GenerateYs(true, n, prg) {
+		params.G1 = append(params.G1, NewG1(y.(*FP256BN.ECP)))
+	}
+	for _, y := range GenerateYs(false, n, prg) {
+		params.G2 = append(params.G2, NewG2(y.(*FP256BN.ECP2)))
+	}
+
+	return
+}
+
+// TypedGrothParams converts the untyped y-values (as used by Delegate, Prove, etc.) to GrothParams.
+// Returns error if the values are not in the expected groups: grothYs[0] in G2 and grothYs[1] in G1.
+func TypedGrothParams(grothYs [][]interface{}) (params GrothParams, e error) {
+	if len(grothYs) != 2 {
+		return params, fmt.Errorf("expected y-values for exactly 2 groups, got %d", len(grothYs))
+	}
+
+	for index, y := range grothYs[1] {
+		g, ok := y.(*FP256BN.ECP)
+		if !ok || g == nil {
+			return GrothParams{}, fmt.Errorf("y-value %d for odd levels must be in G1", index)
+		}
+		params.G1 = append(params.G1, NewG1(g))
+	}
+	for index, y := range grothYs[0] {
+		g, ok := y.(*FP256BN.ECP2)
+		if !ok || g == nil {
+			return GrothParams{}, fmt.Errorf("y-value %d for even levels must be in G2", index)
+		}
+		params.G2 = append(params.G2, NewG2(g))
+	}
+
+	return
+}
+
+// Untyped returns the y-values in the layout the untyped routines expect: index 0 for G2, index 1 for G1
+func (params GrothParams) Untyped() [][]interface{} {
+	ys := make([][]interface{}, 2)
+	for _, y := range params.G2 {
+		ys[0] = append(ys[0], y.Untyped())
+	}
+	for _, y := range params.G1 {
+		ys[1] = append(ys[1], y.Untyped())
+	}
+
+	return ys
+}
+
+// check makes sure there are enough y-values to sign a link of level L with n attributes
+func (params GrothParams) check(L int, n int) error {
+	available := len(params.G2)
+	if LevelInG1(L) {
+		available = len(params.G1)
+	}
+	if available < n+1 {
+		return fmt.Errorf("level %d has %d attributes, which requires at least %d y-values in %s, got %d", L, n, n+1, groupName(LevelInG1(L)), available)
+	}
+
+	return nil
+}
+
+// Typed credentials API
+
+// DelegateG1 extends the credentials by a link of an odd level: the public key and the attributes of the delegatee are in G1.
+// Needs secret key of the delegator.
+// Returns error if the next level of the credentials is even, or there are not enough y-values for the attributes.
+func (creds *Credentials) DelegateG1(sk SK, publicKey G1PublicKey, attributes []G1Attribute, prg *amcl.RAND, params GrothParams) (e error) {
+	untyped := make([]interface{}, len(attributes))
+	for index, attribute := range attributes {
+		untyped[index] = attribute.Untyped()
+	}
+
+	if e = creds.checkLink(true, publicKey.Untyped(), untyped, params); e != nil {
+		return fmt.Errorf("DelegateG1: %v", e)
+	}
+
+	return creds.delegate(sk, publicKey.Untyped(), untyped, prg, params.Untyped())
+}
+
+// DelegateG2 extends the credentials by a link of an even level: the public key and the attributes of the delegatee are in G2.
+// Needs secret key of the delegator.
+// Returns error if the next level of the credentials is odd, or there are not enough y-values for the attributes.
+func (creds *Credentials) DelegateG2(sk SK, publicKey G2PublicKey, attributes []G2Attribute, prg *amcl.RAND, params GrothParams) (e error) {
+	untyped := make([]interface{}, len(attributes))
+	for index, attribute := range attributes {
+		untyped[index] = attribute.Untyped()
+	}
+
+	if e = creds.checkLink(false, publicKey.Untyped(), untyped, params); e != nil {
+		return fmt.Errorf("DelegateG2: %v", e)
+	}
+
+	return creds.delegate(sk, publicKey.Untyped(), untyped, prg, params.Untyped())
+}
+
+// checkLink makes sure the next level is in the group of the link (G1 if first) and the link is complete
+func (creds *Credentials) checkLink(first bool, publicKey interface{}, attributes []interface{}, params GrothParams) error {
+	L := len(creds.signatures)
+	if LevelInG1(L) != first {
+		return fmt.Errorf("the next level %d is in %s, not %s", L, groupName(LevelInG1(L)), groupName(first))
+	}
+
+	if publicKey == nil {
+		return fmt.Errorf("public key for level %d is missing", L)
+	}
+	for index, attribute := range attributes {
+		if attribute == nil {
+			return fmt.Errorf("attribute %d for level %d is missing", index, L)
+		}
+	}
+
+	return params.check(L, len(attributes))
+}
+
+// VerifyTyped checks the validity of the credentials under the root authority's public key, see Verify
+func (creds *Credentials) VerifyTyped(sk SK, authorityPK G2PublicKey, params GrothParams) (e error) {
+	if authorityPK.Untyped() == nil {
+		return fmt.Errorf("trusted authority's public key is missing")
+	}
+	if e = creds.checkParams(params); e != nil {
+		return
+	}
+
+	return creds.verify(sk, authorityPK.Untyped(), params.Untyped())
+}
+
+// ProveTyped generates a NIZK proof of the credentials under the root authority's public key, see Prove.
+// D may be built with Disclose of the attributes, they have to be in the groups of their levels.
+func (creds *Credentials) ProveTyped(prg *amcl.RAND, sk SK, pk G2PublicKey, D Indices, m []byte, params GrothParams, h Point, skNym SK) (proof Proof, e error) {
+	return creds.proveTyped(prg, sk, pk, D, m, params, h, skNym, false)
+}
+
+// proveTyped is ProveTyped that attaches the commitments to the proof if batchable is set
+func (creds *Credentials) proveTyped(prg *amcl.RAND, sk SK, pk G2PublicKey, D Indices, m []byte, params GrothParams, h Point, skNym SK, batchable bool) (proof Proof, e error) {
+	if pk.Untyped() == nil {
+		return proof, fmt.Errorf("trusted authority's public key is missing")
+	}
+	if h == nil || h.Untyped() == nil {
+		return proof, fmt.Errorf("h is missing")
+	}
+	if e = creds.checkParams(params); e != nil {
+		return
+	}
+	if e = D.checkLevelGroups(); e != nil {
+		return
+	}
+
+	return creds.prove(prg, sk, pk.Untyped(), D, m, params.Untyped(), h.Untyped(), skNym, binding{}, batchable)
+}
+
+// VerifyProofTyped verifies a NIZK proof under the root authority's public key, see VerifyProof.
+// h and pkNym have to be in the same group.
+func (proof *Proof) VerifyProofTyped(pk G2PublicKey, params GrothParams, h Point, pkNym Point, D Indices, m []byte) (e error) {
+	return proof.verifyProofTyped(pk, params, h, pkNym, D, m, "")
+}
+
+// verifyProofTyped is VerifyProofTyped for the proof bound to the context
+func (proof *Proof) verifyProofTyped(pk G2PublicKey, params GrothParams, h Point, pkNym Point, D Indices, m []byte, context string) (e error) {
+	if e = proof.checkTyped(pk, params, h, pkNym, D); e != nil {
+		return
+	}
+
+	return proof.verifyProof(pk.Untyped(), params.Untyped(), h.Untyped(), pkNym.Untyped(), D, m, context)
+}
+
+// checkTyped makes sure the typed arguments of VerifyProofTyped agree with each other and with the proof
+func (proof *Proof) checkTyped(pk G2PublicKey, params GrothParams, h Point, pkNym Point, D Indices) (e error) {
+	if pk.Untyped() == nil {
+		return fmt.Errorf("trusted authority's public key is missing")
+	}
+	if h == nil || pkNym == nil || h.Untyped() == nil || pkNym.Untyped() == nil {
+		return fmt.Errorf("h and pkNym are required")
+	}
+	if h.InG1() != pkNym.InG1() {
+		return fmt.Errorf("h (%s) and pkNym (%s) must be in the same group", groupName(h.InG1()), groupName(pkNym.InG1()))
+	}
+	for i := 1; i < len(proof.resA); i++ {
+		if e = params.check(i, len(proof.resA[i])); e != nil {
+			return
+		}
+	}
+
+	return D.checkLevelGroups()
+}
+
+// checkParams makes sure there are enough y-values for the attributes of every level of the credentials
+func (creds *Credentials) checkParams(params GrothParams) (e error) {
+	for L := 1; L < len(creds.Attributes); L++ {
+		if e = params.check(L, len(creds.Attributes[L])); e != nil {
+			return
+		}
+	}
+
+	return
+}
@@ -0,0 +1,267 @@
+package dac
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dbogatov/fabric-amcl/amcl/FP256BN"
+	"gotest.tools/v3/assert"
+)
+
+// helper that constructs a valid credential chain of L levels with n attributes per level (see generateChain),
+// with the authority's public key and the Groth parameters typed
+func generateTypedChain(L int, n int) (creds *Credentials, sk SK, pk G2PublicKey, params GrothParams) {
+	creds, sk, untypedPK, ys, _, _, _, e := generateChain(L, n)
+	if e != nil {
+		panic(e)
+	}
+
+	authorityPK, _ := toG2("pk", untypedPK)
+	params, _ = TypedGrothParams(ys)
+
+	return creds, sk, G2PublicKey{authorityPK}, params
+}
+
+// Tests
+
+func TestTypes(t *testing.T) {
+	for _, test := range []func(*testing.T){
+		testTypesPoints,
+		testTypesLevelParity,
+		testTypesAttributes,
+		testTypesGrothParams,
+		testTypesHappyPath,
+		testTypesDelegateErrors,
+		testTypesProveErrors,
+	} {
+		t.Run(funcToString(reflect.ValueOf(test)), test)
+	}
+}
+
+func testTypesPoints(t *testing.T) {
+	g1, e := TypedPoint(FP256BN.ECP_generator())
+	assert.NilError(t, e)
+	assert.Check(t, g1.InG1())
+
+	g2, e := TypedPoint(FP256BN.ECP2_generator())
+	assert.NilError(t, e)
+	assert.Check(t, !g2.InG1())
+
+	for _, g := range []Point{g1, g2} {
+		recovered, e := ParsePoint(g.ToBytes())
+		assert.NilError(t, e)
+		assert.Check(t, pointEqual(recovered.Untyped(), g.Untyped()))
+	}
+
+	_, e = TypedPoint(nil)
+	assert.ErrorContains(t, e, "not an ECP")
+
+	_, e = TypedPoint((*FP256BN.ECP)(nil))
+	assert.ErrorContains(t, e, "not an ECP")
+
+	_, e = TypedPoint(FP256BN.NewBIGint(0x13))
+	assert.ErrorContains(t, e, "not an ECP")
+
+	_, e = ParsePoint([]byte{})
+	assert.ErrorContains(t, e, "missing")
+
+	assert.Check(t, (*G1)(nil).Untyped() == nil)
+	assert.Check(t, (&G2{}).Untyped() == nil)
+}
+
+func testTypesLevelParity(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	_, pk1 := GenerateG1Keys(prg)
+	assert.Check(t, pk1.InG1())
+	_, pk2 := GenerateG2Keys(prg)
+	assert.Check(t, !pk2.InG1())
+
+	for L := 0; L <= 3; L++ {
+		_, pk := GenerateKeys(prg, L)
+		_, e1 := toG1("public key", pk)
+		_, e2 := toG2("public key", pk)
+		assert.Equal(t, e1 == nil, LevelInG1(L))
+		assert.Equal(t, e2 == nil, !LevelInG1(L))
+	}
+
+	_, pk := GenerateKeys(prg, 2)
+	_, e := toG1("public key for level 1", pk)
+	assert.ErrorContains(t, e, "public key for level 1 must be in G1, got G2")
+
+	_, e = toG2("public key", FP256BN.NewBIGint(0x13))
+	assert.ErrorContains(t, e, "public key must be in G2, got *FP256BN.BIG")
+
+	_, e = toG2("public key", (*FP256BN.ECP2)(nil))
+	assert.ErrorContains(t, e, "public key must be in G2, got nothing")
+}
+
+func testTypesAttributes(t *testing.T) {
+	untyped := ProduceAttributes(1, "hello", "world")
+	for index, attribute := range ProduceG1Attributes("hello", "world") {
+		assert.Check(t, pointEqual(attribute.Untyped(), untyped[index]))
+	}
+
+	untyped = ProduceAttributes(2, "hello", "world")
+	for index, attribute := range ProduceG2Attributes("hello", "world") {
+		assert.Check(t, pointEqual(attribute.Untyped(), untyped[index]))
+	}
+
+	for L := 1; L <= 2; L++ {
+		attribute, e := NewString("hello").Attribute(L)
+		assert.NilError(t, e)
+
+		_, e1 := attribute.G1()
+		_, e2 := attribute.G2()
+		assert.Equal(t, e1 == nil, LevelInG1(L))
+		assert.Equal(t, e2 == nil, !LevelInG1(L))
+	}
+
+	_, e := Attribute{}.G1()
+	assert.ErrorContains(t, e, "attribute must be in G1, got nothing")
+
+	assert.Check(t, Attribute{}.Disclose(1, 0).Attribute == nil)
+	assert.Check(t, G1Attribute{}.Disclose(1, 0).Attribute == nil)
+	assert.Check(t, pointEqual(ProduceG2Attributes("hello")[0].Disclose(2, 0).Attribute, untyped[0]))
+}
+
+func testTypesGrothParams(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	params := GenerateGrothParams(prg, 3)
+	assert.Equal(t, len(params.G1), 3)
+	assert.Equal(t, len(params.G2), 3)
+
+	ys := params.Untyped()
+	_, g2 := ys[0][0].(*FP256BN.ECP2)
+	_, g1 := ys[1][0].(*FP256BN.ECP)
+	assert.Check(t, g1 && g2)
+
+	recovered, e := TypedGrothParams(ys)
+	assert.NilError(t, e)
+	assert.Check(t, pointListEquals(recovered.Untyped()[0], ys[0]))
+	assert.Check(t, pointListEquals(recovered.Untyped()[1], ys[1]))
+
+	_, e = TypedGrothParams([][]interface{}{ys[1], ys[0]})
+	assert.ErrorContains(t, e, "must be in")
+
+	_, e = TypedGrothParams(ys[:1])
+	assert.ErrorContains(t, e, "exactly 2")
+
+	assert.NilError(t, params.check(1, 2))
+	assert.ErrorContains(t, params.check(2, 3), "requires at least 4 y-values in G2, got 3")
+}
+
+func testTypesHappyPath(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, params := generateTypedChain(3, 2)
+
+	assert.NilError(t, creds.VerifyTyped(sk, pk, params))
+
+	h := NewG1(FP256BN.ECP_generator().Mul(FP256BN.Randomnum(FP256BN.NewBIGints(FP256BN.CURVE_Order), prg)))
+	skNym, pkNym := GenerateNymKeys(prg, sk, h.Untyped())
+	typedPkNym, _ := TypedPoint(pkNym)
+
+	D := Indices{ProduceG2Attributes("attribute-2-1")[0].Disclose(2, 1)}
+
+	proof, e := creds.ProveTyped(prg, sk, pk, D, []byte("message"), params, h, skNym)
+	assert.NilError(t, e)
+
+	assert.NilError(t, proof.VerifyProofTyped(pk, params, h, typedPkNym, D, []byte("message")))
+
+	// the typed chain is compatible with the untyped API
+	assert.NilError(t, proof.VerifyProof(pk.Untyped(), params.Untyped(), h.Untyped(), pkNym, D, []byte("message")))
+}
+
+func testTypesDelegateErrors(t *testing.T) {
+	type TestCase string
+	const (
+		WrongLevel     TestCase = "link of the wrong level"
+		MissingPK      TestCase = "public key missing"
+		EmptyAttribute TestCase = "attribute missing"
+		FewYs          TestCase = "too few y-values"
+		UntypedPK      TestCase = "untyped public key in wrong group"
+		UntypedValue   TestCase = "untyped attribute in wrong group"
+	)
+
+	for _, tc := range []TestCase{WrongLevel, MissingPK, EmptyAttribute, FewYs, UntypedPK, UntypedValue} {
+		t.Run(string(tc), func(t *testing.T) {
+			prg := getNewRand(SEED)
+
+			params := GenerateGrothParams(prg, 3)
+			sk, pk := GenerateG2Keys(prg)
+			creds := MakeCredentials(pk.Untyped())
+
+			_, pki := GenerateG1Keys(prg)
+			attributes := ProduceG1Attributes("hello", "world")
+
+			var e error
+			switch tc {
+			case WrongLevel:
+				_, pki := GenerateG2Keys(prg)
+				e = creds.DelegateG2(sk, pki, ProduceG2Attributes("hello", "world"), prg, params)
+				assert.ErrorContains(t, e, "DelegateG2: the next level 1 is in G1, not G2")
+			case MissingPK:
+				e = creds.DelegateG1(sk, G1PublicKey{}, attributes, prg, params)
+				assert.ErrorContains(t, e, "public key for level 1 is missing")
+			case EmptyAttribute:
+				attributes[0] = G1Attribute{}
+				e = creds.DelegateG1(sk, pki, attributes, prg, params)
+				assert.ErrorContains(t, e, "attribute 0 for level 1 is missing")
+			case FewYs:
+				e = creds.DelegateG1(sk, pki, ProduceG1Attributes("a", "b", "c"), prg, params)
+				assert.ErrorContains(t, e, "requires at least 4 y-values in G1")
+			case UntypedPK:
+				_, untyped := GenerateKeys(prg, 2)
+				e = creds.Delegate(sk, untyped, ProduceAttributes(1, "hello", "world"), prg, params.Untyped())
+				assert.ErrorContains(t, e, "Delegate: public key for level 1 must be in G1, got G2")
+			case UntypedValue:
+				untyped := ProduceAttributes(1, "hello", "world")
+				untyped[1] = ProduceAttributes(2, "world")[0]
+				e = creds.Delegate(sk, pki.Untyped(), untyped, prg, params.Untyped())
+				assert.ErrorContains(t, e, "Delegate: attribute 1 for level 1 must be in G1, got G2")
+			}
+
+			assert.Equal(t, len(creds.signatures), 1)
+		})
+	}
+}
+
+func testTypesProveErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+
+	creds, sk, pk, params := generateTypedChain(2, 2)
+
+	h := NewG2(FP256BN.ECP2_generator().Mul(FP256BN.NewBIGint(0x13)))
+	skNym, pkNym := GenerateNymKeys(prg, sk, h.Untyped())
+	typedPkNym, _ := TypedPoint(pkNym)
+
+	wrongAttribute := ProduceG2Attributes("hello")[0]
+	_, e := creds.ProveTyped(prg, sk, pk, Indices{wrongAttribute.Disclose(1, 0)}, []byte("message"), params, h, skNym)
+	assert.ErrorContains(t, e, "disclosed attribute 0 for level 1 must be in G1, got G2")
+
+	_, e = creds.ProveTyped(prg, sk, G2PublicKey{}, Indices{}, []byte("message"), params, h, skNym)
+	assert.ErrorContains(t, e, "authority's public key is missing")
+
+	_, e = creds.ProveTyped(prg, sk, pk, Indices{}, []byte("message"), GenerateGrothParams(prg, 2), h, skNym)
+	assert.ErrorContains(t, e, "requires at least 3 y-values")
+
+	_, wrongPK := GenerateKeys(prg, 1)
+	_, e = creds.Prove(prg, sk, wrongPK, Indices{}, []byte("message"), params.Untyped(), h.Untyped(), skNym)
+	assert.ErrorContains(t, e, "Prove: trusted authority's public key must be in G2")
+
+	proof, e := creds.ProveTyped(prg, sk, pk, Indices{}, []byte("message"), params, h, skNym)
+	assert.NilError(t, e)
+
+	e = proof.VerifyProofTyped(pk, params, NewG1(FP256BN.ECP_generator()), typedPkNym, Indices{}, []byte("message"))
+	assert.ErrorContains(t, e, "same group")
+
+	e = proof.VerifyProofTyped(pk, params, h, nil, Indices{}, []byte("message"))
+	assert.ErrorContains(t, e, "required")
+
+	e = proof.VerifyProof(pk.Untyped(), params.Untyped(), h.Untyped(), nil, Indices{}, []byte("message"))
+	assert.ErrorContains(t, e, "VerifyProof: invalid pkNym")
+
+	assert.NilError(t, proof.VerifyProofTyped(pk, params, h, typedPkNym, Indices{}, []byte("message")))
+}
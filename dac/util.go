@@ -1,6 +1,9 @@
 package dac
 
 import (
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/binary"
 	"fmt"
 
 	"github.com/dbogatov/fabric-amcl/amcl"
@@ -55,6 +58,14 @@ func pointEqual(g interface{}, h interface{}) bool {
 	return g.(*FP256BN.ECP2).Equals(h.(*FP256BN.ECP2))
 }
 
+func fpEqual(a *FP256BN.FP12, b *FP256BN.FP12) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	return a.Equals(b)
+}
+
 func pointListEquals(gs []interface{}, hs []interface{}) (result bool) {
 	if len(gs) != len(hs) {
 		return
@@ -111,6 +122,36 @@ func bigInverse(a *FP256BN.BIG, q *FP256BN.BIG) (aInv *FP256BN.BIG) {
 	return
 }
 
+// bigFromInt64 converts an integer to a scalar, negative integers are taken modulo q
+func bigFromInt64(x int64) (a *FP256BN.BIG) {
+	var raw [_BIGByteLength]byte
+
+	// two's complement negation is correct for math.MinInt64 too, once read as unsigned
+	magnitude := uint64(x)
+	if x < 0 {
+		magnitude = uint64(-x)
+	}
+	binary.BigEndian.PutUint64(raw[_BIGByteLength-8:], magnitude)
+
+	a = FP256BN.FromBytes(raw[:])
+	if x < 0 {
+		a = bigNegate(a, FP256BN.NewBIGints(FP256BN.CURVE_Order))
+	}
+
+	return
+}
+
+// randomSmallBig returns a uniformly random 128-bit scalar used as an exponent in batch verification.
+// It is drawn from the system randomness, so that it cannot be predicted by whoever produced the batch.
+func randomSmallBig() *FP256BN.BIG {
+	raw := make([]byte, _BIGByteLength)
+	if _, e := rand.Read(raw[_BIGByteLength/2:]); e != nil {
+		panic(fmt.Errorf("randomSmallBig: system randomness failed: %v", e))
+	}
+
+	return FP256BN.FromBytes(raw)
+}
+
 func pointNegate(g interface{}) (result interface{}) {
 	_, first := g.(*FP256BN.ECP)
 	if first {
@@ -192,6 +233,78 @@ func ate2(g interface{}, h interface{}, k interface{}, l interface{}) *FP256BN.F
 	return FP256BN.Ate2(b, a, d, c)
 }
 
+// Validation
+
+// ValidatePoint makes sure g is an ECP or ECP2 point that lies on the curve,
+// is not the point at infinity and belongs to the prime-order subgroup.
+// Every point coming from an untrusted source has to pass this check before it is used in pairings.
+func ValidatePoint(g interface{}) (e error) {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	switch point := g.(type) {
+	case *FP256BN.ECP:
+		if point == nil || point.Is_infinity() {
+			return fmt.Errorf("point is missing or is the point at infinity")
+		}
+
+		// work on a copy, since getting affine coordinates modifies the point
+		affine := FP256BN.NewECP()
+		affine.Copy(point)
+		if onCurve := FP256BN.NewECPbigs(affine.GetX(), affine.GetY()); onCurve.Is_infinity() || !onCurve.Equals(point) {
+			return fmt.Errorf("ECP point is not on the curve")
+		}
+
+		// the cofactor of the G1 curve is 1, so every point on the curve is in the subgroup
+	case *FP256BN.ECP2:
+		if point == nil || point.Is_infinity() {
+			return fmt.Errorf("point is missing or is the point at infinity")
+		}
+
+		affine := FP256BN.NewECP2()
+		affine.Copy(point)
+		if onCurve := FP256BN.NewECP2fp2s(affine.GetX(), affine.GetY()); onCurve.Is_infinity() || !onCurve.Equals(point) {
+			return fmt.Errorf("ECP2 point is not on the curve")
+		}
+
+		if !point.Mul(q).Is_infinity() {
+			return fmt.Errorf("ECP2 point is not in the prime-order subgroup")
+		}
+	default:
+		return fmt.Errorf("value of type %T is not an ECP or ECP2 point", g)
+	}
+
+	return
+}
+
+// validatePoints runs ValidatePoint on all points of the list, a missing (nil) point is an error
+func validatePoints(gs ...interface{}) (e error) {
+	for index, g := range gs {
+		if g == nil {
+			return fmt.Errorf("point %d: point is missing", index)
+		}
+		if e = ValidatePoint(g); e != nil {
+			return fmt.Errorf("point %d: %v", index, e)
+		}
+	}
+
+	return
+}
+
+// validateOptionalPoints is validatePoints for the lists where nil denotes an absent component
+// (e.g. the response for a disclosed attribute)
+func validateOptionalPoints(gs ...interface{}) (e error) {
+	for index, g := range gs {
+		if g == nil {
+			continue
+		}
+		if e = ValidatePoint(g); e != nil {
+			return fmt.Errorf("point %d: %v", index, e)
+		}
+	}
+
+	return
+}
+
 // To and from bytes
 
 // PointToBytes converts ECP or ECP2 to byte array
@@ -227,37 +340,171 @@ func bigToBytes(p *FP256BN.BIG) (result []byte) {
 	return
 }
 
-// PointFromBytes converts a byte array to ECP or ECP2
+// PointFromBytes converts a byte array to ECP or ECP2.
+// Empty byte array yields nil point and no error.
+// Returns error if the bytes do not encode (canonically) a point on the curve,
+// encode the point at infinity or a point outside the prime-order subgroup (see ValidatePoint).
 func PointFromBytes(bytes []byte) (g interface{}, e error) {
 	if len(bytes) == 0 {
 		return
 	}
 
 	if len(bytes) == _ECPByteLength {
-		g = FP256BN.ECP_fromBytes(bytes)
+		point := FP256BN.ECP_fromBytes(bytes)
+		if point.Is_infinity() {
+			return nil, fmt.Errorf("bytes do not encode an ECP point on the curve (or encode infinity)")
+		}
+		g = point
 	} else if len(bytes) == _ECP2ByteLength {
-		g = FP256BN.ECP2_fromBytes(bytes)
+		point := FP256BN.ECP2_fromBytes(bytes)
+		if point.Is_infinity() {
+			return nil, fmt.Errorf("bytes do not encode an ECP2 point on the curve (or encode infinity)")
+		}
+		g = point
 	} else {
 		return nil, fmt.Errorf("length of byte array %d does not correspond to ECP or ECP2", len(bytes))
 	}
 
+	if !bytesEqual(PointToBytes(g), bytes) {
+		return nil, fmt.Errorf("bytes are not a canonical encoding of a point")
+	}
+
+	if e = ValidatePoint(g); e != nil {
+		return nil, e
+	}
+
 	return
 }
 
-// Helpers
+// pointFromBytesInGroup is PointFromBytes that also ensures the point is in the expected group.
+// Empty byte array is only accepted (as nil point) if the point is optional.
+func pointFromBytesInGroup(bytes []byte, first bool, optional bool) (g interface{}, e error) {
+	if len(bytes) == 0 {
+		if !optional {
+			e = fmt.Errorf("required point is missing")
+		}
+		return
+	}
 
-// StringToECPb converts a string to a point on the curve.
-// It does so by hashing the string and using it as an exponent to generator.
-func StringToECPb(message string, first bool) interface{} {
+	if g, e = PointFromBytes(bytes); e != nil {
+		return nil, e
+	}
 
-	bytes := []byte(message)
+	if _, isFirst := g.(*FP256BN.ECP); isFirst != first {
+		return nil, fmt.Errorf("point is expected to be in %s", map[bool]string{true: "ECP", false: "ECP2"}[first])
+	}
 
-	a := sha3(FP256BN.NewBIGints(FP256BN.CURVE_Order), bytes)
+	return
+}
 
-	if first {
-		return FP256BN.ECP_generator().Mul(a)
+// requiredPointFromBytes is PointFromBytes that rejects empty input and reports the group of the point
+func requiredPointFromBytes(bytes []byte) (g interface{}, first bool, e error) {
+	if len(bytes) == 0 {
+		return nil, false, fmt.Errorf("required point is missing")
+	}
+
+	if g, e = PointFromBytes(bytes); e != nil {
+		return nil, false, e
+	}
+	_, first = g.(*FP256BN.ECP)
+
+	return
+}
+
+// pointsFromBytesInGroup un-marshals a list of points of the same group using pointFromBytesInGroup
+func pointsFromBytesInGroup(bytes [][]byte, first bool, optional bool) (gs []interface{}, e error) {
+	gs = make([]interface{}, len(bytes))
+	for index := 0; index < len(bytes); index++ {
+		if gs[index], e = pointFromBytesInGroup(bytes[index], first, optional); e != nil {
+			return nil, fmt.Errorf("point %d: %v", index, e)
+		}
+	}
+
+	return
+}
+
+// bigFromBytes converts a byte array to a scalar.
+// Returns error if the length is wrong or the scalar is not reduced modulo the curve order.
+func bigFromBytes(bytes []byte) (a *FP256BN.BIG, e error) {
+	if len(bytes) != _BIGByteLength {
+		return nil, fmt.Errorf("length of byte array %d does not correspond to a scalar (%d)", len(bytes), _BIGByteLength)
+	}
+
+	a = FP256BN.FromBytes(bytes)
+	if FP256BN.Comp(a, FP256BN.NewBIGints(FP256BN.CURVE_Order)) >= 0 {
+		return nil, fmt.Errorf("scalar is not less than the curve order")
+	}
+
+	return
+}
+
+// fpFromBytes converts a byte array to an element of the degree 12 extension field.
+// Returns error if the length is wrong, a coordinate is not reduced modulo p or the element is zero.
+// The element is not checked to be in the pairing group (see fpInGroup), that would cost about as much as a pairing.
+func fpFromBytes(bytes []byte) (p *FP256BN.FP12, e error) {
+	if len(bytes) != _FP12ByteLength {
+		return nil, fmt.Errorf("length of byte array %d does not correspond to a field element (%d)", len(bytes), _FP12ByteLength)
+	}
+
+	modulus := FP256BN.NewBIGints(FP256BN.Modulus)
+	zero := true
+	coordinates := make([]*FP256BN.BIG, 12)
+	for index := 0; index < 12; index++ {
+		coordinates[index] = FP256BN.FromBytes(bytes[index*_BIGByteLength : (index+1)*_BIGByteLength])
+		if FP256BN.Comp(coordinates[index], modulus) >= 0 {
+			return nil, fmt.Errorf("coordinate %d is not less than the field modulus", index)
+		}
+		zero = zero && bigEqual(coordinates[index], FP256BN.NewBIG())
 	}
-	return FP256BN.ECP2_generator().Mul(a)
+	if zero {
+		return nil, fmt.Errorf("field element is zero")
+	}
+
+	fp4 := func(offset int) *FP256BN.FP4 {
+		return FP256BN.NewFP4fp2s(
+			FP256BN.NewFP2bigs(coordinates[offset], coordinates[offset+1]),
+			FP256BN.NewFP2bigs(coordinates[offset+2], coordinates[offset+3]),
+		)
+	}
+	p = FP256BN.NewFP12fp4s(fp4(0), fp4(4), fp4(8))
+
+	return
+}
+
+// bigsFromBytes converts a list of byte arrays to scalars using bigFromBytes
+func bigsFromBytes(bytes ...[]byte) (as []*FP256BN.BIG, e error) {
+	as = make([]*FP256BN.BIG, len(bytes))
+	for index := 0; index < len(bytes); index++ {
+		if as[index], e = bigFromBytes(bytes[index]); e != nil {
+			return nil, fmt.Errorf("scalar %d: %v", index, e)
+		}
+	}
+
+	return
+}
+
+// unmarshal parses ASN1 input into target and makes sure there are no trailing bytes
+func unmarshal(input []byte, target interface{}) (e error) {
+	rest, e := asn1.Unmarshal(input, target)
+	if e != nil {
+		return
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("%d trailing bytes after ASN1 object", len(rest))
+	}
+
+	return
+}
+
+// Helpers
+
+// StringToECPb converts a string to a point on the curve.
+// It does so by hashing the string and using it as an exponent to generator.
+//
+// Deprecated: the discrete logarithm of the point is public, use AttributeFromString for attributes
+// and HashToCurve for anything else.
+func StringToECPb(message string, first bool) interface{} {
+	return AttributeFromString(message, first)
 }
 
 type eArg struct {
@@ -336,18 +583,57 @@ func eProduct(args ...*eArg) (result *FP256BN.FP12) {
 	return
 }
 
+// fpInGroup reports whether x is in the pairing group, that is x^q = 1.
+// The exponentiation is done with fpProductOfPowers, since FP12.Pow is only correct inside the group.
+func fpInGroup(x *FP256BN.FP12) bool {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	return fpProductOfPowers([]*FP256BN.FP12{x}, []*FP256BN.BIG{q}).Isunity()
+}
+
+// fpProductOfPowers computes the product of bases[k]^exponents[k] with one shared chain of squarings.
+// Unlike FP12.Pow, it does not assume the bases are in the pairing group.
+func fpProductOfPowers(bases []*FP256BN.FP12, exponents []*FP256BN.BIG) (result *FP256BN.FP12) {
+	raw := make([][]byte, len(exponents))
+	for k := range exponents {
+		raw[k] = bigToBytes(exponents[k])
+	}
+
+	result = FP256BN.NewFP12int(1)
+	started := false
+	for bit := 0; bit < 8*_BIGByteLength; bit++ {
+		if started {
+			result.Mul(FP256BN.NewFP12copy(result))
+		}
+		for k := range bases {
+			if raw[k][bit/8]>>uint(7-bit%8)&1 == 1 {
+				result.Mul(FP256BN.NewFP12copy(bases[k]))
+				started = true
+			}
+		}
+	}
+
+	return
+}
+
 func sha3(q *FP256BN.BIG, raw []byte) (result *FP256BN.BIG) {
 
-	var hash [32]byte
+	result = FP256BN.FromBytes(sha3Bytes(raw))
+	result.Mod(q)
+
+	return
+}
+
+// sha3Bytes computes the 32-byte SHA3-256 digest of raw
+func sha3Bytes(raw []byte) []byte {
+	hash := make([]byte, 32)
 	sha3 := amcl.NewSHA3(amcl.SHA3_HASH256)
 	for i := 0; i < len(raw); i++ {
 		sha3.Process(raw[i])
 	}
 	sha3.Hash(hash[:])
-	result = FP256BN.FromBytes(hash[:])
-	result.Mod(q)
 
-	return
+	return hash
 }
 
 func generatorSameGroup(a interface{}) (g interface{}) {
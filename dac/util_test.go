@@ -1,10 +1,14 @@
 package dac
 
 import (
+	"encoding/asn1"
 	"fmt"
+	"math"
+	"reflect"
 	"strconv"
 	"strings"
 	"testing"
+	"unsafe"
 
 	"gotest.tools/v3/assert"
 
@@ -22,6 +26,112 @@ func getNewRand(seed byte) (prg *amcl.RAND) {
 	return
 }
 
+// encoding of a point that is not on the curve
+func offCurveBytes(first bool) (bytes []byte) {
+	if first {
+		bytes = PointToBytes(FP256BN.ECP_generator())
+	} else {
+		bytes = PointToBytes(FP256BN.ECP2_generator())
+	}
+	bytes[len(bytes)-1] ^= 0x01
+
+	return
+}
+
+// encoding of the point at infinity
+func infinityBytes(first bool) []byte {
+	if first {
+		return PointToBytes(FP256BN.NewECP())
+	}
+	return PointToBytes(FP256BN.NewECP2())
+}
+
+// encoding of a valid point in the given group
+func pointBytes(first bool) []byte {
+	if first {
+		return PointToBytes(FP256BN.ECP_generator().Mul(FP256BN.NewBIGint(0x13)))
+	}
+	return PointToBytes(FP256BN.ECP2_generator().Mul(FP256BN.NewBIGint(0x13)))
+}
+
+// encoding of a scalar equal to the curve order (not reduced)
+func orderBytes() []byte {
+	return bigToBytes(FP256BN.NewBIGints(FP256BN.CURVE_Order))
+}
+
+// a point whose coordinates do not satisfy the curve equation.
+// AMCL constructors and deserializers return the point at infinity for such coordinates,
+// so the (unexported) y coordinate of a valid point is overwritten.
+func offCurvePoint(first bool) (point interface{}) {
+	var y reflect.Value
+	if first {
+		g := FP256BN.ECP_generator()
+		y = reflect.ValueOf(g).Elem().FieldByName("y")
+		reflect.NewAt(y.Type(), unsafe.Pointer(y.UnsafeAddr())).Elem().Set(reflect.ValueOf(FP256BN.NewFPint(0x13)))
+		point = g
+	} else {
+		g := FP256BN.ECP2_generator()
+		y = reflect.ValueOf(g).Elem().FieldByName("y")
+		reflect.NewAt(y.Type(), unsafe.Pointer(y.UnsafeAddr())).Elem().Set(reflect.ValueOf(FP256BN.NewFP2int(0x13)))
+		point = g
+	}
+
+	return
+}
+
+// a point on the twisted curve that is not in the prime-order subgroup G2
+func twistPoint() *FP256BN.ECP2 {
+	q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+	for x := 1; ; x++ {
+		point := FP256BN.NewECP2fp2(FP256BN.NewFP2int(x))
+		if !point.Is_infinity() && !point.Mul(q).Is_infinity() {
+			return point
+		}
+	}
+}
+
+// a point on the twisted curve of order dividing the cofactor (a small-subgroup point)
+func smallSubgroupPoint() *FP256BN.ECP2 {
+	return twistPoint().Mul(FP256BN.NewBIGints(FP256BN.CURVE_Order))
+}
+
+// points that are not valid group elements and have to be rejected by the verifiers
+func invalidPoints(first bool) map[string]interface{} {
+	if first {
+		return map[string]interface{}{
+			"infinity":  FP256BN.NewECP(),
+			"off curve": offCurvePoint(true),
+		}
+	}
+	return map[string]interface{}{
+		"infinity":       FP256BN.NewECP2(),
+		"off curve":      FP256BN.ECP2_fromBytes(offCurveBytes(false)),
+		"twist":          twistPoint(),
+		"small subgroup": smallSubgroupPoint(),
+	}
+}
+
+// invalid points of the same group as g
+func invalidPointsLike(g interface{}) map[string]interface{} {
+	_, first := g.(*FP256BN.ECP)
+	return invalidPoints(first)
+}
+
+// re-marshals the object after applying tamper to its marshal structure
+func remarshal(t *testing.T, bytes []byte, marshal interface{}, tamper func()) []byte {
+	rest, e := asn1.Unmarshal(bytes, marshal)
+	assert.NilError(t, e)
+	assert.Equal(t, len(rest), 0)
+
+	tamper()
+
+	bytes, e = asn1.Marshal(reflect.ValueOf(marshal).Elem().Interface())
+	assert.NilError(t, e)
+
+	return bytes
+}
+
 // verify certain assumptions on how AMCL works
 func TestAMCLAssumptions(t *testing.T) {
 
@@ -420,6 +530,107 @@ func TestMiscellaneous(t *testing.T) {
 	t.Run("PointFromBytes", func(t *testing.T) {
 		_, e := PointFromBytes(make([]byte, (_ECPByteLength+_ECP2ByteLength)/2))
 		assert.ErrorContains(t, e, "length")
+
+		for _, first := range []bool{true, false} {
+			t.Run(fmt.Sprintf("first=%t", first), func(t *testing.T) {
+				g, e := PointFromBytes(pointBytes(first))
+				assert.NilError(t, e)
+				assert.Check(t, bytesEqual(PointToBytes(g), pointBytes(first)))
+
+				g, e = PointFromBytes([]byte{})
+				assert.NilError(t, e)
+				assert.Check(t, g == nil)
+
+				_, e = PointFromBytes(offCurveBytes(first))
+				assert.ErrorContains(t, e, "curve")
+
+				_, e = PointFromBytes(infinityBytes(first))
+				assert.ErrorContains(t, e, "infinity")
+
+				_, e = pointFromBytesInGroup(pointBytes(first), !first, false)
+				assert.ErrorContains(t, e, "expected")
+
+				_, e = pointFromBytesInGroup([]byte{}, first, false)
+				assert.ErrorContains(t, e, "missing")
+			})
+		}
+
+		// compressed encoding of a valid point is not canonical
+		bytes := pointBytes(true)
+		bytes[0] = 0x02
+		_, e = PointFromBytes(bytes)
+		assert.ErrorContains(t, e, "canonical")
+	})
+
+	t.Run("ValidatePoint", func(t *testing.T) {
+		for _, first := range []bool{true, false} {
+			t.Run(fmt.Sprintf("first=%t", first), func(t *testing.T) {
+				g, _ := PointFromBytes(pointBytes(first))
+				assert.NilError(t, ValidatePoint(g))
+				assert.NilError(t, ValidatePoint(generatorSameGroup(g)))
+
+				for name, point := range invalidPoints(first) {
+					assert.Check(t, ValidatePoint(point) != nil, name)
+				}
+			})
+		}
+
+		assert.ErrorContains(t, ValidatePoint(twistPoint()), "subgroup")
+		assert.ErrorContains(t, ValidatePoint(smallSubgroupPoint()), "subgroup")
+		assert.ErrorContains(t, ValidatePoint(FP256BN.NewECP()), "infinity")
+		assert.ErrorContains(t, ValidatePoint(nil), "not an ECP")
+		assert.ErrorContains(t, ValidatePoint(FP256BN.NewBIGint(0x13)), "not an ECP")
+		assert.ErrorContains(t, ValidatePoint((*FP256BN.ECP2)(nil)), "missing")
+
+		assert.ErrorContains(t, ValidatePoint(offCurvePoint(true)), "ECP point is not on the curve")
+		assert.ErrorContains(t, ValidatePoint(offCurvePoint(false)), "ECP2 point is not on the curve")
+
+		assert.NilError(t, validatePoints(FP256BN.ECP_generator(), FP256BN.ECP2_generator()))
+		assert.ErrorContains(t, validatePoints(FP256BN.ECP_generator(), nil), "point 1: point is missing")
+		assert.ErrorContains(t, validatePoints(FP256BN.ECP_generator(), twistPoint()), "point 1")
+
+		assert.NilError(t, validateOptionalPoints(nil, FP256BN.ECP_generator(), nil))
+		assert.ErrorContains(t, validateOptionalPoints(nil, twistPoint()), "point 1")
+
+		_, e := PointFromBytes(PointToBytes(twistPoint()))
+		assert.ErrorContains(t, e, "subgroup")
+
+		_, e = PointFromBytes(PointToBytes(smallSubgroupPoint()))
+		assert.ErrorContains(t, e, "subgroup")
+	})
+
+	t.Run("bigFromBytes", func(t *testing.T) {
+		a, e := bigFromBytes(bigToBytes(FP256BN.NewBIGint(0x13)))
+		assert.NilError(t, e)
+		assert.Check(t, bigEqual(a, FP256BN.NewBIGint(0x13)))
+
+		_, e = bigFromBytes(orderBytes())
+		assert.ErrorContains(t, e, "order")
+
+		_, e = bigFromBytes(make([]byte, _BIGByteLength-1))
+		assert.ErrorContains(t, e, "length")
+	})
+
+	t.Run("bigFromInt64", func(t *testing.T) {
+		q := FP256BN.NewBIGints(FP256BN.CURVE_Order)
+
+		assert.Check(t, bigEqual(bigFromInt64(0x13), FP256BN.NewBIGint(0x13)))
+		assert.Check(t, bigEqual(bigFromInt64(-0x13), bigNegate(FP256BN.NewBIGint(0x13), q)))
+		assert.Check(t, bigEqual(bigFromInt64(0), FP256BN.NewBIGint(0)))
+
+		// 2^63 - 1 + 1 = 2^63 = -(-2^63)
+		max := bigFromInt64(math.MaxInt64)
+		max = max.Plus(FP256BN.NewBIGint(1))
+		assert.Check(t, bigEqual(bigNegate(bigFromInt64(math.MinInt64), q), max))
+	})
+
+	t.Run("unmarshal", func(t *testing.T) {
+		var target []byte
+		bytes, _ := asn1.Marshal([]byte{0x13})
+
+		assert.NilError(t, unmarshal(bytes, &target))
+		assert.ErrorContains(t, unmarshal(append(bytes, 0x13), &target), "trailing")
+		assert.ErrorContains(t, unmarshal(bytes[:len(bytes)-1], &target), "")
 	})
 
 	t.Run("subtraction and addition", func(t *testing.T) {
@@ -0,0 +1,32 @@
+// Package atomicfile replaces files so that readers never see them partially written.
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Write writes the data to a temporary file next to path and renames it over path,
+// so that the file at path is always complete
+func Write(path string, data []byte) (e error) {
+	file, e := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if e != nil {
+		return
+	}
+	defer os.Remove(file.Name())
+
+	if _, e = file.Write(data); e != nil {
+		file.Close()
+		return
+	}
+	if e = file.Sync(); e != nil {
+		file.Close()
+		return
+	}
+	if e = file.Close(); e != nil {
+		return
+	}
+
+	return os.Rename(file.Name(), path)
+}
@@ -0,0 +1,34 @@
+package atomicfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWrite(t *testing.T) {
+	directory, e := ioutil.TempDir("", "atomicfile")
+	assert.NilError(t, e)
+	defer os.RemoveAll(directory)
+
+	path := filepath.Join(directory, "state")
+
+	// creates and then replaces the file
+	for _, data := range []string{"first", "second"} {
+		assert.NilError(t, Write(path, []byte(data)))
+
+		written, e := ioutil.ReadFile(path)
+		assert.NilError(t, e)
+		assert.Equal(t, string(written), data)
+	}
+
+	// no temporary files are left behind
+	entries, e := ioutil.ReadDir(directory)
+	assert.NilError(t, e)
+	assert.Equal(t, len(entries), 1)
+
+	assert.ErrorContains(t, Write(filepath.Join(directory, "missing", "state"), []byte("data")), "no such file or directory")
+}
@@ -0,0 +1,188 @@
+// Package registry is the auditor-side registry of enrolled users.
+// It records users' public keys at issuance time and resolves the points recovered from auditing encryptions
+// back to the enrollment records.
+package registry
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dbogatov/dac-lib/dac"
+	"github.com/dbogatov/dac-lib/internal/atomicfile"
+)
+
+// Record is the enrollment record of a user
+type Record struct {
+	// PK is the user's public key encoded with dac.PointToBytes
+	PK []byte
+	// Identity is how the issuer knows the user (e.g. a name or an account)
+	Identity string
+	// Enrolled is the time of issuance
+	Enrolled time.Time
+	// Request is the serialized credential request the public key comes from
+	Request []byte
+}
+
+// TraceResult is the outcome of tracing a single auditing encryption
+type TraceResult struct {
+	// Index is the position of the encryption in the traced block
+	Index int
+	// Record is the enrollment record of the user, nil if the encryption could not be traced
+	Record *Record
+	// Error describes why the encryption could not be traced
+	Error error
+}
+
+// Registry maps users' public keys to their enrollment records.
+// It is backed by a file, every change is written to it before the call returns.
+// It is safe for concurrent use.
+type Registry struct {
+	path    string
+	mutex   sync.RWMutex
+	records map[string]Record
+}
+
+// Open loads the registry from the file at path, or starts an empty one if the file does not exist
+func Open(path string) (registry *Registry, e error) {
+	registry = &Registry{path: path, records: make(map[string]Record)}
+
+	data, e := ioutil.ReadFile(path)
+	if os.IsNotExist(e) {
+		return registry, nil
+	}
+	if e != nil {
+		return nil, fmt.Errorf("Open: %v", e)
+	}
+
+	var records []Record
+	if e = json.Unmarshal(data, &records); e != nil {
+		return nil, fmt.Errorf("Open: malformed registry %s: %v", path, e)
+	}
+
+	for _, record := range records {
+		if _, e = dac.PointFromBytes(record.PK); e != nil {
+			return nil, fmt.Errorf("Open: record of %s: %v", record.Identity, e)
+		}
+		registry.records[key(record.PK)] = record
+	}
+
+	return
+}
+
+// Enroll validates the credential request, checks that it is made for the nonce the issuer has given out
+// and records the public key under the identity.
+// Returns error if the public key is already enrolled.
+func (registry *Registry) Enroll(request *dac.CredRequest, nonce []byte, identity string) (record Record, e error) {
+	if request == nil {
+		return record, fmt.Errorf("Enroll: request is missing")
+	}
+	if e = request.Validate(); e != nil {
+		return record, fmt.Errorf("Enroll: %v", e)
+	}
+	if !bytes.Equal(request.Nonce, nonce) {
+		return record, fmt.Errorf("Enroll: request is made for another nonce")
+	}
+
+	record = Record{
+		PK:       dac.PointToBytes(request.Pk),
+		Identity: identity,
+		Enrolled: time.Now().UTC(),
+		Request:  request.ToBytes(),
+	}
+
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if existing, ok := registry.records[key(record.PK)]; ok {
+		return Record{}, fmt.Errorf("Enroll: public key is already enrolled for %s", existing.Identity)
+	}
+
+	registry.records[key(record.PK)] = record
+	if e = registry.save(); e != nil {
+		delete(registry.records, key(record.PK))
+		return Record{}, fmt.Errorf("Enroll: %v", e)
+	}
+
+	return
+}
+
+// Lookup finds the enrollment record of the public key (e.g. the one recovered with AuditingDecrypt)
+func (registry *Registry) Lookup(pk dac.PK) (record Record, e error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	record, ok := registry.records[key(dac.PointToBytes(pk))]
+	if !ok {
+		return record, fmt.Errorf("Lookup: public key is not enrolled")
+	}
+
+	return
+}
+
+// Len returns the number of enrolled users
+func (registry *Registry) Len() int {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	return len(registry.records)
+}
+
+// Trace decrypts a block of serialized auditing encryptions with the auditor's secret key
+// and resolves each of them to the enrollment record.
+// Returns a result per encryption, in order; an encryption that is malformed or whose user is not enrolled
+// gets an error in its result rather than failing the whole block.
+func (registry *Registry) Trace(audSk dac.SK, encryptions [][]byte) (results []TraceResult) {
+	results = make([]TraceResult, len(encryptions))
+
+	for index, input := range encryptions {
+		results[index].Index = index
+
+		encryption, e := dac.ParseAuditingEncryption(input)
+		if e != nil {
+			results[index].Error = fmt.Errorf("Trace: encryption %d: %v", index, e)
+			continue
+		}
+
+		record, e := registry.Lookup(encryption.AuditingDecrypt(audSk))
+		if e != nil {
+			results[index].Error = fmt.Errorf("Trace: encryption %d: %v", index, e)
+			continue
+		}
+		results[index].Record = &record
+	}
+
+	return
+}
+
+// save writes the records atomically (see atomicfile.Write), so that the file always holds a complete registry.
+// The caller must hold the write lock.
+func (registry *Registry) save() (e error) {
+	keys := make([]string, 0, len(registry.records))
+	for k := range registry.records {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	records := make([]Record, len(keys))
+	for index, k := range keys {
+		records[index] = registry.records[k]
+	}
+
+	data, e := json.MarshalIndent(records, "", "\t")
+	if e != nil {
+		return
+	}
+
+	return atomicfile.Write(registry.path, data)
+}
+
+func key(pk []byte) string {
+	return hex.EncodeToString(pk)
+}
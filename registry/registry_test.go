@@ -0,0 +1,230 @@
+package registry
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/dbogatov/dac-lib/dac"
+	"github.com/dbogatov/fabric-amcl/amcl"
+	"gotest.tools/v3/assert"
+)
+
+const SEED = 0x13
+
+func getNewRand(seed byte) (prg *amcl.RAND) {
+	prg = amcl.NewRAND()
+
+	prg.Clean()
+	prg.Seed(1, []byte{seed})
+
+	return
+}
+
+// helper that opens an empty registry in a temporary directory, the caller removes it with cleanup
+func openRegistry(t *testing.T) (registry *Registry, path string, cleanup func()) {
+	directory, e := ioutil.TempDir("", "registry")
+	assert.NilError(t, e)
+	cleanup = func() { os.RemoveAll(directory) }
+
+	path = filepath.Join(directory, "registry.json")
+
+	registry, e = Open(path)
+	assert.NilError(t, e)
+
+	return
+}
+
+// helper that enrolls n users of level L and returns their secret keys
+func enroll(t *testing.T, prg *amcl.RAND, registry *Registry, n int, L int) (sks []dac.SK, pks []dac.PK) {
+	for index := 0; index < n; index++ {
+		sk, pk := dac.GenerateKeys(prg, L)
+		nonce := []byte("nonce-" + strconv.Itoa(index))
+
+		_, e := registry.Enroll(dac.MakeCredRequest(prg, sk, nonce, L), nonce, "user-"+strconv.Itoa(index))
+		assert.NilError(t, e)
+
+		sks = append(sks, sk)
+		pks = append(pks, pk)
+	}
+
+	return
+}
+
+// Tests
+
+func TestRegistry(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		run  func(*testing.T)
+	}{
+		{"enroll and lookup", testRegistryEnrollLookup},
+		{"enroll errors", testRegistryEnrollErrors},
+		{"persistence", testRegistryPersistence},
+		{"open errors", testRegistryOpenErrors},
+		{"trace", testRegistryTrace},
+	} {
+		t.Run(test.name, test.run)
+	}
+}
+
+func testRegistryEnrollLookup(t *testing.T) {
+	prg := getNewRand(SEED)
+	registry, _, cleanup := openRegistry(t)
+	defer cleanup()
+
+	for _, L := range []int{1, 2} {
+		sk, pk := dac.GenerateKeys(prg, L)
+		request := dac.MakeCredRequest(prg, sk, []byte("nonce"), L)
+
+		record, e := registry.Enroll(request, []byte("nonce"), "alice-"+strconv.Itoa(L))
+		assert.NilError(t, e)
+		assert.DeepEqual(t, record.PK, dac.PointToBytes(pk))
+
+		found, e := registry.Lookup(pk)
+		assert.NilError(t, e)
+		assert.Equal(t, found.Identity, "alice-"+strconv.Itoa(L))
+		assert.DeepEqual(t, found.Request, request.ToBytes())
+	}
+
+	assert.Equal(t, registry.Len(), 2)
+
+	_, stranger := dac.GenerateKeys(prg, 1)
+	_, e := registry.Lookup(stranger)
+	assert.ErrorContains(t, e, "not enrolled")
+
+	_, e = registry.Lookup(nil)
+	assert.ErrorContains(t, e, "not enrolled")
+}
+
+func testRegistryEnrollErrors(t *testing.T) {
+	prg := getNewRand(SEED)
+	registry, _, cleanup := openRegistry(t)
+	defer cleanup()
+
+	sk, _ := dac.GenerateKeys(prg, 1)
+	request := dac.MakeCredRequest(prg, sk, []byte("nonce"), 1)
+
+	_, e := registry.Enroll(nil, []byte("nonce"), "alice")
+	assert.ErrorContains(t, e, "missing")
+
+	_, e = registry.Enroll(request, []byte("another nonce"), "alice")
+	assert.ErrorContains(t, e, "another nonce")
+
+	tampered := *request
+	tampered.Nonce = []byte("another nonce")
+	_, e = registry.Enroll(&tampered, []byte("another nonce"), "alice")
+	assert.ErrorContains(t, e, "verification failed")
+
+	_, e = registry.Enroll(request, []byte("nonce"), "alice")
+	assert.NilError(t, e)
+
+	_, e = registry.Enroll(request, []byte("nonce"), "mallory")
+	assert.ErrorContains(t, e, "already enrolled for alice")
+
+	assert.Equal(t, registry.Len(), 1)
+}
+
+func testRegistryPersistence(t *testing.T) {
+	prg := getNewRand(SEED)
+	registry, path, cleanup := openRegistry(t)
+	defer cleanup()
+
+	_, pks := enroll(t, prg, registry, 3, 2)
+
+	reopened, e := Open(path)
+	assert.NilError(t, e)
+	assert.Equal(t, reopened.Len(), 3)
+
+	for index, pk := range pks {
+		record, e := reopened.Lookup(pk)
+		assert.NilError(t, e)
+		assert.Equal(t, record.Identity, "user-"+strconv.Itoa(index))
+	}
+
+	// no temporary files are left behind
+	entries, e := ioutil.ReadDir(filepath.Dir(path))
+	assert.NilError(t, e)
+	assert.Equal(t, len(entries), 1)
+}
+
+func testRegistryOpenErrors(t *testing.T) {
+	_, path, cleanup := openRegistry(t)
+	defer cleanup()
+
+	assert.NilError(t, ioutil.WriteFile(path, []byte("not json"), 0600))
+	_, e := Open(path)
+	assert.ErrorContains(t, e, "malformed registry")
+
+	data, _ := json.Marshal([]Record{{PK: []byte{0x13}, Identity: "mallory"}})
+	assert.NilError(t, ioutil.WriteFile(path, data, 0600))
+	_, e = Open(path)
+	assert.ErrorContains(t, e, "record of mallory")
+
+	_, e = Open(filepath.Dir(path))
+	assert.ErrorContains(t, e, "Open")
+}
+
+func testRegistryTrace(t *testing.T) {
+	prg := getNewRand(SEED)
+	registry, _, cleanup := openRegistry(t)
+	defer cleanup()
+
+	// auditing encrypts the keys in the group of h, here G1
+	_, pks := enroll(t, prg, registry, 3, 1)
+	audSk, audPk := dac.GenerateKeys(prg, 1)
+
+	var block [][]byte
+	for _, index := range []int{2, 0, 2} {
+		encryption, _ := dac.AuditingEncrypt(prg, audPk, pks[index])
+		block = append(block, encryption.ToBytes())
+	}
+
+	_, stranger := dac.GenerateKeys(prg, 1)
+	encryption, _ := dac.AuditingEncrypt(prg, audPk, stranger)
+	block = append(block, encryption.ToBytes(), []byte{0x13})
+
+	results := registry.Trace(audSk, block)
+	assert.Equal(t, len(results), 5)
+
+	for index, expected := range []string{"user-2", "user-0", "user-2"} {
+		assert.Equal(t, results[index].Index, index)
+		assert.NilError(t, results[index].Error)
+		assert.Equal(t, results[index].Record.Identity, expected)
+	}
+
+	assert.Check(t, results[3].Record == nil)
+	assert.ErrorContains(t, results[3].Error, "encryption 3: Lookup: public key is not enrolled")
+
+	assert.Check(t, results[4].Record == nil)
+	assert.ErrorContains(t, results[4].Error, "encryption 4: ParseAuditingEncryption")
+}
+
+// Benchmarks
+
+func BenchmarkRegistryTrace(b *testing.B) {
+	prg := getNewRand(SEED)
+
+	directory, _ := ioutil.TempDir("", "registry")
+	defer os.RemoveAll(directory)
+	registry, _ := Open(filepath.Join(directory, "registry.json"))
+
+	audSk, audPk := dac.GenerateKeys(prg, 1)
+
+	var block [][]byte
+	for index := 0; index < 100; index++ {
+		sk, pk := dac.GenerateKeys(prg, 1)
+		_, _ = registry.Enroll(dac.MakeCredRequest(prg, sk, nil, 1), nil, "user-"+strconv.Itoa(index))
+
+		encryption, _ := dac.AuditingEncrypt(prg, audPk, pk)
+		block = append(block, encryption.ToBytes())
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		registry.Trace(audSk, block)
+	}
+}